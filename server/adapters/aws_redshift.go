@@ -40,6 +40,10 @@ var (
 		typing.TIMESTAMP: "timestamp",
 		typing.BOOL:      "boolean",
 		typing.UNKNOWN:   "character varying(65535)",
+		typing.JSON:      "character varying(65535)",
+		typing.ARRAY:     "character varying(65535)",
+		typing.DATE:      "date",
+		typing.DECIMAL:   "numeric(38,18)",
 	}
 )
 
@@ -12,6 +12,9 @@ import (
 	"strings"
 )
 
+//repeatedFieldPrefix is a prefix of SQL type of repeated fields (arrays) e.g. REPEATED STRING
+const repeatedFieldPrefix = "REPEATED "
+
 var (
 	SchemaToBigQueryString = map[typing.DataType]string{
 		typing.STRING:    string(bigquery.StringFieldType),
@@ -20,6 +23,10 @@ var (
 		typing.TIMESTAMP: string(bigquery.TimestampFieldType),
 		typing.BOOL:      string(bigquery.BooleanFieldType),
 		typing.UNKNOWN:   string(bigquery.StringFieldType),
+		typing.JSON:      "JSON",
+		typing.ARRAY:     repeatedFieldPrefix + string(bigquery.StringFieldType),
+		typing.DATE:      string(bigquery.DateFieldType),
+		typing.DECIMAL:   string(bigquery.NumericFieldType),
 	}
 )
 
@@ -46,7 +53,7 @@ func NewBigQuery(ctx context.Context, config *GoogleConfig, queryLogger *logging
 func (bq *BigQuery) Copy(fileKey, tableName string) error {
 	table := bq.client.Dataset(bq.config.Dataset).Table(tableName)
 
-	gcsRef := bigquery.NewGCSReference(fmt.Sprintf("gs://%s/%s", bq.config.Bucket, fileKey))
+	gcsRef := bigquery.NewGCSReference(fmt.Sprintf("gs://%s/%s", bq.config.Bucket, bq.config.ObjectKey(fileKey)))
 	gcsRef.SourceFormat = bigquery.JSON
 	loader := table.LoaderFrom(gcsRef)
 	loader.CreateDisposition = bigquery.CreateNever
@@ -95,7 +102,11 @@ func (bq *BigQuery) GetTableSchema(tableName string) (*Table, error) {
 	}
 
 	for _, field := range meta.Schema {
-		table.Columns[field.Name] = Column{SQLType: string(field.Type)}
+		sqlType := string(field.Type)
+		if field.Repeated {
+			sqlType = repeatedFieldPrefix + sqlType
+		}
+		table.Columns[field.Name] = Column{SQLType: sqlType}
 	}
 
 	return table, nil
@@ -117,12 +128,12 @@ func (bq *BigQuery) CreateTable(table *Table) error {
 
 	bqSchema := bigquery.Schema{}
 	for columnName, column := range table.Columns {
-		bigQueryType := bigquery.FieldType(strings.ToUpper(column.SQLType))
+		sqlType := column.SQLType
 		castedType, ok := bq.mappingTypeCasts[columnName]
 		if ok {
-			bigQueryType = bigquery.FieldType(strings.ToUpper(castedType))
+			sqlType = castedType
 		}
-		bqSchema = append(bqSchema, &bigquery.FieldSchema{Name: columnName, Type: bigQueryType})
+		bqSchema = append(bqSchema, bigQueryFieldSchema(columnName, sqlType))
 	}
 	bq.logQuery("Creating table for schema: ", bqSchema, true)
 	if err := bqTable.Create(bq.ctx, &bigquery.TableMetadata{Name: table.Name, Schema: bqSchema}); err != nil {
@@ -159,12 +170,12 @@ func (bq *BigQuery) PatchTableSchema(patchSchema *Table) error {
 	}
 
 	for columnName, column := range patchSchema.Columns {
-		bigQueryType := bigquery.FieldType(strings.ToUpper(column.SQLType))
+		sqlType := column.SQLType
 		castedType, ok := bq.mappingTypeCasts[columnName]
 		if ok {
-			bigQueryType = bigquery.FieldType(strings.ToUpper(castedType))
+			sqlType = castedType
 		}
-		metadata.Schema = append(metadata.Schema, &bigquery.FieldSchema{Name: columnName, Type: bigQueryType})
+		metadata.Schema = append(metadata.Schema, bigQueryFieldSchema(columnName, sqlType))
 	}
 	updateReq := bigquery.TableMetadataToUpdate{Schema: metadata.Schema}
 	bq.logQuery("Patch update request: ", updateReq, true)
@@ -196,6 +207,18 @@ func (bq *BigQuery) Close() error {
 	return bq.client.Close()
 }
 
+//bigQueryFieldSchema returns BigQuery field schema by SQL type
+//REPEATED <type> SQL types are repeated fields (arrays) of the type
+func bigQueryFieldSchema(name, sqlType string) *bigquery.FieldSchema {
+	fieldType := strings.ToUpper(strings.TrimSpace(sqlType))
+	repeated := strings.HasPrefix(fieldType, repeatedFieldPrefix)
+	if repeated {
+		fieldType = strings.TrimSpace(strings.TrimPrefix(fieldType, repeatedFieldPrefix))
+	}
+
+	return &bigquery.FieldSchema{Name: name, Type: bigquery.FieldType(fieldType), Repeated: repeated}
+}
+
 //Return true if google err is 404
 func isNotFoundErr(err error) bool {
 	e, ok := err.(*googleapi.Error)
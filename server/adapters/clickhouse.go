@@ -20,18 +20,31 @@ const (
 	tableSchemaCHQuery        = `SELECT name, type FROM system.columns WHERE database = ? and table = ?`
 	createCHDBTemplate        = `CREATE DATABASE IF NOT EXISTS "%s" %s`
 	addColumnCHTemplate       = `ALTER TABLE "%s"."%s" %s ADD COLUMN %s %s`
+	modifyColumnCHTemplate    = `ALTER TABLE "%s"."%s" %s MODIFY COLUMN %s %s`
 	insertCHTemplate          = `INSERT INTO "%s"."%s" (%s) VALUES (%s)`
 	deleteQueryChTemplate     = `ALTER TABLE %s.%s DELETE WHERE %s`
 	onClusterCHClauseTemplate = ` ON CLUSTER "%s" `
 	columnCHNullableTemplate  = ` Nullable(%s) `
 
 	createTableCHTemplate            = `CREATE TABLE "%s"."%s" %s (%s) %s %s %s %s`
-	createDistributedTableCHTemplate = `CREATE TABLE "%s"."dist_%s" %s AS "%s"."%s" ENGINE = Distributed(%s,%s,%s,rand())`
+	createDistributedTableCHTemplate = `CREATE TABLE "%s"."dist_%s" %s AS "%s"."%s" ENGINE = Distributed(%s,%s,%s,%s)`
 	dropDistributedTableCHTemplate   = `DROP TABLE "%s"."dist_%s" %s`
+	selectCurrentRowCHTemplate       = `SELECT * FROM "%s"."%s" FINAL WHERE %s AND ` + SignColumn + ` = 1 LIMIT 1`
 
 	defaultPartition  = `PARTITION BY (toYYYYMM(_timestamp))`
 	defaultOrderBy    = `ORDER BY (eventn_ctx_event_id)`
 	defaultPrimaryKey = ``
+	//defaultPKField is used for rows deduplication if primary key fields aren't configured (it is the default sorting key)
+	defaultPKField = "eventn_ctx_event_id"
+
+	//ReplacingEngine is a default tables engine: the last inserted row with the max _timestamp version
+	//is kept from rows with the same sorting key
+	ReplacingEngine = "replacing"
+	//CollapsingEngine is a tables engine which collapses pairs of rows with the same sorting key and opposite SignColumn values
+	CollapsingEngine = "collapsing"
+	//SignColumn is a CollapsingMergeTree sign column: 1 - the row state, -1 - the cancel of the row state
+	SignColumn = "_sign"
+	signCHType = "Int8"
 )
 
 var (
@@ -42,6 +55,10 @@ var (
 		typing.TIMESTAMP: "DateTime",
 		typing.BOOL:      "UInt8",
 		typing.UNKNOWN:   "String",
+		typing.JSON:      "String",
+		typing.ARRAY:     "Array(String)",
+		typing.DATE:      "Date",
+		typing.DECIMAL:   "Decimal(38,18)",
 	}
 
 	defaultValues = map[string]interface{}{
@@ -50,6 +67,14 @@ var (
 		"float32":                  0.0,
 		"float64":                  0.0,
 		"datetime":                 time.Time{},
+		"date":                     time.Time{},
+		"decimal(38,18)":           0.0,
+		"int8":                     0,
+		"array(string)":            []string{},
+		"array(int64)":             []int64{},
+		"array(float64)":           []float64{},
+		"array(datetime)":          []time.Time{},
+		"array(uint8)":             []uint8{},
 		"uint8":                    false,
 		"string":                   "",
 		"lowcardinality(int32)":    0,
@@ -62,6 +87,16 @@ var (
 	}
 )
 
+//PartialInsertError is returned from BulkInsert if inserting block has been sent but the insertion has failed:
+//ClickHouse inserts aren't transactional so part of objects might have been inserted
+type PartialInsertError struct {
+	Err error
+}
+
+func (pie *PartialInsertError) Error() string {
+	return pie.Err.Error()
+}
+
 //ClickHouseConfig dto for deserialized clickhouse config
 type ClickHouseConfig struct {
 	Dsns     []string          `mapstructure:"dsns" json:"dsns,omitempty" yaml:"dsns,omitempty"`
@@ -78,6 +113,12 @@ type EngineConfig struct {
 	PartitionFields []FieldConfig `mapstructure:"partition_fields" json:"partition_fields,omitempty" yaml:"partition_fields,omitempty"`
 	OrderFields     []FieldConfig `mapstructure:"order_fields" json:"order_fields,omitempty" yaml:"order_fields,omitempty"`
 	PrimaryKeys     []string      `mapstructure:"primary_keys" json:"primary_keys,omitempty" yaml:"primary_keys,omitempty"`
+	Type            string        `mapstructure:"type" json:"type,omitempty" yaml:"type,omitempty"`
+}
+
+//IsCollapsing returns true if CollapsingMergeTree engine is configured
+func (ec *EngineConfig) IsCollapsing() bool {
+	return ec != nil && ec.Type == CollapsingEngine
 }
 
 //FieldConfig dto for deserialized clickhouse engine fields
@@ -110,6 +151,14 @@ func (chc *ClickHouseConfig) Validate() error {
 		return errors.New("db is required parameter")
 	}
 
+	if chc.Engine != nil {
+		switch chc.Engine.Type {
+		case "", ReplacingEngine, CollapsingEngine:
+		default:
+			return fmt.Errorf("Unknown engine type: %s. Available types: [%s, %s]", chc.Engine.Type, ReplacingEngine, CollapsingEngine)
+		}
+	}
+
 	return nil
 }
 
@@ -124,9 +173,15 @@ type TableStatementFactory struct {
 	primaryKeyClause string
 
 	engineStatementFormat bool
+
+	//rows with the same primary key fields are deduplicated (they are used as a sorting and a sharding key)
+	pkFields   []string
+	collapsing bool
 }
 
-func NewTableStatementFactory(config *ClickHouseConfig) (*TableStatementFactory, error) {
+//NewTableStatementFactory returns TableStatementFactory
+//tables are sorted by primary key fields (if they are provided and order fields aren't configured)
+func NewTableStatementFactory(config *ClickHouseConfig, pkFields []string) (*TableStatementFactory, error) {
 	if config == nil {
 		return nil, errors.New("Clickhouse config can't be nil")
 	}
@@ -135,8 +190,14 @@ func NewTableStatementFactory(config *ClickHouseConfig) (*TableStatementFactory,
 		onClusterClause = fmt.Sprintf(onClusterCHClauseTemplate, config.Cluster)
 	}
 
+	sortedPKFields := append([]string{}, pkFields...)
+	sort.Strings(sortedPKFields)
+
 	partitionClause := defaultPartition
 	orderByClause := defaultOrderBy
+	if len(sortedPKFields) > 0 {
+		orderByClause = "ORDER BY (" + strings.Join(sortedPKFields, ",") + ")"
+	}
 	primaryKeyClause := defaultPrimaryKey
 	if config.Engine != nil {
 		//raw statement overrides all provided config parameters
@@ -159,15 +220,22 @@ func NewTableStatementFactory(config *ClickHouseConfig) (*TableStatementFactory,
 		}
 	}
 
+	//ReplacingMergeTree(_timestamp) or CollapsingMergeTree(_sign)
+	engine, engineParameter := "ReplacingMergeTree", "_timestamp"
+	collapsing := config.Engine.IsCollapsing()
+	if collapsing {
+		engine, engineParameter = "CollapsingMergeTree", SignColumn
+	}
+
 	var engineStatement string
 	var engineStatementFormat bool
 	if config.Cluster != "" {
-		//create engine statement with ReplicatedReplacingMergeTree() engine. We need to replace %s with tableName on creating statement
-		engineStatement = `ENGINE = ReplicatedReplacingMergeTree('/clickhouse/tables/{shard}/` + config.Database + `/%s', '{replica}', _timestamp)`
+		//create engine statement with Replicated* engine. We need to replace %s with tableName on creating statement
+		engineStatement = `ENGINE = Replicated` + engine + `('/clickhouse/tables/{shard}/` + config.Database + `/%s', '{replica}', ` + engineParameter + `)`
 		engineStatementFormat = true
 	} else {
-		//create table template with ReplacingMergeTree() engine
-		engineStatement = `ENGINE = ReplacingMergeTree(_timestamp)`
+		//create table template with *MergeTree() engine
+		engineStatement = `ENGINE = ` + engine + `(` + engineParameter + `)`
 	}
 
 	return &TableStatementFactory{
@@ -178,6 +246,8 @@ func NewTableStatementFactory(config *ClickHouseConfig) (*TableStatementFactory,
 		orderByClause:         orderByClause,
 		primaryKeyClause:      primaryKeyClause,
 		engineStatementFormat: engineStatementFormat,
+		pkFields:              sortedPKFields,
+		collapsing:            collapsing,
 	}, nil
 }
 
@@ -201,6 +271,10 @@ type ClickHouse struct {
 	nullableFields        map[string]bool
 	queryLogger           *logging.QueryLogger
 	mappingTypeCasts      map[string]string
+
+	//sorted primary key fields (from TableStatementFactory)
+	pkFields   []string
+	collapsing bool
 }
 
 //NewClickHouse return configured ClickHouse adapter instance
@@ -241,6 +315,13 @@ func NewClickHouse(ctx context.Context, connectionString, database, cluster stri
 		return nil, err
 	}
 
+	var pkFields []string
+	var collapsing bool
+	if tableStatementFactory != nil {
+		pkFields = tableStatementFactory.pkFields
+		collapsing = tableStatementFactory.collapsing
+	}
+
 	return &ClickHouse{
 		ctx:                   ctx,
 		database:              database,
@@ -250,6 +331,8 @@ func NewClickHouse(ctx context.Context, connectionString, database, cluster stri
 		nullableFields:        nullableFields,
 		queryLogger:           queryLogger,
 		mappingTypeCasts:      reformatMappings(mappingTypeCasts, SchemaToClickhouse),
+		pkFields:              pkFields,
+		collapsing:            collapsing,
 	}, nil
 }
 
@@ -291,8 +374,12 @@ func (ch *ClickHouse) CreateDB(dbName string) error {
 
 //CreateTable create database table with name,columns provided in Table representation
 //New tables will have MergeTree() or ReplicatedMergeTree() engine depends on config.cluster empty or not
+//CollapsingMergeTree tables have additional _sign column
 func (ch *ClickHouse) CreateTable(tableSchema *Table) error {
 	var columnsDDL []string
+	if _, ok := tableSchema.Columns[SignColumn]; ch.collapsing && !ok {
+		columnsDDL = append(columnsDDL, SignColumn+" "+signCHType)
+	}
 	for columnName, column := range tableSchema.Columns {
 		//get sql type
 		sqlType := column.SQLType
@@ -400,8 +487,49 @@ func (ch *ClickHouse) PatchTableSchema(patchSchema *Table) error {
 	return wrappedTx.tx.Commit()
 }
 
+//AlterColumnType changes type of the existing column (it is a mutation: existing data is converted in background)
+//recreates distributed table if cluster is configured
+func (ch *ClickHouse) AlterColumnType(tableName, columnName string, column Column) error {
+	wrappedTx, err := ch.OpenTx()
+	if err != nil {
+		return err
+	}
+
+	//get nullable or plain
+	columnTypeDDL := column.SQLType
+	if _, ok := ch.nullableFields[columnName]; ok {
+		columnTypeDDL = fmt.Sprintf(columnCHNullableTemplate, column.SQLType)
+	}
+
+	query := fmt.Sprintf(modifyColumnCHTemplate, ch.database, tableName, ch.getOnClusterClause(), columnName, columnTypeDDL)
+	ch.queryLogger.LogDDL(query)
+	alterStmt, err := wrappedTx.tx.PrepareContext(ch.ctx, query)
+	if err != nil {
+		wrappedTx.Rollback()
+		return fmt.Errorf("Error preparing modifying table %s column statement: %v", tableName, err)
+	}
+
+	if _, err = alterStmt.ExecContext(ch.ctx); err != nil {
+		wrappedTx.Rollback()
+		return fmt.Errorf("Error changing %s table column [%s] type to %s: %v", tableName, columnName, columnTypeDDL, err)
+	}
+
+	//drop and create distributed table if ReplicatedMergeTree engine
+	if ch.cluster != "" {
+		ch.dropDistributedTableInTransaction(wrappedTx, tableName)
+		ch.createDistributedTableInTransaction(tableName)
+	}
+
+	return wrappedTx.tx.Commit()
+}
+
 //Insert provided object in ClickHouse in stream mode
+//CollapsingMergeTree rows are inserted as new versions of rows (see Update)
 func (ch *ClickHouse) Insert(table *Table, valuesMap map[string]interface{}) error {
+	if ch.collapsing {
+		return ch.Update(table, []map[string]interface{}{valuesMap})
+	}
+
 	var header, placeholders []string
 	var values []interface{}
 	for name, value := range valuesMap {
@@ -415,7 +543,7 @@ func (ch *ClickHouse) Insert(table *Table, valuesMap map[string]interface{}) err
 		return err
 	}
 
-	query := fmt.Sprintf(insertCHTemplate, ch.database, table.Name, strings.Join(header, ", "), strings.Join(placeholders, ", "))
+	query := fmt.Sprintf(insertCHTemplate, ch.database, ch.insertTableName(table.Name), strings.Join(header, ", "), strings.Join(placeholders, ", "))
 	ch.queryLogger.LogQueryWithValues(query, values)
 	insertStmt, err := wrappedTx.tx.PrepareContext(ch.ctx, query)
 	if err != nil {
@@ -432,6 +560,83 @@ func (ch *ClickHouse) Insert(table *Table, valuesMap map[string]interface{}) err
 	return wrappedTx.DirectCommit()
 }
 
+//Update inserts new versions of rows in one statement:
+//ReplacingMergeTree keeps the last inserted row from rows with the same sorting key (primary key fields) and version (_timestamp)
+//current CollapsingMergeTree row with the same primary key fields is cancelled (inserted with _sign = -1)
+//only the last object is inserted from objects with the same primary key fields
+func (ch *ClickHouse) Update(table *Table, objects []map[string]interface{}) error {
+	rows := objects
+	if ch.collapsing {
+		objects = deduplicateObjects(ch.getPKFields(), objects)
+		rows = make([]map[string]interface{}, 0, len(objects)*2)
+		for _, object := range objects {
+			currentRow, err := ch.selectCurrentRow(table, object)
+			if err != nil {
+				return err
+			}
+
+			if currentRow != nil {
+				currentRow[SignColumn] = -1
+				rows = append(rows, currentRow)
+			}
+
+			//don't change the caller's object
+			row := make(map[string]interface{}, len(object)+1)
+			for k, v := range object {
+				row[k] = v
+			}
+			row[SignColumn] = 1
+			rows = append(rows, row)
+		}
+	}
+
+	return ch.BulkInsert(table, rows)
+}
+
+//selectCurrentRow returns the current state of the row with the same primary key fields values as the object
+//or nil if the row doesn't exist
+func (ch *ClickHouse) selectCurrentRow(table *Table, object map[string]interface{}) (map[string]interface{}, error) {
+	var conditions []string
+	var values []interface{}
+	for _, pkField := range ch.getPKFields() {
+		conditions = append(conditions, pkField+" = "+ch.getPlaceholder(pkField))
+		values = append(values, ch.reformatValue(object[pkField]))
+	}
+
+	query := fmt.Sprintf(selectCurrentRowCHTemplate, ch.database, ch.insertTableName(table.Name), strings.Join(conditions, " AND "))
+	ch.queryLogger.LogQueryWithValues(query, values)
+	rows, err := ch.dataSource.QueryContext(ch.ctx, query, values...)
+	if err != nil {
+		return nil, fmt.Errorf("Error selecting current row from %s table with statement: %s values: %v: %v", table.Name, query, values, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("Error getting %s table columns: %v", table.Name, err)
+	}
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	rowValues := make([]interface{}, len(columns))
+	rowPointers := make([]interface{}, len(columns))
+	for i := range rowValues {
+		rowPointers[i] = &rowValues[i]
+	}
+	if err := rows.Scan(rowPointers...); err != nil {
+		return nil, fmt.Errorf("Error scanning current row of %s table: %v", table.Name, err)
+	}
+
+	currentRow := map[string]interface{}{}
+	for i, column := range columns {
+		currentRow[column] = rowValues[i]
+	}
+
+	return currentRow, nil
+}
+
 func (ch *ClickHouse) BulkUpdate(table *Table, objects []map[string]interface{}, deleteConditions *DeleteConditions) error {
 	wrappedTx, err := ch.OpenTx()
 	if err != nil {
@@ -471,12 +676,22 @@ func (ch *ClickHouse) deleteInTransaction(wrappedTx *Transaction, table *Table,
 //BulkInsert insert objects into table in one prepared statement
 func (ch *ClickHouse) BulkInsert(table *Table, objects []map[string]interface{}) error {
 	wrappedTx, err := ch.OpenTx()
+	if err != nil {
+		return err
+	}
+
 	err = ch.insertInTransaction(wrappedTx, table, objects)
 	if err != nil {
 		wrappedTx.Rollback()
 		return err
 	}
-	return wrappedTx.DirectCommit()
+
+	//inserting block is sent on commit
+	if err := wrappedTx.DirectCommit(); err != nil {
+		return &PartialInsertError{Err: err}
+	}
+
+	return nil
 }
 
 func (ch *ClickHouse) toDeleteQuery(conditions *DeleteConditions) (string, []interface{}) {
@@ -495,8 +710,12 @@ func (ch *ClickHouse) insertInTransaction(wrappedTx *Transaction, table *Table,
 		header = append(header, name)
 		placeholders = append(placeholders, ch.getPlaceholder(name))
 	}
+	if _, ok := table.Columns[SignColumn]; ch.collapsing && !ok {
+		header = append(header, SignColumn)
+		placeholders = append(placeholders, "?")
+	}
 
-	query := fmt.Sprintf(insertCHTemplate, ch.database, table.Name, strings.Join(header, ", "), strings.Join(placeholders, ", "))
+	query := fmt.Sprintf(insertCHTemplate, ch.database, ch.insertTableName(table.Name), strings.Join(header, ", "), strings.Join(placeholders, ", "))
 	insertStmt, err := wrappedTx.tx.PrepareContext(ch.ctx, query)
 	if err != nil {
 		return fmt.Errorf("Error preparing bulk insert statement [%s] table %s statement: %v", query, table.Name, err)
@@ -508,6 +727,9 @@ func (ch *ClickHouse) insertInTransaction(wrappedTx *Transaction, table *Table,
 			value, ok := row[column]
 			if ok {
 				values = append(values, ch.reformatValue(value))
+			} else if column == SignColumn && ch.collapsing {
+				//row state
+				values = append(values, 1)
 			} else {
 				column, _ := table.Columns[column]
 				defaultValue, ok := ch.getDefaultValue(column.SQLType)
@@ -548,10 +770,38 @@ func (ch *ClickHouse) getOnClusterClause() string {
 	return fmt.Sprintf(onClusterCHClauseTemplate, ch.cluster)
 }
 
+//return primary key fields or default eventn_ctx_event_id field if they aren't configured
+func (ch *ClickHouse) getPKFields() []string {
+	if len(ch.pkFields) > 0 {
+		return ch.pkFields
+	}
+
+	return []string{defaultPKField}
+}
+
+//return distributed table name if rows are sharded by primary key fields (rows with the same primary key
+//must be written into the same shard for deduplication) otherwise table name
+func (ch *ClickHouse) insertTableName(tableName string) string {
+	if ch.cluster != "" && len(ch.pkFields) > 0 {
+		return "dist_" + tableName
+	}
+
+	return tableName
+}
+
+//return sharding key of distributed tables: hash of primary key fields or rand()
+func (ch *ClickHouse) getShardingKey() string {
+	if len(ch.pkFields) > 0 {
+		return "cityHash64(" + strings.Join(ch.pkFields, ",") + ")"
+	}
+
+	return "rand()"
+}
+
 //create distributed table, ignore errors
 func (ch *ClickHouse) createDistributedTableInTransaction(originTableName string) {
 	statement := fmt.Sprintf(createDistributedTableCHTemplate,
-		ch.database, originTableName, ch.getOnClusterClause(), ch.database, originTableName, ch.cluster, ch.database, originTableName)
+		ch.database, originTableName, ch.getOnClusterClause(), ch.database, originTableName, ch.cluster, ch.database, originTableName, ch.getShardingKey())
 	ch.queryLogger.LogDDL(statement)
 
 	_, err := ch.dataSource.ExecContext(ch.ctx, statement)
@@ -601,6 +851,7 @@ func (ch *ClickHouse) getDefaultValue(sqlType string) (interface{}, bool) {
 }
 
 //if value is boolean - reformat it [true = 1; false = 0] ClickHouse supports UInt8 instead of boolean
+//arrays of booleans are reformatted into arrays of UInt8
 //otherwise return value as is
 func (ch *ClickHouse) reformatValue(v interface{}) interface{} {
 	//reformat boolean
@@ -613,6 +864,17 @@ func (ch *ClickHouse) reformatValue(v interface{}) interface{} {
 		return 0
 	}
 
+	if booleanValues, ok := v.([]bool); ok {
+		uintValues := make([]uint8, len(booleanValues))
+		for i, booleanValue := range booleanValues {
+			if booleanValue {
+				uintValues[i] = 1
+			}
+		}
+
+		return uintValues
+	}
+
 	return v
 }
 
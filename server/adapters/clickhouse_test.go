@@ -150,7 +150,7 @@ func TestTableStatementFactory(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			factory, err := NewTableStatementFactory(tt.inputConfig)
+			factory, err := NewTableStatementFactory(tt.inputConfig, nil)
 			if tt.expectedTableStatement == "" {
 				require.Error(t, err, "Clickhouse config can't be nil")
 				return
@@ -162,3 +162,75 @@ func TestTableStatementFactory(t *testing.T) {
 		})
 	}
 }
+
+func TestTableStatementFactoryPrimaryKeyFields(t *testing.T) {
+	tests := []struct {
+		name                   string
+		inputConfig            *ClickHouseConfig
+		pkFields               []string
+		expectedTableStatement string
+	}{
+		{
+			"Primary key fields are used as sorting key",
+			&ClickHouseConfig{
+				Dsns:     []string{},
+				Database: "db1",
+			},
+			[]string{"user_id", "id"},
+			"CREATE TABLE \"db1\".\"test_table\"  (a String,b String,c String,d String) ENGINE = ReplacingMergeTree(_timestamp) PARTITION BY (toYYYYMM(_timestamp)) ORDER BY (id,user_id)",
+		},
+		{
+			"Order fields override primary key fields",
+			&ClickHouseConfig{
+				Dsns:     []string{},
+				Database: "db1",
+				Engine: &EngineConfig{
+					OrderFields: []FieldConfig{{Field: "a"}},
+				},
+			},
+			[]string{"id"},
+			"CREATE TABLE \"db1\".\"test_table\"  (a String,b String,c String,d String) ENGINE = ReplacingMergeTree(_timestamp) PARTITION BY (toYYYYMM(_timestamp)) ORDER BY (a)",
+		},
+		{
+			"Collapsing engine",
+			&ClickHouseConfig{
+				Dsns:     []string{},
+				Database: "db1",
+				Engine:   &EngineConfig{Type: CollapsingEngine},
+			},
+			[]string{"id"},
+			"CREATE TABLE \"db1\".\"test_table\"  (a String,b String,c String,d String) ENGINE = CollapsingMergeTree(_sign) PARTITION BY (toYYYYMM(_timestamp)) ORDER BY (id)",
+		},
+		{
+			"Collapsing engine with cluster",
+			&ClickHouseConfig{
+				Dsns:     []string{},
+				Database: "db1",
+				Cluster:  "cluster1",
+				Engine:   &EngineConfig{Type: CollapsingEngine},
+			},
+			[]string{"id"},
+			"CREATE TABLE \"db1\".\"test_table\"  ON CLUSTER \"cluster1\"  (a String,b String,c String,d String) ENGINE = ReplicatedCollapsingMergeTree('/clickhouse/tables/{shard}/db1/test_table', '{replica}', _sign) PARTITION BY (toYYYYMM(_timestamp)) ORDER BY (id)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			factory, err := NewTableStatementFactory(tt.inputConfig, tt.pkFields)
+			require.NoError(t, err)
+
+			actual := factory.CreateTableStatement("test_table", "a String,b String,c String,d String")
+			require.Equal(t, tt.expectedTableStatement, strings.TrimSpace(actual), "Statements aren't equal")
+		})
+	}
+}
+
+func TestEngineConfigValidate(t *testing.T) {
+	require.NoError(t, (&ClickHouseConfig{Dsns: []string{"http://localhost:8123"}, Database: "db1", Engine: &EngineConfig{Type: ReplacingEngine}}).Validate())
+	require.NoError(t, (&ClickHouseConfig{Dsns: []string{"http://localhost:8123"}, Database: "db1", Engine: &EngineConfig{Type: CollapsingEngine}}).Validate())
+	require.Error(t, (&ClickHouseConfig{Dsns: []string{"http://localhost:8123"}, Database: "db1", Engine: &EngineConfig{Type: "summing"}}).Validate())
+}
+
+func TestClickHousePKFields(t *testing.T) {
+	require.Equal(t, []string{"eventn_ctx_event_id"}, (&ClickHouse{}).getPKFields())
+	require.Equal(t, []string{"id", "user_id"}, (&ClickHouse{pkFields: []string{"id", "user_id"}}).getPKFields())
+}
@@ -0,0 +1,39 @@
+package adapters
+
+import (
+	"fmt"
+	"strings"
+)
+
+//file formats and compressions of object storages (S3, GCS) destinations
+const (
+	JSONFileFormat    = "json"
+	CSVFileFormat     = "csv"
+	ParquetFileFormat = "parquet"
+
+	GZIPCompression = "gzip"
+	ZSTDCompression = "zstd"
+)
+
+//ValidateFileLayout returns err if format or compression isn't supported
+//or if key template doesn't contain {file} placeholder (every batch would overwrite objects of the previous one)
+//empty values are allowed (json format without compression and the default key template are used)
+func ValidateFileLayout(format, compression, keyTemplate string) error {
+	switch format {
+	case "", JSONFileFormat, CSVFileFormat, ParquetFileFormat:
+	default:
+		return fmt.Errorf("format [%s] isn't supported. Supported formats: [%s, %s, %s]", format, JSONFileFormat, CSVFileFormat, ParquetFileFormat)
+	}
+
+	switch compression {
+	case "", GZIPCompression, ZSTDCompression:
+	default:
+		return fmt.Errorf("compression [%s] isn't supported. Supported compressions: [%s, %s]", compression, GZIPCompression, ZSTDCompression)
+	}
+
+	if keyTemplate != "" && !strings.Contains(keyTemplate, "{file}") {
+		return fmt.Errorf("key_template [%s] must contain {file} placeholder", keyTemplate)
+	}
+
+	return nil
+}
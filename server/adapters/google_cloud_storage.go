@@ -26,6 +26,11 @@ type GoogleConfig struct {
 	Project string      `mapstructure:"bq_project" json:"bq_project,omitempty" yaml:"bq_project,omitempty"`
 	Dataset string      `mapstructure:"bq_dataset" json:"bq_dataset,omitempty" yaml:"bq_dataset,omitempty"`
 	KeyFile interface{} `mapstructure:"key_file" json:"key_file,omitempty" yaml:"key_file,omitempty"`
+	Folder  string      `mapstructure:"folder" json:"folder,omitempty" yaml:"folder,omitempty"`
+	//GCS destination output layout (isn't used by BigQuery and Snowflake stages)
+	Format      string `mapstructure:"format" json:"format,omitempty" yaml:"format,omitempty"`
+	Compression string `mapstructure:"compression" json:"compression,omitempty" yaml:"compression,omitempty"`
+	KeyTemplate string `mapstructure:"key_template" json:"key_template,omitempty" yaml:"key_template,omitempty"`
 
 	//will be set on validation
 	credentials option.ClientOption
@@ -39,6 +44,9 @@ func (gc *GoogleConfig) Validate(streamMode bool) error {
 	if !streamMode && gc.Bucket == "" {
 		return errors.New("Google cloud storage bucket(gcs_bucket) is required parameter")
 	}
+	if err := ValidateFileLayout(gc.Format, gc.Compression, gc.KeyTemplate); err != nil {
+		return fmt.Errorf("Google cloud storage %v", err)
+	}
 
 	switch gc.KeyFile.(type) {
 	case map[string]interface{}:
@@ -68,6 +76,15 @@ func (gc *GoogleConfig) Validate(streamMode bool) error {
 	return nil
 }
 
+//ObjectKey returns key with folder prefix (if it is configured)
+func (gc *GoogleConfig) ObjectKey(key string) string {
+	if gc.Folder != "" {
+		return gc.Folder + "/" + key
+	}
+
+	return key
+}
+
 func NewGoogleCloudStorage(ctx context.Context, config *GoogleConfig) (*GoogleCloudStorage, error) {
 	client, err := storage.NewClient(ctx, config.credentials)
 	if err != nil {
@@ -80,7 +97,7 @@ func NewGoogleCloudStorage(ctx context.Context, config *GoogleConfig) (*GoogleCl
 //Create named file on google cloud storage with payload
 func (gcs *GoogleCloudStorage) UploadBytes(fileName string, fileBytes []byte) error {
 	bucket := gcs.client.Bucket(gcs.config.Bucket)
-	object := bucket.Object(fileName)
+	object := bucket.Object(gcs.config.ObjectKey(fileName))
 	w := object.NewWriter(gcs.ctx)
 
 	if _, err := w.Write(fileBytes); err != nil {
@@ -97,7 +114,7 @@ func (gcs *GoogleCloudStorage) UploadBytes(fileName string, fileBytes []byte) er
 //Return google cloud storage bucket file names filtered by prefix
 func (gcs *GoogleCloudStorage) ListBucket(prefix string) ([]string, error) {
 	bucket := gcs.client.Bucket(gcs.config.Bucket)
-	it := bucket.Objects(gcs.ctx, &storage.Query{Prefix: prefix})
+	it := bucket.Objects(gcs.ctx, &storage.Query{Prefix: gcs.config.ObjectKey(prefix)})
 	var files []string
 	for {
 		attrs, err := it.Next()
@@ -116,7 +133,7 @@ func (gcs *GoogleCloudStorage) ListBucket(prefix string) ([]string, error) {
 //Delete object from google cloud storage bucket
 func (gcs *GoogleCloudStorage) DeleteObject(key string) error {
 	bucket := gcs.client.Bucket(gcs.config.Bucket)
-	obj := bucket.Object(key)
+	obj := bucket.Object(gcs.config.ObjectKey(key))
 
 	if err := obj.Delete(gcs.ctx); err != nil {
 		return fmt.Errorf("Error deleting file %s from google cloud storage %v", key, err)
@@ -128,7 +145,7 @@ func (gcs *GoogleCloudStorage) DeleteObject(key string) error {
 //Get object from google cloud storage bucket
 func (gcs *GoogleCloudStorage) GetObject(key string) ([]byte, error) {
 	bucket := gcs.client.Bucket(gcs.config.Bucket)
-	obj := bucket.Object(key)
+	obj := bucket.Object(gcs.config.ObjectKey(key))
 
 	r, err := obj.NewReader(gcs.ctx)
 	if err != nil {
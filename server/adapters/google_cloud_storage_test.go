@@ -0,0 +1,106 @@
+package adapters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoogleConfigObjectKey(t *testing.T) {
+	require.Equal(t, "events/2021/file.log", (&GoogleConfig{}).ObjectKey("events/2021/file.log"))
+	require.Equal(t, "jitsu/events/2021/file.log", (&GoogleConfig{Folder: "jitsu"}).ObjectKey("events/2021/file.log"))
+}
+
+func TestGoogleConfigValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      *GoogleConfig
+		streamMode  bool
+		expectedErr string
+	}{
+		{
+			"empty config",
+			nil,
+			false,
+			"Google config is required",
+		},
+		{
+			"bucket is required in batch mode",
+			&GoogleConfig{KeyFile: "/key.json"},
+			false,
+			"Google cloud storage bucket(gcs_bucket) is required parameter",
+		},
+		{
+			"bucket isn't required in stream mode",
+			&GoogleConfig{KeyFile: "/key.json"},
+			true,
+			"",
+		},
+		{
+			"unknown format",
+			&GoogleConfig{Bucket: "bucket", KeyFile: "/key.json", Format: "xml"},
+			false,
+			"Google cloud storage format [xml] isn't supported. Supported formats: [json, csv, parquet]",
+		},
+		{
+			"unknown compression",
+			&GoogleConfig{Bucket: "bucket", KeyFile: "/key.json", Compression: "lz4"},
+			false,
+			"Google cloud storage compression [lz4] isn't supported. Supported compressions: [gzip, zstd]",
+		},
+		{
+			"key template without file",
+			&GoogleConfig{Bucket: "bucket", KeyFile: "/key.json", KeyTemplate: "{table}/{yyyy-mm-dd}"},
+			false,
+			"Google cloud storage key_template [{table}/{yyyy-mm-dd}] must contain {file} placeholder",
+		},
+		{
+			"empty key file",
+			&GoogleConfig{Bucket: "bucket", KeyFile: ""},
+			false,
+			"Google key file is required parameter",
+		},
+		{
+			"empty key file object",
+			&GoogleConfig{Bucket: "bucket", KeyFile: map[string]interface{}{}},
+			false,
+			"Google key_file is required parameter",
+		},
+		{
+			"wrong key file type",
+			&GoogleConfig{Bucket: "bucket", KeyFile: 1},
+			false,
+			"Google key_file must be string or json object",
+		},
+		{
+			"key file path with layout",
+			&GoogleConfig{Bucket: "bucket", KeyFile: "/key.json", Format: CSVFileFormat, Compression: GZIPCompression, KeyTemplate: "{table}/{file}"},
+			false,
+			"",
+		},
+		{
+			"key file json object",
+			&GoogleConfig{Bucket: "bucket", KeyFile: map[string]interface{}{"type": "service_account"}},
+			false,
+			"",
+		},
+		{
+			"key file json string",
+			&GoogleConfig{Bucket: "bucket", KeyFile: `{"type": "service_account"}`},
+			false,
+			"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate(tt.streamMode)
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.NotNil(t, tt.config.credentials)
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package adapters
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/jitsucom/jitsu/server/jsonutils"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/typing"
+)
+
+const (
+	defaultKafkaClientID = "jitsu"
+	maxKafkaTopicLength  = 249
+)
+
+var (
+	//Kafka doesn't use types
+	SchemaToKafka = map[typing.DataType]string{
+		typing.STRING:    "string",
+		typing.INT64:     "string",
+		typing.FLOAT64:   "string",
+		typing.TIMESTAMP: "string",
+		typing.BOOL:      "string",
+		typing.UNKNOWN:   "string",
+	}
+
+	kafkaTopicRegex = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+	kafkaCompressionCodecs = map[string]sarama.CompressionCodec{
+		"":       sarama.CompressionNone,
+		"none":   sarama.CompressionNone,
+		"gzip":   sarama.CompressionGZIP,
+		"snappy": sarama.CompressionSnappy,
+		"lz4":    sarama.CompressionLZ4,
+		"zstd":   sarama.CompressionZSTD,
+	}
+
+	kafkaSASLMechanisms = map[string]sarama.SASLMechanism{
+		"":      sarama.SASLTypePlaintext,
+		"plain": sarama.SASLTypePlaintext,
+	}
+)
+
+//KafkaConfig dto for deserialized destination config (e.g. in Kafka destination)
+//Topic is taken from data_layout.table_name_template
+type KafkaConfig struct {
+	Brokers      []string         `mapstructure:"brokers" json:"brokers,omitempty" yaml:"brokers,omitempty"`
+	ClientID     string           `mapstructure:"client_id" json:"client_id,omitempty" yaml:"client_id,omitempty"`
+	Version      string           `mapstructure:"version" json:"version,omitempty" yaml:"version,omitempty"`
+	PartitionKey string           `mapstructure:"partition_key" json:"partition_key,omitempty" yaml:"partition_key,omitempty"`
+	Compression  string           `mapstructure:"compression" json:"compression,omitempty" yaml:"compression,omitempty"`
+	TLS          bool             `mapstructure:"tls" json:"tls,omitempty" yaml:"tls,omitempty"`
+	SASL         *KafkaSASLConfig `mapstructure:"sasl" json:"sasl,omitempty" yaml:"sasl,omitempty"`
+}
+
+//KafkaSASLConfig dto for SASL/PLAIN authentication
+type KafkaSASLConfig struct {
+	Mechanism string `mapstructure:"mechanism" json:"mechanism,omitempty" yaml:"mechanism,omitempty"`
+	Username  string `mapstructure:"username" json:"username,omitempty" yaml:"username,omitempty"`
+	Password  string `mapstructure:"password" json:"password,omitempty" yaml:"password,omitempty"`
+}
+
+//Validate required fields in KafkaConfig
+func (kc *KafkaConfig) Validate() error {
+	if kc == nil {
+		return errors.New("kafka config is required")
+	}
+
+	if len(kc.Brokers) == 0 {
+		return errors.New("brokers is required parameter")
+	}
+
+	if _, ok := kafkaCompressionCodecs[strings.ToLower(kc.Compression)]; !ok {
+		return fmt.Errorf("Unsupported compression [%s]. Supported: none, gzip, snappy, lz4, zstd", kc.Compression)
+	}
+
+	if kc.Version != "" {
+		if _, err := sarama.ParseKafkaVersion(kc.Version); err != nil {
+			return fmt.Errorf("Error parsing kafka version [%s]: %v", kc.Version, err)
+		}
+	}
+
+	if kc.SASL != nil {
+		if _, ok := kafkaSASLMechanisms[strings.ToLower(kc.SASL.Mechanism)]; !ok {
+			return fmt.Errorf("Unsupported SASL mechanism [%s]. Supported: plain", kc.SASL.Mechanism)
+		}
+
+		if kc.SASL.Username == "" {
+			return errors.New("sasl.username is required parameter")
+		}
+	}
+
+	return nil
+}
+
+//Kafka adapter for producing events into Kafka topics
+//event is serialized into JSON message value, message key is taken from partition_key JSON path (if configured)
+type Kafka struct {
+	config           *KafkaConfig
+	producer         sarama.SyncProducer
+	partitionKeyPath *jsonutils.JSONPath
+	debugLogger      *logging.QueryLogger
+}
+
+//NewKafka return Kafka adapter with connected sync producer
+func NewKafka(config *KafkaConfig, requestDebugLogger *logging.QueryLogger) (*Kafka, error) {
+	saramaConfig, err := config.saramaConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	producer, err := sarama.NewSyncProducer(config.Brokers, saramaConfig)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating kafka producer: %v", err)
+	}
+
+	return NewKafkaWithProducer(config, producer, requestDebugLogger), nil
+}
+
+//NewKafkaWithProducer return Kafka adapter with the provided producer (e.g. mock producer in tests)
+func NewKafkaWithProducer(config *KafkaConfig, producer sarama.SyncProducer, requestDebugLogger *logging.QueryLogger) *Kafka {
+	return &Kafka{
+		config:           config,
+		producer:         producer,
+		partitionKeyPath: jsonutils.NewJSONPath(config.PartitionKey),
+		debugLogger:      requestDebugLogger,
+	}
+}
+
+//Send produce one object into the topic
+func (k *Kafka) Send(topic string, object map[string]interface{}) error {
+	message, err := k.toMessage(topic, object)
+	if err != nil {
+		return err
+	}
+
+	k.logMessages(message)
+
+	if _, _, err := k.producer.SendMessage(message); err != nil {
+		return fmt.Errorf("Error producing message to kafka topic [%s]: %v", topic, err)
+	}
+
+	return nil
+}
+
+//SendBatch produce all objects into the topic in one request
+//return error if at least one message hasn't been produced
+func (k *Kafka) SendBatch(topic string, objects []map[string]interface{}) error {
+	messages := make([]*sarama.ProducerMessage, 0, len(objects))
+	for _, object := range objects {
+		message, err := k.toMessage(topic, object)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, message)
+	}
+
+	k.logMessages(messages...)
+
+	if err := k.producer.SendMessages(messages); err != nil {
+		if producerErrors, ok := err.(sarama.ProducerErrors); ok {
+			return fmt.Errorf("Error producing %d of %d messages to kafka topic [%s]: %v", len(producerErrors), len(messages), topic, producerErrors[0].Err)
+		}
+		return fmt.Errorf("Error producing messages to kafka topic [%s]: %v", topic, err)
+	}
+
+	return nil
+}
+
+//GetTableSchema always return empty schema
+func (k *Kafka) GetTableSchema(tableName string) (*Table, error) {
+	return &Table{
+		Name:           tableName,
+		Columns:        Columns{},
+		PKFields:       map[string]bool{},
+		DeletePkFields: false,
+		Version:        0,
+	}, nil
+}
+
+//CreateTable Kafka doesn't use tables (topics are created by the brokers auto creation or by admins)
+func (k *Kafka) CreateTable(schemaToCreate *Table) error {
+	return nil
+}
+
+//PatchTableSchema Kafka doesn't use tables
+func (k *Kafka) PatchTableSchema(schemaToAdd *Table) error {
+	return nil
+}
+
+func (k *Kafka) Close() error {
+	return k.producer.Close()
+}
+
+//toMessage return producer message with JSON value and partition key (if configured and exists in the object)
+func (k *Kafka) toMessage(topic string, object map[string]interface{}) (*sarama.ProducerMessage, error) {
+	if err := validateKafkaTopic(topic); err != nil {
+		return nil, err
+	}
+
+	value, err := json.Marshal(object)
+	if err != nil {
+		return nil, fmt.Errorf("Error serializing object to JSON: %v", err)
+	}
+
+	message := &sarama.ProducerMessage{Topic: topic, Value: sarama.ByteEncoder(value)}
+
+	if !k.partitionKeyPath.IsEmpty() {
+		key, ok := k.partitionKeyPath.Get(object)
+		if ok && key != nil {
+			message.Key = sarama.StringEncoder(fmt.Sprint(key))
+		}
+	}
+
+	return message, nil
+}
+
+func (k *Kafka) logMessages(messages ...*sarama.ProducerMessage) {
+	if k.debugLogger == nil {
+		return
+	}
+
+	for _, message := range messages {
+		value, _ := message.Value.Encode()
+		k.debugLogger.LogQueryWithValues("PRODUCE "+message.Topic, []interface{}{string(value)})
+	}
+}
+
+//saramaConfig return sarama producer configuration
+func (kc *KafkaConfig) saramaConfig() (*sarama.Config, error) {
+	saramaConfig := sarama.NewConfig()
+	saramaConfig.ClientID = kc.ClientID
+	if saramaConfig.ClientID == "" {
+		saramaConfig.ClientID = defaultKafkaClientID
+	}
+
+	saramaConfig.Producer.Compression = kafkaCompressionCodecs[strings.ToLower(kc.Compression)]
+
+	if kc.Version != "" {
+		version, err := sarama.ParseKafkaVersion(kc.Version)
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing kafka version [%s]: %v", kc.Version, err)
+		}
+		saramaConfig.Version = version
+	} else if saramaConfig.Producer.Compression == sarama.CompressionZSTD {
+		//zstd requires at least Kafka 2.1.0
+		saramaConfig.Version = sarama.V2_1_0_0
+	}
+
+	saramaConfig.Net.DialTimeout = 10 * time.Second
+	saramaConfig.Producer.Timeout = 10 * time.Second
+	saramaConfig.Producer.RequiredAcks = sarama.WaitForAll
+	//required by sync producer
+	saramaConfig.Producer.Return.Successes = true
+	saramaConfig.Producer.Return.Errors = true
+
+	if kc.TLS {
+		saramaConfig.Net.TLS.Enable = true
+		saramaConfig.Net.TLS.Config = &tls.Config{}
+	}
+
+	if kc.SASL != nil {
+		saramaConfig.Net.SASL.Enable = true
+		saramaConfig.Net.SASL.Mechanism = kafkaSASLMechanisms[strings.ToLower(kc.SASL.Mechanism)]
+		saramaConfig.Net.SASL.User = kc.SASL.Username
+		saramaConfig.Net.SASL.Password = kc.SASL.Password
+	}
+
+	return saramaConfig, nil
+}
+
+//validateKafkaTopic return error if topic name isn't valid Kafka topic name
+func validateKafkaTopic(topic string) error {
+	if topic == "." || topic == ".." || len(topic) > maxKafkaTopicLength || !kafkaTopicRegex.MatchString(topic) {
+		return fmt.Errorf("Invalid kafka topic name [%s]: it must contain only [a-zA-Z0-9._-] and be up to %d characters long", topic, maxKafkaTopicLength)
+	}
+
+	return nil
+}
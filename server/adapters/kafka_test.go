@@ -0,0 +1,164 @@
+package adapters
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKafkaConfigValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      *KafkaConfig
+		expectedErr string
+	}{
+		{
+			"Nil config",
+			nil,
+			"kafka config is required",
+		},
+		{
+			"Empty brokers",
+			&KafkaConfig{},
+			"brokers is required parameter",
+		},
+		{
+			"Unknown compression",
+			&KafkaConfig{Brokers: []string{"localhost:9092"}, Compression: "brotli"},
+			"Unsupported compression [brotli]. Supported: none, gzip, snappy, lz4, zstd",
+		},
+		{
+			"Unknown SASL mechanism",
+			&KafkaConfig{Brokers: []string{"localhost:9092"}, SASL: &KafkaSASLConfig{Mechanism: "GSSAPI", Username: "user"}},
+			"Unsupported SASL mechanism [GSSAPI]. Supported: plain",
+		},
+		{
+			"Valid config",
+			&KafkaConfig{Brokers: []string{"localhost:9092"}, Compression: "gzip", Version: "2.6.0", PartitionKey: "/user/id"},
+			"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.expectedErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestKafkaMessage(t *testing.T) {
+	tests := []struct {
+		name         string
+		partitionKey string
+		topic        string
+		object       map[string]interface{}
+		expectedKey  sarama.Encoder
+		expectedErr  string
+	}{
+		{
+			"Without partition key",
+			"",
+			"events",
+			map[string]interface{}{"user": map[string]interface{}{"id": 1}},
+			nil,
+			"",
+		},
+		{
+			"Nested partition key",
+			"/user/id",
+			"events",
+			map[string]interface{}{"user": map[string]interface{}{"id": 1}},
+			sarama.StringEncoder("1"),
+			"",
+		},
+		{
+			"Partition key doesn't exist",
+			"/user/email",
+			"events",
+			map[string]interface{}{"user": map[string]interface{}{"id": 1}},
+			nil,
+			"",
+		},
+		{
+			"Invalid topic",
+			"",
+			"events 2021",
+			map[string]interface{}{"a": "b"},
+			nil,
+			"Invalid kafka topic name [events 2021]: it must contain only [a-zA-Z0-9._-] and be up to 249 characters long",
+		},
+		{
+			"Too long topic",
+			"",
+			strings.Repeat("a", 250),
+			map[string]interface{}{"a": "b"},
+			nil,
+			"Invalid kafka topic name [" + strings.Repeat("a", 250) + "]: it must contain only [a-zA-Z0-9._-] and be up to 249 characters long",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kafka := NewKafkaWithProducer(&KafkaConfig{PartitionKey: tt.partitionKey}, nil, nil)
+			message, err := kafka.toMessage(tt.topic, tt.object)
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.topic, message.Topic)
+			require.Equal(t, tt.expectedKey, message.Key)
+
+			value, err := message.Value.Encode()
+			require.NoError(t, err)
+			expectedValue, _ := json.Marshal(tt.object)
+			require.Equal(t, string(expectedValue), string(value))
+		})
+	}
+}
+
+func TestKafkaSend(t *testing.T) {
+	producer := mocks.NewSyncProducer(t, nil)
+	producer.ExpectSendMessageWithCheckerFunctionAndSucceed(expectJSON(`{"event_id":"1"}`))
+	producer.ExpectSendMessageAndFail(sarama.ErrNotLeaderForPartition)
+
+	kafka := NewKafkaWithProducer(&KafkaConfig{}, producer, nil)
+
+	require.NoError(t, kafka.Send("events", map[string]interface{}{"event_id": "1"}))
+	require.EqualError(t, kafka.Send("events", map[string]interface{}{"event_id": "2"}),
+		"Error producing message to kafka topic [events]: "+sarama.ErrNotLeaderForPartition.Error())
+
+	require.NoError(t, kafka.Close())
+}
+
+func TestKafkaSendBatch(t *testing.T) {
+	producer := mocks.NewSyncProducer(t, nil)
+	producer.ExpectSendMessageWithCheckerFunctionAndSucceed(expectJSON(`{"event_id":"1"}`))
+	producer.ExpectSendMessageWithCheckerFunctionAndSucceed(expectJSON(`{"event_id":"2"}`))
+
+	kafka := NewKafkaWithProducer(&KafkaConfig{}, producer, nil)
+
+	require.NoError(t, kafka.SendBatch("events", []map[string]interface{}{{"event_id": "1"}, {"event_id": "2"}}))
+	require.EqualError(t, kafka.SendBatch("bad topic", []map[string]interface{}{{"event_id": "3"}}),
+		"Invalid kafka topic name [bad topic]: it must contain only [a-zA-Z0-9._-] and be up to 249 characters long")
+
+	require.NoError(t, kafka.Close())
+}
+
+func expectJSON(expected string) mocks.ValueChecker {
+	return func(value []byte) error {
+		if string(value) != expected {
+			return errors.New("unexpected message value: " + string(value))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,166 @@
+package adapters
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/timestamp"
+)
+
+//FileConfig dto for deserialized local file destination configuration
+type FileConfig struct {
+	Path        string `mapstructure:"path" json:"path,omitempty" yaml:"path,omitempty"`
+	Format      string `mapstructure:"format" json:"format,omitempty" yaml:"format,omitempty"`
+	Compression string `mapstructure:"compression" json:"compression,omitempty" yaml:"compression,omitempty"`
+	KeyTemplate string `mapstructure:"key_template" json:"key_template,omitempty" yaml:"key_template,omitempty"`
+}
+
+//Validate required fields in FileConfig
+func (fc *FileConfig) Validate() error {
+	if fc == nil {
+		return errors.New("File config is required")
+	}
+	if fc.Path == "" {
+		return errors.New("File path is required parameter")
+	}
+	if err := ValidateFileLayout(fc.Format, fc.Compression, fc.KeyTemplate); err != nil {
+		return fmt.Errorf("File %v", err)
+	}
+
+	return nil
+}
+
+//LocalFileStorage is a Stage implementation which stores objects as files in the local directory
+//object keys are relative paths: subdirectories are created automatically
+type LocalFileStorage struct {
+	dir string
+}
+
+//NewLocalFileStorage returns LocalFileStorage and creates the directory if it doesn't exist
+func NewLocalFileStorage(config *FileConfig) (*LocalFileStorage, error) {
+	if err := os.MkdirAll(config.Path, 0755); err != nil {
+		return nil, fmt.Errorf("Error creating directory [%s]: %v", config.Path, err)
+	}
+
+	return &LocalFileStorage{dir: config.Path}, nil
+}
+
+//UploadBytes writes payload to the file atomically (via temporary file and rename)
+//so readers never see partially written files
+func (lfs *LocalFileStorage) UploadBytes(fileName string, fileBytes []byte) error {
+	filePath, err := lfs.path(fileName)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("Error creating directory for file [%s]: %v", filePath, err)
+	}
+
+	tmpFile, err := ioutil.TempFile(filepath.Dir(filePath), "."+filepath.Base(filePath)+".tmp")
+	if err != nil {
+		return fmt.Errorf("Error creating temporary file for [%s]: %v", filePath, err)
+	}
+
+	if _, err := tmpFile.Write(fileBytes); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return fmt.Errorf("Error writing file [%s]: %v", filePath, err)
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		os.Remove(tmpFile.Name())
+		return fmt.Errorf("Error closing file [%s]: %v", filePath, err)
+	}
+
+	if err := os.Rename(tmpFile.Name(), filePath); err != nil {
+		os.Remove(tmpFile.Name())
+		return fmt.Errorf("Error renaming file [%s]: %v", filePath, err)
+	}
+
+	return nil
+}
+
+//ListBucket returns relative paths of files filtered by prefix
+func (lfs *LocalFileStorage) ListBucket(prefix string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(lfs.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(lfs.dir, path)
+		if err != nil {
+			return err
+		}
+		relativePath = filepath.ToSlash(relativePath)
+		if strings.HasPrefix(relativePath, prefix) {
+			files = append(files, relativePath)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error listing directory [%s]: %v", lfs.dir, err)
+	}
+
+	return files, nil
+}
+
+//GetObject returns file content
+func (lfs *LocalFileStorage) GetObject(key string) ([]byte, error) {
+	filePath, err := lfs.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadFile(filePath)
+}
+
+//DeleteObject removes the file
+func (lfs *LocalFileStorage) DeleteObject(key string) error {
+	filePath, err := lfs.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(filePath); err != nil {
+		return fmt.Errorf("Error deleting file [%s]: %v", filePath, err)
+	}
+
+	return nil
+}
+
+//path returns absolute file path by the key
+//returns err if the key points outside of the directory (e.g. contains ../ after table name rendering)
+func (lfs *LocalFileStorage) path(key string) (string, error) {
+	filePath := filepath.Join(lfs.dir, filepath.FromSlash(key))
+	relativePath, err := filepath.Rel(lfs.dir, filePath)
+	if err != nil || relativePath == ".." || strings.HasPrefix(relativePath, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("File key [%s] points outside of the directory [%s]", key, lfs.dir)
+	}
+
+	return filePath, nil
+}
+
+//ValidateWritePermission tries to create temporary file and remove it
+func (lfs *LocalFileStorage) ValidateWritePermission() error {
+	fileName := fmt.Sprintf("test_%v", timestamp.NowUTC())
+
+	if err := lfs.UploadBytes(fileName, []byte{}); err != nil {
+		return err
+	}
+
+	return lfs.DeleteObject(fileName)
+}
+
+func (lfs *LocalFileStorage) Close() error {
+	return nil
+}
@@ -3,122 +3,65 @@ package adapters
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
-	"errors"
 	"fmt"
+	"net/url"
 	"sort"
-	"strconv"
 	"strings"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/typing"
 )
 
 const (
-	tableNamesQueryMysql  = `SELECT table_name FROM information_schema.tables WHERE table_schema=$1`
-	tableSchemaQueryMysql = `SELECT 
- 							pg_attribute.attname AS name,
-    						pg_catalog.format_type(pg_attribute.atttypid,pg_attribute.atttypmod) AS column_type
-						FROM pg_attribute
-         					JOIN pg_class ON pg_class.oid = pg_attribute.attrelid
-         					LEFT JOIN pg_attrdef pg_attrdef ON pg_attrdef.adrelid = pg_class.oid AND pg_attrdef.adnum = pg_attribute.attnum
-         					LEFT JOIN pg_namespace ON pg_namespace.oid = pg_class.relnamespace
-         					LEFT JOIN pg_constraint ON pg_constraint.conrelid = pg_class.oid AND pg_attribute.attnum = ANY (pg_constraint.conkey)
-						WHERE pg_class.relkind = 'r'::char
-  							AND  pg_namespace.nspname = $1
-  							AND pg_class.relname = $2
-  							AND pg_attribute.attnum > 0`
-	primaryKeyFieldsQueryMysql = `SELECT
-							pg_attribute.attname
-						FROM pg_index, pg_class, pg_attribute, pg_namespace
-						WHERE
-								pg_class.oid = $1::regclass AND
-								indrelid = pg_class.oid AND
-								nspname = $2 AND
-								pg_class.relnamespace = pg_namespace.oid AND
-								pg_attribute.attrelid = pg_class.oid AND
-								pg_attribute.attnum = any(pg_index.indkey)
-					  	AND indisprimary`
-	createDbSchemaIfNotExistsTemplateMysql = `CREATE SCHEMA IF NOT EXISTS "%s"`
-	addColumnTemplateMysql                 = `ALTER TABLE "%s"."%s" ADD COLUMN %s`
-	dropPrimaryKeyTemplateMysql            = "ALTER TABLE %s.%s DROP CONSTRAINT %s"
-	alterPrimaryKeyTemplateMysql           = `ALTER TABLE "%s"."%s" ADD CONSTRAINT %s PRIMARY KEY (%s)`
-	createTableTemplateMysql               = `CREATE TABLE "%s"."%s" (%s)`
-	insertTemplateMysql                    = `INSERT INTO "%s"."%s" (%s) VALUES %s`
-	mergeTemplateMysql                     = `INSERT INTO "%s"."%s"(%s) VALUES %s ON CONFLICT ON CONSTRAINT %s DO UPDATE set %s;`
-	deleteQueryTemplateMysql               = `DELETE FROM "%s"."%s" WHERE %s`
-
-	copyColumnTemplateMysql   = `UPDATE "%s"."%s" SET %s = %s`
-	dropColumnTemplateMysql   = `ALTER TABLE "%s"."%s" DROP COLUMN %s`
-	renameColumnTemplateMysql = `ALTER TABLE "%s"."%s" RENAME COLUMN %s TO %s`
-
-	mysqlValuesLimit                        = 65535 // this is a limitation of parameters one can pass as query values. If more parameters are passed, error is returned
+	tableNamesQueryMySQL       = `SELECT table_name FROM information_schema.tables WHERE table_schema = ?`
+	tableSchemaQueryMySQL      = `SELECT column_name, column_type FROM information_schema.columns WHERE table_schema = ? AND table_name = ?`
+	primaryKeyFieldsQueryMySQL = `SELECT column_name FROM information_schema.key_column_usage WHERE table_schema = ? AND table_name = ? AND constraint_name = 'PRIMARY'`
+
+	addColumnTemplateMySQL       = "ALTER TABLE `%s`.`%s` ADD COLUMN %s"
+	modifyColumnTemplateMySQL    = "ALTER TABLE `%s`.`%s` MODIFY COLUMN `%s` %s"
+	dropPrimaryKeyTemplateMySQL  = "ALTER TABLE `%s`.`%s` DROP PRIMARY KEY"
+	alterPrimaryKeyTemplateMySQL = "ALTER TABLE `%s`.`%s` ADD PRIMARY KEY (%s)"
+	createTableTemplateMySQL     = "CREATE TABLE `%s`.`%s` (%s)"
+	insertTemplateMySQL          = "INSERT INTO `%s`.`%s` (%s) VALUES %s"
+	mergeTemplateMySQL           = "INSERT INTO `%s`.`%s` (%s) VALUES %s ON DUPLICATE KEY UPDATE %s"
+	deleteQueryTemplateMySQL     = "DELETE FROM `%s`.`%s` WHERE %s"
+
+	mySQLValuesLimit = 65535 // this is a limitation of parameters one can pass as query values. If more parameters are passed, error is returned
+	//MySQL can't use TEXT columns in primary keys without a key length
+	mySQLPrimaryKeyTextType = "VARCHAR(255)"
 )
 
 var (
-	SchemaToMysql = map[typing.DataType]string{
-		typing.STRING:    "text",
-		typing.INT64:     "bigint",
-		typing.FLOAT64:   "float(38,18)",
-		typing.TIMESTAMP: "timestamp",
-		typing.BOOL:      "boolean",
-		typing.UNKNOWN:   "text",
+	SchemaToMySQL = map[typing.DataType]string{
+		typing.STRING:    "TEXT",
+		typing.INT64:     "BIGINT",
+		typing.FLOAT64:   "DOUBLE",
+		typing.TIMESTAMP: "DATETIME(6)",
+		typing.BOOL:      "BOOLEAN",
+		typing.UNKNOWN:   "TEXT",
+		typing.JSON:      "JSON",
+		typing.ARRAY:     "JSON",
+		typing.DATE:      "DATE",
+		typing.DECIMAL:   "DECIMAL(38,18)",
 	}
 )
 
-//DataSourceConfigMysql dto for deserialized datasource config (e.g. in Mysql or AwsRedshift destination)
-type DataSourceConfigMysql struct {
-	Host       string            `mapstructure:"host" json:"host,omitempty" yaml:"host,omitempty"`
-	Port       json.Number       `mapstructure:"port" json:"port,omitempty" yaml:"port,omitempty"`
-	Db         string            `mapstructure:"db" json:"db,omitempty" yaml:"db,omitempty"`
-	Schema     string            `mapstructure:"schema" json:"schema,omitempty" yaml:"schema,omitempty"`
-	Username   string            `mapstructure:"username" json:"username,omitempty" yaml:"username,omitempty"`
-	Password   string            `mapstructure:"password" json:"password,omitempty" yaml:"password,omitempty"`
-	Parameters map[string]string `mapstructure:"parameters" json:"parameters,omitempty" yaml:"parameters,omitempty"`
-}
-
-//Validate required fields in DataSourceConfigMysql
-func (dsc *DataSourceConfigMysql) Validate() error {
-	if dsc == nil {
-		return errors.New("Datasource config is required")
-	}
-	if dsc.Host == "" {
-		return errors.New("Datasource host is required parameter")
-	}
-	if dsc.Db == "" {
-		return errors.New("Datasource db is required parameter")
-	}
-	if dsc.Username == "" {
-		return errors.New("Datasource username is required parameter")
-	}
-
-	if dsc.Parameters == nil {
-		dsc.Parameters = map[string]string{}
-	}
-	return nil
-}
-
-//MySql is adapter for creating,patching (schema or table), inserting data to MySQL
-type Mysql struct {
+//MySQL is adapter for creating,patching (schema or table), inserting data to MySQL
+//DataSourceConfig.Db is used as a database (MySQL schema), DataSourceConfig.Schema is ignored
+type MySQL struct {
 	ctx         context.Context
-	config      *DataSourceConfigMysql
+	config      *DataSourceConfig
 	dataSource  *sql.DB
 	queryLogger *logging.QueryLogger
 
 	mappingTypeCasts map[string]string
 }
 
-//NewMysqlUnderRedshift return configured Mysql adapter instance without mapping old types
-func NewMysqlUnderRedshift(ctx context.Context, config *DataSourceConfigMysql, queryLogger *logging.QueryLogger, mappingTypeCasts map[string]string) (*Mysql, error) {
-	connectionString := fmt.Sprintf("host=%s port=%s dbname=%s user=%s password=%s ",
-		config.Host, config.Port.String(), config.Db, config.Username, config.Password)
-	//concat provided connection parameters
-	for k, v := range config.Parameters {
-		connectionString += k + "=" + v + " "
-	}
-	dataSource, err := sql.Open("mysql", connectionString)
+//NewMySQL return configured MySQL adapter instance
+func NewMySQL(ctx context.Context, config *DataSourceConfig, queryLogger *logging.QueryLogger, mappingTypeCasts map[string]string) (*MySQL, error) {
+	dataSource, err := sql.Open("mysql", mySQLConnectionString(config))
 	if err != nil {
 		return nil, err
 	}
@@ -131,80 +74,132 @@ func NewMysqlUnderRedshift(ctx context.Context, config *DataSourceConfigMysql, q
 	//set default value
 	dataSource.SetConnMaxLifetime(10 * time.Minute)
 
-	return &Mysql{ctx: ctx, config: config, dataSource: dataSource, queryLogger: queryLogger, mappingTypeCasts: mappingTypeCasts}, nil
+	return &MySQL{ctx: ctx, config: config, dataSource: dataSource, queryLogger: queryLogger, mappingTypeCasts: reformatMappings(mappingTypeCasts, SchemaToMySQL)}, nil
 }
 
-//NewMysql return configured Mysql adapter instance
-func NewMysql(ctx context.Context, config *DataSourceConfigMysql, queryLogger *logging.QueryLogger, mappingTypeCasts map[string]string) (*Mysql, error) {
-	connectionString := fmt.Sprintf("host=%s port=%s dbname=%s user=%s password=%s ",
-		config.Host, config.Port.String(), config.Db, config.Username, config.Password)
-	//concat provided connection parameters
-	for k, v := range config.Parameters {
-		connectionString += k + "=" + v + " "
-	}
-	dataSource, err := sql.Open("mysql", connectionString)
+func (MySQL) Name() string {
+	return "MySQL"
+}
+
+//OpenTx open underline sql transaction and return wrapped instance
+func (m *MySQL) OpenTx() (*Transaction, error) {
+	tx, err := m.dataSource.BeginTx(m.ctx, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := dataSource.Ping(); err != nil {
-		dataSource.Close()
-		return nil, err
+	return &Transaction{tx: tx, dbType: m.Name()}, nil
+}
+
+//CreateTable create database table with name,columns provided in Table representation
+//primary key is created in the same statement
+func (m *MySQL) CreateTable(table *Table) error {
+	var columnsDDL []string
+	pkFields := table.GetPKFieldsMap()
+	for columnName, column := range table.Columns {
+		columnsDDL = append(columnsDDL, m.columnDDL(columnName, column, pkFields))
 	}
 
-	//set default value
-	dataSource.SetConnMaxLifetime(10 * time.Minute)
+	//sorting columns asc
+	sort.Strings(columnsDDL)
+	if len(table.PKFields) > 0 {
+		columnsDDL = append(columnsDDL, fmt.Sprintf("PRIMARY KEY (%s)", m.quotedColumns(table.GetPKFields())))
+	}
 
-	return &Mysql{ctx: ctx, config: config, dataSource: dataSource, queryLogger: queryLogger, mappingTypeCasts: reformatMappings(mappingTypeCasts, SchemaToMysql)}, nil
-}
+	//MySQL DDL statements are committed implicitly, so transaction isn't used
+	query := fmt.Sprintf(createTableTemplateMySQL, m.config.Db, table.Name, strings.Join(columnsDDL, ","))
+	m.queryLogger.LogDDL(query)
 
-func (Mysql) Name() string {
-	return "Mysql"
+	if _, err := m.dataSource.ExecContext(m.ctx, query); err != nil {
+		return fmt.Errorf("Error creating [%s] table: %v", table.Name, err)
+	}
+
+	return nil
 }
 
-//OpenTx open underline sql transaction and return wrapped instance
-func (p *Mysql) OpenTx() (*Transaction, error) {
-	tx, err := p.dataSource.BeginTx(p.ctx, nil)
-	if err != nil {
-		return nil, err
+//PatchTableSchema add new columns(from provided Table) to existing table
+//recreate primary key (if not empty) or delete primary key if Table.DeletePkFields is true
+func (m *MySQL) PatchTableSchema(patchTable *Table) error {
+	pkFields := patchTable.GetPKFieldsMap()
+	//patch columns
+	for columnName, column := range patchTable.Columns {
+		columnDDL := m.columnDDL(columnName, column, pkFields)
+		query := fmt.Sprintf(addColumnTemplateMySQL, m.config.Db, patchTable.Name, columnDDL)
+		m.queryLogger.LogDDL(query)
+
+		if _, err := m.dataSource.ExecContext(m.ctx, query); err != nil {
+			return fmt.Errorf("Error patching %s table with [%s] DDL: %v", patchTable.Name, columnDDL, err)
+		}
 	}
 
-	return &Transaction{tx: tx, dbType: p.Name()}, nil
-}
+	//patch primary keys - delete old
+	if patchTable.DeletePkFields {
+		query := fmt.Sprintf(dropPrimaryKeyTemplateMySQL, m.config.Db, patchTable.Name)
+		m.queryLogger.LogDDL(query)
 
-//CreateDbSchema create database schema instance if doesn't exist
-func (p *Mysql) CreateDbSchema(dbSchemaName string) error {
-	wrappedTx, err := p.OpenTx()
-	if err != nil {
-		return err
+		if _, err := m.dataSource.ExecContext(m.ctx, query); err != nil {
+			return fmt.Errorf("Failed to drop primary key for table %s.%s: %v", m.config.Db, patchTable.Name, err)
+		}
 	}
 
-	return createDbSchemaInTransactionMysql(p.ctx, wrappedTx, createDbSchemaIfNotExistsTemplate, dbSchemaName, p.queryLogger)
+	//patch primary keys - create new
+	if len(patchTable.PKFields) > 0 {
+		if err := m.modifyPrimaryKeyTextColumns(patchTable); err != nil {
+			return err
+		}
+
+		query := fmt.Sprintf(alterPrimaryKeyTemplateMySQL, m.config.Db, patchTable.Name, m.quotedColumns(patchTable.GetPKFields()))
+		m.queryLogger.LogDDL(query)
+
+		if _, err := m.dataSource.ExecContext(m.ctx, query); err != nil {
+			return fmt.Errorf("Error setting primary key [%s] %s table: %v", strings.Join(patchTable.GetPKFields(), ","), patchTable.Name, err)
+		}
+	}
+
+	return nil
 }
 
-//CreateTable create database table with name,columns provided in Table representation
-func (p *Mysql) CreateTable(table *Table) error {
-	wrappedTx, err := p.OpenTx()
+//modifyPrimaryKeyTextColumns changes type of existing TEXT primary key columns to VARCHAR(255)
+//(TEXT columns can't be used in a primary key without a key length). New columns are mapped in columnDDL
+func (m *MySQL) modifyPrimaryKeyTextColumns(patchTable *Table) error {
+	dbTable, err := m.getTable(patchTable.Name)
 	if err != nil {
 		return err
 	}
 
-	return p.createTableInTransaction(wrappedTx, table)
+	for pkField := range patchTable.PKFields {
+		if _, ok := patchTable.Columns[pkField]; ok {
+			continue
+		}
+
+		column, ok := dbTable.Columns[pkField]
+		if !ok || !strings.EqualFold(column.SQLType, SchemaToMySQL[typing.STRING]) {
+			continue
+		}
+
+		if err := m.AlterColumnType(patchTable.Name, pkField, Column{SQLType: mySQLPrimaryKeyTextType + " NOT NULL"}); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
-//PatchTableSchema add new columns(from provided Table) to existing table
-func (p *Mysql) PatchTableSchema(patchTable *Table) error {
-	wrappedTx, err := p.OpenTx()
-	if err != nil {
-		return err
+//AlterColumnType changes type of the existing column (existing values are converted by MySQL)
+func (m *MySQL) AlterColumnType(tableName, columnName string, column Column) error {
+	query := fmt.Sprintf(modifyColumnTemplateMySQL, m.config.Db, tableName, columnName, column.SQLType)
+	m.queryLogger.LogDDL(query)
+
+	if _, err := m.dataSource.ExecContext(m.ctx, query); err != nil {
+		return fmt.Errorf("Error changing %s table column [%s] type to %s: %v", tableName, columnName, column.SQLType, err)
 	}
 
-	return p.patchTableSchemaInTransaction(wrappedTx, patchTable)
+	return nil
 }
 
 //GetTableSchema return table (name,columns with name and types) representation wrapped in Table struct
-func (p *Mysql) GetTableSchema(tableName string) (*Table, error) {
-	table, err := p.getTable(tableName)
+func (m *MySQL) GetTableSchema(tableName string) (*Table, error) {
+	table, err := m.getTable(tableName)
 	if err != nil {
 		return nil, err
 	}
@@ -214,7 +209,7 @@ func (p *Mysql) GetTableSchema(tableName string) (*Table, error) {
 		return table, nil
 	}
 
-	pkFields, err := p.getPrimaryKeys(tableName)
+	pkFields, err := m.getPrimaryKeys(tableName)
 	if err != nil {
 		return nil, err
 	}
@@ -223,25 +218,21 @@ func (p *Mysql) GetTableSchema(tableName string) (*Table, error) {
 	return table, nil
 }
 
-func (p *Mysql) getTable(tableName string) (*Table, error) {
+func (m *MySQL) getTable(tableName string) (*Table, error) {
 	table := &Table{Name: tableName, Columns: map[string]Column{}, PKFields: map[string]bool{}}
-	rows, err := p.dataSource.QueryContext(p.ctx, tableSchemaQuery, p.config.Schema, tableName)
+	rows, err := m.dataSource.QueryContext(m.ctx, tableSchemaQueryMySQL, m.config.Db, tableName)
 	if err != nil {
 		return nil, fmt.Errorf("Error querying table [%s] schema: %v", tableName, err)
 	}
 
 	defer rows.Close()
 	for rows.Next() {
-		var columnName, columnMysqlType string
-		if err := rows.Scan(&columnName, &columnMysqlType); err != nil {
+		var columnName, columnMySQLType string
+		if err := rows.Scan(&columnName, &columnMySQLType); err != nil {
 			return nil, fmt.Errorf("Error scanning result: %v", err)
 		}
-		if columnMysqlType == "-" {
-			//skip dropped mysql field
-			continue
-		}
 
-		table.Columns[columnName] = Column{SQLType: columnMysqlType}
+		table.Columns[columnName] = Column{SQLType: columnMySQLType}
 	}
 
 	if err := rows.Err(); err != nil {
@@ -251,324 +242,169 @@ func (p *Mysql) getTable(tableName string) (*Table, error) {
 	return table, nil
 }
 
-//create table columns and pk key
-//override input table sql type with configured cast type
-//make fields from Table PkFields - 'not null'
-func (p *Mysql) createTableInTransaction(wrappedTx *Transaction, table *Table) error {
-	var columnsDDL []string
-	pkFields := table.GetPKFieldsMap()
-	for columnName, column := range table.Columns {
-		columnsDDL = append(columnsDDL, p.columnDDL(columnName, column, pkFields))
-	}
-
-	//sorting columns asc
-	sort.Strings(columnsDDL)
-	query := fmt.Sprintf(createTableTemplate, p.config.Schema, table.Name, strings.Join(columnsDDL, ","))
-	p.queryLogger.LogDDL(query)
-
-	_, err := wrappedTx.tx.ExecContext(p.ctx, query)
-
-	if err != nil {
-		wrappedTx.Rollback()
-		return fmt.Errorf("Error creating [%s] table: %v", table.Name, err)
-	}
-
-	err = p.createPrimaryKeyInTransaction(wrappedTx, table)
+func (m *MySQL) getPrimaryKeys(tableName string) (map[string]bool, error) {
+	primaryKeys := map[string]bool{}
+	pkFieldsRows, err := m.dataSource.QueryContext(m.ctx, primaryKeyFieldsQueryMySQL, m.config.Db, tableName)
 	if err != nil {
-		wrappedTx.Rollback()
-		return err
-	}
-
-	return wrappedTx.tx.Commit()
-}
-
-//alter table with columns (if not empty)
-//recreate primary key (if not empty) or delete primary key if Table.DeletePkFields is true
-func (p *Mysql) patchTableSchemaInTransaction(wrappedTx *Transaction, patchTable *Table) error {
-	pkFields := patchTable.GetPKFieldsMap()
-	//patch columns
-	for columnName, column := range patchTable.Columns {
-		columnDDL := p.columnDDL(columnName, column, pkFields)
-		query := fmt.Sprintf(addColumnTemplate, p.config.Schema, patchTable.Name, columnDDL)
-		p.queryLogger.LogDDL(query)
-
-		_, err := wrappedTx.tx.ExecContext(p.ctx, query)
-		if err != nil {
-			wrappedTx.Rollback()
-			return fmt.Errorf("Error patching %s table with [%s] DDL: %v", patchTable.Name, columnDDL, err)
-		}
+		return nil, fmt.Errorf("Error querying primary keys for [%s.%s] table: %v", m.config.Db, tableName, err)
 	}
 
-	//patch primary keys - delete old
-	if patchTable.DeletePkFields {
-		err := p.deletePrimaryKeyInTransaction(wrappedTx, patchTable)
-		if err != nil {
-			wrappedTx.Rollback()
-			return err
+	defer pkFieldsRows.Close()
+	for pkFieldsRows.Next() {
+		var fieldName string
+		if err := pkFieldsRows.Scan(&fieldName); err != nil {
+			return nil, fmt.Errorf("error scanning primary key result: %v", err)
 		}
+		primaryKeys[fieldName] = true
 	}
-
-	//patch primary keys - create new
-	if len(patchTable.PKFields) > 0 {
-		err := p.createPrimaryKeyInTransaction(wrappedTx, patchTable)
-		if err != nil {
-			wrappedTx.Rollback()
-			return err
-		}
+	if err := pkFieldsRows.Err(); err != nil {
+		return nil, fmt.Errorf("pk last rows.Err: %v", err)
 	}
 
-	return wrappedTx.DirectCommit()
+	return primaryKeys, nil
 }
 
-//createPrimaryKeyInTransaction create primary key constraint
-//re-create fields as not null (if unable to create constraint)
-func (p *Mysql) createPrimaryKeyInTransaction(wrappedTx *Transaction, table *Table) error {
-	if len(table.PKFields) == 0 {
-		return nil
+//Insert provided object in MySQL
+//if table has primary key fields, the row is updated on duplicate key
+func (m *MySQL) Insert(table *Table, valuesMap map[string]interface{}) error {
+	header := make([]string, 0, len(valuesMap))
+	values := make([]interface{}, 0, len(valuesMap))
+	for name, value := range valuesMap {
+		header = append(header, name)
+		values = append(values, value)
 	}
 
-	query := fmt.Sprintf(alterPrimaryKeyTemplate,
-		p.config.Schema, table.Name, buildConstraintName(p.config.Schema, table.Name), strings.Join(table.GetPKFields(), ","))
-	p.queryLogger.LogDDL(query)
+	query := m.insertQuery(table, header, placeholdersMySQL(len(header)))
+	m.queryLogger.LogQueryWithValues(query, values)
 
-	_, err := wrappedTx.tx.ExecContext(p.ctx, query)
-	if err != nil {
-		return fmt.Errorf("Error setting primary key [%s] %s table: %v", strings.Join(table.GetPKFields(), ","), table.Name, err)
+	if _, err := m.dataSource.ExecContext(m.ctx, query, values...); err != nil {
+		return fmt.Errorf("Error inserting in %s table with statement: %s values: %v: %v", table.Name, query, values, err)
 	}
 
 	return nil
 }
 
-//delete primary key
-func (p *Mysql) deletePrimaryKeyInTransaction(wrappedTx *Transaction, table *Table) error {
-	query := fmt.Sprintf(dropPrimaryKeyTemplate, p.config.Schema, table.Name, buildConstraintName(p.config.Schema, table.Name))
-	p.queryLogger.LogDDL(query)
-	_, err := wrappedTx.tx.ExecContext(p.ctx, query)
+//BulkInsert insert objects into table in one transaction
+func (m *MySQL) BulkInsert(table *Table, objects []map[string]interface{}) error {
+	wrappedTx, err := m.OpenTx()
 	if err != nil {
-		return fmt.Errorf("Failed to drop primary key constraint for table %s.%s: %v", p.config.Schema, table.Name, err)
+		return err
 	}
 
-	return nil
-}
-
-//Insert provided object in mysql with typecasts
-func (p *Mysql) Insert(table *Table, valuesMap map[string]interface{}) error {
-	header, placeholders, values := p.buildQueryPayload(valuesMap)
-	query := p.insertQuery(table.GetPKFields(), table.Name, header, "("+placeholders+")")
-	p.queryLogger.LogQueryWithValues(query, values)
-
-	_, err := p.dataSource.ExecContext(p.ctx, query, values...)
-	if err != nil {
-		return fmt.Errorf("Error inserting in %s table with statement: %s values: %v: %v", table.Name, query, values, err)
+	if err = m.bulkInsertInTransaction(wrappedTx, table, objects); err != nil {
+		wrappedTx.Rollback()
+		return err
 	}
 
-	return nil
+	return wrappedTx.DirectCommit()
 }
 
-func (p *Mysql) BulkUpdate(table *Table, objects []map[string]interface{}, deleteConditions *DeleteConditions) error {
-	wrappedTx, err := p.OpenTx()
+//BulkUpdate delete rows by conditions and insert objects into table in one transaction
+func (m *MySQL) BulkUpdate(table *Table, objects []map[string]interface{}, deleteConditions *DeleteConditions) error {
+	wrappedTx, err := m.OpenTx()
 	if err != nil {
 		return err
 	}
 
 	if !deleteConditions.IsEmpty() {
-		err := p.deleteInTransaction(wrappedTx, table, deleteConditions)
-		if err != nil {
+		if err := m.deleteInTransaction(wrappedTx, table, deleteConditions); err != nil {
 			wrappedTx.Rollback()
 			return err
 		}
 	}
 
-	if err := p.bulkStoreInTransaction(wrappedTx, table, objects); err != nil {
+	if err := m.bulkInsertInTransaction(wrappedTx, table, objects); err != nil {
 		wrappedTx.Rollback()
 		return err
 	}
-	return wrappedTx.DirectCommit()
-}
 
-func (p *Mysql) deleteInTransaction(wrappedTx *Transaction, table *Table, deleteConditions *DeleteConditions) error {
-	deleteCondition, values := p.toDeleteQuery(deleteConditions)
-	query := fmt.Sprintf(deleteQueryTemplate, p.config.Schema, table.Name, deleteCondition)
-	p.queryLogger.LogQueryWithValues(query, values)
-	deleteStmt, err := wrappedTx.tx.PrepareContext(p.ctx, query)
-	if err != nil {
-		return fmt.Errorf("Error preparing delete table %s statement: %v", table.Name, err)
-	}
-	_, err = deleteStmt.ExecContext(p.ctx, values...)
-	if err != nil {
-		return fmt.Errorf("Error deleting using query: %s:, error: %v", query, err)
-	}
-	return nil
+	return wrappedTx.DirectCommit()
 }
 
-func (p *Mysql) toDeleteQuery(conditions *DeleteConditions) (string, []interface{}) {
+func (m *MySQL) deleteInTransaction(wrappedTx *Transaction, table *Table, deleteConditions *DeleteConditions) error {
 	var queryConditions []string
 	var values []interface{}
-	for i, condition := range conditions.Conditions {
-		queryConditions = append(queryConditions, condition.Field+" "+condition.Clause+" $"+strconv.Itoa(i+1)+p.castClause(condition.Field))
+	for _, condition := range deleteConditions.Conditions {
+		queryConditions = append(queryConditions, "`"+condition.Field+"` "+condition.Clause+" ?")
 		values = append(values, condition.Value)
 	}
-	return strings.Join(queryConditions, conditions.JoinCondition), values
-}
-
-func (p *Mysql) castClause(field string) string {
-	castClause := ""
-	castType, ok := p.mappingTypeCasts[field]
-	if ok {
-		castClause = "::" + castType
-	}
-	return castClause
-}
-
-//BulkInsert insert objects into table in one transaction
-func (p *Mysql) BulkInsert(table *Table, objects []map[string]interface{}) error {
-	wrappedTx, err := p.OpenTx()
-	if err != nil {
-		return err
-	}
-	if err = p.bulkStoreInTransaction(wrappedTx, table, objects); err != nil {
-		wrappedTx.Rollback()
-		return err
-	}
 
-	return wrappedTx.DirectCommit()
-}
+	query := fmt.Sprintf(deleteQueryTemplateMySQL, m.config.Db, table.Name, strings.Join(queryConditions, deleteConditions.JoinCondition))
+	m.queryLogger.LogQueryWithValues(query, values)
 
-func (p *Mysql) bulkStoreInTransaction(wrappedTx *Transaction, table *Table, objects []map[string]interface{}) error {
-	if len(table.PKFields) == 0 {
-		return p.bulkInsertInTransaction(wrappedTx, table, objects)
+	if _, err := wrappedTx.tx.ExecContext(m.ctx, query, values...); err != nil {
+		return fmt.Errorf("Error deleting using query: %s:, error: %v", query, err)
 	}
 
-	return p.bulkMergeInTransaction(wrappedTx, table, objects)
+	return nil
 }
 
-//Must be used when table has no primary keys. Inserts data in batches to improve performance.
-//Prefer to use bulkStoreInTransaction instead of calling this method directly
-func (p *Mysql) bulkInsertInTransaction(wrappedTx *Transaction, table *Table, objects []map[string]interface{}) error {
-	var placeholdersBuilder strings.Builder
+//bulkInsertInTransaction inserts data in batches to improve performance
+//if table has primary key fields, rows are updated on duplicate key
+func (m *MySQL) bulkInsertInTransaction(wrappedTx *Transaction, table *Table, objects []map[string]interface{}) error {
 	var header []string
 	for name := range table.Columns {
 		header = append(header, name)
 	}
-	maxValues := len(objects) * len(table.Columns)
-	if maxValues > mysqlValuesLimit {
-		maxValues = mysqlValuesLimit
+	sort.Strings(header)
+
+	maxValues := len(objects) * len(header)
+	if maxValues > mySQLValuesLimit {
+		maxValues = mySQLValuesLimit
 	}
+
+	var placeholders []string
 	valueArgs := make([]interface{}, 0, maxValues)
-	placeholdersCounter := 1
 	for _, row := range objects {
 		// if number of values exceeds limit, we have to execute insert query on processed rows
-		if len(valueArgs)+len(header) > mysqlValuesLimit {
-			err := p.executeInsert(wrappedTx, table, header, placeholdersBuilder, valueArgs)
-			if err != nil {
+		if len(valueArgs)+len(header) > mySQLValuesLimit {
+			if err := m.executeInsert(wrappedTx, table, header, placeholders, valueArgs); err != nil {
 				return err
 			}
-			placeholdersBuilder.Reset()
-			placeholdersCounter = 1
+			placeholders = nil
 			valueArgs = make([]interface{}, 0, maxValues)
 		}
-		_, err := placeholdersBuilder.WriteString("(")
-		if err != nil {
-			return fmt.Errorf(placeholdersStringBuildErrTemplate, err)
-		}
-		for i, column := range header {
-			value, _ := row[column]
-			valueArgs = append(valueArgs, value)
-			castClause := ""
-			castType, ok := p.mappingTypeCasts[column]
-			if ok {
-				castClause = "::" + castType
-			}
-			_, err = placeholdersBuilder.WriteString("$" + strconv.Itoa(placeholdersCounter) + castClause)
-			if err != nil {
-				return fmt.Errorf(placeholdersStringBuildErrTemplate, err)
-			}
 
-			if i < len(header)-1 {
-				_, err = placeholdersBuilder.WriteString(",")
-				if err != nil {
-					return fmt.Errorf(placeholdersStringBuildErrTemplate, err)
-				}
-			}
-			placeholdersCounter++
-		}
-		_, err = placeholdersBuilder.WriteString("),")
-		if err != nil {
-			return fmt.Errorf(placeholdersStringBuildErrTemplate, err)
+		for _, column := range header {
+			valueArgs = append(valueArgs, row[column])
 		}
+		placeholders = append(placeholders, placeholdersMySQL(len(header)))
 	}
+
 	if len(valueArgs) > 0 {
-		err := p.executeInsert(wrappedTx, table, header, placeholdersBuilder, valueArgs)
-		if err != nil {
+		if err := m.executeInsert(wrappedTx, table, header, placeholders, valueArgs); err != nil {
 			return err
 		}
 	}
+
 	return nil
 }
 
-//Must be used only if table has primary key fields. Slower than bulkInsert as each query executed separately.
-//Prefer to use bulkStoreInTransaction instead of calling this method directly
-func (p *Mysql) bulkMergeInTransaction(wrappedTx *Transaction, table *Table, objects []map[string]interface{}) error {
-	var placeholders string
-	var header []string
-	i := 1
-	for name := range table.Columns {
-		header = append(header, name)
+func (m *MySQL) executeInsert(wrappedTx *Transaction, table *Table, header []string, placeholders []string, valueArgs []interface{}) error {
+	query := m.insertQuery(table, header, strings.Join(placeholders, ","))
+	m.queryLogger.LogQueryWithValues(query, valueArgs)
 
-		//$1::type, $2::type, $3, etc
-		castClause := ""
-		castType, ok := p.mappingTypeCasts[name]
-		if ok {
-			castClause = "::" + castType
-		}
-		placeholders += "$" + strconv.Itoa(i) + castClause + ","
-
-		i++
-	}
-	placeholders = "(" + removeLastComma(placeholders) + ")"
-
-	headerClause := strings.Join(header, ",")
-	query := fmt.Sprintf(mergeTemplate, p.config.Schema, table.Name, headerClause, placeholders, buildConstraintName(p.config.Schema, table.Name), updateSectionMysql(headerClause))
-	mergeStmt, err := wrappedTx.tx.PrepareContext(p.ctx, query)
-	if err != nil {
-		return fmt.Errorf("Error preparing bulk insert statement [%s] table %s statement: %v", query, table.Name, err)
-	}
-
-	for _, row := range objects {
-		var values []interface{}
-		for _, column := range header {
-			value, _ := row[column]
-			values = append(values, value)
-		}
-		p.queryLogger.LogQueryWithValues(query, values)
-		_, err = mergeStmt.ExecContext(p.ctx, values...)
-		if err != nil {
-			return fmt.Errorf("Error bulk inserting in %s table with statement: %s values: %v: %v", table.Name, query, values, err)
-		}
+	if _, err := wrappedTx.tx.ExecContext(m.ctx, query, valueArgs...); err != nil {
+		return fmt.Errorf("Error bulk inserting in %s table with statement: %s: %v", table.Name, query, err)
 	}
 
 	return nil
 }
 
-func (p *Mysql) executeInsert(wrappedTx *Transaction, table *Table, header []string, placeholdersBuilder strings.Builder, valueArgs []interface{}) error {
-	query := p.insertQuery(table.GetPKFields(), table.Name, strings.Join(header, ","), removeLastComma(placeholdersBuilder.String()))
-	_, err := wrappedTx.tx.Exec(query, valueArgs...)
-	return err
-}
-
-//get insert statement or merge on conflict statement
-func (p *Mysql) insertQuery(pkFields []string, tableName string, header string, placeholders string) string {
-	if len(pkFields) == 0 {
-		return fmt.Sprintf(insertTemplate, p.config.Schema, tableName, header, placeholders)
-	} else {
-		return fmt.Sprintf(mergeTemplate, p.config.Schema, tableName, header, placeholders, buildConstraintName(p.config.Schema, tableName), updateSectionMysql(header))
+//insertQuery return insert statement or insert on duplicate key update statement
+func (m *MySQL) insertQuery(table *Table, header []string, placeholders string) string {
+	headerClause := m.quotedColumns(header)
+	if len(table.PKFields) == 0 {
+		return fmt.Sprintf(insertTemplateMySQL, m.config.Db, table.Name, headerClause, placeholders)
 	}
+
+	return fmt.Sprintf(mergeTemplateMySQL, m.config.Db, table.Name, headerClause, placeholders, updateSectionMySQL(header))
 }
 
-//TablesList return slice of mysql table names
-func (p *Mysql) TablesList() ([]string, error) {
+//TablesList return slice of MySQL table names
+func (m *MySQL) TablesList() ([]string, error) {
 	var tableNames []string
-	rows, err := p.dataSource.QueryContext(p.ctx, tableNamesQuery, p.config.Schema)
+	rows, err := m.dataSource.QueryContext(m.ctx, tableNamesQueryMySQL, m.config.Db)
 	if err != nil {
 		return tableNames, fmt.Errorf("Error querying tables names: %v", err)
 	}
@@ -588,106 +424,78 @@ func (p *Mysql) TablesList() ([]string, error) {
 	return tableNames, nil
 }
 
-//columnDDL return column DDL (column name, mapped sql type and 'not null' if pk field)
-func (p *Mysql) columnDDL(name string, column Column, pkFields map[string]bool) string {
+//columnDDL return column DDL (quoted column name, mapped sql type and 'not null' if pk field)
+func (m *MySQL) columnDDL(name string, column Column, pkFields map[string]bool) string {
 	var notNullClause string
 	sqlType := column.SQLType
 	//casted
-	if castedSQLType, ok := p.mappingTypeCasts[name]; ok {
+	if castedSQLType, ok := m.mappingTypeCasts[name]; ok {
 		sqlType = castedSQLType
 	}
 
 	//not null
 	if _, ok := pkFields[name]; ok {
-		notNullClause = "not null " + p.getDefaultValueStatement(sqlType)
+		if strings.EqualFold(sqlType, SchemaToMySQL[typing.STRING]) {
+			sqlType = mySQLPrimaryKeyTextType
+		}
+		notNullClause = "NOT NULL"
 	}
 
-	return fmt.Sprintf(`%s %s %s`, name, sqlType, notNullClause)
+	return strings.TrimSpace(fmt.Sprintf("`%s` %s %s", name, sqlType, notNullClause))
 }
 
-//return default value statement for creating column
-func (p *Mysql) getDefaultValueStatement(sqlType string) string {
-	//get default value based on type
-	if strings.Contains(sqlType, "var") || strings.Contains(sqlType, "text") {
-		return "default ''"
+func (m *MySQL) quotedColumns(columns []string) string {
+	quoted := make([]string, 0, len(columns))
+	for _, column := range columns {
+		quoted = append(quoted, "`"+column+"`")
 	}
 
-	return "default 0"
+	return strings.Join(quoted, ",")
 }
 
 //Close underlying sql.DB
-func (p *Mysql) Close() error {
-	return p.dataSource.Close()
+func (m *MySQL) Close() error {
+	return m.dataSource.Close()
 }
 
-func updateSectionMysql(header string) string {
-	split := strings.Split(header, ",")
-	var result string
-	for i, columnName := range split {
-		result = strings.TrimSpace(result) + columnName + "=$" + strconv.Itoa(i+1) + ","
-	}
-	return removeLastComma(result)
-}
+//mySQLConnectionString return go-sql-driver DSN with parameters from DataSourceConfig.Parameters
+func mySQLConnectionString(config *DataSourceConfig) string {
+	driverConfig := mysql.NewConfig()
+	driverConfig.User = config.Username
+	driverConfig.Passwd = config.Password
+	driverConfig.Net = "tcp"
+	driverConfig.Addr = config.Host + ":" + config.Port.String()
+	driverConfig.DBName = config.Db
 
-//create database and commit transaction
-func createDbSchemaInTransactionMysql(ctx context.Context, wrappedTx *Transaction, statementTemplate,
-	dbSchemaName string, queryLogger *logging.QueryLogger) error {
-	query := fmt.Sprintf(statementTemplate, dbSchemaName)
-	queryLogger.LogDDL(query)
-	createStmt, err := wrappedTx.tx.PrepareContext(ctx, query)
-	if err != nil {
-		wrappedTx.Rollback()
-		return fmt.Errorf("Error preparing create db schema %s statement: %v", dbSchemaName, err)
+	connectionString := driverConfig.FormatDSN()
+	if len(config.Parameters) == 0 {
+		return connectionString
 	}
 
-	_, err = createStmt.ExecContext(ctx)
-
-	if err != nil {
-		wrappedTx.Rollback()
-		return fmt.Errorf("Error creating [%s] db schema: %v", dbSchemaName, err)
+	parameters := url.Values{}
+	for k, v := range config.Parameters {
+		parameters.Set(k, v)
 	}
 
-	return wrappedTx.tx.Commit()
-}
-
-func (p *Mysql) getPrimaryKeys(tableName string) (map[string]bool, error) {
-	primaryKeys := map[string]bool{}
-	pkFieldsRows, err := p.dataSource.QueryContext(p.ctx, primaryKeyFieldsQuery, p.config.Schema+"."+tableName, p.config.Schema)
-	if err != nil {
-		return nil, fmt.Errorf("Error querying primary keys for [%s.%s] table: %v", p.config.Schema, tableName, err)
+	separator := "?"
+	if strings.Contains(connectionString, "?") {
+		separator = "&"
 	}
 
-	defer pkFieldsRows.Close()
-	var pkFields []string
-	for pkFieldsRows.Next() {
-		var fieldName string
-		if err := pkFieldsRows.Scan(&fieldName); err != nil {
-			return nil, fmt.Errorf("error scanning primary key result: %v", err)
-		}
-		pkFields = append(pkFields, fieldName)
-	}
-	if err := pkFieldsRows.Err(); err != nil {
-		return nil, fmt.Errorf("pk last rows.Err: %v", err)
-	}
-	for _, field := range pkFields {
-		primaryKeys[field] = true
-	}
+	return connectionString + separator + parameters.Encode()
+}
 
-	return primaryKeys, nil
+//placeholdersMySQL return (?,?,?) with count placeholders
+func placeholdersMySQL(count int) string {
+	return "(" + strings.TrimSuffix(strings.Repeat("?,", count), ",") + ")"
 }
 
-func (p *Mysql) buildQueryPayload(valuesMap map[string]interface{}) (string, string, []interface{}) {
-	header := make([]string, len(valuesMap), len(valuesMap))
-	placeholders := make([]string, len(valuesMap), len(valuesMap))
-	values := make([]interface{}, len(valuesMap), len(valuesMap))
-	i := 0
-	for name, value := range valuesMap {
-		header[i] = name
-		//$1::type, $2::type, $3, etc ($0 - wrong)
-		placeholders[i] = fmt.Sprintf("$%d%s", i+1, p.castClause(name))
-		values[i] = value
-		i++
+//updateSectionMySQL return `a`=VALUES(`a`),`b`=VALUES(`b`)
+func updateSectionMySQL(header []string) string {
+	updates := make([]string, 0, len(header))
+	for _, column := range header {
+		updates = append(updates, fmt.Sprintf("`%s`=VALUES(`%s`)", column, column))
 	}
 
-	return strings.Join(header, ", "), strings.Join(placeholders, ", "), values
+	return strings.Join(updates, ",")
 }
@@ -16,11 +16,12 @@ import (
 func TestBulkInsertMysql(t *testing.T) {
 	table := &Table{
 		Name:    "test_insert",
-		Columns: Columns{"field1": Column{"text"}, "field2": Column{"text"}, "field3": Column{"bigint"}},
+		Columns: Columns{"field1": Column{"TEXT"}, "field2": Column{"TEXT"}, "field3": Column{"BIGINT"}},
 	}
-	container, pg := setupMysqlDatabase(t, table)
+	container, mySQL := setupMysqlDatabase(t, table)
 	defer container.Close()
-	err := pg.BulkInsert(table, createMysqlObjects(5))
+	defer mySQL.Close()
+	err := mySQL.BulkInsert(table, createMysqlObjects(5))
 	require.NoError(t, err, "Failed to bulk insert 5 objects")
 	rows, err := container.CountRows(table.Name)
 	require.NoError(t, err, "Failed to count objects at "+table.Name)
@@ -30,37 +31,110 @@ func TestBulkInsertMysql(t *testing.T) {
 func TestBulkMergeMysql(t *testing.T) {
 	table := &Table{
 		Name:     "test_merge",
-		Columns:  Columns{"field1": Column{"text"}, "field2": Column{"text"}, "field3": Column{"bigint"}},
+		Columns:  Columns{"field1": Column{"TEXT"}, "field2": Column{"TEXT"}, "field3": Column{"BIGINT"}},
 		PKFields: map[string]bool{"field1": true},
 	}
-	container, pg := setupMysqlDatabase(t, table)
+	container, mySQL := setupMysqlDatabase(t, table)
 	defer container.Close()
+	defer mySQL.Close()
+
+	dbTable, err := mySQL.GetTableSchema(table.Name)
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{"field1": true}, dbTable.PKFields)
+
 	// store 8 objects with 3 id duplications, the result must be 5 objects
 	objects := createMysqlObjects(5)
 	objects = append(objects, objects[0])
 	objects = append(objects, objects[2])
 	objects = append(objects, objects[3])
-	err := pg.BulkInsert(table, objects)
+	err = mySQL.BulkInsert(table, objects)
 	require.NoError(t, err, "Failed to bulk merge objects")
 	rows, err := container.CountRows(table.Name)
 	require.NoError(t, err, "Failed to count objects at "+table.Name)
 	assert.Equal(t, rows, 5)
+
+	//streaming insert updates the row on duplicate key
+	err = mySQL.Insert(table, map[string]interface{}{"field1": objects[0]["field1"], "field2": "updated", "field3": 1})
+	require.NoError(t, err)
+	updated, err := container.GetAllSortedRows(table.Name, fmt.Sprintf("WHERE field1 = '%s'", objects[0]["field1"]))
+	require.NoError(t, err)
+	require.Len(t, updated, 1)
+	require.Equal(t, "updated", updated[0]["field2"])
+}
+
+func TestBulkUpdateMysql(t *testing.T) {
+	table := &Table{
+		Name:    "test_update",
+		Columns: Columns{"field1": Column{"TEXT"}, "field2": Column{"TEXT"}, "field3": Column{"BIGINT"}},
+	}
+	container, mySQL := setupMysqlDatabase(t, table)
+	defer container.Close()
+	defer mySQL.Close()
+
+	objects := createMysqlObjects(5)
+	require.NoError(t, mySQL.BulkInsert(table, objects))
+
+	deleteConditions := &DeleteConditions{
+		Conditions:    []DeleteCondition{{Field: "field1", Value: objects[0]["field1"], Clause: "="}},
+		JoinCondition: "AND",
+	}
+	require.NoError(t, mySQL.BulkUpdate(table, createMysqlObjects(2), deleteConditions))
+
+	rows, err := container.CountRows(table.Name)
+	require.NoError(t, err)
+	assert.Equal(t, rows, 6)
+}
+
+func TestPatchTableSchemaMysql(t *testing.T) {
+	table := &Table{
+		Name:    "test_patch",
+		Columns: Columns{"field1": Column{"TEXT"}},
+	}
+	container, mySQL := setupMysqlDatabase(t, table)
+	defer container.Close()
+	defer mySQL.Close()
+
+	err := mySQL.PatchTableSchema(&Table{Name: table.Name, Columns: Columns{"field2": Column{"DATETIME(6)"}}})
+	require.NoError(t, err)
+
+	dbTable, err := mySQL.GetTableSchema(table.Name)
+	require.NoError(t, err)
+	require.Equal(t, Columns{"field1": Column{"text"}, "field2": Column{"datetime(6)"}}, dbTable.Columns)
+}
+
+func TestPatchTablePrimaryKeyMysql(t *testing.T) {
+	table := &Table{
+		Name:    "test_patch_pk",
+		Columns: Columns{"field1": Column{"TEXT"}, "field2": Column{"TEXT"}},
+	}
+	container, mySQL := setupMysqlDatabase(t, table)
+	defer container.Close()
+	defer mySQL.Close()
+
+	err := mySQL.PatchTableSchema(&Table{Name: table.Name, Columns: Columns{"field3": Column{"TEXT"}}, PKFields: map[string]bool{"field1": true, "field3": true}})
+	require.NoError(t, err)
+
+	dbTable, err := mySQL.GetTableSchema(table.Name)
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{"field1": true, "field3": true}, dbTable.PKFields)
+	require.Equal(t, Columns{"field1": Column{"varchar(255)"}, "field2": Column{"text"}, "field3": Column{"varchar(255)"}}, dbTable.Columns)
 }
 
-func setupMysqlDatabase(t *testing.T, table *Table) (*test.MysqlContainer, *Mysql) {
+func setupMysqlDatabase(t *testing.T, table *Table) (*test.MySQLContainer, *MySQL) {
 	ctx := context.Background()
-	container, err := test.NewMysqlContainer(ctx)
+	container, err := test.NewMySQLContainer(ctx)
 	if err != nil {
 		t.Fatalf("failed to initialize container: %v", err)
 	}
-	dsConfig := &DataSourceConfigMysql{Host: container.Host, Port: json.Number(fmt.Sprint(container.Port)), Username: container.Username, Password: container.Password, Db: container.Database, Schema: container.Schema, Parameters: map[string]string{"sslmode": "disable"}}
-	pg, err := NewMysql(ctx, dsConfig, &logging.QueryLogger{}, map[string]string{})
+	dsConfig := &DataSourceConfig{Host: container.Host, Port: json.Number(fmt.Sprint(container.Port)), Username: container.Username, Password: container.Password, Db: container.Database}
+	mySQL, err := NewMySQL(ctx, dsConfig, &logging.QueryLogger{}, map[string]string{})
 	if err != nil {
-		t.Fatalf("Failed to create Mysql adapter: %v", err)
+		container.Close()
+		t.Fatalf("Failed to create MySQL adapter: %v", err)
 	}
-	err = pg.CreateTable(table)
+	err = mySQL.CreateTable(table)
 	require.NoError(t, err, "Failed to create table")
-	return container, pg
+	return container, mySQL
 }
 
 func createMysqlObjects(num int) []map[string]interface{} {
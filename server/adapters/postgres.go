@@ -7,16 +7,22 @@ import (
 	"errors"
 	"fmt"
 	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/timestamp"
 	"github.com/jitsucom/jitsu/server/typing"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
 const (
-	tableNamesQuery  = `SELECT table_name FROM information_schema.tables WHERE table_schema=$1`
+	tableNamesQuery = `SELECT table_name FROM information_schema.tables WHERE table_schema=$1 AND table_name NOT IN (
+							SELECT child.relname FROM pg_inherits
+								JOIN pg_class child ON child.oid = pg_inherits.inhrelid
+								JOIN pg_namespace ON pg_namespace.oid = child.relnamespace
+							WHERE pg_namespace.nspname = $1)`
 	tableSchemaQuery = `SELECT 
  							pg_attribute.attname AS name,
     						pg_catalog.format_type(pg_attribute.atttypid,pg_attribute.atttypmod) AS column_type
@@ -25,7 +31,7 @@ const (
          					LEFT JOIN pg_attrdef pg_attrdef ON pg_attrdef.adrelid = pg_class.oid AND pg_attrdef.adnum = pg_attribute.attnum
          					LEFT JOIN pg_namespace ON pg_namespace.oid = pg_class.relnamespace
          					LEFT JOIN pg_constraint ON pg_constraint.conrelid = pg_class.oid AND pg_attribute.attnum = ANY (pg_constraint.conkey)
-						WHERE pg_class.relkind = 'r'::char
+						WHERE pg_class.relkind IN ('r'::char, 'p'::char)
   							AND  pg_namespace.nspname = $1
   							AND pg_class.relname = $2
   							AND pg_attribute.attnum > 0`
@@ -49,12 +55,32 @@ const (
 	mergeTemplate                     = `INSERT INTO "%s"."%s"(%s) VALUES %s ON CONFLICT ON CONSTRAINT %s DO UPDATE set %s;`
 	deleteQueryTemplate               = `DELETE FROM "%s"."%s" WHERE %s`
 
-	copyColumnTemplate   = `UPDATE "%s"."%s" SET %s = %s`
-	dropColumnTemplate   = `ALTER TABLE "%s"."%s" DROP COLUMN %s`
-	renameColumnTemplate = `ALTER TABLE "%s"."%s" RENAME COLUMN %s TO %s`
+	createTempTableTemplate        = `CREATE TEMP TABLE "%s" (LIKE "%s"."%s") ON COMMIT DROP`
+	mergeFromTempTableTemplate     = `INSERT INTO "%s"."%s"(%s) SELECT %s FROM "%s" ON CONFLICT ON CONSTRAINT %s DO UPDATE SET %s`
+	createPartitionedTableTemplate = `CREATE TABLE "%s"."%s" (%s) PARTITION BY RANGE (%s)`
+	createPartitionTemplate        = `CREATE TABLE IF NOT EXISTS "%s"."%s" PARTITION OF "%s"."%s" FOR VALUES FROM ('%s') TO ('%s')`
+	createDefaultPartitionTemplate = `CREATE TABLE IF NOT EXISTS "%s"."%s" PARTITION OF "%s"."%s" DEFAULT`
+	partitionedTableQuery          = `SELECT count(*) FROM pg_partitioned_table
+							JOIN pg_class ON pg_class.oid = pg_partitioned_table.partrelid
+							JOIN pg_namespace ON pg_namespace.oid = pg_class.relnamespace
+						WHERE pg_namespace.nspname = $1 AND pg_class.relname = $2`
+
+	copyColumnTemplate      = `UPDATE "%s"."%s" SET %s = %s`
+	dropColumnTemplate      = `ALTER TABLE "%s"."%s" DROP COLUMN %s`
+	renameColumnTemplate    = `ALTER TABLE "%s"."%s" RENAME COLUMN %s TO %s`
+	alterColumnTypeTemplate = `ALTER TABLE "%s"."%s" ALTER COLUMN %s TYPE %s USING %s::%s`
 
 	placeholdersStringBuildErrTemplate = `Error building placeholders string: %v`
 	postgresValuesLimit                = 65535 // this is a limitation of parameters one can pass as query values. If more parameters are passed, error is returned
+
+	//PartitioningMonthly is a Postgres tables partitioning by _timestamp months
+	PartitioningMonthly = "monthly"
+	//PartitioningDaily is a Postgres tables partitioning by _timestamp days
+	PartitioningDaily = "daily"
+
+	defaultCopyThreshold = 1000
+	partitionDateLayout  = "2006-01-02 15:04:05"
+	defaultPartitionName = "default"
 )
 
 var (
@@ -65,6 +91,10 @@ var (
 		typing.TIMESTAMP: "timestamp",
 		typing.BOOL:      "boolean",
 		typing.UNKNOWN:   "text",
+		typing.JSON:      "jsonb",
+		typing.ARRAY:     "text[]",
+		typing.DATE:      "date",
+		typing.DECIMAL:   "numeric",
 	}
 )
 
@@ -100,6 +130,60 @@ func (dsc *DataSourceConfig) Validate() error {
 	return nil
 }
 
+//PostgresConfig dto for deserialized Postgres destination specific config
+//(bulk loading via COPY and tables partitioning by _timestamp)
+type PostgresConfig struct {
+	Partitioning  string `mapstructure:"partitioning" json:"partitioning,omitempty" yaml:"partitioning,omitempty"`
+	CopyThreshold int    `mapstructure:"copy_threshold" json:"copy_threshold,omitempty" yaml:"copy_threshold,omitempty"`
+}
+
+//Validate partitioning value
+func (pc *PostgresConfig) Validate() error {
+	if pc == nil {
+		return nil
+	}
+
+	switch pc.Partitioning {
+	case "", PartitioningMonthly, PartitioningDaily:
+		return nil
+	default:
+		return fmt.Errorf("Unknown partitioning: %s. Available values: [%s, %s]", pc.Partitioning, PartitioningMonthly, PartitioningDaily)
+	}
+}
+
+//ValidatePrimaryKeyFields returns err if tables are partitioned and primary key fields don't include _timestamp:
+//Postgres requires every unique constraint of a partitioned table to include the partition key
+func (pc *PostgresConfig) ValidatePrimaryKeyFields(pkFields map[string]bool) error {
+	if pc.GetPartitioning() == "" || len(pkFields) == 0 || pkFields[timestamp.Key] {
+		return nil
+	}
+
+	return fmt.Errorf("primary_key_fields must include %s field if postgres.partitioning is configured", timestamp.Key)
+}
+
+//GetPartitioning returns configured partitioning or empty string if tables aren't partitioned
+func (pc *PostgresConfig) GetPartitioning() string {
+	if pc == nil {
+		return ""
+	}
+
+	return pc.Partitioning
+}
+
+//GetCopyThreshold returns min objects count which are loaded via COPY (1000 by default)
+//or 0 if COPY is disabled (negative copy_threshold)
+func (pc *PostgresConfig) GetCopyThreshold() int {
+	if pc == nil || pc.CopyThreshold == 0 {
+		return defaultCopyThreshold
+	}
+
+	if pc.CopyThreshold < 0 {
+		return 0
+	}
+
+	return pc.CopyThreshold
+}
+
 //Postgres is adapter for creating,patching (schema or table), inserting data to postgres
 type Postgres struct {
 	ctx         context.Context
@@ -108,6 +192,13 @@ type Postgres struct {
 	queryLogger *logging.QueryLogger
 
 	mappingTypeCasts map[string]string
+
+	//0 means COPY is disabled
+	copyThreshold int
+	partitioning  string
+	//table name -> the last period start which partitions have been ensured for
+	ensuredPartitions map[string]time.Time
+	partitionsMutex   sync.Mutex
 }
 
 //NewPostgresUnderRedshift return configured Postgres adapter instance without mapping old types
@@ -135,7 +226,7 @@ func NewPostgresUnderRedshift(ctx context.Context, config *DataSourceConfig, que
 }
 
 //NewPostgres return configured Postgres adapter instance
-func NewPostgres(ctx context.Context, config *DataSourceConfig, queryLogger *logging.QueryLogger, mappingTypeCasts map[string]string) (*Postgres, error) {
+func NewPostgres(ctx context.Context, config *DataSourceConfig, queryLogger *logging.QueryLogger, mappingTypeCasts map[string]string, pgConfig *PostgresConfig) (*Postgres, error) {
 	connectionString := fmt.Sprintf("host=%s port=%s dbname=%s user=%s password=%s ",
 		config.Host, config.Port.String(), config.Db, config.Username, config.Password)
 	//concat provided connection parameters
@@ -155,7 +246,8 @@ func NewPostgres(ctx context.Context, config *DataSourceConfig, queryLogger *log
 	//set default value
 	dataSource.SetConnMaxLifetime(10 * time.Minute)
 
-	return &Postgres{ctx: ctx, config: config, dataSource: dataSource, queryLogger: queryLogger, mappingTypeCasts: reformatMappings(mappingTypeCasts, SchemaToPostgres)}, nil
+	return &Postgres{ctx: ctx, config: config, dataSource: dataSource, queryLogger: queryLogger, mappingTypeCasts: reformatMappings(mappingTypeCasts, SchemaToPostgres),
+		copyThreshold: pgConfig.GetCopyThreshold(), partitioning: pgConfig.GetPartitioning(), ensuredPartitions: map[string]time.Time{}}, nil
 }
 
 func (Postgres) Name() string {
@@ -202,6 +294,18 @@ func (p *Postgres) PatchTableSchema(patchTable *Table) error {
 	return p.patchTableSchemaInTransaction(wrappedTx, patchTable)
 }
 
+//AlterColumnType changes type of the existing column (existing values are casted to the new type)
+func (p *Postgres) AlterColumnType(tableName, columnName string, column Column) error {
+	query := fmt.Sprintf(alterColumnTypeTemplate, p.config.Schema, tableName, columnName, column.SQLType, columnName, column.SQLType)
+	p.queryLogger.LogDDL(query)
+
+	if _, err := p.dataSource.ExecContext(p.ctx, query); err != nil {
+		return fmt.Errorf("Error changing %s table column [%s] type to %s: %v", tableName, columnName, column.SQLType, err)
+	}
+
+	return nil
+}
+
 //GetTableSchema return table (name,columns with name and types) representation wrapped in Table struct
 func (p *Postgres) GetTableSchema(tableName string) (*Table, error) {
 	table, err := p.getTable(tableName)
@@ -263,7 +367,11 @@ func (p *Postgres) createTableInTransaction(wrappedTx *Transaction, table *Table
 
 	//sorting columns asc
 	sort.Strings(columnsDDL)
+	partitioned := p.isPartitionable(table)
 	query := fmt.Sprintf(createTableTemplate, p.config.Schema, table.Name, strings.Join(columnsDDL, ","))
+	if partitioned {
+		query = fmt.Sprintf(createPartitionedTableTemplate, p.config.Schema, table.Name, strings.Join(columnsDDL, ","), timestamp.Key)
+	}
 	p.queryLogger.LogDDL(query)
 
 	_, err := wrappedTx.tx.ExecContext(p.ctx, query)
@@ -279,7 +387,97 @@ func (p *Postgres) createTableInTransaction(wrappedTx *Transaction, table *Table
 		return err
 	}
 
-	return wrappedTx.tx.Commit()
+	//default partition keeps rows which don't fit into created partitions
+	if partitioned {
+		query := fmt.Sprintf(createDefaultPartitionTemplate, p.config.Schema, table.Name+"_"+defaultPartitionName, p.config.Schema, table.Name)
+		p.queryLogger.LogDDL(query)
+
+		if _, err := wrappedTx.tx.ExecContext(p.ctx, query); err != nil {
+			wrappedTx.Rollback()
+			return fmt.Errorf("Error creating [%s] table default partition: %v", table.Name, err)
+		}
+	}
+
+	if err := wrappedTx.tx.Commit(); err != nil {
+		return err
+	}
+
+	//partitions of a new table must be created
+	p.partitionsMutex.Lock()
+	delete(p.ensuredPartitions, table.Name)
+	p.partitionsMutex.Unlock()
+
+	return nil
+}
+
+//isPartitionable returns true if partitioning is configured and the table has _timestamp column
+//and primary key (if exists) includes _timestamp (otherwise the table is created without partitioning)
+func (p *Postgres) isPartitionable(table *Table) bool {
+	if p.partitioning == "" {
+		return false
+	}
+
+	if _, ok := table.Columns[timestamp.Key]; !ok {
+		return false
+	}
+
+	if len(table.PKFields) > 0 && !table.PKFields[timestamp.Key] {
+		logging.Warnf("Table [%s] will be created without partitioning: primary key fields [%s] don't include %s", table.Name, strings.Join(table.GetPKFields(), ", "), timestamp.Key)
+		return false
+	}
+
+	return true
+}
+
+//EnsurePartitions creates partitions of the period which contains now and of the next period
+//if the table is partitioned (see PostgresConfig). Partitions are checked once per period:
+//the period is marked as ensured only after partitions have been created successfully
+func (p *Postgres) EnsurePartitions(tableName string, now time.Time) error {
+	if p.partitioning == "" {
+		return nil
+	}
+
+	start, end := partitionRange(p.partitioning, now)
+
+	p.partitionsMutex.Lock()
+	defer p.partitionsMutex.Unlock()
+
+	if ensuredStart, ok := p.ensuredPartitions[tableName]; ok && ensuredStart.Equal(start) {
+		return nil
+	}
+
+	partitioned, err := p.isPartitioned(tableName)
+	if err != nil {
+		return err
+	}
+	if !partitioned {
+		p.ensuredPartitions[tableName] = start
+		return nil
+	}
+
+	_, nextEnd := partitionRange(p.partitioning, end)
+	for _, period := range [][2]time.Time{{start, end}, {end, nextEnd}} {
+		query := fmt.Sprintf(createPartitionTemplate, p.config.Schema, partitionName(tableName, period[0].Format(partitionSuffixLayout(p.partitioning))),
+			p.config.Schema, tableName, period[0].Format(partitionDateLayout), period[1].Format(partitionDateLayout))
+		p.queryLogger.LogDDL(query)
+
+		if _, err := p.dataSource.ExecContext(p.ctx, query); err != nil {
+			return fmt.Errorf("Error creating [%s] table partition: %v", tableName, err)
+		}
+	}
+
+	p.ensuredPartitions[tableName] = start
+	return nil
+}
+
+//isPartitioned returns true if the table is a partitioned one
+func (p *Postgres) isPartitioned(tableName string) (bool, error) {
+	var count int
+	if err := p.dataSource.QueryRowContext(p.ctx, partitionedTableQuery, p.config.Schema, tableName).Scan(&count); err != nil {
+		return false, fmt.Errorf("Error querying [%s] table partitioning: %v", tableName, err)
+	}
+
+	return count > 0, nil
 }
 
 //alter table with columns (if not empty)
@@ -434,7 +632,16 @@ func (p *Postgres) BulkInsert(table *Table, objects []map[string]interface{}) er
 	return wrappedTx.DirectCommit()
 }
 
+//bulkStoreInTransaction loads large batches via COPY and small ones via INSERT statements
 func (p *Postgres) bulkStoreInTransaction(wrappedTx *Transaction, table *Table, objects []map[string]interface{}) error {
+	if p.copyThreshold > 0 && len(objects) >= p.copyThreshold {
+		if len(table.PKFields) == 0 {
+			return p.copyInTransaction(wrappedTx, table, objects)
+		}
+
+		return p.copyMergeInTransaction(wrappedTx, table, objects)
+	}
+
 	if len(table.PKFields) == 0 {
 		return p.bulkInsertInTransaction(wrappedTx, table, objects)
 	}
@@ -442,6 +649,73 @@ func (p *Postgres) bulkStoreInTransaction(wrappedTx *Transaction, table *Table,
 	return p.bulkMergeInTransaction(wrappedTx, table, objects)
 }
 
+//copyInTransaction loads objects into the table via COPY FROM STDIN
+//Must be used when table has no primary keys. Prefer to use bulkStoreInTransaction instead of calling this method directly
+func (p *Postgres) copyInTransaction(wrappedTx *Transaction, table *Table, objects []map[string]interface{}) error {
+	header := sortedColumnNames(table)
+	return p.copyObjects(wrappedTx, pq.CopyInSchema(p.config.Schema, table.Name, header...), table.Name, header, objects)
+}
+
+//copyMergeInTransaction loads objects via COPY into a temporary table and merges it into the table:
+//existing rows (by primary key) are updated. If there are several objects with the same primary key, the last one is used
+//Must be used only if table has primary key fields. Prefer to use bulkStoreInTransaction instead of calling this method directly
+func (p *Postgres) copyMergeInTransaction(wrappedTx *Transaction, table *Table, objects []map[string]interface{}) error {
+	tmpTableName := "tmp_" + table.Name
+	query := fmt.Sprintf(createTempTableTemplate, tmpTableName, p.config.Schema, table.Name)
+	p.queryLogger.LogDDL(query)
+	if _, err := wrappedTx.tx.ExecContext(p.ctx, query); err != nil {
+		return fmt.Errorf("Error creating temporary table for %s table: %v", table.Name, err)
+	}
+
+	header := sortedColumnNames(table)
+	if err := p.copyObjects(wrappedTx, pq.CopyIn(tmpTableName, header...), tmpTableName, header, deduplicateObjects(table.GetPKFields(), objects)); err != nil {
+		return err
+	}
+
+	var updateSet []string
+	for _, column := range header {
+		updateSet = append(updateSet, column+"=EXCLUDED."+column)
+	}
+	headerClause := strings.Join(header, ",")
+	query = fmt.Sprintf(mergeFromTempTableTemplate, p.config.Schema, table.Name, headerClause, headerClause, tmpTableName,
+		buildConstraintName(p.config.Schema, table.Name), strings.Join(updateSet, ","))
+	p.queryLogger.LogQuery(query)
+	if _, err := wrappedTx.tx.ExecContext(p.ctx, query); err != nil {
+		return fmt.Errorf("Error merging temporary table into %s table with statement: %s: %v", table.Name, query, err)
+	}
+
+	return nil
+}
+
+//copyObjects executes COPY statement with objects values
+func (p *Postgres) copyObjects(wrappedTx *Transaction, statement, tableName string, header []string, objects []map[string]interface{}) error {
+	p.queryLogger.LogQuery(statement)
+	copyStmt, err := wrappedTx.tx.PrepareContext(p.ctx, statement)
+	if err != nil {
+		return fmt.Errorf("Error preparing copy statement [%s] table %s: %v", statement, tableName, err)
+	}
+
+	for _, row := range objects {
+		values := make([]interface{}, len(header))
+		for i, column := range header {
+			values[i] = postgresValue(row[column])
+		}
+
+		if _, err := copyStmt.ExecContext(p.ctx, values...); err != nil {
+			copyStmt.Close()
+			return fmt.Errorf("Error copying in %s table values: %v: %v", tableName, values, err)
+		}
+	}
+
+	//flush buffered data
+	if _, err := copyStmt.ExecContext(p.ctx); err != nil {
+		copyStmt.Close()
+		return fmt.Errorf("Error copying in %s table: %v", tableName, err)
+	}
+
+	return copyStmt.Close()
+}
+
 //Must be used when table has no primary keys. Inserts data in batches to improve performance.
 //Prefer to use bulkStoreInTransaction instead of calling this method directly
 func (p *Postgres) bulkInsertInTransaction(wrappedTx *Transaction, table *Table, objects []map[string]interface{}) error {
@@ -473,7 +747,7 @@ func (p *Postgres) bulkInsertInTransaction(wrappedTx *Transaction, table *Table,
 		}
 		for i, column := range header {
 			value, _ := row[column]
-			valueArgs = append(valueArgs, value)
+			valueArgs = append(valueArgs, postgresValue(value))
 			castClause := ""
 			castType, ok := p.mappingTypeCasts[column]
 			if ok {
@@ -538,7 +812,7 @@ func (p *Postgres) bulkMergeInTransaction(wrappedTx *Transaction, table *Table,
 		var values []interface{}
 		for _, column := range header {
 			value, _ := row[column]
-			values = append(values, value)
+			values = append(values, postgresValue(value))
 		}
 		p.queryLogger.LogQueryWithValues(query, values)
 		_, err = mergeStmt.ExecContext(p.ctx, values...)
@@ -620,6 +894,71 @@ func (p *Postgres) Close() error {
 	return p.dataSource.Close()
 }
 
+//partitionRange returns the start and the end of the partitioning period which contains t (in UTC)
+func partitionRange(partitioning string, t time.Time) (time.Time, time.Time) {
+	t = t.UTC()
+	if partitioning == PartitioningDaily {
+		start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 0, 1)
+	}
+
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, 0)
+}
+
+//partitionSuffixLayout returns partition name suffix time layout: 200601 (monthly) or 20060102 (daily)
+func partitionSuffixLayout(partitioning string) string {
+	if partitioning == PartitioningDaily {
+		return timestamp.DayLayout
+	}
+
+	return timestamp.MonthLayout
+}
+
+//partitionName returns table partition name: <table>_p<suffix>
+func partitionName(tableName, suffix string) string {
+	return tableName + "_p" + suffix
+}
+
+//sortedColumnNames returns table column names sorted asc
+func sortedColumnNames(table *Table) []string {
+	var header []string
+	for name := range table.Columns {
+		header = append(header, name)
+	}
+	sort.Strings(header)
+
+	return header
+}
+
+//deduplicateObjects returns objects with unique primary key values (the last object wins) in the original order
+//(INSERT ON CONFLICT DO UPDATE can't affect the same row twice)
+func deduplicateObjects(pkFields []string, objects []map[string]interface{}) []map[string]interface{} {
+	lastIndexes := map[string]int{}
+	keys := make([]string, len(objects))
+	for i, object := range objects {
+		var values []string
+		for _, pkField := range pkFields {
+			values = append(values, fmt.Sprint(object[pkField]))
+		}
+		keys[i] = strings.Join(values, "\x00")
+		lastIndexes[keys[i]] = i
+	}
+
+	if len(lastIndexes) == len(objects) {
+		return objects
+	}
+
+	deduplicated := make([]map[string]interface{}, 0, len(lastIndexes))
+	for i, object := range objects {
+		if lastIndexes[keys[i]] == i {
+			deduplicated = append(deduplicated, object)
+		}
+	}
+
+	return deduplicated
+}
+
 func buildConstraintName(schemaName string, tableName string) string {
 	return schemaName + "_" + tableName + "_pk"
 }
@@ -689,13 +1028,24 @@ func (p *Postgres) buildQueryPayload(valuesMap map[string]interface{}) (string,
 		header[i] = name
 		//$1::type, $2::type, $3, etc ($0 - wrong)
 		placeholders[i] = fmt.Sprintf("$%d%s", i+1, p.castClause(name))
-		values[i] = value
+		values[i] = postgresValue(value)
 		i++
 	}
 
 	return strings.Join(header, ", "), strings.Join(placeholders, ", "), values
 }
 
+//postgresValue returns value which can be passed as a query parameter
+//typed arrays (values of native array columns) are wrapped with pq.Array
+func postgresValue(value interface{}) interface{} {
+	switch value.(type) {
+	case []string, []int64, []float64, []bool, []time.Time:
+		return pq.Array(value)
+	default:
+		return value
+	}
+}
+
 //handle old (deprecated) mapping types //TODO remove someday
 //put sql types as is
 //if mapping type is inner => map with sql type
@@ -10,6 +10,7 @@ import (
 	"gotest.tools/assert"
 	"math/rand"
 	"testing"
+	"time"
 )
 
 func TestBulkInsert(t *testing.T) {
@@ -46,14 +47,148 @@ func TestBulkMerge(t *testing.T) {
 	assert.Equal(t, rows, 5)
 }
 
+func TestBulkInsertCopy(t *testing.T) {
+	table := &Table{
+		Name:    "test_copy",
+		Columns: Columns{"field1": Column{"text"}, "field2": Column{"text"}, "field3": Column{"bigint"}},
+	}
+	container, pg := setupDatabaseWithConfig(t, table, &PostgresConfig{CopyThreshold: 2})
+	defer container.Close()
+	err := pg.BulkInsert(table, createObjects(5))
+	require.NoError(t, err, "Failed to copy 5 objects")
+	rows, err := container.CountRows(table.Name)
+	require.NoError(t, err, "Failed to count objects at "+table.Name)
+	assert.Equal(t, rows, 5)
+}
+
+func TestBulkMergeCopy(t *testing.T) {
+	table := &Table{
+		Name:     "test_copy_merge",
+		Columns:  Columns{"field1": Column{"text"}, "field2": Column{"text"}, "field3": Column{"bigint"}},
+		PKFields: map[string]bool{"field1": true},
+	}
+	container, pg := setupDatabaseWithConfig(t, table, &PostgresConfig{CopyThreshold: 2})
+	defer container.Close()
+	objects := createObjects(5)
+	require.NoError(t, pg.BulkInsert(table, objects), "Failed to copy objects")
+
+	// merge 6 objects with 3 existing ids and 1 duplication in the batch, the result must be 7 objects
+	updated := map[string]interface{}{"field1": objects[0]["field1"], "field2": "updated", "field3": 1}
+	merged := append(createObjects(2), objects[1], objects[2], objects[0], updated)
+	require.NoError(t, pg.BulkInsert(table, merged), "Failed to copy merge objects")
+	rows, err := container.CountRows(table.Name)
+	require.NoError(t, err, "Failed to count objects at "+table.Name)
+	assert.Equal(t, rows, 7)
+
+	data, err := container.GetAllSortedRows(table.Name, "order by field1")
+	require.NoError(t, err)
+	for _, row := range data {
+		if row["field1"] == objects[0]["field1"] {
+			require.Equal(t, "updated", row["field2"])
+		}
+	}
+}
+
+func TestPartitioning(t *testing.T) {
+	table := &Table{
+		Name:    "test_partitioned",
+		Columns: Columns{"field1": Column{"text"}, "_timestamp": Column{"timestamp"}},
+	}
+	container, pg := setupDatabaseWithConfig(t, table, &PostgresConfig{Partitioning: PartitioningMonthly})
+	defer container.Close()
+
+	now := time.Date(2021, 5, 12, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, pg.EnsurePartitions(table.Name, now))
+	tables, err := pg.TablesList()
+	require.NoError(t, err)
+	require.Contains(t, tables, "test_partitioned")
+	require.NotContains(t, tables, "test_partitioned_default")
+	require.NotContains(t, tables, "test_partitioned_p202105")
+	require.NotContains(t, tables, "test_partitioned_p202106")
+
+	dbTable, err := pg.GetTableSchema(table.Name)
+	require.NoError(t, err)
+	require.Len(t, dbTable.Columns, 2)
+
+	objects := []map[string]interface{}{
+		{"field1": "may", "_timestamp": now},
+		{"field1": "june", "_timestamp": now.AddDate(0, 1, 0)},
+		{"field1": "old", "_timestamp": now.AddDate(-1, 0, 0)},
+	}
+	require.NoError(t, pg.BulkInsert(table, objects))
+	for partition, expected := range map[string]int{"test_partitioned_p202105": 1, "test_partitioned_p202106": 1, "test_partitioned_default": 1} {
+		rows, err := container.CountRows(partition)
+		require.NoError(t, err)
+		require.Equal(t, expected, rows, partition)
+	}
+}
+
+func TestPartitioningWithoutTimestampPrimaryKey(t *testing.T) {
+	table := &Table{
+		Name:     "test_not_partitioned",
+		Columns:  Columns{"id": Column{"text"}, "_timestamp": Column{"timestamp"}},
+		PKFields: map[string]bool{"id": true},
+	}
+	container, pg := setupDatabaseWithConfig(t, table, &PostgresConfig{Partitioning: PartitioningMonthly})
+	defer container.Close()
+
+	partitioned, err := pg.isPartitioned(table.Name)
+	require.NoError(t, err)
+	require.False(t, partitioned)
+
+	now := time.Date(2021, 5, 12, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, pg.EnsurePartitions(table.Name, now))
+	require.NoError(t, pg.Insert(table, map[string]interface{}{"id": "1", "_timestamp": now}))
+	rows, err := container.CountRows(table.Name)
+	require.NoError(t, err)
+	require.Equal(t, 1, rows)
+}
+
+func TestValidatePrimaryKeyFields(t *testing.T) {
+	require.NoError(t, (&PostgresConfig{}).ValidatePrimaryKeyFields(map[string]bool{"id": true}))
+	require.NoError(t, (&PostgresConfig{Partitioning: PartitioningDaily}).ValidatePrimaryKeyFields(nil))
+	require.NoError(t, (&PostgresConfig{Partitioning: PartitioningDaily}).ValidatePrimaryKeyFields(map[string]bool{"id": true, "_timestamp": true}))
+	require.EqualError(t, (&PostgresConfig{Partitioning: PartitioningDaily}).ValidatePrimaryKeyFields(map[string]bool{"id": true}),
+		"primary_key_fields must include _timestamp field if postgres.partitioning is configured")
+}
+
+func TestPartitionRange(t *testing.T) {
+	now := time.Date(2021, 12, 31, 23, 59, 0, 0, time.UTC)
+
+	start, end := partitionRange(PartitioningMonthly, now)
+	require.Equal(t, time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC), start)
+	require.Equal(t, time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), end)
+	require.Equal(t, "events_p202112", partitionName("events", start.Format(partitionSuffixLayout(PartitioningMonthly))))
+
+	start, end = partitionRange(PartitioningDaily, now)
+	require.Equal(t, time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC), start)
+	require.Equal(t, time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), end)
+	require.Equal(t, "events_p20211231", partitionName("events", start.Format(partitionSuffixLayout(PartitioningDaily))))
+}
+
+func TestDeduplicateObjects(t *testing.T) {
+	objects := []map[string]interface{}{
+		{"id": 1, "name": "a"},
+		{"id": 2, "name": "b"},
+		{"id": 1, "name": "c"},
+	}
+
+	require.Equal(t, []map[string]interface{}{{"id": 2, "name": "b"}, {"id": 1, "name": "c"}}, deduplicateObjects([]string{"id"}, objects))
+	require.Equal(t, objects, deduplicateObjects([]string{"id", "name"}, objects))
+}
+
 func setupDatabase(t *testing.T, table *Table) (*test.PostgresContainer, *Postgres) {
+	return setupDatabaseWithConfig(t, table, nil)
+}
+
+func setupDatabaseWithConfig(t *testing.T, table *Table, pgConfig *PostgresConfig) (*test.PostgresContainer, *Postgres) {
 	ctx := context.Background()
 	container, err := test.NewPostgresContainer(ctx)
 	if err != nil {
 		t.Fatalf("failed to initialize container: %v", err)
 	}
 	dsConfig := &DataSourceConfig{Host: container.Host, Port: json.Number(fmt.Sprint(container.Port)), Username: container.Username, Password: container.Password, Db: container.Database, Schema: container.Schema, Parameters: map[string]string{"sslmode": "disable"}}
-	pg, err := NewPostgres(ctx, dsConfig, &logging.QueryLogger{}, map[string]string{})
+	pg, err := NewPostgres(ctx, dsConfig, &logging.QueryLogger{}, map[string]string{}, pgConfig)
 	if err != nil {
 		t.Fatalf("Failed to create Postgres adapter: %v", err)
 	}
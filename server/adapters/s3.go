@@ -27,6 +27,11 @@ type S3Config struct {
 	Region      string `mapstructure:"region" json:"region,omitempty" yaml:"region,omitempty"`
 	Endpoint    string `mapstructure:"endpoint" json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
 	Folder      string `mapstructure:"folder" json:"folder,omitempty" yaml:"folder,omitempty"`
+	//S3 destination output layout (isn't used by Redshift and Snowflake stages)
+	Format         string `mapstructure:"format" json:"format,omitempty" yaml:"format,omitempty"`
+	Compression    string `mapstructure:"compression" json:"compression,omitempty" yaml:"compression,omitempty"`
+	KeyTemplate    string `mapstructure:"key_template" json:"key_template,omitempty" yaml:"key_template,omitempty"`
+	ForcePathStyle bool   `mapstructure:"force_path_style" json:"force_path_style,omitempty" yaml:"force_path_style,omitempty"`
 }
 
 func (s3c *S3Config) Validate() error {
@@ -45,6 +50,9 @@ func (s3c *S3Config) Validate() error {
 	if s3c.Region == "" {
 		return errors.New("S3 region is required parameter")
 	}
+	if err := ValidateFileLayout(s3c.Format, s3c.Compression, s3c.KeyTemplate); err != nil {
+		return fmt.Errorf("S3 %v", err)
+	}
 
 	return nil
 }
@@ -60,6 +68,10 @@ func NewS3(s3Config *S3Config) (*S3, error) {
 	if s3Config.Endpoint != "" {
 		awsConfig.WithEndpoint(s3Config.Endpoint)
 	}
+	//path style is required by some S3 compatible storages (e.g. MinIO)
+	if s3Config.ForcePathStyle {
+		awsConfig.WithS3ForcePathStyle(true)
+	}
 	s3Session := session.Must(session.NewSession())
 
 	return &S3{client: s3.New(s3Session, awsConfig), config: s3Config}, nil
@@ -18,6 +18,9 @@ const (
 	gcpFrom                 = `FROM @%s
    							   %s
                                PATTERN = '%s'`
+	gcpSelectFrom = `FROM (SELECT %s FROM @%s)
+   							   %s
+                               PATTERN = '%s'`
 	awsS3From = `FROM 's3://%s/%s'
 					           CREDENTIALS = (aws_key_id='%s' aws_secret_key='%s') 
                                %s`
@@ -26,6 +29,9 @@ const (
 	addSFColumnTemplate                 = `ALTER TABLE %s.%s ADD COLUMN %s %s`
 	createSFTableTemplate               = `CREATE TABLE %s.%s (%s)`
 	insertSFTemplate                    = `INSERT INTO %s.%s (%s) VALUES (%s)`
+	insertSFSelectTemplate              = `INSERT INTO %s.%s (%s) SELECT %s`
+
+	variantSFType = "variant"
 )
 
 var (
@@ -36,6 +42,10 @@ var (
 		typing.TIMESTAMP: "timestamp(6)",
 		typing.BOOL:      "boolean",
 		typing.UNKNOWN:   "text",
+		typing.JSON:      "variant",
+		typing.ARRAY:     "variant",
+		typing.DATE:      "date",
+		typing.DECIMAL:   "numeric(38,18)",
 	}
 )
 
@@ -238,10 +248,20 @@ func (s *Snowflake) GetTableSchema(tableName string) (*Table, error) {
 }
 
 //Copy transfer data from s3 to Snowflake by passing COPY request to Snowflake
-func (s *Snowflake) Copy(fileName, tableName string, header []string) error {
-	var reformattedHeader []string
-	for _, v := range header {
+//JSON values of variant columns are parsed during loading from the named stage (google cloud storage)
+//(from s3 they are loaded as JSON strings because Snowflake doesn't support transformations of external locations)
+func (s *Snowflake) Copy(fileName string, table *Table, header []string) error {
+	tableName := table.Name
+	var reformattedHeader, selectColumns []string
+	parseJSON := false
+	for i, v := range header {
 		reformattedHeader = append(reformattedHeader, reformatValue(v))
+		if s.isVariant(table, v) {
+			selectColumns = append(selectColumns, fmt.Sprintf("PARSE_JSON($%d)", i+1))
+			parseJSON = true
+		} else {
+			selectColumns = append(selectColumns, fmt.Sprintf("$%d", i+1))
+		}
 	}
 
 	wrappedTx, err := s.OpenTx()
@@ -256,6 +276,9 @@ func (s *Snowflake) Copy(fileName, tableName string, header []string) error {
 			fileName = s.s3Config.Folder + "/" + fileName
 		}
 		statement += fmt.Sprintf(awsS3From, s.s3Config.Bucket, fileName, s.s3Config.AccessKeyID, s.s3Config.SecretKey, copyStatementFileFormat)
+	} else if parseJSON {
+		//gcp integration stage with variant columns
+		statement += fmt.Sprintf(gcpSelectFrom, strings.Join(selectColumns, ","), s.config.Stage, copyStatementFileFormat, fileName)
 	} else {
 		//gcp integration stage
 		statement += fmt.Sprintf(gcpFrom, s.config.Stage, copyStatementFileFormat, fileName)
@@ -271,25 +294,36 @@ func (s *Snowflake) Copy(fileName, tableName string, header []string) error {
 }
 
 //Insert provided object in snowflake
+//JSON values of variant columns are parsed with PARSE_JSON (it isn't allowed in VALUES clause: INSERT SELECT is used)
 func (s *Snowflake) Insert(table *Table, valuesMap map[string]interface{}) error {
 	var header, placeholders string
 	var values []interface{}
+	parseJSON := false
 	for name, value := range valuesMap {
 		header += reformatValue(name) + ","
 
-		castClause := ""
-		castType, ok := s.mappingTypeCasts[name]
-		if ok {
-			castClause = "::" + castType
+		if s.isVariant(table, name) {
+			placeholders += "PARSE_JSON(?),"
+			parseJSON = true
+		} else {
+			castClause := ""
+			castType, ok := s.mappingTypeCasts[name]
+			if ok {
+				castClause = "::" + castType
+			}
+			placeholders += "?" + castClause + ","
 		}
-		placeholders += "?" + castClause + ","
 		values = append(values, value)
 	}
 
 	header = removeLastComma(header)
 	placeholders = removeLastComma(placeholders)
 
-	query := fmt.Sprintf(insertSFTemplate, s.config.Schema, reformatValue(table.Name), header, placeholders)
+	insertTemplate := insertSFTemplate
+	if parseJSON {
+		insertTemplate = insertSFSelectTemplate
+	}
+	query := fmt.Sprintf(insertTemplate, s.config.Schema, reformatValue(table.Name), header, placeholders)
 	s.queryLogger.LogQueryWithValues(query, values)
 
 	wrappedTx, err := s.OpenTx()
@@ -312,6 +346,16 @@ func (s *Snowflake) Insert(table *Table, valuesMap map[string]interface{}) error
 	return wrappedTx.DirectCommit()
 }
 
+//isVariant returns true if column type (or configured mapping type) is variant
+func (s *Snowflake) isVariant(table *Table, columnName string) bool {
+	sqlType, ok := s.mappingTypeCasts[columnName]
+	if !ok {
+		sqlType = table.Columns[columnName].SQLType
+	}
+
+	return strings.EqualFold(sqlType, variantSFType)
+}
+
 //Close underlying sql.DB
 func (s *Snowflake) Close() (multiErr error) {
 	return s.dataSource.Close()
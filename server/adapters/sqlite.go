@@ -0,0 +1,459 @@
+package adapters
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/typing"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const (
+	tableNamesQuerySQLite       = `SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'`
+	tableSchemaQuerySQLite      = `SELECT name, type FROM pragma_table_info(?)`
+	primaryKeyFieldsQuerySQLite = `SELECT name FROM pragma_index_info(?)`
+
+	addColumnTemplateSQLite        = `ALTER TABLE "%s" ADD COLUMN %s`
+	createTableTemplateSQLite      = `CREATE TABLE "%s" (%s)`
+	dropPrimaryKeyTemplateSQLite   = `DROP INDEX IF EXISTS "%s"`
+	createPrimaryKeyTemplateSQLite = `CREATE UNIQUE INDEX "%s" ON "%s" (%s)`
+	insertTemplateSQLite           = `INSERT INTO "%s" (%s) VALUES %s`
+	mergeTemplateSQLite            = `INSERT OR REPLACE INTO "%s" (%s) VALUES %s`
+	deleteQueryTemplateSQLite      = `DELETE FROM "%s" WHERE %s`
+
+	//SQLite default SQLITE_MAX_VARIABLE_NUMBER (since 3.32.0)
+	sqLiteValuesLimit = 32766
+	//primary key is a unique index (SQLite can't alter primary key of an existing table)
+	primaryKeyIndexSuffixSQLite = "_pk"
+)
+
+var (
+	SchemaToSQLite = map[typing.DataType]string{
+		typing.STRING:    "TEXT",
+		typing.INT64:     "INTEGER",
+		typing.FLOAT64:   "REAL",
+		typing.TIMESTAMP: "TIMESTAMP",
+		typing.BOOL:      "BOOLEAN",
+		typing.UNKNOWN:   "TEXT",
+		typing.JSON:      "TEXT",
+		typing.ARRAY:     "TEXT",
+		typing.DATE:      "DATE",
+		typing.DECIMAL:   "NUMERIC",
+	}
+)
+
+//SQLiteConfig dto for deserialized embedded SQLite destination configuration
+type SQLiteConfig struct {
+	Path string `mapstructure:"path" json:"path,omitempty" yaml:"path,omitempty"`
+}
+
+//Validate required fields in SQLiteConfig
+func (sc *SQLiteConfig) Validate() error {
+	if sc == nil {
+		return errors.New("SQLite config is required")
+	}
+	if sc.Path == "" {
+		return errors.New("SQLite path is required parameter")
+	}
+
+	return nil
+}
+
+//SQLite is adapter for creating,patching (schema or table), inserting data to embedded SQLite database file
+//Primary key fields are implemented as a unique index [table]_pk because SQLite doesn't support altering primary keys
+type SQLite struct {
+	ctx         context.Context
+	config      *SQLiteConfig
+	dataSource  *sql.DB
+	queryLogger *logging.QueryLogger
+
+	mappingTypeCasts map[string]string
+}
+
+//NewSQLite return configured SQLite adapter instance (database file and its directory are created if they don't exist)
+func NewSQLite(ctx context.Context, config *SQLiteConfig, queryLogger *logging.QueryLogger, mappingTypeCasts map[string]string) (*SQLite, error) {
+	if dir := filepath.Dir(config.Path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("Error creating SQLite database directory [%s]: %v", dir, err)
+		}
+	}
+
+	dataSource, err := sql.Open("sqlite3", "file:"+config.Path+"?_busy_timeout=5000&_journal_mode=WAL")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := dataSource.Ping(); err != nil {
+		dataSource.Close()
+		return nil, err
+	}
+
+	//SQLite supports only one writer at a time
+	dataSource.SetMaxOpenConns(1)
+
+	return &SQLite{ctx: ctx, config: config, dataSource: dataSource, queryLogger: queryLogger, mappingTypeCasts: reformatMappings(mappingTypeCasts, SchemaToSQLite)}, nil
+}
+
+func (SQLite) Name() string {
+	return "SQLite"
+}
+
+//OpenTx open underline sql transaction and return wrapped instance
+func (s *SQLite) OpenTx() (*Transaction, error) {
+	tx, err := s.dataSource.BeginTx(s.ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Transaction{tx: tx, dbType: s.Name()}, nil
+}
+
+//CreateTable create database table with name,columns provided in Table representation
+//and primary key unique index in one transaction
+func (s *SQLite) CreateTable(table *Table) error {
+	wrappedTx, err := s.OpenTx()
+	if err != nil {
+		return err
+	}
+
+	var columnsDDL []string
+	for columnName, column := range table.Columns {
+		columnsDDL = append(columnsDDL, s.columnDDL(columnName, column))
+	}
+
+	//sorting columns asc
+	sort.Strings(columnsDDL)
+	query := fmt.Sprintf(createTableTemplateSQLite, table.Name, strings.Join(columnsDDL, ","))
+	s.queryLogger.LogDDL(query)
+
+	if _, err := wrappedTx.tx.ExecContext(s.ctx, query); err != nil {
+		wrappedTx.Rollback()
+		return fmt.Errorf("Error creating [%s] table: %v", table.Name, err)
+	}
+
+	if err := s.createPrimaryKeyInTransaction(wrappedTx, table); err != nil {
+		wrappedTx.Rollback()
+		return err
+	}
+
+	return wrappedTx.DirectCommit()
+}
+
+//PatchTableSchema add new columns(from provided Table) to existing table
+//recreate primary key unique index (if not empty) or delete it if Table.DeletePkFields is true
+func (s *SQLite) PatchTableSchema(patchTable *Table) error {
+	wrappedTx, err := s.OpenTx()
+	if err != nil {
+		return err
+	}
+
+	//patch columns
+	for columnName, column := range patchTable.Columns {
+		columnDDL := s.columnDDL(columnName, column)
+		query := fmt.Sprintf(addColumnTemplateSQLite, patchTable.Name, columnDDL)
+		s.queryLogger.LogDDL(query)
+
+		if _, err := wrappedTx.tx.ExecContext(s.ctx, query); err != nil {
+			wrappedTx.Rollback()
+			return fmt.Errorf("Error patching %s table with [%s] DDL: %v", patchTable.Name, columnDDL, err)
+		}
+	}
+
+	//patch primary keys - delete old
+	if patchTable.DeletePkFields {
+		query := fmt.Sprintf(dropPrimaryKeyTemplateSQLite, primaryKeyIndexName(patchTable.Name))
+		s.queryLogger.LogDDL(query)
+
+		if _, err := wrappedTx.tx.ExecContext(s.ctx, query); err != nil {
+			wrappedTx.Rollback()
+			return fmt.Errorf("Failed to drop primary key for table %s: %v", patchTable.Name, err)
+		}
+	}
+
+	//patch primary keys - create new
+	if err := s.createPrimaryKeyInTransaction(wrappedTx, patchTable); err != nil {
+		wrappedTx.Rollback()
+		return err
+	}
+
+	return wrappedTx.DirectCommit()
+}
+
+//createPrimaryKeyInTransaction create primary key unique index if Table.PKFields isn't empty
+func (s *SQLite) createPrimaryKeyInTransaction(wrappedTx *Transaction, table *Table) error {
+	if len(table.PKFields) == 0 {
+		return nil
+	}
+
+	query := fmt.Sprintf(createPrimaryKeyTemplateSQLite, primaryKeyIndexName(table.Name), table.Name, s.quotedColumns(table.GetPKFields()))
+	s.queryLogger.LogDDL(query)
+
+	if _, err := wrappedTx.tx.ExecContext(s.ctx, query); err != nil {
+		return fmt.Errorf("Error setting primary key [%s] %s table: %v", strings.Join(table.GetPKFields(), ","), table.Name, err)
+	}
+
+	return nil
+}
+
+//GetTableSchema return table (name,columns with name and types) representation wrapped in Table struct
+func (s *SQLite) GetTableSchema(tableName string) (*Table, error) {
+	table, err := s.getTable(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	//don't select primary keys of non-existent table
+	if len(table.Columns) == 0 {
+		return table, nil
+	}
+
+	pkFields, err := s.getPrimaryKeys(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	table.PKFields = pkFields
+	return table, nil
+}
+
+func (s *SQLite) getTable(tableName string) (*Table, error) {
+	table := &Table{Name: tableName, Columns: map[string]Column{}, PKFields: map[string]bool{}}
+	rows, err := s.dataSource.QueryContext(s.ctx, tableSchemaQuerySQLite, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("Error querying table [%s] schema: %v", tableName, err)
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var columnName, columnSQLiteType string
+		if err := rows.Scan(&columnName, &columnSQLiteType); err != nil {
+			return nil, fmt.Errorf("Error scanning result: %v", err)
+		}
+
+		table.Columns[columnName] = Column{SQLType: columnSQLiteType}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Last rows.Err: %v", err)
+	}
+
+	return table, nil
+}
+
+func (s *SQLite) getPrimaryKeys(tableName string) (map[string]bool, error) {
+	primaryKeys := map[string]bool{}
+	pkFieldsRows, err := s.dataSource.QueryContext(s.ctx, primaryKeyFieldsQuerySQLite, primaryKeyIndexName(tableName))
+	if err != nil {
+		return nil, fmt.Errorf("Error querying primary keys for [%s] table: %v", tableName, err)
+	}
+
+	defer pkFieldsRows.Close()
+	for pkFieldsRows.Next() {
+		var fieldName string
+		if err := pkFieldsRows.Scan(&fieldName); err != nil {
+			return nil, fmt.Errorf("error scanning primary key result: %v", err)
+		}
+		primaryKeys[fieldName] = true
+	}
+	if err := pkFieldsRows.Err(); err != nil {
+		return nil, fmt.Errorf("pk last rows.Err: %v", err)
+	}
+
+	return primaryKeys, nil
+}
+
+//Insert provided object in SQLite
+//if table has primary key fields, the row is replaced on conflict
+func (s *SQLite) Insert(table *Table, valuesMap map[string]interface{}) error {
+	header := make([]string, 0, len(valuesMap))
+	values := make([]interface{}, 0, len(valuesMap))
+	for name, value := range valuesMap {
+		header = append(header, name)
+		values = append(values, value)
+	}
+
+	query := s.insertQuery(table, header, placeholdersMySQL(len(header)))
+	s.queryLogger.LogQueryWithValues(query, values)
+
+	if _, err := s.dataSource.ExecContext(s.ctx, query, values...); err != nil {
+		return fmt.Errorf("Error inserting in %s table with statement: %s values: %v: %v", table.Name, query, values, err)
+	}
+
+	return nil
+}
+
+//BulkInsert insert objects into table in one transaction
+func (s *SQLite) BulkInsert(table *Table, objects []map[string]interface{}) error {
+	wrappedTx, err := s.OpenTx()
+	if err != nil {
+		return err
+	}
+
+	if err = s.bulkInsertInTransaction(wrappedTx, table, objects); err != nil {
+		wrappedTx.Rollback()
+		return err
+	}
+
+	return wrappedTx.DirectCommit()
+}
+
+//BulkUpdate delete rows by conditions and insert objects into table in one transaction
+func (s *SQLite) BulkUpdate(table *Table, objects []map[string]interface{}, deleteConditions *DeleteConditions) error {
+	wrappedTx, err := s.OpenTx()
+	if err != nil {
+		return err
+	}
+
+	if !deleteConditions.IsEmpty() {
+		if err := s.deleteInTransaction(wrappedTx, table, deleteConditions); err != nil {
+			wrappedTx.Rollback()
+			return err
+		}
+	}
+
+	if err := s.bulkInsertInTransaction(wrappedTx, table, objects); err != nil {
+		wrappedTx.Rollback()
+		return err
+	}
+
+	return wrappedTx.DirectCommit()
+}
+
+func (s *SQLite) deleteInTransaction(wrappedTx *Transaction, table *Table, deleteConditions *DeleteConditions) error {
+	var queryConditions []string
+	var values []interface{}
+	for _, condition := range deleteConditions.Conditions {
+		queryConditions = append(queryConditions, `"`+condition.Field+`" `+condition.Clause+" ?")
+		values = append(values, condition.Value)
+	}
+
+	query := fmt.Sprintf(deleteQueryTemplateSQLite, table.Name, strings.Join(queryConditions, " "+deleteConditions.JoinCondition+" "))
+	s.queryLogger.LogQueryWithValues(query, values)
+
+	if _, err := wrappedTx.tx.ExecContext(s.ctx, query, values...); err != nil {
+		return fmt.Errorf("Error deleting using query: %s:, error: %v", query, err)
+	}
+
+	return nil
+}
+
+//bulkInsertInTransaction inserts data in batches to improve performance
+//if table has primary key fields, rows are replaced on conflict
+func (s *SQLite) bulkInsertInTransaction(wrappedTx *Transaction, table *Table, objects []map[string]interface{}) error {
+	var header []string
+	for name := range table.Columns {
+		header = append(header, name)
+	}
+	sort.Strings(header)
+
+	maxValues := len(objects) * len(header)
+	if maxValues > sqLiteValuesLimit {
+		maxValues = sqLiteValuesLimit
+	}
+
+	var placeholders []string
+	valueArgs := make([]interface{}, 0, maxValues)
+	for _, row := range objects {
+		//if number of values exceeds limit, we have to execute insert query on processed rows
+		if len(valueArgs)+len(header) > sqLiteValuesLimit {
+			if err := s.executeInsert(wrappedTx, table, header, placeholders, valueArgs); err != nil {
+				return err
+			}
+			placeholders = nil
+			valueArgs = make([]interface{}, 0, maxValues)
+		}
+
+		for _, column := range header {
+			valueArgs = append(valueArgs, row[column])
+		}
+		placeholders = append(placeholders, placeholdersMySQL(len(header)))
+	}
+
+	if len(valueArgs) > 0 {
+		if err := s.executeInsert(wrappedTx, table, header, placeholders, valueArgs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *SQLite) executeInsert(wrappedTx *Transaction, table *Table, header []string, placeholders []string, valueArgs []interface{}) error {
+	query := s.insertQuery(table, header, strings.Join(placeholders, ","))
+	s.queryLogger.LogQueryWithValues(query, valueArgs)
+
+	if _, err := wrappedTx.tx.ExecContext(s.ctx, query, valueArgs...); err != nil {
+		return fmt.Errorf("Error bulk inserting in %s table with statement: %s: %v", table.Name, query, err)
+	}
+
+	return nil
+}
+
+//insertQuery return insert statement or insert or replace statement
+func (s *SQLite) insertQuery(table *Table, header []string, placeholders string) string {
+	if len(table.PKFields) == 0 {
+		return fmt.Sprintf(insertTemplateSQLite, table.Name, s.quotedColumns(header), placeholders)
+	}
+
+	return fmt.Sprintf(mergeTemplateSQLite, table.Name, s.quotedColumns(header), placeholders)
+}
+
+//TablesList return slice of SQLite table names
+func (s *SQLite) TablesList() ([]string, error) {
+	var tableNames []string
+	rows, err := s.dataSource.QueryContext(s.ctx, tableNamesQuerySQLite)
+	if err != nil {
+		return tableNames, fmt.Errorf("Error querying tables names: %v", err)
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var tableName string
+		if err := rows.Scan(&tableName); err != nil {
+			return tableNames, fmt.Errorf("Error scanning table name: %v", err)
+		}
+		tableNames = append(tableNames, tableName)
+	}
+	if err := rows.Err(); err != nil {
+		return tableNames, fmt.Errorf("Last rows.Err: %v", err)
+	}
+
+	return tableNames, nil
+}
+
+//columnDDL return column DDL (quoted column name and mapped sql type)
+//primary key fields aren't 'not null' because columns can be added to existing table only with default value
+func (s *SQLite) columnDDL(name string, column Column) string {
+	sqlType := column.SQLType
+	//casted
+	if castedSQLType, ok := s.mappingTypeCasts[name]; ok {
+		sqlType = castedSQLType
+	}
+
+	return fmt.Sprintf(`"%s" %s`, name, sqlType)
+}
+
+func (s *SQLite) quotedColumns(columns []string) string {
+	quoted := make([]string, 0, len(columns))
+	for _, column := range columns {
+		quoted = append(quoted, `"`+column+`"`)
+	}
+
+	return strings.Join(quoted, ",")
+}
+
+//Close underlying sql.DB
+func (s *SQLite) Close() error {
+	return s.dataSource.Close()
+}
+
+func primaryKeyIndexName(tableName string) string {
+	return tableName + primaryKeyIndexSuffixSQLite
+}
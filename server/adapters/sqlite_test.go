@@ -0,0 +1,137 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkInsertSQLite(t *testing.T) {
+	table := &Table{
+		Name:    "test_insert",
+		Columns: Columns{"field1": Column{"TEXT"}, "field2": Column{"TIMESTAMP"}, "field3": Column{"INTEGER"}},
+	}
+	sqLite := setupSQLiteDatabase(t, table)
+	defer sqLite.Close()
+
+	require.NoError(t, sqLite.BulkInsert(table, createSQLiteObjects(5)))
+	require.Equal(t, 5, countSQLiteRows(t, sqLite, table.Name))
+}
+
+func TestBulkMergeSQLite(t *testing.T) {
+	table := &Table{
+		Name:     "test_merge",
+		Columns:  Columns{"field1": Column{"TEXT"}, "field2": Column{"TIMESTAMP"}, "field3": Column{"INTEGER"}},
+		PKFields: map[string]bool{"field1": true},
+	}
+	sqLite := setupSQLiteDatabase(t, table)
+	defer sqLite.Close()
+
+	dbTable, err := sqLite.GetTableSchema(table.Name)
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{"field1": true}, dbTable.PKFields)
+
+	//store 8 objects with 3 id duplications, the result must be 5 objects
+	objects := createSQLiteObjects(5)
+	objects = append(objects, objects[0], objects[2], objects[3])
+	require.NoError(t, sqLite.BulkInsert(table, objects))
+	require.Equal(t, 5, countSQLiteRows(t, sqLite, table.Name))
+
+	//streaming insert replaces the row with the same primary key
+	require.NoError(t, sqLite.Insert(table, map[string]interface{}{"field1": "id0", "field3": 100}))
+	require.Equal(t, 5, countSQLiteRows(t, sqLite, table.Name))
+
+	var field3 int
+	require.NoError(t, sqLite.dataSource.QueryRow(`SELECT field3 FROM test_merge WHERE field1 = 'id0'`).Scan(&field3))
+	require.Equal(t, 100, field3)
+}
+
+func TestBulkUpdateSQLite(t *testing.T) {
+	table := &Table{
+		Name:    "test_update",
+		Columns: Columns{"field1": Column{"TEXT"}, "field2": Column{"TIMESTAMP"}, "field3": Column{"INTEGER"}},
+	}
+	sqLite := setupSQLiteDatabase(t, table)
+	defer sqLite.Close()
+
+	require.NoError(t, sqLite.BulkInsert(table, createSQLiteObjects(5)))
+
+	deleteConditions := &DeleteConditions{
+		Conditions:    []DeleteCondition{{Field: "field1", Value: "id0", Clause: "="}, {Field: "field3", Value: 0, Clause: "="}},
+		JoinCondition: "AND",
+	}
+	require.NoError(t, sqLite.BulkUpdate(table, createSQLiteObjects(2)[1:], deleteConditions))
+	require.Equal(t, 5, countSQLiteRows(t, sqLite, table.Name))
+}
+
+func TestPatchTableSchemaSQLite(t *testing.T) {
+	table := &Table{
+		Name:    "test_patch",
+		Columns: Columns{"field1": Column{"TEXT"}},
+	}
+	sqLite := setupSQLiteDatabase(t, table)
+	defer sqLite.Close()
+
+	err := sqLite.PatchTableSchema(&Table{Name: table.Name, Columns: Columns{"field2": Column{"TIMESTAMP"}}, PKFields: map[string]bool{"field1": true}})
+	require.NoError(t, err)
+
+	dbTable, err := sqLite.GetTableSchema(table.Name)
+	require.NoError(t, err)
+	require.Equal(t, Columns{"field1": Column{"TEXT"}, "field2": Column{"TIMESTAMP"}}, dbTable.Columns)
+	require.Equal(t, map[string]bool{"field1": true}, dbTable.PKFields)
+
+	//re-create primary key
+	err = sqLite.PatchTableSchema(&Table{Name: table.Name, Columns: Columns{}, PKFields: map[string]bool{"field1": true, "field2": true}, DeletePkFields: true})
+	require.NoError(t, err)
+	dbTable, err = sqLite.GetTableSchema(table.Name)
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{"field1": true, "field2": true}, dbTable.PKFields)
+
+	//delete primary key
+	err = sqLite.PatchTableSchema(&Table{Name: table.Name, Columns: Columns{}, DeletePkFields: true})
+	require.NoError(t, err)
+	dbTable, err = sqLite.GetTableSchema(table.Name)
+	require.NoError(t, err)
+	require.Empty(t, dbTable.PKFields)
+
+	tables, err := sqLite.TablesList()
+	require.NoError(t, err)
+	require.Equal(t, []string{table.Name}, tables)
+}
+
+func setupSQLiteDatabase(t *testing.T, table *Table) *SQLite {
+	dir, err := ioutil.TempDir("", "sqlite_test")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	sqLite, err := NewSQLite(context.Background(), &SQLiteConfig{Path: filepath.Join(dir, "db", "test.db")}, &logging.QueryLogger{}, map[string]string{})
+	require.NoError(t, err, "Failed to create SQLite adapter")
+
+	require.NoError(t, sqLite.CreateTable(table), "Failed to create table")
+	return sqLite
+}
+
+func createSQLiteObjects(num int) []map[string]interface{} {
+	var objects []map[string]interface{}
+	for i := 0; i < num; i++ {
+		objects = append(objects, map[string]interface{}{
+			"field1": fmt.Sprintf("id%d", i),
+			"field2": time.Date(2021, 5, 18, 10, i, 0, 0, time.UTC),
+			"field3": i,
+		})
+	}
+	return objects
+}
+
+func countSQLiteRows(t *testing.T, sqLite *SQLite, tableName string) int {
+	var count int
+	require.NoError(t, sqLite.dataSource.QueryRow(fmt.Sprintf(`SELECT COUNT(*) FROM "%s"`, tableName)).Scan(&count))
+	return count
+}
@@ -1,7 +1,21 @@
 package adapters
 
+import "time"
+
 type TableManager interface {
 	GetTableSchema(tableName string) (*Table, error)
 	CreateTable(schemaToCreate *Table) error
 	PatchTableSchema(schemaToAdd *Table) error
 }
+
+//ColumnTypeManager is implemented by adapters which are able to change type of an existing column
+//(it is used by schema evolution 'widen' policy)
+type ColumnTypeManager interface {
+	AlterColumnType(tableName, columnName string, column Column) error
+}
+
+//PartitionManager is implemented by adapters which are able to create time partitioned tables
+//(partitions are created in advance for the period which contains the provided time and the next one)
+type PartitionManager interface {
+	EnsurePartitions(tableName string, now time.Time) error
+}
@@ -0,0 +1,344 @@
+package adapters
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/typing"
+)
+
+const (
+	maxWebHookResponseBodySize = 1024
+
+	//functions which are added to the end of every template action pipeline (see webHookActionsFinalizer)
+	webHookValueFunc      = "webHookValue"
+	webHookPathValueFunc  = "webHookPathValue"
+	webHookQueryValueFunc = "webHookQueryValue"
+)
+
+var (
+	//WebHook doesn't use types
+	SchemaToWebHook = map[typing.DataType]string{
+		typing.STRING:    "string",
+		typing.INT64:     "string",
+		typing.FLOAT64:   "string",
+		typing.TIMESTAMP: "string",
+		typing.BOOL:      "string",
+		typing.UNKNOWN:   "string",
+	}
+
+	webHookMethods = map[string]bool{
+		http.MethodGet:    true,
+		http.MethodPost:   true,
+		http.MethodPut:    true,
+		http.MethodPatch:  true,
+		http.MethodDelete: true,
+	}
+
+	//webHookTemplateFuncs are available in all webhook templates
+	//e.g. {"email": {{.user.email | json}}}
+	webHookTemplateFuncs = template.FuncMap{
+		"json": func(value interface{}) (string, error) {
+			b, err := json.Marshal(value)
+			return string(b), err
+		},
+		webHookValueFunc: func(value interface{}) interface{} {
+			if value == nil {
+				return ""
+			}
+			return value
+		},
+		webHookPathValueFunc: func(value interface{}) string {
+			if value == nil {
+				return ""
+			}
+			return url.PathEscape(fmt.Sprint(value))
+		},
+		webHookQueryValueFunc: func(value interface{}) string {
+			if value == nil {
+				return ""
+			}
+			return url.QueryEscape(fmt.Sprint(value))
+		},
+	}
+)
+
+//RetryableError is returned when the request might succeed later (e.g. 5xx HTTP response code)
+//such events are retried by the streaming worker instead of being written to fallback
+type RetryableError struct {
+	Err error
+}
+
+func (re *RetryableError) Error() string {
+	return re.Err.Error()
+}
+
+//WebHookConfig dto for deserialized destination config (e.g. in WebHook destination)
+//url, headers values and body are Go text/template expressions. The subject of expressions is the processed event
+type WebHookConfig struct {
+	URL     string            `mapstructure:"url" json:"url,omitempty" yaml:"url,omitempty"`
+	Method  string            `mapstructure:"method" json:"method,omitempty" yaml:"method,omitempty"`
+	Body    string            `mapstructure:"body" json:"body,omitempty" yaml:"body,omitempty"`
+	Headers map[string]string `mapstructure:"headers" json:"headers,omitempty" yaml:"headers,omitempty"`
+}
+
+//Validate required fields in WebHookConfig
+func (whc *WebHookConfig) Validate() error {
+	if whc == nil {
+		return errors.New("webhook config is required")
+	}
+
+	if whc.URL == "" {
+		return errors.New("url is required parameter")
+	}
+
+	if whc.Method != "" && !webHookMethods[strings.ToUpper(whc.Method)] {
+		return fmt.Errorf("Unsupported method [%s]. Supported: GET, POST, PUT, PATCH, DELETE", whc.Method)
+	}
+
+	return nil
+}
+
+//WebHook adapter for sending events via HTTP requests rendered from templates
+type WebHook struct {
+	method      string
+	url         *template.Template
+	body        *template.Template
+	headers     map[string]*template.Template
+	client      *http.Client
+	debugLogger *logging.QueryLogger
+}
+
+//NewWebHook return WebHook adapter with parsed templates
+//if body template isn't configured, event JSON is sent
+func NewWebHook(config *WebHookConfig, requestDebugLogger *logging.QueryLogger) (*WebHook, error) {
+	method := strings.ToUpper(config.Method)
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	urlTmpl, err := parseWebHookTemplate("url", config.URL, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var bodyTmpl *template.Template
+	if config.Body != "" {
+		bodyTmpl, err = parseWebHookTemplate("body", config.Body, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	headers := map[string]*template.Template{}
+	for name, value := range config.Headers {
+		headerTmpl, err := parseWebHookTemplate("header "+name, value, false)
+		if err != nil {
+			return nil, err
+		}
+		headers[name] = headerTmpl
+	}
+
+	return &WebHook{
+		method:  method,
+		url:     urlTmpl,
+		body:    bodyTmpl,
+		headers: headers,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+			Transport: &http.Transport{
+				MaxIdleConns:        1000,
+				MaxIdleConnsPerHost: 1000,
+			},
+		},
+		debugLogger: requestDebugLogger,
+	}, nil
+}
+
+//Send render HTTP request from the object and send it
+//return RetryableError if response code is 5xx or request is timed out
+func (wh *WebHook) Send(object map[string]interface{}) error {
+	reqURL, err := executeWebHookTemplate(wh.url, object)
+	if err != nil {
+		return err
+	}
+
+	var body []byte
+	if wh.body != nil {
+		rendered, err := executeWebHookTemplate(wh.body, object)
+		if err != nil {
+			return err
+		}
+		body = []byte(rendered)
+	} else {
+		body, err = json.Marshal(object)
+		if err != nil {
+			return fmt.Errorf("Error serializing object to JSON: %v", err)
+		}
+	}
+
+	req, err := http.NewRequest(wh.method, reqURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("Error creating webhook request: %v", err)
+	}
+
+	if wh.body == nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, headerTmpl := range wh.headers {
+		value, err := executeWebHookTemplate(headerTmpl, object)
+		if err != nil {
+			return err
+		}
+		req.Header.Set(name, value)
+	}
+
+	if wh.debugLogger != nil {
+		wh.debugLogger.LogQueryWithValues(wh.method+" "+reqURL, []interface{}{string(body)})
+	}
+
+	r, err := wh.client.Do(req)
+	if err != nil {
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			return &RetryableError{Err: err}
+		}
+		return err
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode >= 200 && r.StatusCode < 300 {
+		return nil
+	}
+
+	responseBody, _ := ioutil.ReadAll(&io.LimitedReader{R: r.Body, N: maxWebHookResponseBodySize})
+	responseErr := fmt.Errorf("WebHook response code: %d body: %s", r.StatusCode, string(responseBody))
+	if r.StatusCode >= 500 {
+		return &RetryableError{Err: responseErr}
+	}
+
+	return responseErr
+}
+
+//GetTableSchema always return empty schema
+func (wh *WebHook) GetTableSchema(tableName string) (*Table, error) {
+	return &Table{
+		Name:           tableName,
+		Columns:        Columns{},
+		PKFields:       map[string]bool{},
+		DeletePkFields: false,
+		Version:        0,
+	}, nil
+}
+
+//CreateTable WebHook doesn't use tables
+func (wh *WebHook) CreateTable(schemaToCreate *Table) error {
+	return nil
+}
+
+//PatchTableSchema WebHook doesn't use tables
+func (wh *WebHook) PatchTableSchema(schemaToAdd *Table) error {
+	return nil
+}
+
+func (wh *WebHook) Close() error {
+	wh.client.CloseIdleConnections()
+
+	return nil
+}
+
+//parseWebHookTemplate return parsed template where missing values are rendered as empty strings:
+//missingkey=zero renders a missing map[string]interface{} value as nil interface which is printed as <no value>,
+//so a value func is added to the end of every printing action pipeline (see webHookActionsFinalizer)
+func parseWebHookTemplate(name, expression string, isURL bool) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(webHookTemplateFuncs).Option("missingkey=zero").Parse(expression)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing webhook %s template: %v", name, err)
+	}
+
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			finalizer := &webHookActionsFinalizer{isURL: isURL}
+			finalizer.walk(t.Tree.Root)
+		}
+	}
+
+	return tmpl, nil
+}
+
+//webHookActionsFinalizer walks template nodes in the document order and adds a value func to the end of every printing action:
+//URL values before '?' are escaped with url.PathEscape and after '?' with url.QueryEscape
+type webHookActionsFinalizer struct {
+	isURL   bool
+	inQuery bool
+}
+
+func (waf *webHookActionsFinalizer) walk(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			waf.walk(child)
+		}
+	case *parse.TextNode:
+		if bytes.ContainsRune(n.Text, '?') {
+			waf.inQuery = true
+		}
+	case *parse.ActionNode:
+		//variable declarations aren't printed
+		if len(n.Pipe.Decl) > 0 {
+			return
+		}
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{NodeType: parse.NodeCommand, Pos: n.Pos, Args: []parse.Node{parse.NewIdentifier(waf.valueFunc()).SetPos(n.Pos)}})
+	case *parse.IfNode:
+		waf.walk(n.List)
+		waf.walk(n.ElseList)
+	case *parse.RangeNode:
+		waf.walk(n.List)
+		waf.walk(n.ElseList)
+	case *parse.WithNode:
+		waf.walk(n.List)
+		waf.walk(n.ElseList)
+	}
+}
+
+func (waf *webHookActionsFinalizer) valueFunc() string {
+	if !waf.isURL {
+		return webHookValueFunc
+	}
+	if waf.inQuery {
+		return webHookQueryValueFunc
+	}
+	return webHookPathValueFunc
+}
+
+//executeWebHookTemplate return rendered template. Missing values are rendered as empty strings
+func executeWebHookTemplate(tmpl *template.Template, object map[string]interface{}) (result string, err error) {
+	//panic handler
+	defer func() {
+		if r := recover(); r != nil {
+			result = ""
+			err = fmt.Errorf("Error executing webhook %s template: %v", tmpl.Name(), r)
+		}
+	}()
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, object); err != nil {
+		return "", fmt.Errorf("Error executing webhook %s template: %v", tmpl.Name(), err)
+	}
+
+	return buf.String(), nil
+}
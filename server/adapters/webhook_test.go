@@ -0,0 +1,143 @@
+package adapters
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebHookSend(t *testing.T) {
+	var requestMethod, requestPath, requestBody string
+	var requestHeaders http.Header
+	var responseCode int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		requestMethod = r.Method
+		requestPath = r.URL.RequestURI()
+		requestBody = string(b)
+		requestHeaders = r.Header
+		w.WriteHeader(responseCode)
+		w.Write([]byte("response"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name             string
+		config           *WebHookConfig
+		object           map[string]interface{}
+		responseCode     int
+		expectedMethod   string
+		expectedPath     string
+		expectedBody     string
+		expectedHeaders  map[string]string
+		expectedErr      string
+		expectedRetrying bool
+	}{
+		{
+			"Default body and method",
+			&WebHookConfig{URL: server.URL + "/events"},
+			map[string]interface{}{"event_type": "signup", "user": map[string]interface{}{"id": 1}},
+			http.StatusOK,
+			http.MethodPost,
+			"/events",
+			`{"event_type":"signup","user":{"id":1}}`,
+			map[string]string{"Content-Type": "application/json"},
+			"",
+			false,
+		},
+		{
+			"Templated url, headers and body",
+			&WebHookConfig{
+				URL:     server.URL + "/users/{{.user.id}}?type={{.event_type}}",
+				Method:  "put",
+				Body:    `{"email": {{.user.email | json}}, "name": "{{.user.name}}", "missing": {{.missing | json}}}`,
+				Headers: map[string]string{"Content-Type": "application/json; charset=utf-8", "X-Event-Type": "{{.event_type}}", "X-Missing": "{{.missing}}"},
+			},
+			map[string]interface{}{"event_type": "signup", "user": map[string]interface{}{"id": 1, "email": "a@b.com", "name": "John"}},
+			http.StatusCreated,
+			http.MethodPut,
+			"/users/1?type=signup",
+			`{"email": "a@b.com", "name": "John", "missing": null}`,
+			map[string]string{"Content-Type": "application/json; charset=utf-8", "X-Event-Type": "signup", "X-Missing": ""},
+			"",
+			false,
+		},
+		{
+			"Escaped url values and not missing <no value> strings",
+			&WebHookConfig{
+				URL:  server.URL + "/users/{{.user.id}}?email={{.user.email}}{{if .user.name}}&name={{.user.name}}{{end}}",
+				Body: `{{.text}}{{.missing}}`,
+			},
+			map[string]interface{}{"text": "<no value>", "user": map[string]interface{}{"id": "a b/c", "email": "a+b@c.com", "name": "J&J"}},
+			http.StatusOK,
+			http.MethodPost,
+			"/users/a%20b%2Fc?email=a%2Bb%40c.com&name=J%26J",
+			`<no value>`,
+			nil,
+			"",
+			false,
+		},
+		{
+			"Server error is retryable",
+			&WebHookConfig{URL: server.URL},
+			map[string]interface{}{"a": "b"},
+			http.StatusServiceUnavailable,
+			http.MethodPost,
+			"/",
+			`{"a":"b"}`,
+			nil,
+			"WebHook response code: 503 body: response",
+			true,
+		},
+		{
+			"Client error isn't retryable",
+			&WebHookConfig{URL: server.URL},
+			map[string]interface{}{"a": "b"},
+			http.StatusBadRequest,
+			http.MethodPost,
+			"/",
+			`{"a":"b"}`,
+			nil,
+			"WebHook response code: 400 body: response",
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, tt.config.Validate())
+			responseCode = tt.responseCode
+
+			webHook, err := NewWebHook(tt.config, nil)
+			require.NoError(t, err)
+			defer webHook.Close()
+
+			err = webHook.Send(tt.object)
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				_, retrying := err.(*RetryableError)
+				require.Equal(t, tt.expectedRetrying, retrying)
+			} else {
+				require.NoError(t, err)
+			}
+
+			require.Equal(t, tt.expectedMethod, requestMethod)
+			require.Equal(t, tt.expectedPath, requestPath)
+			require.Equal(t, tt.expectedBody, requestBody)
+			for name, value := range tt.expectedHeaders {
+				require.Equal(t, value, requestHeaders.Get(name), name)
+			}
+		})
+	}
+}
+
+func TestWebHookConfigValidate(t *testing.T) {
+	require.EqualError(t, (*WebHookConfig)(nil).Validate(), "webhook config is required")
+	require.EqualError(t, (&WebHookConfig{}).Validate(), "url is required parameter")
+	require.EqualError(t, (&WebHookConfig{URL: "http://localhost", Method: "HEAD"}).Validate(), "Unsupported method [HEAD]. Supported: GET, POST, PUT, PATCH, DELETE")
+
+	_, err := NewWebHook(&WebHookConfig{URL: "http://localhost/{{.a"}, nil)
+	require.EqualError(t, err, "Error parsing webhook url template: template: url:1: unclosed action")
+}
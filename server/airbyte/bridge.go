@@ -0,0 +1,33 @@
+package airbyte
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+)
+
+var Instance *Bridge
+
+//Bridge keeps Airbyte connectors configuration: directory for connectors json files (config, catalog, state)
+//and connectors logs (stderr) writer
+type Bridge struct {
+	ConfigDir string
+	LogWriter io.Writer
+}
+
+func Init(configDir string, logWriter io.Writer) error {
+	if configDir == "" {
+		return errors.New("Airbyte bridge config dir can't be empty")
+	}
+
+	if logWriter == nil {
+		logWriter = ioutil.Discard
+	}
+
+	Instance = &Bridge{
+		ConfigDir: configDir,
+		LogWriter: logWriter,
+	}
+
+	return nil
+}
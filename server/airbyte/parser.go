@@ -0,0 +1,167 @@
+package airbyte
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/schema"
+	"github.com/jitsucom/jitsu/server/singer"
+)
+
+const batchSize = 500
+
+//StreamParseOutput parses read command output: RECORD messages are collected into singer.OutputRepresentation,
+//LOG messages are written into logger. Collected records are passed to consumer with the last STATE
+//every time when >= batchSize records are collected and STATE message is received.
+//Stream primary key fields are passed as destination table primary key fields
+func StreamParseOutput(stdout io.Reader, catalog *ConfiguredCatalog, consumer singer.PortionConsumer, logger logging.TaskLogger) error {
+	logger.INFO("Airbyte sync will store data as batches >= [%d] elements size", batchSize)
+
+	keyFields := catalog.GetKeyFields()
+	overwriteStreams := catalog.GetOverwriteStreams()
+	outputPortion := &singer.OutputRepresentation{
+		Streams: map[string]*singer.StreamRepresentation{},
+	}
+
+	records := 0
+	stateChanged := false
+	err := scanMessages(stdout, func(message *Message, line []byte) error {
+		switch message.Type {
+		case RecordType:
+			if message.Record == nil || message.Record.Data == nil {
+				return fmt.Errorf("Error parsing airbyte record line %s: malformed record line 'record.data' doesn't exist", string(line))
+			}
+
+			records++
+			tableName := schema.Reformat(message.Record.Stream)
+			stream, ok := outputPortion.Streams[tableName]
+			if !ok {
+				stream = &singer.StreamRepresentation{
+					BatchHeader: &schema.BatchHeader{TableName: tableName, PKFields: pkFields(keyFields[message.Record.Stream])},
+					KeyFields:   keyFields[message.Record.Stream],
+					Overwrite:   overwriteStreams[message.Record.Stream],
+				}
+				outputPortion.Streams[tableName] = stream
+			}
+			stream.Objects = append(stream.Objects, message.Record.Data)
+		case StateType:
+			if message.State == nil {
+				return fmt.Errorf("Error parsing airbyte state line %s: malformed state line 'state' doesn't exist", string(line))
+			}
+
+			outputPortion.State = message.State.Data
+			stateChanged = true
+
+			//persist batch and recreate variables
+			if records >= batchSize {
+				if err := consumer.Consume(outputPortion); err != nil {
+					return err
+				}
+
+				//remove already persisted objects
+				for _, stream := range outputPortion.Streams {
+					stream.Objects = []map[string]interface{}{}
+				}
+				records = 0
+				stateChanged = false
+			}
+		case LogType:
+			writeLog(message.Log, logger)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	//persist last batch
+	if records > 0 || stateChanged {
+		return consumer.Consume(outputPortion)
+	}
+
+	return nil
+}
+
+//ParseMessage returns the first message with messageType from spec, check or discover commands output
+//LOG messages are written into logWriter
+func ParseMessage(stdout io.Reader, messageType string, logWriter io.Writer) (*Message, error) {
+	var result *Message
+	err := scanMessages(stdout, func(message *Message, line []byte) error {
+		if message.Type == LogType && message.Log != nil {
+			fmt.Fprintf(logWriter, "[%s] %s\n", message.Log.Level, message.Log.Message)
+		} else if message.Type == messageType && result == nil {
+			result = message
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result == nil {
+		return nil, fmt.Errorf("Airbyte connector output doesn't contain %s message", messageType)
+	}
+
+	return result, nil
+}
+
+//scanMessages parses every JSON line of connector output as Message and passes it to messageFunc
+//not JSON lines are skipped (connectors might write plain text logs into stdout)
+func scanMessages(stdout io.Reader, messageFunc func(message *Message, line []byte) error) error {
+	scanner := bufio.NewScanner(stdout)
+	buf := make([]byte, 0, 64*1024)
+	scanner.Buffer(buf, 10*1024*1024)
+
+	for scanner.Scan() {
+		lineBytes := scanner.Bytes()
+		if len(lineBytes) == 0 || lineBytes[0] != '{' {
+			continue
+		}
+
+		message := &Message{}
+		if err := json.Unmarshal(lineBytes, message); err != nil {
+			return fmt.Errorf("Error unmarshalling airbyte output line %s into json: %v", string(lineBytes), err)
+		}
+
+		if message.Type == "" {
+			return fmt.Errorf("Error getting airbyte message 'type' field from: %s", string(lineBytes))
+		}
+
+		if err := messageFunc(message, lineBytes); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+//pkFields returns reformatted primary key fields set or nil if key fields are empty
+func pkFields(keyFields []string) map[string]bool {
+	if len(keyFields) == 0 {
+		return nil
+	}
+
+	result := map[string]bool{}
+	for _, field := range keyFields {
+		result[schema.Reformat(field)] = true
+	}
+
+	return result
+}
+
+func writeLog(log *Log, logger logging.TaskLogger) {
+	if log == nil {
+		return
+	}
+
+	if log.IsError() {
+		logger.ERROR("[airbyte] %s", log.Message)
+	} else {
+		logger.INFO("[airbyte] %s: %s", log.Level, log.Message)
+	}
+}
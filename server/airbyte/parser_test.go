@@ -0,0 +1,119 @@
+package airbyte
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/singer"
+	"github.com/stretchr/testify/require"
+)
+
+type testLogger struct {
+	errors []string
+}
+
+func (tl *testLogger) INFO(format string, v ...interface{}) {}
+
+func (tl *testLogger) ERROR(format string, v ...interface{}) {
+	tl.errors = append(tl.errors, format)
+}
+
+//testConsumer keeps copies of consumed portions (parser reuses the same representation)
+type testConsumer struct {
+	states    []interface{}
+	objects   map[string][]map[string]interface{}
+	keys      map[string][]string
+	pkFields  map[string]map[string]bool
+	overwrite map[string]bool
+}
+
+func newTestConsumer() *testConsumer {
+	return &testConsumer{objects: map[string][]map[string]interface{}{}, keys: map[string][]string{},
+		pkFields: map[string]map[string]bool{}, overwrite: map[string]bool{}}
+}
+
+func (tc *testConsumer) Consume(representation *singer.OutputRepresentation) error {
+	tc.states = append(tc.states, representation.State)
+	for tableName, stream := range representation.Streams {
+		tc.objects[tableName] = append(tc.objects[tableName], stream.Objects...)
+		tc.keys[tableName] = stream.KeyFields
+		tc.pkFields[tableName] = stream.BatchHeader.PKFields
+		tc.overwrite[tableName] = stream.Overwrite
+	}
+
+	return nil
+}
+
+func TestStreamParseOutput(t *testing.T) {
+	output := `starting connector
+{"type": "LOG", "log": {"level": "INFO", "message": "reading users"}}
+{"type": "RECORD", "record": {"stream": "users", "data": {"id": 1, "name": "a"}, "emitted_at": 1}}
+{"type": "RECORD", "record": {"stream": "users", "data": {"id": 2, "name": "b"}, "emitted_at": 1}}
+{"type": "RECORD", "record": {"stream": "Orders", "data": {"id": 10}, "emitted_at": 1}}
+{"type": "LOG", "log": {"level": "ERROR", "message": "something went wrong"}}
+{"type": "STATE", "state": {"data": {"users": 2}}}
+`
+	catalog := &ConfiguredCatalog{Streams: []*ConfiguredStream{
+		{Stream: &Stream{Name: "users"}, PrimaryKey: [][]string{{"id"}}, DestinationSyncMode: AppendSyncMode},
+		{Stream: &Stream{Name: "Orders"}, DestinationSyncMode: OverwriteSyncMode},
+	}}
+
+	consumer := newTestConsumer()
+	logger := &testLogger{}
+	require.NoError(t, StreamParseOutput(strings.NewReader(output), catalog, consumer, logger))
+
+	require.Equal(t, []interface{}{map[string]interface{}{"users": float64(2)}}, consumer.states)
+	require.Len(t, consumer.objects["users"], 2)
+	require.Len(t, consumer.objects["orders"], 1)
+	require.Equal(t, []string{"id"}, consumer.keys["users"])
+	require.Empty(t, consumer.keys["orders"])
+	require.Equal(t, map[string]bool{"id": true}, consumer.pkFields["users"])
+	require.Nil(t, consumer.pkFields["orders"])
+	require.False(t, consumer.overwrite["users"])
+	require.True(t, consumer.overwrite["orders"])
+	require.Len(t, logger.errors, 1)
+}
+
+func TestStreamParseOutputMalformed(t *testing.T) {
+	consumer := newTestConsumer()
+
+	err := StreamParseOutput(strings.NewReader(`{"type": "RECORD", "record": {"stream": "users"}}`), nil, consumer, &testLogger{})
+	require.Error(t, err)
+
+	err = StreamParseOutput(strings.NewReader(`{"record": {"stream": "users", "data": {}}}`), nil, consumer, &testLogger{})
+	require.Error(t, err)
+	require.Empty(t, consumer.states)
+}
+
+func TestParseMessage(t *testing.T) {
+	output := `{"type": "LOG", "log": {"level": "INFO", "message": "checking"}}
+{"type": "CONNECTION_STATUS", "connectionStatus": {"status": "FAILED", "message": "wrong password"}}
+`
+	message, err := ParseMessage(strings.NewReader(output), ConnectionStatusType, ioutil.Discard)
+	require.NoError(t, err)
+	require.Equal(t, &ConnectionStatus{Status: "FAILED", Message: "wrong password"}, message.ConnectionStatus)
+
+	_, err = ParseMessage(strings.NewReader(output), CatalogType, ioutil.Discard)
+	require.EqualError(t, err, "Airbyte connector output doesn't contain CATALOG message")
+}
+
+func TestNewConfiguredCatalog(t *testing.T) {
+	catalog := &Catalog{Streams: []*Stream{
+		{Name: "users", SupportedSyncModes: []string{FullRefreshSyncMode, IncrementalSyncMode}, SourceDefinedCursor: true, SourceDefinedPrimaryKey: [][]string{{"id"}}},
+		{Name: "events", SupportedSyncModes: []string{FullRefreshSyncMode, IncrementalSyncMode}},
+		{Name: "orders", SupportedSyncModes: []string{FullRefreshSyncMode}},
+	}}
+
+	configured := NewConfiguredCatalog(catalog)
+	require.Len(t, configured.Streams, 3)
+	require.Equal(t, IncrementalSyncMode, configured.Streams[0].SyncMode)
+	require.Equal(t, FullRefreshSyncMode, configured.Streams[1].SyncMode)
+	require.Equal(t, FullRefreshSyncMode, configured.Streams[2].SyncMode)
+	require.Equal(t, AppendSyncMode, configured.Streams[0].DestinationSyncMode)
+	require.Equal(t, OverwriteSyncMode, configured.Streams[1].DestinationSyncMode)
+	require.Equal(t, OverwriteSyncMode, configured.Streams[2].DestinationSyncMode)
+
+	require.Equal(t, map[string][]string{"users": {"id"}, "events": nil, "orders": nil}, configured.GetKeyFields())
+	require.Equal(t, map[string]bool{"events": true, "orders": true}, configured.GetOverwriteStreams())
+}
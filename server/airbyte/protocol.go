@@ -0,0 +1,155 @@
+package airbyte
+
+import "strings"
+
+//Airbyte protocol message types
+const (
+	RecordType           = "RECORD"
+	StateType            = "STATE"
+	LogType              = "LOG"
+	SpecType             = "SPEC"
+	ConnectionStatusType = "CONNECTION_STATUS"
+	CatalogType          = "CATALOG"
+	TraceType            = "TRACE"
+
+	SucceededStatus = "SUCCEEDED"
+
+	FullRefreshSyncMode = "full_refresh"
+	IncrementalSyncMode = "incremental"
+	AppendSyncMode      = "append"
+	OverwriteSyncMode   = "overwrite"
+)
+
+//Message is an Airbyte protocol message (one line of connector output)
+type Message struct {
+	Type             string                 `json:"type"`
+	Log              *Log                   `json:"log,omitempty"`
+	Spec             map[string]interface{} `json:"spec,omitempty"`
+	ConnectionStatus *ConnectionStatus      `json:"connectionStatus,omitempty"`
+	Catalog          *Catalog               `json:"catalog,omitempty"`
+	Record           *Record                `json:"record,omitempty"`
+	State            *State                 `json:"state,omitempty"`
+}
+
+type Log struct {
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+//IsError returns true if log level is ERROR or FATAL
+func (l *Log) IsError() bool {
+	level := strings.ToUpper(l.Level)
+	return level == "ERROR" || level == "FATAL"
+}
+
+type ConnectionStatus struct {
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+type Record struct {
+	Stream    string                 `json:"stream"`
+	Data      map[string]interface{} `json:"data"`
+	EmittedAt int64                  `json:"emitted_at"`
+	Namespace string                 `json:"namespace,omitempty"`
+}
+
+type State struct {
+	Data interface{} `json:"data"`
+}
+
+//Catalog is a result of discover command: all available streams
+type Catalog struct {
+	Streams []*Stream `json:"streams"`
+}
+
+type Stream struct {
+	Name                    string                 `json:"name"`
+	JSONSchema              map[string]interface{} `json:"json_schema"`
+	SupportedSyncModes      []string               `json:"supported_sync_modes,omitempty"`
+	SourceDefinedCursor     bool                   `json:"source_defined_cursor,omitempty"`
+	DefaultCursorField      []string               `json:"default_cursor_field,omitempty"`
+	SourceDefinedPrimaryKey [][]string             `json:"source_defined_primary_key,omitempty"`
+	Namespace               string                 `json:"namespace,omitempty"`
+}
+
+//ConfiguredCatalog is a read command parameter: streams for synchronization with sync modes
+type ConfiguredCatalog struct {
+	Streams []*ConfiguredStream `json:"streams"`
+}
+
+type ConfiguredStream struct {
+	Stream              *Stream    `json:"stream"`
+	SyncMode            string     `json:"sync_mode"`
+	CursorField         []string   `json:"cursor_field,omitempty"`
+	DestinationSyncMode string     `json:"destination_sync_mode"`
+	PrimaryKey          [][]string `json:"primary_key,omitempty"`
+}
+
+//NewConfiguredCatalog returns ConfiguredCatalog with all catalog streams:
+//streams are synchronized in incremental mode if it is supported (with source defined cursor) otherwise in full refresh mode.
+//Full refresh streams are overwritten in destinations (objects of the previous synchronization are deleted)
+func NewConfiguredCatalog(catalog *Catalog) *ConfiguredCatalog {
+	configuredCatalog := &ConfiguredCatalog{Streams: []*ConfiguredStream{}}
+	for _, stream := range catalog.Streams {
+		syncMode := FullRefreshSyncMode
+		destinationSyncMode := OverwriteSyncMode
+		for _, supportedSyncMode := range stream.SupportedSyncModes {
+			if supportedSyncMode == IncrementalSyncMode && (stream.SourceDefinedCursor || len(stream.DefaultCursorField) > 0) {
+				syncMode = IncrementalSyncMode
+				destinationSyncMode = AppendSyncMode
+				break
+			}
+		}
+
+		configuredCatalog.Streams = append(configuredCatalog.Streams, &ConfiguredStream{
+			Stream:              stream,
+			SyncMode:            syncMode,
+			CursorField:         stream.DefaultCursorField,
+			DestinationSyncMode: destinationSyncMode,
+			PrimaryKey:          stream.SourceDefinedPrimaryKey,
+		})
+	}
+
+	return configuredCatalog
+}
+
+//GetKeyFields returns stream name - primary key fields (top level fields of primary key paths)
+func (cc *ConfiguredCatalog) GetKeyFields() map[string][]string {
+	keyFields := map[string][]string{}
+	if cc == nil {
+		return keyFields
+	}
+
+	for _, configuredStream := range cc.Streams {
+		if configuredStream.Stream == nil {
+			continue
+		}
+
+		var fields []string
+		for _, keyPath := range configuredStream.PrimaryKey {
+			if len(keyPath) > 0 {
+				fields = append(fields, keyPath[0])
+			}
+		}
+		keyFields[configuredStream.Stream.Name] = fields
+	}
+
+	return keyFields
+}
+
+//GetOverwriteStreams returns names of streams with overwrite destination sync mode
+func (cc *ConfiguredCatalog) GetOverwriteStreams() map[string]bool {
+	overwriteStreams := map[string]bool{}
+	if cc == nil {
+		return overwriteStreams
+	}
+
+	for _, configuredStream := range cc.Streams {
+		if configuredStream.Stream != nil && configuredStream.DestinationSyncMode == OverwriteSyncMode {
+			overwriteStreams[configuredStream.Stream.Name] = true
+		}
+	}
+
+	return overwriteStreams
+}
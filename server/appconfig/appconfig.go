@@ -21,6 +21,7 @@ type AppConfig struct {
 	GlobalDDLLogsWriter   io.Writer
 	GlobalQueryLogsWriter io.Writer
 	SingerLogsWriter      io.Writer
+	AirbyteLogsWriter     io.Writer
 	DisableSkipEventsWarn bool
 
 	closeMe []io.Closer
@@ -48,6 +49,11 @@ func setDefaultParams(containerized bool) {
 	viper.SetDefault("server.cache.events.size", 100)
 	viper.SetDefault("server.strict_auth_tokens", false)
 	viper.SetDefault("server.max_columns", 100)
+	viper.SetDefault("server.bulk.max_body_size_bytes", 10*1024*1024)
+	viper.SetDefault("server.bulk.max_decompressed_size_bytes", 100*1024*1024)
+	viper.SetDefault("server.bulk.max_line_size_bytes", 1024*1024)
+	viper.SetDefault("server.bulk.max_events", 10000)
+	viper.SetDefault("server.fallback.auto_retry_check_sec", 30)
 	viper.SetDefault("log.show_in_server", false)
 	viper.SetDefault("log.rotation_min", 5)
 	viper.SetDefault("sql_debug_log.queries.rotation_min", "1440")
@@ -58,16 +64,19 @@ func setDefaultParams(containerized bool) {
 	viper.SetDefault("singer-bridge.python", "python3")
 	viper.SetDefault("singer-bridge.install_taps", true)
 	viper.SetDefault("singer-bridge.log.rotation_min", "1440")
+	viper.SetDefault("airbyte-bridge.log.rotation_min", "1440")
 	if containerized {
 		viper.SetDefault("geo.maxmind_path", "/home/eventnative/data/config")
 		viper.SetDefault("log.path", "/home/eventnative/data/logs/events")
 		viper.SetDefault("server.log.path", "/home/eventnative/data/logs")
 		viper.SetDefault("singer-bridge.venv_dir", "/home/eventnative/data/venv")
+		viper.SetDefault("airbyte-bridge.config_dir", "/home/eventnative/data/airbyte")
 	} else {
 		viper.SetDefault("geo.maxmind_path", "./")
 		viper.SetDefault("log.path", "./logs/events")
 		viper.SetDefault("server.log.path", "./logs")
 		viper.SetDefault("singer-bridge.venv_dir", "./venv")
+		viper.SetDefault("airbyte-bridge.config_dir", "./airbyte")
 	}
 }
 
@@ -151,6 +160,18 @@ func Init(containerized bool, dockerHubID string) error {
 		appConfig.SingerLogsWriter = logging.CreateLogWriter(&logging.Config{FileDir: logging.GlobalType})
 	}
 
+	// Airbyte logger
+	if viper.IsSet("airbyte-bridge.log.path") {
+		airbyteLoggerViper := viper.Sub("airbyte-bridge.log")
+		appConfig.AirbyteLogsWriter = logging.CreateLogWriter(&logging.Config{
+			FileName:    serverName + "-" + "airbyte",
+			FileDir:     airbyteLoggerViper.GetString("path"),
+			RotationMin: airbyteLoggerViper.GetInt64("rotation_min"),
+			MaxBackups:  airbyteLoggerViper.GetInt("max_backups")})
+	} else {
+		appConfig.AirbyteLogsWriter = logging.CreateLogWriter(&logging.Config{FileDir: logging.GlobalType})
+	}
+
 	port := viper.GetString("port")
 	if port == "" {
 		port = viper.GetString("server.port")
@@ -63,8 +63,8 @@ func NewEtcdService(ctx context.Context, serverName, endpoint string, connectTim
 	return es, nil
 }
 
-//NewService return EtcdService (etcd) if was configured or InMemoryService otherwise
-//starts EtcdService heart beat goroutine: see EtcdService.startHeartBeating()
+//NewService return EtcdService (etcd) or RedisService (redis) if was configured or InMemoryService otherwise
+//starts EtcdService/RedisService heart beat goroutine: see EtcdService.startHeartBeating()
 func NewService(ctx context.Context, serverName string, viper *viper.Viper) (Service, error) {
 	if viper == nil {
 		logging.Warn("Using in-memory coordination service so as no configuration is provided")
@@ -91,8 +91,10 @@ func NewService(ctx context.Context, serverName string, viper *viper.Viper) (Ser
 
 		logging.Info("Using etcd as a coordination service")
 		return es, nil
+	} else if viper.IsSet("redis") {
+		return NewRedisService(ctx, serverName, viper.GetString("redis.host"), viper.GetInt("redis.port"), viper.GetString("redis.password"))
 	} else {
-		return nil, fmt.Errorf("Unknown coordination service type. Supported: etcd")
+		return nil, fmt.Errorf("Unknown coordination service type. Supported: etcd, redis")
 	}
 }
 
@@ -2,40 +2,81 @@ package coordination
 
 import (
 	"github.com/stretchr/testify/require"
+	"sync"
 	"testing"
-	"time"
 )
 
 func TestIncrementVersion(t *testing.T) {
-	ims := NewInMemoryService([]string{"instance1"})
+	testIncrementVersion(t, NewInMemoryService([]string{"instance1"}))
+}
+
+func TestLock(t *testing.T) {
+	testLock(t, NewInMemoryService([]string{"instance1"}))
+}
 
+//testIncrementVersion checks concurrent versions incrementing
+//is used for all Service implementations
+func testIncrementVersion(t *testing.T, service Service) {
+	wg := sync.WaitGroup{}
+	wg.Add(2)
 	go func() {
+		defer wg.Done()
 		for i := 0; i < 10000; i++ {
-			ims.GetVersion("system1", "collection1")
-			ims.IncrementVersion("system1", "collection1")
+			service.GetVersion("system1", "collection1")
+			service.IncrementVersion("system1", "collection1")
 
-			ims.IncrementVersion("system2", "collection2")
-			ims.GetVersion("system2", "collection2")
+			service.IncrementVersion("system2", "collection2")
+			service.GetVersion("system2", "collection2")
 		}
 	}()
 
 	go func() {
+		defer wg.Done()
 		for i := 0; i < 4000; i++ {
-			ims.IncrementVersion("system2", "collection2")
-			ims.GetVersion("system2", "collection2")
+			service.IncrementVersion("system2", "collection2")
+			service.GetVersion("system2", "collection2")
 
-			ims.GetVersion("system1", "collection1")
-			ims.IncrementVersion("system1", "collection1")
+			service.GetVersion("system1", "collection1")
+			service.IncrementVersion("system1", "collection1")
 		}
 	}()
 
-	time.Sleep(1 * time.Second)
+	wg.Wait()
 
-	version1, err1 := ims.GetVersion("system1", "collection1")
+	version1, err1 := service.GetVersion("system1", "collection1")
 	require.NoError(t, err1)
 	require.Equal(t, version1, int64(14000))
 
-	version2, err2 := ims.GetVersion("system2", "collection2")
+	version2, err2 := service.GetVersion("system2", "collection2")
 	require.NoError(t, err2)
 	require.Equal(t, version2, int64(14000))
 }
+
+//testLock checks locking and unlocking
+//is used for all Service implementations
+func testLock(t *testing.T, service Service) {
+	lock, err := service.TryLock("system1", "collection1")
+	require.NoError(t, err)
+	require.Equal(t, "system1_collection1", lock.Identifier())
+
+	_, err = service.TryLock("system1", "collection1")
+	require.Error(t, err)
+
+	locked, err := service.IsLocked("system1", "collection1")
+	require.NoError(t, err)
+	require.True(t, locked)
+
+	anotherLock, err := service.TryLock("system1", "collection2")
+	require.NoError(t, err)
+
+	require.NoError(t, service.Unlock(lock))
+	require.NoError(t, service.Unlock(anotherLock))
+
+	locked, err = service.IsLocked("system1", "collection1")
+	require.NoError(t, err)
+	require.False(t, locked)
+
+	lock, err = service.Lock("system1", "collection1")
+	require.NoError(t, err)
+	require.NoError(t, service.Unlock(lock))
+}
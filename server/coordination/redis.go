@@ -0,0 +1,337 @@
+package coordination
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/jitsucom/jitsu/server/safego"
+	"github.com/jitsucom/jitsu/server/storages"
+	"github.com/jitsucom/jitsu/server/uuid"
+)
+
+const (
+	redisLockPrefix     = "coordination_lock:"
+	redisVersionPrefix  = "coordination_version:"
+	redisInstancePrefix = "coordination_instance:"
+
+	defaultRedisLockLeaseTTL = 60 * time.Second
+	redisLockWaitTimeout     = 2 * time.Minute
+	redisLockRetryInterval   = 100 * time.Millisecond
+
+	redisHeartBeatInterval = 90 * time.Second
+	redisInstanceTTL       = 120 * time.Second
+)
+
+//delete lock key only if it is held by the lock owner (value = owner token)
+var unlockScript = redis.NewScript(1, `
+if redis.call('get', KEYS[1]) == ARGV[1] then
+  return redis.call('del', KEYS[1])
+end
+return 0`)
+
+//prolong lock key TTL only if it is held by the lock owner (value = owner token)
+var renewLockScript = redis.NewScript(1, `
+if redis.call('get', KEYS[1]) == ARGV[1] then
+  return redis.call('pexpire', KEYS[1], ARGV[2])
+end
+return 0`)
+
+//redis key [variables] - description
+//
+//coordination_lock:system_collection [token] - lock owner token with lease TTL (it is renewed while the lock is held)
+//coordination_version:system_collection [version] - table version counter
+//coordination_instance:serverName [serverName] - instance heart beat with TTL
+
+//RedisService - Redis implementation for Service
+type RedisService struct {
+	serverName   string
+	ctx          context.Context
+	pool         *redis.Pool
+	lockLeaseTTL time.Duration
+
+	mutex    sync.RWMutex
+	unlockMe map[string]*storages.RetryableLock
+	closed   bool
+}
+
+//redisLock is a storages.ResourceLock implementation with owner token
+type redisLock struct {
+	pool  *redis.Pool
+	key   string
+	token string
+}
+
+//NewRedisService returns configured RedisService and starts heart beat goroutine: see RedisService.startHeartBeating()
+func NewRedisService(ctx context.Context, serverName, host string, port int, password string) (*RedisService, error) {
+	if host == "" {
+		return nil, fmt.Errorf("'coordination.redis.host' is required parameter")
+	}
+	if port == 0 {
+		port = 6379
+	}
+
+	pool := meta.NewRedisPool(host, port, password)
+
+	//test connection
+	connection := pool.Get()
+	defer connection.Close()
+	if _, err := redis.String(connection.Do("PING")); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("Error testing connection to Redis: %v", err)
+	}
+
+	rs := &RedisService{
+		serverName:   serverName,
+		ctx:          ctx,
+		pool:         pool,
+		lockLeaseTTL: defaultRedisLockLeaseTTL,
+		unlockMe:     map[string]*storages.RetryableLock{},
+	}
+	rs.startHeartBeating()
+
+	logging.Infof("Using redis [%s:%d] as a coordination service", host, port)
+	return rs, nil
+}
+
+//Lock try to get Redis lock with timeout (2 minutes)
+//wait if lock has been already acquired
+func (rs *RedisService) Lock(system string, collection string) (storages.Lock, error) {
+	identifier := getIdentifier(system, collection)
+	deadline := time.Now().Add(redisLockWaitTimeout)
+	for {
+		lock, err := rs.acquire(identifier)
+		if err != ErrAlreadyLocked {
+			return lock, err
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("Error locking [%s]: timeout %s exceeded", identifier, redisLockWaitTimeout)
+		}
+
+		time.Sleep(redisLockRetryInterval)
+	}
+}
+
+//TryLock try to get Redis lock
+//err if locked immediately
+func (rs *RedisService) TryLock(system string, collection string) (storages.Lock, error) {
+	return rs.acquire(getIdentifier(system, collection))
+}
+
+func (rs *RedisService) Unlock(lock storages.Lock) error {
+	lock.Unlock()
+
+	rs.mutex.Lock()
+	delete(rs.unlockMe, lock.Identifier())
+	rs.mutex.Unlock()
+
+	return nil
+}
+
+//IsLocked return true if already locked
+func (rs *RedisService) IsLocked(system string, collection string) (bool, error) {
+	conn := rs.pool.Get()
+	defer conn.Close()
+
+	return redis.Bool(conn.Do("EXISTS", redisLockPrefix+getIdentifier(system, collection)))
+}
+
+func (rs *RedisService) GetVersion(system string, collection string) (int64, error) {
+	conn := rs.pool.Get()
+	defer conn.Close()
+
+	version, err := redis.Int64(conn.Do("GET", redisVersionPrefix+getIdentifier(system, collection)))
+	if err != nil {
+		//initial version is requested
+		if err == redis.ErrNil {
+			return 0, nil
+		}
+
+		return -1, err
+	}
+
+	return version, nil
+}
+
+//IncrementVersion increments version atomically
+func (rs *RedisService) IncrementVersion(system string, collection string) (int64, error) {
+	conn := rs.pool.Get()
+	defer conn.Close()
+
+	version, err := redis.Int64(conn.Do("INCR", redisVersionPrefix+getIdentifier(system, collection)))
+	if err != nil {
+		return -1, err
+	}
+
+	return version, nil
+}
+
+//GetInstances returns server names which have sent heart beat during last 120 seconds
+func (rs *RedisService) GetInstances() ([]string, error) {
+	conn := rs.pool.Get()
+	defer conn.Close()
+
+	instances := []string{}
+	iter := 0
+	for {
+		values, err := redis.Values(conn.Do("SCAN", iter, "MATCH", redisInstancePrefix+"*"))
+		if err != nil {
+			return nil, fmt.Errorf("Error getting instances from Redis: %v", err)
+		}
+
+		iter, _ = redis.Int(values[0], nil)
+		keys, _ := redis.Strings(values[1], nil)
+		for _, key := range keys {
+			instance, err := redis.String(conn.Do("GET", key))
+			if err != nil {
+				//key has been expired between SCAN and GET
+				if err == redis.ErrNil {
+					continue
+				}
+
+				return nil, fmt.Errorf("Error getting instance [%s] from Redis: %v", key, err)
+			}
+
+			instances = append(instances, instance)
+		}
+
+		if iter == 0 {
+			break
+		}
+	}
+
+	return instances, nil
+}
+
+func (rs *RedisService) Close() error {
+	rs.closed = true
+
+	rs.mutex.Lock()
+	for identifier, lock := range rs.unlockMe {
+		logging.Infof("Unlocking [%s]..", identifier)
+
+		lock.Unlock()
+	}
+	rs.mutex.Unlock()
+
+	conn := rs.pool.Get()
+	if _, err := conn.Do("DEL", redisInstancePrefix+rs.serverName); err != nil {
+		logging.Errorf("Error deleting instance [%s] heart beat from Redis: %v", rs.serverName, err)
+	}
+	conn.Close()
+
+	return rs.pool.Close()
+}
+
+//acquire sets lock key with lease TTL if it doesn't exist and starts lease renewal goroutine
+//returns ErrAlreadyLocked if the key exists
+func (rs *RedisService) acquire(identifier string) (storages.Lock, error) {
+	conn := rs.pool.Get()
+	defer conn.Close()
+
+	resourceLock := &redisLock{pool: rs.pool, key: redisLockPrefix + identifier, token: uuid.New()}
+	_, err := redis.String(conn.Do("SET", resourceLock.key, resourceLock.token, "NX", "PX", rs.lockLeaseTTL.Milliseconds()))
+	if err != nil {
+		if err == redis.ErrNil {
+			return nil, ErrAlreadyLocked
+		}
+
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(rs.ctx)
+	rs.startLeaseRenewal(ctx, resourceLock)
+
+	lock := storages.NewRetryableLock(identifier, resourceLock, nil, cancel, 5)
+
+	rs.mutex.Lock()
+	rs.unlockMe[identifier] = lock
+	rs.mutex.Unlock()
+
+	return lock, nil
+}
+
+//startLeaseRenewal starts a new goroutine for prolonging lock lease every 1/3 of lease TTL until the lock is released
+func (rs *RedisService) startLeaseRenewal(ctx context.Context, lock *redisLock) {
+	safego.Run(func() {
+		ticker := time.NewTicker(rs.lockLeaseTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				renewed, err := lock.renew(rs.lockLeaseTTL)
+				if err != nil {
+					logging.Errorf("Error renewing lock [%s] lease: %v", lock.key, err)
+					continue
+				}
+
+				if !renewed {
+					logging.SystemErrorf("Lock [%s] has been lost: lease was expired", lock.key)
+					return
+				}
+			}
+		}
+	})
+}
+
+//starts a new goroutine for pushing serverName every 90 seconds to Redis with 120 seconds TTL
+func (rs *RedisService) startHeartBeating() {
+	safego.RunWithRestart(func() {
+		for {
+			if rs.closed {
+				break
+			}
+
+			if err := rs.heartBeat(); err != nil {
+				logging.Errorf("Error heart beat to Redis: %v", err)
+				//delay after error
+				time.Sleep(10 * time.Second)
+				continue
+			}
+
+			time.Sleep(redisHeartBeatInterval)
+		}
+	})
+}
+
+func (rs *RedisService) heartBeat() error {
+	conn := rs.pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("SET", redisInstancePrefix+rs.serverName, rs.serverName, "EX", int(redisInstanceTTL.Seconds()))
+	if err != nil {
+		return fmt.Errorf("error pushing value: %v", err)
+	}
+
+	return nil
+}
+
+//Unlock deletes lock key if it is still held by the lock owner
+func (rl *redisLock) Unlock(ctx context.Context) error {
+	conn := rl.pool.Get()
+	defer conn.Close()
+
+	_, err := unlockScript.Do(conn, rl.key, rl.token)
+	return err
+}
+
+//renew prolongs lock key TTL
+//returns false if the lock isn't held by the lock owner anymore
+func (rl *redisLock) renew(ttl time.Duration) (bool, error) {
+	conn := rl.pool.Get()
+	defer conn.Close()
+
+	result, err := redis.Int(renewLockScript.Do(conn, rl.key, rl.token, ttl.Milliseconds()))
+	if err != nil {
+		return false, err
+	}
+
+	return result == 1, nil
+}
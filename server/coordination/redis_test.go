@@ -0,0 +1,104 @@
+package coordination
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisIncrementVersion(t *testing.T) {
+	container := newTestRedisContainer(t)
+	defer container.Close()
+
+	service := newTestRedisService(t, container, "instance1")
+	defer service.Close()
+
+	testIncrementVersion(t, service)
+}
+
+func TestRedisLock(t *testing.T) {
+	container := newTestRedisContainer(t)
+	defer container.Close()
+
+	service := newTestRedisService(t, container, "instance1")
+	defer service.Close()
+
+	testLock(t, service)
+}
+
+func TestRedisLockLeaseRenewal(t *testing.T) {
+	container := newTestRedisContainer(t)
+	defer container.Close()
+
+	service := newTestRedisService(t, container, "instance1")
+	defer service.Close()
+	service.lockLeaseTTL = 300 * time.Millisecond
+
+	lock, err := service.TryLock("system1", "collection1")
+	require.NoError(t, err)
+
+	//lease is renewed while the lock is held
+	time.Sleep(time.Second)
+	_, err = service.TryLock("system1", "collection1")
+	require.Equal(t, ErrAlreadyLocked, err)
+
+	//Lock waits until the lock is released
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		service.Unlock(lock)
+	}()
+	lock, err = service.Lock("system1", "collection1")
+	require.NoError(t, err)
+	require.NoError(t, service.Unlock(lock))
+}
+
+func TestRedisGetInstances(t *testing.T) {
+	container := newTestRedisContainer(t)
+	defer container.Close()
+
+	service1 := newTestRedisService(t, container, "instance1")
+	defer service1.Close()
+	service2 := newTestRedisService(t, container, "instance2")
+
+	instances, err := service1.GetInstances()
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"instance1", "instance2"}, instances)
+
+	require.NoError(t, service2.Close())
+
+	instances, err = service1.GetInstances()
+	require.NoError(t, err)
+	require.Equal(t, []string{"instance1"}, instances)
+}
+
+func newTestRedisContainer(t *testing.T) *test.RedisContainer {
+	container, err := test.NewRedisContainer(context.Background())
+	if err != nil {
+		t.Fatalf("failed to initialize container: %v", err)
+	}
+
+	return container
+}
+
+//newTestRedisService returns RedisService with cleaned up coordination keys (Redis might be shared in CI)
+func newTestRedisService(t *testing.T, container *test.RedisContainer, serverName string) *RedisService {
+	service, err := NewRedisService(context.Background(), serverName, container.Host, container.Port, "")
+	if err != nil {
+		t.Fatalf("failed to initialize redis coordination service: %v", err)
+	}
+
+	conn := service.pool.Get()
+	defer conn.Close()
+	for _, identifier := range []string{"system1_collection1", "system1_collection2", "system2_collection2"} {
+		_, err := conn.Do("DEL", redisLockPrefix+identifier, redisVersionPrefix+identifier)
+		require.NoError(t, err)
+	}
+
+	//wait for the first heart beat
+	time.Sleep(100 * time.Millisecond)
+
+	return service
+}
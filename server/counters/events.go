@@ -61,3 +61,14 @@ func SkipEvents(destinationID string, value int) {
 		logging.SystemErrorf("Error updating skipped events counter destination [%s] value [%d]: %v", destinationID, value, err)
 	}
 }
+
+func FilterEvents(destinationID string, value int) {
+	if eventsInstance == nil {
+		return
+	}
+
+	err := eventsInstance.storage.FilterEvents(destinationID, meta.DestinationNamespace, time.Now().UTC(), value)
+	if err != nil {
+		logging.SystemErrorf("Error updating filtered events counter destination [%s] value [%d]: %v", destinationID, value, err)
+	}
+}
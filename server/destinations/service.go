@@ -57,6 +57,11 @@ func NewTestService(consumersByTokenID TokenizedConsumers, storagesByTokenID Tok
 	}
 }
 
+//only for tests
+func NewTestServiceWithUnits(unitsByName map[string]*Unit) *Service {
+	return &Service{unitsByName: unitsByName}
+}
+
 //NewService return loaded Service instance and call resources.Watcher() if destinations source is http url or file path
 func NewService(destinations *viper.Viper, destinationsSource string, storageFactory storages.Factory, loggerFactory *logging.Factory) (*Service, error) {
 	service := &Service{
@@ -129,6 +134,20 @@ func (s *Service) GetStorageByID(id string) (storages.StorageProxy, bool) {
 	return unit.storage, true
 }
 
+//GetAutoRetryConfig returns fallback auto retry configuration of the destination
+//or nil if the destination wasn't found or auto retry isn't enabled
+func (s *Service) GetAutoRetryConfig(id string) *storages.AutoRetryConfig {
+	s.RLock()
+	defer s.RUnlock()
+
+	unit, ok := s.unitsByName[id]
+	if !ok {
+		return nil
+	}
+
+	return unit.autoRetry
+}
+
 func (s *Service) GetStorages(tokenID string) (storages []storages.StorageProxy) {
 	s.RLock()
 	defer s.RUnlock()
@@ -237,6 +256,7 @@ func (s *Service) init(dc map[string]storages.DestinationConfig) {
 			storage:    newStorageProxy,
 			tokenIDs:   destinationConfig.OnlyTokens,
 			hash:       hash,
+			autoRetry:  destinationConfig.Fallback.GetAutoRetry(),
 		}
 
 		//create:
@@ -12,8 +12,14 @@ type Unit struct {
 	eventQueue *events.PersistentQueue
 	storage    storages.StorageProxy
 
-	tokenIDs []string
-	hash     uint64
+	tokenIDs  []string
+	hash      uint64
+	autoRetry *storages.AutoRetryConfig
+}
+
+//only for tests
+func NewTestUnit(storage storages.StorageProxy, autoRetry *storages.AutoRetryConfig) *Unit {
+	return &Unit{storage: storage, autoRetry: autoRetry}
 }
 
 //Close eventsQueue if exists and storage
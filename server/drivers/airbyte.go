@@ -0,0 +1,423 @@
+package drivers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/jitsucom/jitsu/server/airbyte"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/safego"
+	"github.com/jitsucom/jitsu/server/singer"
+	"github.com/jitsucom/jitsu/server/uuid"
+)
+
+const (
+	airbyteConfigFileName  = "config.json"
+	airbyteCatalogFileName = "catalog.json"
+	airbyteStateFileName   = "state.json"
+
+	//airbyteContainerDir is a directory in the connector docker container where config dir is mounted
+	airbyteContainerDir = "/data"
+)
+
+//AirbyteConfig is an Airbyte connector configuration:
+//connector docker image or local executable command and connector JSON files (config, configured catalog, initial state)
+type AirbyteConfig struct {
+	Image        string      `mapstructure:"image" json:"image,omitempty" yaml:"image,omitempty"`
+	Command      string      `mapstructure:"command" json:"command,omitempty" yaml:"command,omitempty"`
+	Config       interface{} `mapstructure:"config" json:"config,omitempty" yaml:"config,omitempty"`
+	Catalog      interface{} `mapstructure:"catalog" json:"catalog,omitempty" yaml:"catalog,omitempty"`
+	InitialState interface{} `mapstructure:"initial_state" json:"initial_state,omitempty" yaml:"initial_state,omitempty"`
+}
+
+func (ac *AirbyteConfig) Validate() error {
+	if ac == nil {
+		return errors.New("Airbyte config is required")
+	}
+
+	if ac.Image == "" && ac.Command == "" {
+		return errors.New("Airbyte connector image or command is required")
+	}
+
+	if ac.Image != "" && ac.Command != "" {
+		return errors.New("Airbyte connector image and command can't be configured together")
+	}
+
+	if ac.Config == nil {
+		return errors.New("Airbyte connector config is required")
+	}
+
+	return nil
+}
+
+//Airbyte is an Airbyte protocol connector driver. It runs connector commands (spec, check, discover, read)
+//as a local executable or as a docker image
+type Airbyte struct {
+	sync.RWMutex
+	commands map[string]*exec.Cmd
+
+	ctx        context.Context
+	sourceName string
+	image      string
+	command    string
+	configDir  string
+
+	//initial state file name (empty if isn't configured)
+	initialState string
+	//configured catalog (nil if isn't configured: all discovered streams will be synchronized)
+	catalog *airbyte.ConfiguredCatalog
+
+	closed bool
+}
+
+func init() {
+	if err := RegisterDriver(AirbyteType, NewAirbyte); err != nil {
+		logging.Errorf("Failed to register driver %s: %v", AirbyteType, err)
+	}
+}
+
+//NewAirbyte return Airbyte driver and
+//write json files (config, catalog, state) into source config dir
+func NewAirbyte(ctx context.Context, sourceConfig *SourceConfig, collection *Collection) (Driver, error) {
+	config := &AirbyteConfig{}
+	err := unmarshalConfig(sourceConfig.Config, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	if airbyte.Instance == nil {
+		return nil, errors.New("airbyte-bridge must be configured")
+	}
+
+	configDir, err := filepath.Abs(path.Join(airbyte.Instance.ConfigDir, sourceConfig.Name))
+	if err != nil {
+		return nil, fmt.Errorf("Error getting airbyte config dir: %v", err)
+	}
+	if err := logging.EnsureDir(configDir); err != nil {
+		return nil, fmt.Errorf("Error creating airbyte config dir: %v", err)
+	}
+
+	if err := writeJSONFile(path.Join(configDir, airbyteConfigFileName), config.Config); err != nil {
+		return nil, fmt.Errorf("Error parsing airbyte config [%v]: %v", config.Config, err)
+	}
+
+	var catalog *airbyte.ConfiguredCatalog
+	if config.Catalog != nil {
+		catalogPath := path.Join(configDir, airbyteCatalogFileName)
+		if err := writeJSONFile(catalogPath, config.Catalog); err != nil {
+			return nil, fmt.Errorf("Error parsing airbyte catalog [%v]: %v", config.Catalog, err)
+		}
+
+		catalog = &airbyte.ConfiguredCatalog{}
+		if err := readJSONFile(catalogPath, catalog); err != nil {
+			return nil, fmt.Errorf("Error parsing airbyte catalog [%v]: %v", config.Catalog, err)
+		}
+	}
+
+	var initialState string
+	if config.InitialState != nil {
+		initialState = "initial_" + airbyteStateFileName
+		if err := writeJSONFile(path.Join(configDir, initialState), config.InitialState); err != nil {
+			return nil, fmt.Errorf("Error parsing airbyte initial state [%v]: %v", config.InitialState, err)
+		}
+	}
+
+	return &Airbyte{
+		ctx:          ctx,
+		commands:     map[string]*exec.Cmd{},
+		sourceName:   sourceConfig.Name,
+		image:        config.Image,
+		command:      config.Command,
+		configDir:    configDir,
+		initialState: initialState,
+		catalog:      catalog,
+	}, nil
+}
+
+//GetCollectionTable unsupported
+func (a *Airbyte) GetCollectionTable() string {
+	return ""
+}
+
+//GetAllAvailableIntervals unsupported
+func (a *Airbyte) GetAllAvailableIntervals() ([]*TimeInterval, error) {
+	return nil, errors.New("Airbyte driver doesn't support GetAllAvailableIntervals() func. Please use Load()")
+}
+
+//GetObjectsFor unsupported
+func (a *Airbyte) GetObjectsFor(interval *TimeInterval) ([]map[string]interface{}, error) {
+	return nil, errors.New("Airbyte driver doesn't support GetObjectsFor() func. Please use Load()")
+}
+
+//GetConnector returns connector image without tag or command. It is used as a state key in meta.Storage
+//so the state is kept after connector image version upgrade
+func (a *Airbyte) GetConnector() string {
+	if a.image != "" {
+		return imageWithoutTag(a.image)
+	}
+
+	return a.command
+}
+
+//Spec returns connector specification (spec command)
+func (a *Airbyte) Spec() (map[string]interface{}, error) {
+	message, err := a.execMessageCmd(airbyte.SpecType, "spec")
+	if err != nil {
+		return nil, err
+	}
+
+	return message.Spec, nil
+}
+
+//Check returns error if connector can't connect with the configuration (check command)
+func (a *Airbyte) Check() error {
+	message, err := a.execMessageCmd(airbyte.ConnectionStatusType, "check", "--config", a.filePath(airbyteConfigFileName))
+	if err != nil {
+		return err
+	}
+
+	if message.ConnectionStatus == nil || message.ConnectionStatus.Status != airbyte.SucceededStatus {
+		var errMsg string
+		if message.ConnectionStatus != nil {
+			errMsg = message.ConnectionStatus.Message
+		}
+		return fmt.Errorf("Airbyte connection check failed: %s", errMsg)
+	}
+
+	return nil
+}
+
+//Discover returns all available streams (discover command)
+func (a *Airbyte) Discover() (*airbyte.Catalog, error) {
+	message, err := a.execMessageCmd(airbyte.CatalogType, "discover", "--config", a.filePath(airbyteConfigFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	if message.Catalog == nil {
+		return nil, errors.New("Airbyte discover output doesn't contain catalog")
+	}
+
+	return message.Catalog, nil
+}
+
+//Load runs read command with configured catalog (or with all discovered streams) and state
+//and passes parsed records and states to portionConsumer
+func (a *Airbyte) Load(state string, taskLogger logging.TaskLogger, portionConsumer singer.PortionConsumer) error {
+	if a.closed {
+		return errors.New("Airbyte has already been closed")
+	}
+
+	catalog := a.catalog
+	if catalog == nil {
+		taskLogger.INFO("Catalog isn't configured. Running discover..")
+		discovered, err := a.Discover()
+		if err != nil {
+			return fmt.Errorf("Error discovering streams: %v", err)
+		}
+
+		catalog = airbyte.NewConfiguredCatalog(discovered)
+		if err := writeJSONFile(path.Join(a.configDir, airbyteCatalogFileName), catalog); err != nil {
+			return fmt.Errorf("Error writing airbyte catalog: %v", err)
+		}
+	}
+
+	args := []string{"read", "--config", a.filePath(airbyteConfigFileName), "--catalog", a.filePath(airbyteCatalogFileName)}
+
+	//override initial state with existing one and put it to a file as is (the stored state is a connector JSON)
+	if state != "" {
+		if err := ioutil.WriteFile(path.Join(a.configDir, airbyteStateFileName), []byte(state), 0644); err != nil {
+			return fmt.Errorf("Error writing airbyte state %s: %v", state, err)
+		}
+		args = append(args, "--state", a.filePath(airbyteStateFileName))
+	} else if a.initialState != "" {
+		args = append(args, "--state", a.filePath(a.initialState))
+	}
+
+	syncCmd := a.buildCmd(args...)
+	taskLogger.INFO("exec airbyte %s", syncCmd.String())
+
+	stdout, _ := syncCmd.StdoutPipe()
+	defer stdout.Close()
+	stderr, _ := syncCmd.StderrPipe()
+	defer stderr.Close()
+
+	commandID := uuid.New()
+	a.Lock()
+	a.commands[commandID] = syncCmd
+	a.Unlock()
+
+	defer func() {
+		a.Lock()
+		delete(a.commands, commandID)
+		a.Unlock()
+	}()
+
+	if err := syncCmd.Start(); err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	var parsingErr error
+
+	//writing result (airbyte connector writes messages to stdout)
+	wg.Add(1)
+	safego.Run(func() {
+		defer wg.Done()
+		parsingErr = airbyte.StreamParseOutput(stdout, catalog, portionConsumer, taskLogger)
+		if parsingErr != nil {
+			taskLogger.ERROR("Parse output error: %v. Process will be killed", parsingErr)
+			logging.Errorf("[%s] parse airbyte output error: %v. Process will be killed", a.sourceName, parsingErr)
+
+			killErr := syncCmd.Process.Kill()
+			if killErr != nil {
+				taskLogger.ERROR("Error killing process: %v", killErr)
+				logging.Errorf("[%s] error killing airbyte process: %v", a.sourceName, killErr)
+			}
+		}
+	})
+
+	//writing process logs
+	wg.Add(1)
+	safego.Run(func() {
+		defer wg.Done()
+		io.Copy(airbyte.Instance.LogWriter, stderr)
+	})
+
+	wg.Wait()
+
+	if err := syncCmd.Wait(); err != nil {
+		if parsingErr != nil {
+			return parsingErr
+		}
+		return err
+	}
+
+	return parsingErr
+}
+
+//TestConnection runs check command
+func (a *Airbyte) TestConnection() error {
+	return a.Check()
+}
+
+func (a *Airbyte) Type() string {
+	return AirbyteType
+}
+
+func (a *Airbyte) Close() (multiErr error) {
+	a.closed = true
+
+	a.Lock()
+	for _, command := range a.commands {
+		logging.Infof("[%s] killing process: %s", a.sourceName, command.String())
+		if err := command.Process.Kill(); err != nil {
+			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error killing airbyte sync command: %v", a.sourceName, err))
+		}
+	}
+	a.Unlock()
+
+	return multiErr
+}
+
+//execMessageCmd runs connector command and returns the first message with messageType from the output
+func (a *Airbyte) execMessageCmd(messageType string, args ...string) (*airbyte.Message, error) {
+	cmd := a.buildCmd(args...)
+	errWriter := logging.NewStringWriter()
+	cmd.Stderr = errWriter
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("Error airbyte %s: %v. %s", args[0], err, errWriter.String())
+	}
+
+	message, err := airbyte.ParseMessage(bytes.NewReader(output), messageType, airbyte.Instance.LogWriter)
+	if err != nil {
+		return nil, fmt.Errorf("Error airbyte %s: %v", args[0], err)
+	}
+
+	return message, nil
+}
+
+//buildCmd returns local executable command or docker run command with mounted config dir
+func (a *Airbyte) buildCmd(args ...string) *exec.Cmd {
+	if a.image != "" {
+		dockerArgs := append([]string{"run", "--rm", "-i", "-v", a.configDir + ":" + airbyteContainerDir, a.image}, args...)
+		return exec.CommandContext(a.ctx, "docker", dockerArgs...)
+	}
+
+	commandParts := strings.Fields(a.command)
+	return exec.CommandContext(a.ctx, commandParts[0], append(commandParts[1:], args...)...)
+}
+
+//filePath returns path to file from config dir for the connector: local path or path in the docker container
+func (a *Airbyte) filePath(fileName string) string {
+	if a.image != "" {
+		return path.Join(airbyteContainerDir, fileName)
+	}
+
+	return path.Join(a.configDir, fileName)
+}
+
+//imageWithoutTag returns docker image name without tag and digest
+func imageWithoutTag(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+
+	//colon before the last slash is a registry port
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+
+	return image
+}
+
+//writeJSONFile writes value into a json file
+//value might be an object, raw JSON string or path to a JSON file
+func writeJSONFile(filePath string, value interface{}) error {
+	var payload []byte
+	switch v := value.(type) {
+	case string:
+		if json.Valid([]byte(v)) {
+			payload = []byte(v)
+		} else {
+			content, err := ioutil.ReadFile(v)
+			if err != nil {
+				return fmt.Errorf("Error reading file %s: %v", v, err)
+			}
+			payload = content
+		}
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("Malformed value: %v", err)
+		}
+		payload = b
+	}
+
+	return ioutil.WriteFile(filePath, payload, 0644)
+}
+
+func readJSONFile(filePath string, value interface{}) error {
+	b, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, value)
+}
@@ -0,0 +1,147 @@
+package drivers
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/airbyte"
+	"github.com/jitsucom/jitsu/server/singer"
+	"github.com/stretchr/testify/require"
+)
+
+//testConnector is a fake Airbyte connector: read command returns 2 users without state and 1 user with state
+const testConnector = `#!/bin/sh
+command=$1
+shift
+state=""
+while [ $# -gt 0 ]; do
+  if [ "$1" = "--state" ]; then state=$2; fi
+  shift
+done
+
+case $command in
+  spec)
+    echo '{"type": "SPEC", "spec": {"connectionSpecification": {"required": ["api_key"]}}}'
+    ;;
+  check)
+    echo '{"type": "LOG", "log": {"level": "INFO", "message": "checking connection"}}'
+    echo '{"type": "CONNECTION_STATUS", "connectionStatus": {"status": "SUCCEEDED"}}'
+    ;;
+  discover)
+    echo '{"type": "CATALOG", "catalog": {"streams": [{"name": "users", "json_schema": {}, "supported_sync_modes": ["full_refresh", "incremental"], "source_defined_cursor": true, "source_defined_primary_key": [["id"]]}]}}'
+    ;;
+  read)
+    if [ -z "$state" ]; then
+      echo '{"type": "RECORD", "record": {"stream": "users", "data": {"id": 1}, "emitted_at": 1}}'
+    fi
+    echo '{"type": "RECORD", "record": {"stream": "users", "data": {"id": 2}, "emitted_at": 1}}'
+    echo '{"type": "STATE", "state": {"data": {"users": 2}}}'
+    ;;
+esac
+`
+
+type testTaskLogger struct{}
+
+func (tl *testTaskLogger) INFO(format string, v ...interface{})  {}
+func (tl *testTaskLogger) ERROR(format string, v ...interface{}) {}
+
+type testPortionConsumer struct {
+	state   interface{}
+	objects []map[string]interface{}
+	keys    []string
+}
+
+func (tpc *testPortionConsumer) Consume(representation *singer.OutputRepresentation) error {
+	tpc.state = representation.State
+	for _, stream := range representation.Streams {
+		tpc.objects = append(tpc.objects, stream.Objects...)
+		tpc.keys = stream.KeyFields
+	}
+
+	return nil
+}
+
+func TestAirbyteConfigValidate(t *testing.T) {
+	require.Error(t, (&AirbyteConfig{Config: map[string]interface{}{}}).Validate())
+	require.Error(t, (&AirbyteConfig{Image: "airbyte/source-stripe", Command: "./source", Config: map[string]interface{}{}}).Validate())
+	require.Error(t, (&AirbyteConfig{Image: "airbyte/source-stripe"}).Validate())
+	require.NoError(t, (&AirbyteConfig{Image: "airbyte/source-stripe", Config: map[string]interface{}{}}).Validate())
+}
+
+func TestAirbyteCommands(t *testing.T) {
+	dir, err := ioutil.TempDir("", "airbyte")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	connectorPath := path.Join(dir, "connector.sh")
+	require.NoError(t, ioutil.WriteFile(connectorPath, []byte(testConnector), 0755))
+	require.NoError(t, airbyte.Init(path.Join(dir, "config"), ioutil.Discard))
+
+	sourceConfig := &SourceConfig{Name: "airbyte_source", Type: AirbyteType, Config: map[string]interface{}{
+		"command": connectorPath,
+		"config":  map[string]interface{}{"api_key": "key"},
+	}}
+	driver, err := NewAirbyte(context.Background(), sourceConfig, &Collection{Name: DefaultSingerCollection})
+	require.NoError(t, err)
+	defer driver.Close()
+
+	airbyteDriver := driver.(*Airbyte)
+	require.Equal(t, connectorPath, airbyteDriver.GetConnector())
+
+	spec, err := airbyteDriver.Spec()
+	require.NoError(t, err)
+	require.Contains(t, spec, "connectionSpecification")
+
+	require.NoError(t, airbyteDriver.TestConnection())
+
+	catalog, err := airbyteDriver.Discover()
+	require.NoError(t, err)
+	require.Len(t, catalog.Streams, 1)
+	require.Equal(t, "users", catalog.Streams[0].Name)
+
+	consumer := &testPortionConsumer{}
+	require.NoError(t, airbyteDriver.Load("", &testTaskLogger{}, consumer))
+	require.Len(t, consumer.objects, 2)
+	require.Equal(t, []string{"id"}, consumer.keys)
+	require.Equal(t, map[string]interface{}{"users": float64(2)}, consumer.state)
+
+	//discovered catalog is written into config dir
+	_, err = os.Stat(path.Join(dir, "config", "airbyte_source", airbyteCatalogFileName))
+	require.NoError(t, err)
+
+	consumer = &testPortionConsumer{}
+	require.NoError(t, airbyteDriver.Load(`{"users": 2}`, &testTaskLogger{}, consumer))
+	require.Len(t, consumer.objects, 1)
+
+	//stored state is written as is
+	arrayState := `[{"type": "STREAM", "stream": {"stream_descriptor": {"name": "users"}, "stream_state": {"id": 2}}}]`
+	consumer = &testPortionConsumer{}
+	require.NoError(t, airbyteDriver.Load(arrayState, &testTaskLogger{}, consumer))
+	require.Len(t, consumer.objects, 1)
+	stateContent, err := ioutil.ReadFile(path.Join(dir, "config", "airbyte_source", airbyteStateFileName))
+	require.NoError(t, err)
+	require.Equal(t, arrayState, string(stateContent))
+}
+
+func TestAirbyteGetConnector(t *testing.T) {
+	tests := []struct {
+		image    string
+		expected string
+	}{
+		{"airbyte/source-stripe", "airbyte/source-stripe"},
+		{"airbyte/source-stripe:0.1.10", "airbyte/source-stripe"},
+		{"localhost:5000/airbyte/source-stripe", "localhost:5000/airbyte/source-stripe"},
+		{"localhost:5000/airbyte/source-stripe:0.1.10", "localhost:5000/airbyte/source-stripe"},
+		{"airbyte/source-stripe:0.1.10@sha256:4d3e", "airbyte/source-stripe"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			require.Equal(t, tt.expected, (&Airbyte{image: tt.image}).GetConnector())
+		})
+	}
+
+	require.Equal(t, "./source --debug", (&Airbyte{command: "./source --debug"}).GetConnector())
+}
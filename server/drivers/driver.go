@@ -20,3 +20,15 @@ type Driver interface {
 	//TestConnection returns error if can't do anything
 	TestConnection() error
 }
+
+//PageConsumer processes one page of objects. Returned error stops loading
+type PageConsumer func(objects []map[string]interface{}) error
+
+//StreamingDriver is an optional interface of drivers which are able to load objects by pages
+//instead of materializing the whole time interval in memory
+type StreamingDriver interface {
+	Driver
+	//StreamObjectsFor loads objects of the time interval and passes them to consumer by pages.
+	//Returns the first consumer error if occurred
+	StreamObjectsFor(interval *TimeInterval, consumer PageConsumer) error
+}
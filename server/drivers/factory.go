@@ -152,9 +152,9 @@ func Create(ctx context.Context, name string, sourceConfig *SourceConfig, cronSc
 }
 
 //parseCollections return serialized Collection objects slice
-//or return one default collection with 'schedule' if singer type
+//or return one default collection with 'schedule' if singer or airbyte type
 func parseCollections(sourceConfig *SourceConfig) ([]*Collection, error) {
-	if sourceConfig.Type == SingerType {
+	if sourceConfig.Type == SingerType || sourceConfig.Type == AirbyteType {
 		return []*Collection{{SourceID: sourceConfig.Name, Name: DefaultSingerCollection, Schedule: sourceConfig.Schedule}}, nil
 	}
 
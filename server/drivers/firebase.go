@@ -97,12 +97,20 @@ func (f *Firebase) GetAllAvailableIntervals() ([]*TimeInterval, error) {
 }
 
 func (f *Firebase) GetObjectsFor(interval *TimeInterval) ([]map[string]interface{}, error) {
+	return collectPages(func(consumer PageConsumer) error {
+		return f.StreamObjectsFor(interval, consumer)
+	})
+}
+
+//StreamObjectsFor passes firestore documents or users to consumer by pages
+func (f *Firebase) StreamObjectsFor(interval *TimeInterval, consumer PageConsumer) error {
+	page := newPageBuffer(pageSize, consumer)
 	if f.collection.Type == FirestoreCollection {
-		return f.loadCollection()
+		return f.loadCollection(page)
 	} else if f.collection.Type == UsersCollection {
-		return f.loadUsers()
+		return f.loadUsers(page)
 	}
-	return nil, fmt.Errorf("Unknown collection: %s", f.collection.Type)
+	return fmt.Errorf("Unknown collection: %s", f.collection.Type)
 }
 
 func (f *Firebase) TestConnection() error {
@@ -116,22 +124,24 @@ func (f *Firebase) TestConnection() error {
 	return nil
 }
 
-func (f *Firebase) loadCollection() ([]map[string]interface{}, error) {
-	var documentJSONs []map[string]interface{}
+func (f *Firebase) loadCollection(page *pageBuffer) error {
 	iter := f.firestoreClient.Collection(f.collection.Name).Documents(f.ctx)
+	defer iter.Stop()
 	for {
 		doc, err := iter.Next()
 		if err == iterator.Done {
 			break
 		}
 		if err != nil {
-			return nil, fmt.Errorf("failed to get API keys from firestore: %v", err)
+			return fmt.Errorf("failed to get API keys from firestore: %v", err)
 		}
 		data := doc.Data()
 		data[firestoreDocumentIDField] = doc.Ref.ID
-		documentJSONs = append(documentJSONs, data)
+		if err := page.add(data); err != nil {
+			return err
+		}
 	}
-	return documentJSONs, nil
+	return page.flush()
 }
 
 func (f *Firebase) Type() string {
@@ -142,16 +152,15 @@ func (f *Firebase) Close() error {
 	return f.firestoreClient.Close()
 }
 
-func (f *Firebase) loadUsers() ([]map[string]interface{}, error) {
+func (f *Firebase) loadUsers(page *pageBuffer) error {
 	iter := f.authClient.Users(f.ctx, "")
-	var users []map[string]interface{}
 	for {
 		authUser, err := iter.Next()
 		if err == iterator.Done {
 			break
 		}
 		if err != nil {
-			return nil, err
+			return err
 		}
 		user := make(map[string]interface{})
 		user["email"] = authUser.Email
@@ -166,9 +175,11 @@ func (f *Firebase) loadUsers() ([]map[string]interface{}, error) {
 		user["created_at"] = f.unixTimestampToISOString(authUser.UserMetadata.CreationTimestamp)
 		user["last_login"] = f.unixTimestampToISOString(authUser.UserMetadata.LastLogInTimestamp)
 		user["last_refresh"] = f.unixTimestampToISOString(authUser.UserMetadata.LastRefreshTimestamp)
-		users = append(users, user)
+		if err := page.add(user); err != nil {
+			return err
+		}
 	}
-	return users, nil
+	return page.flush()
 }
 
 func (f *Firebase) unixTimestampToISOString(nanoseconds int64) string {
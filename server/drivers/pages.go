@@ -0,0 +1,56 @@
+package drivers
+
+//pageSize is a max count of objects in a page of StreamingDriver
+const pageSize = 10000
+
+//pageBuffer collects objects and passes them to consumer by pages of size objects
+type pageBuffer struct {
+	size     int
+	consumer PageConsumer
+	objects  []map[string]interface{}
+}
+
+func newPageBuffer(size int, consumer PageConsumer) *pageBuffer {
+	return &pageBuffer{size: size, consumer: consumer}
+}
+
+//isFull returns true if the page has size objects
+func (pb *pageBuffer) isFull() bool {
+	return len(pb.objects) >= pb.size
+}
+
+//add appends object to the page and passes the page to consumer if it is full
+func (pb *pageBuffer) add(object map[string]interface{}) error {
+	pb.objects = append(pb.objects, object)
+	if pb.isFull() {
+		return pb.flush()
+	}
+
+	return nil
+}
+
+//flush passes collected objects (if any) to consumer and starts a new page
+func (pb *pageBuffer) flush() error {
+	if len(pb.objects) == 0 {
+		return nil
+	}
+
+	page := pb.objects
+	pb.objects = nil
+	return pb.consumer(page)
+}
+
+//collectPages returns all objects loaded by stream function
+//is used for GetObjectsFor implementation in streaming drivers
+func collectPages(stream func(consumer PageConsumer) error) ([]map[string]interface{}, error) {
+	var objects []map[string]interface{}
+	err := stream(func(page []map[string]interface{}) error {
+		objects = append(objects, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return objects, nil
+}
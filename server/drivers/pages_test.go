@@ -0,0 +1,50 @@
+package drivers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageBuffer(t *testing.T) {
+	var pages [][]map[string]interface{}
+	page := newPageBuffer(3, func(objects []map[string]interface{}) error {
+		pages = append(pages, objects)
+		return nil
+	})
+
+	for i := 0; i < 7; i++ {
+		require.NoError(t, page.add(map[string]interface{}{"id": i}))
+	}
+	require.Len(t, pages, 2)
+
+	require.NoError(t, page.flush())
+	require.Len(t, pages, 3)
+	require.Len(t, pages[0], 3)
+	require.Len(t, pages[1], 3)
+	require.Equal(t, []map[string]interface{}{{"id": 6}}, pages[2])
+
+	//empty page isn't passed to consumer
+	require.NoError(t, page.flush())
+	require.Len(t, pages, 3)
+}
+
+func TestCollectPages(t *testing.T) {
+	objects, err := collectPages(func(consumer PageConsumer) error {
+		page := newPageBuffer(2, consumer)
+		for i := 0; i < 5; i++ {
+			if err := page.add(map[string]interface{}{"id": i}); err != nil {
+				return err
+			}
+		}
+		return page.flush()
+	})
+	require.NoError(t, err)
+	require.Len(t, objects, 5)
+
+	_, err = collectPages(func(consumer PageConsumer) error {
+		return errors.New("connection refused")
+	})
+	require.EqualError(t, err, "connection refused")
+}
@@ -72,25 +72,58 @@ func (r *Redis) GetAllAvailableIntervals() ([]*TimeInterval, error) {
 }
 
 func (r *Redis) GetObjectsFor(interval *TimeInterval) ([]map[string]interface{}, error) {
+	return collectPages(func(consumer PageConsumer) error {
+		return r.StreamObjectsFor(interval, consumer)
+	})
+}
+
+//StreamObjectsFor scans hash with HSCAN and passes parsed values to consumer by pages
+func (r *Redis) StreamObjectsFor(interval *TimeInterval, consumer PageConsumer) error {
 	connection := r.connectionPool.Get()
 	defer connection.Close()
 
-	configsByID, err := redis.StringMap(connection.Do("hgetall", r.collection.Name))
-	if err != nil {
-		return nil, err
-	}
-	var configs []map[string]interface{}
-	for id, stringConfig := range configsByID {
-		config := map[string]interface{}{}
-		err := json.Unmarshal([]byte(stringConfig), &config)
+	page := newPageBuffer(pageSize, consumer)
+	//HSCAN may return the same field several times
+	scannedIDs := map[string]bool{}
+	cursor := "0"
+	for {
+		values, err := redis.Values(connection.Do("hscan", r.collection.Name, cursor, "count", pageSize))
 		if err != nil {
-			logging.Errorf("Failed to parse collection %s, id=[%s], %v", r.collection.Name, id, err)
-			return nil, err
+			return err
+		}
+
+		var fields []interface{}
+		if _, err := redis.Scan(values, &cursor, &fields); err != nil {
+			return err
+		}
+		configsByID, err := redis.StringMap(fields, nil)
+		if err != nil {
+			return err
+		}
+		for id, stringConfig := range configsByID {
+			if scannedIDs[id] {
+				continue
+			}
+			scannedIDs[id] = true
+
+			config := map[string]interface{}{}
+			err := json.Unmarshal([]byte(stringConfig), &config)
+			if err != nil {
+				logging.Errorf("Failed to parse collection %s, id=[%s], %v", r.collection.Name, id, err)
+				return err
+			}
+			config[idField] = id
+			if err := page.add(config); err != nil {
+				return err
+			}
+		}
+
+		if cursor == "0" {
+			break
 		}
-		config[idField] = id
-		configs = append(configs, config)
 	}
-	return configs, nil
+
+	return page.flush()
 }
 
 func (r *Redis) TestConnection() error {
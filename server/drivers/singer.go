@@ -284,6 +284,26 @@ func (s *Singer) TestConnection() error {
 	return nil
 }
 
+//Discover runs tap in --discover mode and returns catalog with all available streams
+func (s *Singer) Discover() (*singer.Catalog, error) {
+	ready, notReadyError := s.Ready()
+	if !ready {
+		return nil, notReadyError
+	}
+
+	outWriter := logging.NewStringWriter()
+	errWriter := logging.NewStringWriter()
+
+	command := path.Join(singer.Instance.VenvDir, s.tap, "bin", s.tap)
+
+	err := singer.Instance.ExecCmd(command, outWriter, errWriter, "-c", s.configPath, "--discover")
+	if err != nil {
+		return nil, fmt.Errorf("Error singer --discover: %v. %s", err, errWriter.String())
+	}
+
+	return singer.ParseCatalog([]byte(outWriter.String()))
+}
+
 func (s *Singer) Type() string {
 	return SingerType
 }
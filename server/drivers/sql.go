@@ -0,0 +1,425 @@
+package drivers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/logging"
+)
+
+const (
+	PostgresEngine = "postgres"
+	MySQLEngine    = "mysql"
+
+	FullRefreshMode = "full_refresh"
+	IncrementalMode = "incremental"
+
+	//CursorSignatureKey is a meta.Storage signature key of the incremental collection cursor value (high-water mark)
+	CursorSignatureKey = "cursor"
+
+	selectSQLTemplate      = `SELECT * FROM %s`
+	incrementalSQLTemplate = ` WHERE %s >= %s`
+	orderBySQLTemplate     = ` ORDER BY %s`
+
+	postgresCursorTimeLayout = "2006-01-02 15:04:05.999999Z07:00"
+	mySQLCursorTimeLayout    = "2006-01-02 15:04:05.999999"
+)
+
+//integerTypes and floatTypes are database column types which values are returned as []byte by drivers
+var (
+	integerTypes = map[string]bool{"TINYINT": true, "SMALLINT": true, "MEDIUMINT": true, "INT": true, "INTEGER": true, "BIGINT": true,
+		"UNSIGNED TINYINT": true, "UNSIGNED SMALLINT": true, "UNSIGNED MEDIUMINT": true, "UNSIGNED INT": true, "UNSIGNED BIGINT": true,
+		"INT2": true, "INT4": true, "INT8": true, "YEAR": true}
+	floatTypes = map[string]bool{"DECIMAL": true, "NUMERIC": true, "FLOAT": true, "DOUBLE": true, "REAL": true, "FLOAT4": true, "FLOAT8": true}
+)
+
+//SQLConfig is a SQL database source configuration: engine and connection parameters
+type SQLConfig struct {
+	Engine string `mapstructure:"engine" json:"engine,omitempty" yaml:"engine,omitempty"`
+
+	adapters.DataSourceConfig `mapstructure:",squash" yaml:",inline"`
+}
+
+//Validate returns err if engine or connection parameters are invalid
+func (sc *SQLConfig) Validate() error {
+	if sc.Engine != PostgresEngine && sc.Engine != MySQLEngine {
+		return fmt.Errorf("Unknown engine: [%s]. Only [%s] and [%s] are supported now", sc.Engine, PostgresEngine, MySQLEngine)
+	}
+
+	return sc.DataSourceConfig.Validate()
+}
+
+//SQLCollectionParameters is a SQL collection configuration:
+//table or custom query, synchronization mode, cursor field (for incremental mode) and primary key fields
+type SQLCollectionParameters struct {
+	Table            string   `mapstructure:"table" json:"table,omitempty" yaml:"table,omitempty"`
+	Query            string   `mapstructure:"query" json:"query,omitempty" yaml:"query,omitempty"`
+	Mode             string   `mapstructure:"mode" json:"mode,omitempty" yaml:"mode,omitempty"`
+	CursorField      string   `mapstructure:"cursor_field" json:"cursor_field,omitempty" yaml:"cursor_field,omitempty"`
+	PrimaryKeyFields []string `mapstructure:"primary_key_fields" json:"primary_key_fields,omitempty" yaml:"primary_key_fields,omitempty"`
+}
+
+//Validate returns err if parameters are invalid
+func (scp *SQLCollectionParameters) Validate() error {
+	if scp.Table != "" && scp.Query != "" {
+		return errors.New("table and query can't be configured together")
+	}
+
+	switch scp.Mode {
+	case FullRefreshMode:
+	case IncrementalMode:
+		if scp.CursorField == "" {
+			return fmt.Errorf("cursor_field is required in %s mode", IncrementalMode)
+		}
+	default:
+		return fmt.Errorf("Unknown mode: [%s]. Only [%s] and [%s] are supported now", scp.Mode, FullRefreshMode, IncrementalMode)
+	}
+
+	return nil
+}
+
+//SQL is a Postgres or MySQL source driver. It loads table or custom query rows:
+//all rows (full refresh mode) or only rows with cursor field value greater than or equal to the stored one (incremental mode)
+//rows with the stored cursor value are loaded again because new rows with the same value might have been added after
+//the previous synchronization. They are deduplicated by primary key fields in destinations
+type SQL struct {
+	ctx        context.Context
+	config     *SQLConfig
+	collection *Collection
+	parameters *SQLCollectionParameters
+	pkFields   []string
+
+	dataSource *sql.DB
+}
+
+func init() {
+	if err := RegisterDriver(SQLType, NewSQL); err != nil {
+		logging.Errorf("Failed to register driver %s: %v", SQLType, err)
+	}
+}
+
+//NewSQL returns SQL driver and
+//1. opens connection to the database
+//2. uses collection name as a table name if table and query aren't configured
+func NewSQL(ctx context.Context, sourceConfig *SourceConfig, collection *Collection) (Driver, error) {
+	config := &SQLConfig{}
+	if err := unmarshalConfig(sourceConfig.Config, config); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	parameters := &SQLCollectionParameters{}
+	if err := unmarshalConfig(collection.Parameters, parameters); err != nil {
+		return nil, err
+	}
+	if parameters.Table == "" && parameters.Query == "" {
+		parameters.Table = collection.Name
+	}
+	if parameters.Mode == "" {
+		parameters.Mode = FullRefreshMode
+	}
+	if err := parameters.Validate(); err != nil {
+		return nil, err
+	}
+
+	pkFields := append([]string{}, parameters.PrimaryKeyFields...)
+	sort.Strings(pkFields)
+
+	var driverName, connectionString string
+	if config.Engine == PostgresEngine {
+		if config.Port.String() == "" {
+			config.Port = "5432"
+			logging.Warnf("[%s] port wasn't provided. Will be used default one: %s", sourceConfig.Name, config.Port.String())
+		}
+		driverName, connectionString = "postgres", postgresSourceConnectionString(&config.DataSourceConfig)
+	} else {
+		if config.Port.String() == "" {
+			config.Port = "3306"
+			logging.Warnf("[%s] port wasn't provided. Will be used default one: %s", sourceConfig.Name, config.Port.String())
+		}
+		driverName, connectionString = "mysql", mySQLSourceConnectionString(&config.DataSourceConfig)
+	}
+
+	dataSource, err := sql.Open(driverName, connectionString)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := dataSource.PingContext(ctx); err != nil {
+		dataSource.Close()
+		return nil, err
+	}
+
+	dataSource.SetConnMaxLifetime(10 * time.Minute)
+
+	return &SQL{ctx: ctx, config: config, collection: collection, parameters: parameters, pkFields: pkFields, dataSource: dataSource}, nil
+}
+
+//GetAllAvailableIntervals return only ALL interval: table rows can't be split by date
+func (s *SQL) GetAllAvailableIntervals() ([]*TimeInterval, error) {
+	return []*TimeInterval{NewTimeInterval(ALL, time.Time{})}, nil
+}
+
+//GetObjectsFor returns all table (query) rows
+func (s *SQL) GetObjectsFor(interval *TimeInterval) ([]map[string]interface{}, error) {
+	return collectPages(func(consumer PageConsumer) error {
+		return s.StreamObjectsFor(interval, consumer)
+	})
+}
+
+//StreamObjectsFor passes all table (query) rows to consumer by pages
+func (s *SQL) StreamObjectsFor(interval *TimeInterval, consumer PageConsumer) error {
+	return s.stream("", func(objects []map[string]interface{}, cursor string) error {
+		return consumer(objects)
+	})
+}
+
+//GetObjectsAfter returns rows with cursor field value greater than or equal to cursor (all rows if cursor is empty)
+//and the new cursor value (the max cursor field value of loaded rows or the same cursor if there are no new rows)
+func (s *SQL) GetObjectsAfter(cursor string) ([]map[string]interface{}, string, error) {
+	newCursor := cursor
+	var objects []map[string]interface{}
+	err := s.StreamObjectsAfter(cursor, func(page []map[string]interface{}, pageCursor string) error {
+		objects = append(objects, page...)
+		newCursor = pageCursor
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return objects, newCursor, nil
+}
+
+//StreamObjectsAfter passes rows with cursor field value greater than or equal to cursor (all rows if cursor is empty) to consumer
+//by pages with the max cursor field value of the page rows. Rows with the same cursor field value are always in the same page
+//so the page cursor value can be used as a checkpoint
+func (s *SQL) StreamObjectsAfter(cursor string, consumer func(objects []map[string]interface{}, cursor string) error) error {
+	return s.stream(cursor, consumer)
+}
+
+//NewTestSQL is used only for tests
+func NewTestSQL(collection *Collection, parameters *SQLCollectionParameters) *SQL {
+	return &SQL{collection: collection, parameters: parameters}
+}
+
+//IsIncremental returns true if the collection is configured in incremental mode
+func (s *SQL) IsIncremental() bool {
+	return s.parameters.Mode == IncrementalMode
+}
+
+//GetCursorField returns configured cursor field
+func (s *SQL) GetCursorField() string {
+	return s.parameters.CursorField
+}
+
+//GetPrimaryKeyFields returns sorted primary key fields of the collection
+func (s *SQL) GetPrimaryKeyFields() []string {
+	return s.pkFields
+}
+
+func (s *SQL) TestConnection() error {
+	return s.dataSource.PingContext(s.ctx)
+}
+
+func (s *SQL) Type() string {
+	return SQLType
+}
+
+func (s *SQL) GetCollectionTable() string {
+	return s.collection.GetTableName()
+}
+
+func (s *SQL) Close() error {
+	return s.dataSource.Close()
+}
+
+//stream selects rows (from cursor if it isn't empty in incremental mode), converts them into objects
+//and passes them to consumer by pages with the max cursor field value of the page rows
+func (s *SQL) stream(cursor string, consumer func(objects []map[string]interface{}, cursor string) error) error {
+	query, values := s.buildQuery(cursor)
+	rows, err := s.dataSource.QueryContext(s.ctx, query, values...)
+	if err != nil {
+		return fmt.Errorf("Error executing query [%s] with values %v: %v", query, values, err)
+	}
+	defer rows.Close()
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return fmt.Errorf("Error getting query [%s] column types: %v", query, err)
+	}
+
+	pageCursor := cursor
+	page := newPageBuffer(pageSize, func(objects []map[string]interface{}) error {
+		return consumer(objects, pageCursor)
+	})
+	for rows.Next() {
+		rowValues := make([]interface{}, len(columnTypes))
+		rowPointers := make([]interface{}, len(columnTypes))
+		for i := range rowValues {
+			rowPointers[i] = &rowValues[i]
+		}
+
+		if err := rows.Scan(rowPointers...); err != nil {
+			return fmt.Errorf("Error scanning query [%s] row: %v", query, err)
+		}
+
+		object := make(map[string]interface{}, len(columnTypes))
+		for i, columnType := range columnTypes {
+			object[columnType.Name()] = convertSQLValue(rowValues[i], columnType.DatabaseTypeName())
+		}
+
+		//rows are sorted by cursor field: the last not null value is the max one
+		var objectCursor string
+		if s.IsIncremental() {
+			if cursorValue, ok := object[s.parameters.CursorField]; ok && cursorValue != nil {
+				objectCursor = s.formatCursorValue(cursorValue)
+			}
+		}
+
+		//rows with the same cursor value mustn't be split into different pages
+		if page.isFull() && (objectCursor == "" || objectCursor != pageCursor) {
+			if err := page.flush(); err != nil {
+				return err
+			}
+		}
+
+		if objectCursor != "" {
+			pageCursor = objectCursor
+		}
+		page.objects = append(page.objects, object)
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("Error reading query [%s] rows: %v", query, err)
+	}
+
+	return page.flush()
+}
+
+//buildQuery returns SELECT statement with placeholder values
+//SELECT * FROM table (or custom query) [WHERE cursor_field >= $cursor ORDER BY cursor_field]
+func (s *SQL) buildQuery(cursor string) (string, []interface{}) {
+	var source string
+	if s.parameters.Query != "" {
+		source = "(" + s.parameters.Query + ") AS source_query"
+	} else if s.config.Engine == PostgresEngine && s.config.Schema != "" {
+		source = s.quote(s.config.Schema) + "." + s.quote(s.parameters.Table)
+	} else {
+		source = s.quote(s.parameters.Table)
+	}
+
+	query := fmt.Sprintf(selectSQLTemplate, source)
+	if !s.IsIncremental() {
+		return query, nil
+	}
+
+	var values []interface{}
+	cursorField := s.quote(s.parameters.CursorField)
+	if cursor != "" {
+		placeholder := "?"
+		if s.config.Engine == PostgresEngine {
+			placeholder = "$1"
+		}
+
+		query += fmt.Sprintf(incrementalSQLTemplate, cursorField, placeholder)
+		values = append(values, cursor)
+	}
+
+	return query + fmt.Sprintf(orderBySQLTemplate, cursorField), values
+}
+
+//quote returns quoted identifier: "identifier" in Postgres and `identifier` in MySQL
+func (s *SQL) quote(identifier string) string {
+	if s.config.Engine == PostgresEngine {
+		return `"` + strings.ReplaceAll(identifier, `"`, `""`) + `"`
+	}
+
+	return "`" + strings.ReplaceAll(identifier, "`", "``") + "`"
+}
+
+//formatCursorValue returns string representation of the cursor value which is comparable in SQL queries
+func (s *SQL) formatCursorValue(value interface{}) string {
+	t, ok := value.(time.Time)
+	if !ok {
+		return fmt.Sprint(value)
+	}
+
+	if s.config.Engine == PostgresEngine {
+		return t.Format(postgresCursorTimeLayout)
+	}
+
+	return t.UTC().Format(mySQLCursorTimeLayout)
+}
+
+//convertSQLValue returns numbers and strings instead of []byte values (drivers return numeric and text values as []byte)
+func convertSQLValue(value interface{}, databaseTypeName string) interface{} {
+	b, ok := value.([]byte)
+	if !ok {
+		return value
+	}
+
+	str := string(b)
+	databaseTypeName = strings.ToUpper(databaseTypeName)
+	if integerTypes[databaseTypeName] {
+		if intValue, err := strconv.ParseInt(str, 10, 64); err == nil {
+			return intValue
+		}
+	} else if floatTypes[databaseTypeName] {
+		if floatValue, err := strconv.ParseFloat(str, 64); err == nil {
+			return floatValue
+		}
+	}
+
+	return str
+}
+
+//postgresSourceConnectionString returns lib/pq connection string with parameters from DataSourceConfig.Parameters
+func postgresSourceConnectionString(config *adapters.DataSourceConfig) string {
+	connectionString := fmt.Sprintf("host=%s port=%s dbname=%s user=%s password=%s ",
+		config.Host, config.Port.String(), config.Db, config.Username, config.Password)
+	for k, v := range config.Parameters {
+		connectionString += k + "=" + v + " "
+	}
+
+	return connectionString
+}
+
+//mySQLSourceConnectionString returns go-sql-driver DSN with parsing time values and parameters from DataSourceConfig.Parameters
+func mySQLSourceConnectionString(config *adapters.DataSourceConfig) string {
+	driverConfig := mysql.NewConfig()
+	driverConfig.User = config.Username
+	driverConfig.Passwd = config.Password
+	driverConfig.Net = "tcp"
+	driverConfig.Addr = config.Host + ":" + config.Port.String()
+	driverConfig.DBName = config.Db
+	driverConfig.ParseTime = true
+
+	connectionString := driverConfig.FormatDSN()
+	if len(config.Parameters) == 0 {
+		return connectionString
+	}
+
+	parameters := url.Values{}
+	for k, v := range config.Parameters {
+		parameters.Set(k, v)
+	}
+
+	separator := "?"
+	if strings.Contains(connectionString, "?") {
+		separator = "&"
+	}
+
+	return connectionString + separator + parameters.Encode()
+}
@@ -0,0 +1,189 @@
+package drivers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLBuildQuery(t *testing.T) {
+	tests := []struct {
+		name           string
+		config         *SQLConfig
+		parameters     *SQLCollectionParameters
+		cursor         string
+		expectedQuery  string
+		expectedValues []interface{}
+	}{
+		{
+			"Postgres full refresh table",
+			&SQLConfig{Engine: PostgresEngine, DataSourceConfig: adapters.DataSourceConfig{Schema: "public"}},
+			&SQLCollectionParameters{Table: "users", Mode: FullRefreshMode},
+			"",
+			`SELECT * FROM "public"."users"`,
+			nil,
+		},
+		{
+			"Postgres incremental table initial load",
+			&SQLConfig{Engine: PostgresEngine},
+			&SQLCollectionParameters{Table: "users", Mode: IncrementalMode, CursorField: "updated_at"},
+			"",
+			`SELECT * FROM "users" ORDER BY "updated_at"`,
+			nil,
+		},
+		{
+			"Postgres incremental table",
+			&SQLConfig{Engine: PostgresEngine},
+			&SQLCollectionParameters{Table: "users", Mode: IncrementalMode, CursorField: "updated_at"},
+			"2021-05-01 10:00:00Z",
+			`SELECT * FROM "users" WHERE "updated_at" >= $1 ORDER BY "updated_at"`,
+			[]interface{}{"2021-05-01 10:00:00Z"},
+		},
+		{
+			"MySQL incremental query",
+			&SQLConfig{Engine: MySQLEngine},
+			&SQLCollectionParameters{Query: "SELECT id, name FROM users WHERE active = 1", Mode: IncrementalMode, CursorField: "id"},
+			"100",
+			"SELECT * FROM (SELECT id, name FROM users WHERE active = 1) AS source_query WHERE `id` >= ? ORDER BY `id`",
+			[]interface{}{"100"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			driver := &SQL{config: tt.config, parameters: tt.parameters}
+			query, values := driver.buildQuery(tt.cursor)
+			require.Equal(t, tt.expectedQuery, query)
+			require.Equal(t, tt.expectedValues, values)
+		})
+	}
+}
+
+func TestSQLCollectionParametersValidate(t *testing.T) {
+	require.NoError(t, (&SQLCollectionParameters{Table: "users", Mode: FullRefreshMode}).Validate())
+	require.NoError(t, (&SQLCollectionParameters{Table: "users", Mode: IncrementalMode, CursorField: "id"}).Validate())
+	require.Error(t, (&SQLCollectionParameters{Table: "users", Mode: IncrementalMode}).Validate())
+	require.Error(t, (&SQLCollectionParameters{Table: "users", Query: "SELECT 1", Mode: FullRefreshMode}).Validate())
+	require.Error(t, (&SQLCollectionParameters{Table: "users", Mode: "cdc"}).Validate())
+}
+
+func TestPostgresIncrementalSync(t *testing.T) {
+	ctx := context.Background()
+	container, err := test.NewPostgresContainer(ctx)
+	if err != nil {
+		t.Fatalf("failed to initialize container: %v", err)
+	}
+	defer container.Close()
+
+	config := map[string]interface{}{
+		"engine":     PostgresEngine,
+		"host":       container.Host,
+		"port":       json.Number(fmt.Sprint(container.Port)),
+		"db":         container.Database,
+		"schema":     container.Schema,
+		"username":   container.Username,
+		"password":   container.Password,
+		"parameters": map[string]string{"sslmode": "disable"},
+	}
+
+	testIncrementalSync(t, ctx, config,
+		`CREATE TABLE sql_source_users (id bigint PRIMARY KEY, name text, amount numeric(10,2), updated_at timestamp)`,
+		`INSERT INTO sql_source_users (id, name, amount, updated_at) VALUES ($1, $2, $3, $4)`)
+}
+
+func TestMySQLIncrementalSync(t *testing.T) {
+	ctx := context.Background()
+	container, err := test.NewMySQLContainer(ctx)
+	if err != nil {
+		t.Fatalf("failed to initialize container: %v", err)
+	}
+	defer container.Close()
+
+	config := map[string]interface{}{
+		"engine":   MySQLEngine,
+		"host":     container.Host,
+		"port":     json.Number(fmt.Sprint(container.Port)),
+		"db":       container.Database,
+		"username": container.Username,
+		"password": container.Password,
+	}
+
+	testIncrementalSync(t, ctx, config,
+		"CREATE TABLE sql_source_users (id bigint PRIMARY KEY, name text, amount decimal(10,2), updated_at datetime(6))",
+		"INSERT INTO sql_source_users (id, name, amount, updated_at) VALUES (?, ?, ?, ?)")
+}
+
+func testIncrementalSync(t *testing.T, ctx context.Context, config map[string]interface{}, createTableStatement, insertStatement string) {
+	sourceConfig := &SourceConfig{Name: "sql_source", Type: SQLType, Config: config}
+	collection := &Collection{SourceID: "sql_source", Name: "sql_source_users", Parameters: map[string]interface{}{
+		"mode":               IncrementalMode,
+		"cursor_field":       "updated_at",
+		"primary_key_fields": []string{"id"},
+	}}
+
+	driver, err := NewSQL(ctx, sourceConfig, collection)
+	require.NoError(t, err)
+	defer driver.Close()
+
+	sqlDriver := driver.(*SQL)
+	require.True(t, sqlDriver.IsIncremental())
+	require.Equal(t, []string{"id"}, sqlDriver.GetPrimaryKeyFields())
+	require.NoError(t, sqlDriver.TestConnection())
+
+	_, err = sqlDriver.dataSource.Exec(createTableStatement)
+	require.NoError(t, err)
+
+	start := time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC)
+	for i := 1; i <= 3; i++ {
+		_, err = sqlDriver.dataSource.Exec(insertStatement, i, fmt.Sprintf("user%d", i), 10.5, start.Add(time.Duration(i)*time.Minute))
+		require.NoError(t, err)
+	}
+
+	//initial load
+	objects, cursor, err := sqlDriver.GetObjectsAfter("")
+	require.NoError(t, err)
+	require.Len(t, objects, 3)
+	require.Equal(t, int64(1), objects[0]["id"])
+	require.Equal(t, "user1", objects[0]["name"])
+	require.Equal(t, 10.5, objects[0]["amount"])
+	require.NotEmpty(t, cursor)
+
+	//no new rows: rows with the cursor value are loaded again
+	objects, sameCursor, err := sqlDriver.GetObjectsAfter(cursor)
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+	require.Equal(t, int64(3), objects[0]["id"])
+	require.Equal(t, cursor, sameCursor)
+
+	//new rows
+	for i := 4; i <= 5; i++ {
+		_, err = sqlDriver.dataSource.Exec(insertStatement, i, fmt.Sprintf("user%d", i), 1, start.Add(time.Duration(i)*time.Minute))
+		require.NoError(t, err)
+	}
+
+	objects, newCursor, err := sqlDriver.GetObjectsAfter(cursor)
+	require.NoError(t, err)
+	require.Len(t, objects, 3)
+	require.Equal(t, int64(4), objects[1]["id"])
+	require.Equal(t, int64(5), objects[2]["id"])
+	require.NotEqual(t, cursor, newCursor)
+
+	//new row with the same cursor value as the last synchronized one isn't skipped
+	_, err = sqlDriver.dataSource.Exec(insertStatement, 6, "user6", 1, start.Add(5*time.Minute))
+	require.NoError(t, err)
+
+	objects, _, err = sqlDriver.GetObjectsAfter(newCursor)
+	require.NoError(t, err)
+	require.Len(t, objects, 2)
+	require.ElementsMatch(t, []interface{}{int64(5), int64(6)}, []interface{}{objects[0]["id"], objects[1]["id"]})
+
+	//full refresh
+	allObjects, err := sqlDriver.GetObjectsFor(NewTimeInterval(ALL, time.Time{}))
+	require.NoError(t, err)
+	require.Len(t, allObjects, 6)
+}
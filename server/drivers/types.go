@@ -13,8 +13,10 @@ const (
 	GoogleAnalyticsType = "google_analytics"
 	GooglePlayType      = "google_play"
 	RedisType           = "redis"
+	SQLType             = "sql"
 
-	SingerType = "singer"
+	SingerType  = "singer"
+	AirbyteType = "airbyte"
 )
 
 var errAccountKeyConfiguration = errors.New("service_account_key must be map, JSON file path or JSON content string")
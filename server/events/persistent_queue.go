@@ -19,6 +19,10 @@ type QueuedEvent struct {
 	FactBytes    []byte
 	DequeuedTime time.Time
 	TokenID      string
+	//Retries is a number of failed insertion attempts
+	Retries int
+	//InsertedEnvelopes are indexes of the event processing results which have been inserted by previous attempts
+	InsertedEnvelopes []int
 }
 
 // QueuedFactBuilder creates and returns a new *events.QueuedEvent (must be pointer).
@@ -48,13 +52,19 @@ func (pq *PersistentQueue) Consume(f map[string]interface{}, tokenID string) {
 }
 
 func (pq *PersistentQueue) ConsumeTimed(f map[string]interface{}, t time.Time, tokenID string) {
+	pq.ConsumeRetry(f, t, tokenID, 0, nil)
+}
+
+//ConsumeRetry puts the event into the queue with the number of failed insertion attempts
+//and indexes of already inserted processing results (they aren't inserted again)
+func (pq *PersistentQueue) ConsumeRetry(f map[string]interface{}, t time.Time, tokenID string, retries int, insertedEnvelopes []int) {
 	factBytes, err := json.Marshal(f)
 	if err != nil {
 		logSkippedEvent(f, fmt.Errorf("Error marshalling events event: %v", err))
 		return
 	}
 
-	if err := pq.queue.Enqueue(&QueuedEvent{FactBytes: factBytes, DequeuedTime: t, TokenID: tokenID}); err != nil {
+	if err := pq.queue.Enqueue(&QueuedEvent{FactBytes: factBytes, DequeuedTime: t, TokenID: tokenID, Retries: retries, InsertedEnvelopes: insertedEnvelopes}); err != nil {
 		logSkippedEvent(f, fmt.Errorf("Error putting event event bytes to the persistent queue: %v", err))
 		return
 	}
@@ -62,28 +72,46 @@ func (pq *PersistentQueue) ConsumeTimed(f map[string]interface{}, t time.Time, t
 	metrics.EnqueuedEvent(pq.identifier)
 }
 
-func (pq *PersistentQueue) DequeueBlock() (Event, time.Time, string, error) {
+//DequeueBlock returns the event and the queued event with time when it should be processed, token ID and retries info
+func (pq *PersistentQueue) DequeueBlock() (Event, *QueuedEvent, error) {
 	iface, err := pq.queue.DequeueBlock()
 	if err != nil {
 		if err == dque.ErrQueueClosed {
 			err = ErrQueueClosed
 		}
-		return nil, time.Time{}, "", err
+		return nil, nil, err
 	}
 
 	metrics.DequeuedEvent(pq.identifier)
 
 	wrappedFact, ok := iface.(*QueuedEvent)
 	if !ok || len(wrappedFact.FactBytes) == 0 {
-		return nil, time.Time{}, "", errors.New("Dequeued object is not a QueuedEvent instance or event bytes is empty")
+		return nil, nil, errors.New("Dequeued object is not a QueuedEvent instance or event bytes is empty")
 	}
 
 	fact, err := parsers.ParseJSON(wrappedFact.FactBytes)
 	if err != nil {
-		return nil, time.Time{}, "", fmt.Errorf("Error unmarshalling events.Event from bytes: %v", err)
+		return nil, nil, fmt.Errorf("Error unmarshalling events.Event from bytes: %v", err)
+	}
+
+	return fact, wrappedFact, nil
+}
+
+//WaitForEvent blocks until the queue contains an event (the event isn't dequeued)
+func (pq *PersistentQueue) WaitForEvent() error {
+	if _, err := pq.queue.PeekBlock(); err != nil {
+		if err == dque.ErrQueueClosed {
+			return ErrQueueClosed
+		}
+		return err
 	}
 
-	return fact, wrappedFact.DequeuedTime, wrappedFact.TokenID, nil
+	return nil
+}
+
+//Size returns the number of events in the queue
+func (pq *PersistentQueue) Size() int {
+	return pq.queue.Size()
 }
 
 func (pq *PersistentQueue) Close() error {
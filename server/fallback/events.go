@@ -0,0 +1,507 @@
+package fallback
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/logfiles"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/metrics"
+	"github.com/jitsucom/jitsu/server/parsers"
+	"github.com/jitsucom/jitsu/server/storages"
+)
+
+const (
+	defaultEventsPageLimit = 100
+	replayEventsBatchSize  = 1000
+)
+
+//ErrEventNotFound is returned when there is no event with the index in the fallback file
+var ErrEventNotFound = errors.New("Event wasn't found in the fallback file")
+
+//EventsFilter selects fallback file events by error substring and/or event IDs
+//empty filter matches all events
+type EventsFilter struct {
+	ErrorContains string
+	EventIDs      map[string]bool
+}
+
+//matches returns true if the event satisfies all configured criteria
+func (ef *EventsFilter) matches(event *FailedEvent) bool {
+	if ef == nil {
+		return true
+	}
+
+	if ef.ErrorContains != "" && !strings.Contains(event.Error, ef.ErrorContains) {
+		return false
+	}
+
+	if len(ef.EventIDs) > 0 && !ef.EventIDs[event.EventID] {
+		return false
+	}
+
+	return true
+}
+
+//FailedEvent is an event from a fallback file with its position (line index) and replay statuses per destination
+type FailedEvent struct {
+	Index          int                         `json:"index"`
+	EventID        string                      `json:"event_id,omitempty"`
+	Error          string                      `json:"error"`
+	Event          json.RawMessage             `json:"event"`
+	ReplayStatuses map[string]*logfiles.Status `json:"replay_statuses,omitempty"`
+}
+
+//ErrorGroup is an amount of events with the same error
+type ErrorGroup struct {
+	Error string `json:"error"`
+	Count int    `json:"count"`
+}
+
+//EventsPage is a page of fallback file events which match the filter
+//Total and Errors are calculated on all matched events
+type EventsPage struct {
+	FileName      string         `json:"file_name"`
+	DestinationID string         `json:"destination_id"`
+	Total         int            `json:"total"`
+	Offset        int            `json:"offset"`
+	Limit         int            `json:"limit"`
+	Events        []*FailedEvent `json:"events"`
+	Errors        []*ErrorGroup  `json:"errors"`
+}
+
+//ReplayResult is a result of replaying events subset
+type ReplayResult struct {
+	DestinationID string         `json:"destination_id"`
+	Replayed      int            `json:"replayed"`
+	Skipped       int            `json:"skipped"`
+	Failed        []*FailedEvent `json:"failed"`
+}
+
+//GetEvents returns page of events from the fallback file which match the filter with replay statuses
+//and error groups sorted by events count
+func (s *Service) GetEvents(fileName string, filter *EventsFilter, offset, limit int) (*EventsPage, error) {
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 {
+		limit = defaultEventsPageLimit
+	}
+
+	destinationID, err := extractDestinationID(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	failedEvents, err := s.readEvents(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := s.statusManager.GetEventsStatuses(fileName)
+	page := &EventsPage{FileName: fileName, DestinationID: destinationID, Offset: offset, Limit: limit, Events: []*FailedEvent{}}
+	errorsCount := map[string]int{}
+	for _, failedEvent := range failedEvents {
+		if !filter.matches(failedEvent) {
+			continue
+		}
+
+		if page.Total >= offset && len(page.Events) < limit {
+			failedEvent.ReplayStatuses = statuses[eventKey(failedEvent.Index)]
+			page.Events = append(page.Events, failedEvent)
+		}
+		page.Total++
+		errorsCount[failedEvent.Error]++
+	}
+
+	page.Errors = make([]*ErrorGroup, 0, len(errorsCount))
+	for errMsg, count := range errorsCount {
+		page.Errors = append(page.Errors, &ErrorGroup{Error: errMsg, Count: count})
+	}
+	sort.Slice(page.Errors, func(i, j int) bool {
+		if page.Errors[i].Count == page.Errors[j].Count {
+			return page.Errors[i].Error < page.Errors[j].Error
+		}
+		return page.Errors[i].Count > page.Errors[j].Count
+	})
+
+	return page, nil
+}
+
+//UpdateEvent overwrites event JSON object under the index in the fallback file
+func (s *Service) UpdateEvent(fileName string, index int, event map[string]interface{}) error {
+	if event == nil {
+		return errors.New("Event JSON object is required")
+	}
+
+	return s.modifyEvent(fileName, index, func(map[string]interface{}) (map[string]interface{}, error) {
+		return event, nil
+	})
+}
+
+//PatchEvent applies JSON merge patch (RFC 7386) to event JSON object under the index in the fallback file:
+//fields with null values are removed, nested objects are merged, other values are replaced
+func (s *Service) PatchEvent(fileName string, index int, patch map[string]interface{}) error {
+	if len(patch) == 0 {
+		return errors.New("Patch JSON object is required")
+	}
+
+	return s.modifyEvent(fileName, index, func(event map[string]interface{}) (map[string]interface{}, error) {
+		return mergePatch(event, patch), nil
+	})
+}
+
+//ReplayEvents stores events which match the filter from the fallback file into the destination in batches
+//and saves replay status of every event. destinationID is taken from the file name if it is empty.
+//Events which have been already replayed into the destination are skipped if force is false.
+//Failed events aren't written into the destination fallback: they are kept in the original fallback file
+func (s *Service) ReplayEvents(fileName, destinationID string, filter *EventsFilter, force bool) (*ReplayResult, error) {
+	if destinationID == "" {
+		var err error
+		destinationID, err = extractDestinationID(fileName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	storage, err := s.getStorage(destinationID)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock, err := s.lockFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	failedEvents, err := s.readEvents(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := s.statusManager.GetEventsStatuses(fileName)
+	result := &ReplayResult{DestinationID: destinationID, Failed: []*FailedEvent{}}
+	var toReplay []*FailedEvent
+	for _, failedEvent := range failedEvents {
+		if !filter.matches(failedEvent) {
+			continue
+		}
+
+		if status, ok := statuses[eventKey(failedEvent.Index)][storage.Name()]; ok && status.Uploaded && !force {
+			result.Skipped++
+			continue
+		}
+
+		toReplay = append(toReplay, failedEvent)
+	}
+
+	for start := 0; start < len(toReplay); start += replayEventsBatchSize {
+		end := start + replayEventsBatchSize
+		if end > len(toReplay) {
+			end = len(toReplay)
+		}
+
+		batch := toReplay[start:end]
+		replayErrs := s.replayBatch(fileName, storage, batch)
+		for i, failedEvent := range batch {
+			replayErr := replayErrs[i]
+			s.statusManager.UpdateEventStatus(fileName, eventKey(failedEvent.Index), storage.Name(), replayErr)
+			if replayErr != nil {
+				logging.Errorf("[%s] Error replaying event [%d] from fallback file %s: %v", storage.Name(), failedEvent.Index, fileName, replayErr)
+				metrics.ErrorTokenEvents(fallbackIdentifier, storage.Name(), 1)
+
+				failedEvent.Error = replayErr.Error()
+				result.Failed = append(result.Failed, failedEvent)
+				continue
+			}
+
+			metrics.SuccessTokenEvents(fallbackIdentifier, storage.Name(), 1)
+			result.Replayed++
+		}
+	}
+
+	return result, nil
+}
+
+//Purge deletes fallback files with their statuses
+func (s *Service) Purge(fileNames []string) error {
+	if len(fileNames) == 0 {
+		return errors.New("File names can't be empty")
+	}
+
+	for _, fileName := range fileNames {
+		if err := validateFileName(fileName); err != nil {
+			return err
+		}
+	}
+
+	for _, fileName := range fileNames {
+		unlock, err := s.lockFile(fileName)
+		if err != nil {
+			return err
+		}
+
+		err = os.Remove(path.Join(s.fallbackDir, fileName))
+		if err != nil && !os.IsNotExist(err) {
+			unlock()
+			return fmt.Errorf("Error deleting fallback file [%s]: %v", fileName, err)
+		}
+
+		s.statusManager.CleanUp(fileName)
+		unlock()
+
+		logging.Infof("Fallback file [%s] has been purged", fileName)
+	}
+
+	return nil
+}
+
+//replayBatch stores events into the storage as a single payload
+//returns replay error per event (nil if the event has been stored). Events which failed processing
+//aren't written into the storage fallback
+func (s *Service) replayBatch(fileName string, storage storages.Storage, batch []*FailedEvent) []error {
+	replayErrs := make([]error, len(batch))
+	lines := make([][]byte, len(batch))
+	payload := bytes.Buffer{}
+	for i, failedEvent := range batch {
+		line, err := json.Marshal(&events.FailedEvent{Event: failedEvent.Event, Error: failedEvent.Error, EventID: failedEvent.EventID})
+		if err != nil {
+			replayErrs[i] = fmt.Errorf("Error serializing event: %v", err)
+			continue
+		}
+
+		lines[i] = line
+		payload.Write(line)
+		payload.WriteString("\n")
+	}
+
+	if payload.Len() == 0 {
+		return replayErrs
+	}
+
+	resultPerTable, processingFailed, err := storage.StoreWithParseFunc(fileName, payload.Bytes(), map[string]bool{}, parsers.ParseFallbackJSON)
+	if err == nil {
+		var tableErrs []string
+		for tableName, result := range resultPerTable {
+			if result.Err != nil {
+				tableErrs = append(tableErrs, fmt.Sprintf("Error storing into table %s: %v", tableName, result.Err))
+			}
+		}
+		if len(tableErrs) > 0 {
+			sort.Strings(tableErrs)
+			err = errors.New(strings.Join(tableErrs, "; "))
+		}
+	}
+
+	//processing failed events are identified by their lines in the payload
+	processingErrs := map[string][]error{}
+	for _, failed := range processingFailed {
+		line := string(failed.Event)
+		processingErrs[line] = append(processingErrs[line], fmt.Errorf("Event processing failed: %s", failed.Error))
+	}
+
+	for i, line := range lines {
+		if line == nil {
+			continue
+		}
+
+		if errs := processingErrs[string(line)]; len(errs) > 0 {
+			replayErrs[i] = errs[0]
+			processingErrs[string(line)] = errs[1:]
+			continue
+		}
+
+		replayErrs[i] = err
+	}
+
+	return replayErrs
+}
+
+//modifyEvent rewrites fallback file with modified event under the index
+func (s *Service) modifyEvent(fileName string, index int, modify func(map[string]interface{}) (map[string]interface{}, error)) error {
+	unlock, err := s.lockFile(fileName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	failedEvents, err := s.readEvents(fileName)
+	if err != nil {
+		return err
+	}
+
+	var target *FailedEvent
+	for _, failedEvent := range failedEvents {
+		if failedEvent.Index == index {
+			target = failedEvent
+			break
+		}
+	}
+	if target == nil {
+		return ErrEventNotFound
+	}
+
+	event, err := parsers.ParseJSON(target.Event)
+	if err != nil {
+		return fmt.Errorf("Error parsing event [%d] JSON: %v", index, err)
+	}
+
+	modified, err := modify(event)
+	if err != nil {
+		return err
+	}
+
+	target.Event, err = json.Marshal(modified)
+	if err != nil {
+		return fmt.Errorf("Error serializing event: %v", err)
+	}
+
+	return s.writeEvents(fileName, failedEvents)
+}
+
+//readEvents returns all events from the fallback file with line indexes
+//malformed lines are skipped
+func (s *Service) readEvents(fileName string) ([]*FailedEvent, error) {
+	if err := validateFileName(fileName); err != nil {
+		return nil, err
+	}
+
+	b, err := ioutil.ReadFile(path.Join(s.fallbackDir, fileName))
+	if err != nil {
+		return nil, fmt.Errorf("Error reading fallback file [%s]: %v", fileName, err)
+	}
+
+	var failedEvents []*FailedEvent
+	reader := bufio.NewReaderSize(bytes.NewReader(b), 64*1024)
+	for index := 0; ; index++ {
+		line, readErr := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			fe := &events.FailedEvent{}
+			if err := json.Unmarshal(line, fe); err != nil {
+				logging.Warnf("Malformed line [%d] in fallback file [%s]: %v", index, fileName, err)
+			} else {
+				failedEvents = append(failedEvents, &FailedEvent{Index: index, EventID: fe.EventID, Error: fe.Error, Event: fe.Event})
+			}
+		}
+
+		if readErr != nil {
+			break
+		}
+	}
+
+	return failedEvents, nil
+}
+
+//writeEvents overwrites fallback file atomically (via temporary file)
+//events keep their line indexes: the file is written with the same lines order
+func (s *Service) writeEvents(fileName string, failedEvents []*FailedEvent) error {
+	buf := bytes.Buffer{}
+	for _, failedEvent := range failedEvents {
+		line, err := json.Marshal(&events.FailedEvent{Event: failedEvent.Event, Error: failedEvent.Error, EventID: failedEvent.EventID})
+		if err != nil {
+			return fmt.Errorf("Error serializing event [%d]: %v", failedEvent.Index, err)
+		}
+
+		//keep line indexes if there were malformed lines
+		for i := linesCount(buf.Bytes()); i < failedEvent.Index; i++ {
+			buf.WriteString("\n")
+		}
+		buf.Write(line)
+		buf.WriteString("\n")
+	}
+
+	filePath := path.Join(s.fallbackDir, fileName)
+	tmpFilePath := filePath + ".tmp"
+	if err := ioutil.WriteFile(tmpFilePath, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("Error writing fallback file [%s]: %v", fileName, err)
+	}
+
+	if err := os.Rename(tmpFilePath, filePath); err != nil {
+		os.Remove(tmpFilePath)
+		return fmt.Errorf("Error replacing fallback file [%s]: %v", fileName, err)
+	}
+
+	return nil
+}
+
+//lockFile returns unlock func or error if the file is being processed
+func (s *Service) lockFile(fileName string) (func(), error) {
+	_, loaded := s.locks.LoadOrStore(fileName, true)
+	if loaded {
+		return nil, fmt.Errorf("File [%s] is being processed", fileName)
+	}
+
+	return func() { s.locks.Delete(fileName) }, nil
+}
+
+//validateFileName returns err if the file name isn't a fallback file name (e.g. contains path)
+func validateFileName(fileName string) error {
+	if fileName == "" {
+		return errors.New("File name can't be empty")
+	}
+
+	if fileName != filepath.Base(fileName) || strings.HasPrefix(fileName, ".") {
+		return fmt.Errorf("File name [%s] must be a fallback file name without path", fileName)
+	}
+
+	if _, err := extractDestinationID(fileName); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+//extractDestinationID returns destination ID from fallback file name
+func extractDestinationID(fileName string) (string, error) {
+	regexResult := destinationIDExtractRegexp.FindStringSubmatch(fileName)
+	if len(regexResult) != 2 {
+		return "", fmt.Errorf("Error processing fallback file %s: Malformed name", fileName)
+	}
+
+	return regexResult[1], nil
+}
+
+//mergePatch returns target with applied JSON merge patch (RFC 7386)
+func mergePatch(target, patch map[string]interface{}) map[string]interface{} {
+	if target == nil {
+		target = map[string]interface{}{}
+	}
+
+	for key, patchValue := range patch {
+		if patchValue == nil {
+			delete(target, key)
+			continue
+		}
+
+		patchObject, ok := patchValue.(map[string]interface{})
+		if !ok {
+			target[key] = patchValue
+			continue
+		}
+
+		targetObject, _ := target[key].(map[string]interface{})
+		target[key] = mergePatch(targetObject, patchObject)
+	}
+
+	return target
+}
+
+func eventKey(index int) string {
+	return strconv.Itoa(index)
+}
+
+func linesCount(b []byte) int {
+	return bytes.Count(b, []byte{'\n'})
+}
@@ -0,0 +1,186 @@
+package fallback
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/destinations"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/logfiles"
+	"github.com/jitsucom/jitsu/server/storages"
+	"github.com/stretchr/testify/require"
+)
+
+const testFallbackFileName = "failed.dst=dst1-2021-05-18T10-00-00.000.log"
+
+const testFallbackFile = `{"event":{"event_type":"pageview","amount":"abc","user":{"id":"1","email":"a@b.com"}},"error":"invalid amount","event_id":"e1"}
+{"event":{"event_type":"click"},"error":"connection refused","event_id":"e2"}
+malformed line
+{"event":{"event_type":"pageview","amount":"def"},"error":"invalid amount","event_id":"e3"}
+`
+
+//testBatchStorage records stored payloads and fails processing of lines which contain failLine
+type testBatchStorage struct {
+	storages.Storage
+
+	failLine  string
+	payloads  []string
+	fallbacks int
+}
+
+func (tbs *testBatchStorage) StoreWithParseFunc(fileName string, payload []byte, alreadyUploadedTables map[string]bool,
+	parseFunc func([]byte) (map[string]interface{}, error)) (map[string]*storages.StoreResult, []*events.FailedEvent, error) {
+	tbs.payloads = append(tbs.payloads, string(payload))
+
+	var failedEvents []*events.FailedEvent
+	rowsCount := 0
+	for _, line := range bytes.Split(bytes.TrimSuffix(payload, []byte("\n")), []byte("\n")) {
+		if _, err := parseFunc(line); err != nil {
+			return nil, nil, err
+		}
+
+		if bytes.Contains(line, []byte(tbs.failLine)) {
+			failedEvents = append(failedEvents, &events.FailedEvent{Event: line, Error: "wrong type"})
+		} else {
+			rowsCount++
+		}
+	}
+
+	return map[string]*storages.StoreResult{"events": {RowsCount: rowsCount}}, failedEvents, nil
+}
+
+func (tbs *testBatchStorage) Fallback(failedEvents ...*events.FailedEvent) {
+	tbs.fallbacks += len(failedEvents)
+}
+
+func (tbs *testBatchStorage) Name() string {
+	return "dst1"
+}
+
+func (tbs *testBatchStorage) IsStaging() bool {
+	return false
+}
+
+func newTestFallbackService(t *testing.T) *Service {
+	dir, err := ioutil.TempDir("", "fallback")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	require.NoError(t, ioutil.WriteFile(path.Join(dir, testFallbackFileName), []byte(testFallbackFile), 0644))
+
+	statusManager, err := logfiles.NewStatusManager(dir)
+	require.NoError(t, err)
+
+	return &Service{fallbackDir: dir, statusManager: statusManager}
+}
+
+func TestGetEvents(t *testing.T) {
+	service := newTestFallbackService(t)
+
+	page, err := service.GetEvents(testFallbackFileName, nil, 0, 0)
+	require.NoError(t, err)
+	require.Equal(t, "dst1", page.DestinationID)
+	require.Equal(t, 3, page.Total)
+	require.Equal(t, defaultEventsPageLimit, page.Limit)
+	require.Equal(t, []int{0, 1, 3}, eventsIndexes(page.Events))
+	require.Equal(t, []*ErrorGroup{{Error: "invalid amount", Count: 2}, {Error: "connection refused", Count: 1}}, page.Errors)
+
+	page, err = service.GetEvents(testFallbackFileName, &EventsFilter{ErrorContains: "amount"}, 1, 1)
+	require.NoError(t, err)
+	require.Equal(t, 2, page.Total)
+	require.Equal(t, []int{3}, eventsIndexes(page.Events))
+
+	page, err = service.GetEvents(testFallbackFileName, &EventsFilter{EventIDs: map[string]bool{"e2": true}}, 0, 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, page.Total)
+	require.Equal(t, "e2", page.Events[0].EventID)
+
+	service.statusManager.UpdateEventStatus(testFallbackFileName, eventKey(1), "dst1", errors.New("replay error"))
+	page, err = service.GetEvents(testFallbackFileName, nil, 0, 10)
+	require.NoError(t, err)
+	require.Nil(t, page.Events[0].ReplayStatuses)
+	require.Equal(t, map[string]*logfiles.Status{"dst1": {Uploaded: false, Err: "replay error"}}, page.Events[1].ReplayStatuses)
+
+	_, err = service.GetEvents("../"+testFallbackFileName, nil, 0, 10)
+	require.Error(t, err)
+}
+
+func TestUpdateAndPatchEvent(t *testing.T) {
+	service := newTestFallbackService(t)
+
+	require.NoError(t, service.UpdateEvent(testFallbackFileName, 1, map[string]interface{}{"event_type": "submit"}))
+	require.NoError(t, service.PatchEvent(testFallbackFileName, 0, map[string]interface{}{
+		"amount": 10,
+		"user":   map[string]interface{}{"email": nil, "name": "John"},
+	}))
+	require.Equal(t, ErrEventNotFound, service.PatchEvent(testFallbackFileName, 2, map[string]interface{}{"a": 1}))
+
+	page, err := service.GetEvents(testFallbackFileName, nil, 0, 10)
+	require.NoError(t, err)
+	require.Equal(t, []int{0, 1, 3}, eventsIndexes(page.Events))
+	require.JSONEq(t, `{"event_type":"pageview","amount":10,"user":{"id":"1","name":"John"}}`, string(page.Events[0].Event))
+	require.JSONEq(t, `{"event_type":"submit"}`, string(page.Events[1].Event))
+	require.Equal(t, "e2", page.Events[1].EventID)
+	require.Equal(t, "connection refused", page.Events[1].Error)
+	require.JSONEq(t, `{"event_type":"pageview","amount":"def"}`, string(page.Events[2].Event))
+}
+
+func TestReplayEvents(t *testing.T) {
+	service := newTestFallbackService(t)
+	storage := &testBatchStorage{failLine: `"amount":"abc"`}
+	service.destinationService = destinations.NewTestServiceWithUnits(map[string]*destinations.Unit{
+		"dst1": destinations.NewTestUnit(&testStorageProxy{storage}, nil),
+	})
+
+	result, err := service.ReplayEvents(testFallbackFileName, "", nil, false)
+	require.NoError(t, err)
+	require.Equal(t, 2, result.Replayed)
+	require.Equal(t, []int{0}, eventsIndexes(result.Failed))
+	require.Equal(t, "Event processing failed: wrong type", result.Failed[0].Error)
+
+	//all events are stored with the one payload, failed event is kept only in the replayed file
+	require.Len(t, storage.payloads, 1)
+	require.Equal(t, 3, linesCount([]byte(storage.payloads[0])))
+	require.Equal(t, 0, storage.fallbacks)
+	b, err := ioutil.ReadFile(path.Join(service.fallbackDir, testFallbackFileName))
+	require.NoError(t, err)
+	require.Equal(t, testFallbackFile, string(b))
+
+	statuses := service.statusManager.GetEventsStatuses(testFallbackFileName)
+	require.Equal(t, &logfiles.Status{Uploaded: false, Err: "Event processing failed: wrong type"}, statuses[eventKey(0)]["dst1"])
+	require.True(t, statuses[eventKey(1)]["dst1"].Uploaded)
+	require.True(t, statuses[eventKey(3)]["dst1"].Uploaded)
+
+	//uploaded events are skipped
+	result, err = service.ReplayEvents(testFallbackFileName, "", nil, false)
+	require.NoError(t, err)
+	require.Equal(t, 2, result.Skipped)
+	require.Equal(t, []int{0}, eventsIndexes(result.Failed))
+	require.Len(t, storage.payloads, 2)
+	require.Equal(t, 1, linesCount([]byte(storage.payloads[1])))
+}
+
+func TestPurge(t *testing.T) {
+	service := newTestFallbackService(t)
+	service.statusManager.UpdateEventStatus(testFallbackFileName, eventKey(0), "dst1", nil)
+
+	require.Error(t, service.Purge(nil))
+	require.NoError(t, service.Purge([]string{testFallbackFileName}))
+
+	_, err := os.Stat(path.Join(service.fallbackDir, testFallbackFileName))
+	require.True(t, os.IsNotExist(err))
+	require.Empty(t, service.statusManager.GetEventsStatuses(testFallbackFileName))
+}
+
+func eventsIndexes(failedEvents []*FailedEvent) []int {
+	var indexes []int
+	for _, failedEvent := range failedEvents {
+		indexes = append(indexes, failedEvent.Index)
+	}
+
+	return indexes
+}
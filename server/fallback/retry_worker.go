@@ -0,0 +1,198 @@
+package fallback
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/logfiles"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/metrics"
+	"github.com/jitsucom/jitsu/server/safego"
+	"github.com/jitsucom/jitsu/server/storages"
+)
+
+//RetryWorker replays fallback files automatically according to destinations fallback.auto_retry policies
+//attempts are kept in logfiles.StatusManager and are cleaned up when the file is replayed successfully
+type RetryWorker struct {
+	service    *Service
+	checkEvery time.Duration
+	//map[fileName]unique errors of fallback file events
+	eventsErrors map[string]*fileErrors
+
+	closed bool
+}
+
+type fileErrors struct {
+	modTime time.Time
+	errors  []string
+}
+
+//NewRetryWorker returns RetryWorker and starts observer goroutine
+func NewRetryWorker(service *Service, checkEvery time.Duration) *RetryWorker {
+	rw := &RetryWorker{
+		service:      service,
+		checkEvery:   checkEvery,
+		eventsErrors: map[string]*fileErrors{},
+	}
+
+	rw.start()
+
+	return rw
+}
+
+func (rw *RetryWorker) start() {
+	safego.RunWithRestart(func() {
+		for {
+			if rw.closed {
+				break
+			}
+
+			rw.retryFiles()
+
+			time.Sleep(rw.checkEvery)
+		}
+	})
+}
+
+//retryFiles makes retry attempts of all fallback files
+//errors are only logged: files will be checked on the next iteration
+func (rw *RetryWorker) retryFiles() {
+	files, err := filepath.Glob(rw.service.fileMask)
+	if err != nil {
+		logging.SystemErrorf("Error finding fallback files by mask [%s]: %v", rw.service.fileMask, err)
+		return
+	}
+
+	existingFiles := map[string]bool{}
+	pendingPerDestination := map[string]int{}
+	for _, filePath := range files {
+		existingFiles[filepath.Base(filePath)] = true
+		if rw.closed {
+			break
+		}
+
+		destinationID, pending := rw.retry(filePath)
+		if pending {
+			pendingPerDestination[destinationID]++
+		}
+	}
+	metrics.PendingFallbackRetries(pendingPerDestination)
+
+	//remove replayed or purged files from the cache
+	for fileName := range rw.eventsErrors {
+		if !existingFiles[fileName] {
+			delete(rw.eventsErrors, fileName)
+		}
+	}
+}
+
+//retry replays the file if the destination auto retry policy allows it and the next attempt time has come
+//returns destination ID and true if the file is waiting for the next attempt
+func (rw *RetryWorker) retry(filePath string) (string, bool) {
+	fileName := filepath.Base(filePath)
+	destinationID, err := extractDestinationID(fileName)
+	if err != nil {
+		return "", false
+	}
+
+	policy := rw.service.destinationService.GetAutoRetryConfig(destinationID)
+	if policy == nil {
+		return destinationID, false
+	}
+
+	retryStatus := rw.service.statusManager.GetRetryStatus(fileName)
+	if retryStatus.Attempts >= policy.GetMaxAttempts() {
+		return destinationID, false
+	}
+
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		return destinationID, false
+	}
+
+	if !rw.isRetryable(policy, fileName, fileInfo.ModTime(), retryStatus) {
+		return destinationID, false
+	}
+
+	//the first attempt is made after the delay from the file rotation
+	lastAttemptAt := retryStatus.LastAttemptAt
+	if retryStatus.Attempts == 0 {
+		lastAttemptAt = fileInfo.ModTime()
+	}
+
+	if time.Now().Before(lastAttemptAt.Add(policy.Delay(retryStatus.Attempts))) {
+		return destinationID, true
+	}
+
+	//file is being replayed manually or destination isn't ready: it isn't an attempt
+	if _, locked := rw.service.locks.Load(fileName); locked {
+		return destinationID, true
+	}
+	if _, err := rw.service.getStorage(destinationID); err != nil {
+		return destinationID, true
+	}
+
+	logging.Infof("[%s] Auto retry attempt %d of fallback file [%s]", destinationID, retryStatus.Attempts+1, fileName)
+	replayErr := rw.service.Replay(fileName, destinationID, false)
+	if replayErr == nil {
+		metrics.SuccessFallbackRetry(destinationID)
+		logging.Infof("[%s] Fallback file [%s] has been replayed automatically", destinationID, fileName)
+		return destinationID, false
+	}
+
+	metrics.ErrorFallbackRetry(destinationID)
+	retryStatus = rw.service.statusManager.IncrementRetryAttempts(fileName, replayErr)
+	logging.Errorf("[%s] Error auto retrying fallback file [%s] (attempt %d of %d): %v", destinationID, fileName, retryStatus.Attempts, policy.GetMaxAttempts(), replayErr)
+
+	if retryStatus.Attempts >= policy.GetMaxAttempts() {
+		metrics.ExhaustedFallbackRetries(destinationID)
+		logging.Warnf("[%s] Fallback file [%s] won't be retried automatically: max attempts (%d) exceeded", destinationID, fileName, policy.GetMaxAttempts())
+		return destinationID, false
+	}
+
+	if !policy.IsRetryable(retryStatus.LastError) {
+		metrics.ExhaustedFallbackRetries(destinationID)
+		logging.Warnf("[%s] Fallback file [%s] won't be retried automatically: error class [%s] isn't allowed", destinationID, fileName, storages.ClassifyError(retryStatus.LastError))
+		return destinationID, false
+	}
+
+	return destinationID, true
+}
+
+//isRetryable returns true if the last attempt error or (if there were no attempts) all fallback events errors
+//belong to allowed error classes
+//events errors are cached until the file is modified (e.g. events are patched via API)
+func (rw *RetryWorker) isRetryable(policy *storages.AutoRetryConfig, fileName string, modTime time.Time, retryStatus logfiles.RetryStatus) bool {
+	if retryStatus.Attempts > 0 {
+		return policy.IsRetryable(retryStatus.LastError)
+	}
+
+	cached, ok := rw.eventsErrors[fileName]
+	if !ok || !cached.modTime.Equal(modTime) {
+		failedEvents, err := rw.service.readEvents(fileName)
+		if err != nil {
+			logging.Errorf("Error reading fallback file [%s] for auto retry: %v", fileName, err)
+			return false
+		}
+
+		uniqueErrors := map[string]bool{}
+		for _, failedEvent := range failedEvents {
+			uniqueErrors[failedEvent.Error] = true
+		}
+
+		cached = &fileErrors{modTime: modTime}
+		for errMsg := range uniqueErrors {
+			cached.errors = append(cached.errors, errMsg)
+		}
+		rw.eventsErrors[fileName] = cached
+	}
+
+	return len(cached.errors) > 0 && policy.IsRetryable(cached.errors...)
+}
+
+func (rw *RetryWorker) Close() error {
+	rw.closed = true
+
+	return nil
+}
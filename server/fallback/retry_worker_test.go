@@ -0,0 +1,124 @@
+package fallback
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/destinations"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/logfiles"
+	"github.com/jitsucom/jitsu/server/storages"
+	"github.com/stretchr/testify/require"
+)
+
+//testReplayStorage records replayed files and returns err as a table result
+type testReplayStorage struct {
+	storages.Storage
+
+	name string
+	err  error
+
+	mutex         sync.Mutex
+	replayedFiles []string
+}
+
+func (trs *testReplayStorage) StoreWithParseFunc(fileName string, payload []byte, alreadyUploadedTables map[string]bool,
+	parseFunc func([]byte) (map[string]interface{}, error)) (map[string]*storages.StoreResult, []*events.FailedEvent, error) {
+	trs.mutex.Lock()
+	defer trs.mutex.Unlock()
+
+	trs.replayedFiles = append(trs.replayedFiles, fileName)
+	return map[string]*storages.StoreResult{"events": {Err: trs.err, RowsCount: 1}}, nil, nil
+}
+
+func (trs *testReplayStorage) Fallback(failedEvents ...*events.FailedEvent) {
+}
+
+func (trs *testReplayStorage) getReplayedFiles() []string {
+	trs.mutex.Lock()
+	defer trs.mutex.Unlock()
+
+	return trs.replayedFiles
+}
+
+func (trs *testReplayStorage) Name() string {
+	return trs.name
+}
+
+func (trs *testReplayStorage) IsStaging() bool {
+	return false
+}
+
+type testStorageProxy struct {
+	storage storages.Storage
+}
+
+func (tsp *testStorageProxy) Get() (storages.Storage, bool) {
+	return tsp.storage, true
+}
+
+func (tsp *testStorageProxy) Close() error {
+	return nil
+}
+
+func TestRetryWorker(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fallback_retry")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	fallbackDir := path.Join(dir, "failed")
+	require.NoError(t, os.Mkdir(fallbackDir, 0755))
+	statusManager, err := logfiles.NewStatusManager(fallbackDir)
+	require.NoError(t, err)
+
+	//files have been rotated before the first attempt delay
+	fileModTime := time.Now().Add(-time.Hour)
+	okFile := "failed.dst=dst1-2021-05-18T10-00-00.000.log"
+	failingFile := "failed.dst=dst2-2021-05-18T10-00-00.000.log"
+	for _, fileName := range []string{okFile, failingFile} {
+		filePath := path.Join(fallbackDir, fileName)
+		require.NoError(t, ioutil.WriteFile(filePath, []byte(`{"event":{"event_type":"click"},"error":"connection refused","event_id":"e1"}`+"\n"), 0644))
+		require.NoError(t, os.Chtimes(filePath, fileModTime, fileModTime))
+	}
+
+	okStorage := &testReplayStorage{name: "dst1"}
+	failingStorage := &testReplayStorage{name: "dst2", err: errors.New("pq: syntax error")}
+	autoRetry := &storages.AutoRetryConfig{Enabled: true, MaxAttempts: 3}
+	service := &Service{
+		fallbackDir:   fallbackDir,
+		fileMask:      "[",
+		statusManager: statusManager,
+		destinationService: destinations.NewTestServiceWithUnits(map[string]*destinations.Unit{
+			"dst1": destinations.NewTestUnit(&testStorageProxy{okStorage}, autoRetry),
+			"dst2": destinations.NewTestUnit(&testStorageProxy{failingStorage}, autoRetry),
+		}),
+		archiver: logfiles.NewArchiver(fallbackDir, path.Join(dir, "archive")),
+	}
+
+	//malformed mask error is only logged
+	rw := &RetryWorker{service: service, checkEvery: 10 * time.Millisecond, eventsErrors: map[string]*fileErrors{}}
+	rw.retryFiles()
+	require.Empty(t, okStorage.getReplayedFiles())
+
+	service.fileMask = path.Join(fallbackDir, fallbackFileMaskPostfix)
+	rw.start()
+	defer rw.Close()
+
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(path.Join(fallbackDir, okFile))
+		return os.IsNotExist(err)
+	}, 5*time.Second, 10*time.Millisecond, "replayed file must be archived")
+	require.Equal(t, []string{okFile}, okStorage.getReplayedFiles())
+	require.Equal(t, 0, statusManager.GetRetryStatus(okFile).Attempts)
+
+	//not retryable error class: the only attempt is made
+	require.Eventually(t, func() bool { return statusManager.GetRetryStatus(failingFile).Attempts == 1 }, 5*time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	require.Equal(t, []string{failingFile}, failingStorage.getReplayedFiles())
+	require.FileExists(t, path.Join(fallbackDir, failingFile))
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/metrics"
 	"github.com/jitsucom/jitsu/server/parsers"
+	"github.com/jitsucom/jitsu/server/storages"
 	"io/ioutil"
 	"os"
 	"path"
@@ -83,26 +84,15 @@ func (s *Service) Replay(fileName, destinationID string, rawFile bool) error {
 
 	if destinationID == "" {
 		//get destinationID from filename
-		regexResult := destinationIDExtractRegexp.FindStringSubmatch(fileName)
-		if len(regexResult) != 2 {
-			return fmt.Errorf("Error processing fallback file %s: Malformed name", fileName)
+		destinationID, err = extractDestinationID(fileName)
+		if err != nil {
+			return err
 		}
-
-		destinationID = regexResult[1]
-	}
-
-	storageProxy, ok := s.destinationService.GetStorageByID(destinationID)
-	if !ok {
-		return fmt.Errorf("Destination [%s] wasn't found", destinationID)
 	}
 
-	storage, ok := storageProxy.Get()
-	if !ok {
-		return fmt.Errorf("Destination [%s] hasn't been initialized yet", destinationID)
-	}
-	if storage.IsStaging() {
-		return fmt.Errorf("Error running fallback for destination [%s] in staged mode, "+
-			"cannot be used to store data (only available for dry-run)", destinationID)
+	storage, err := s.getStorage(destinationID)
+	if err != nil {
+		return err
 	}
 
 	alreadyUploadedTables := map[string]bool{}
@@ -118,7 +108,7 @@ func (s *Service) Replay(fileName, destinationID string, rawFile bool) error {
 		parserFunc = parsers.ParseJSON
 	}
 
-	resultPerTable, errRowsCount, err := storage.StoreWithParseFunc(fileName, b, alreadyUploadedTables, parserFunc)
+	resultPerTable, errRowsCount, err := storages.StoreWithFallback(storage, fileName, b, alreadyUploadedTables, parserFunc)
 	if errRowsCount > 0 {
 		metrics.ErrorTokenEvents(fallbackIdentifier, storage.Name(), errRowsCount)
 	}
@@ -154,6 +144,25 @@ func (s *Service) Replay(fileName, destinationID string, rawFile bool) error {
 	}
 }
 
+//getStorage returns initialized not staged storage by destination ID
+func (s *Service) getStorage(destinationID string) (storages.Storage, error) {
+	storageProxy, ok := s.destinationService.GetStorageByID(destinationID)
+	if !ok {
+		return nil, fmt.Errorf("Destination [%s] wasn't found", destinationID)
+	}
+
+	storage, ok := storageProxy.Get()
+	if !ok {
+		return nil, fmt.Errorf("Destination [%s] hasn't been initialized yet", destinationID)
+	}
+	if storage.IsStaging() {
+		return nil, fmt.Errorf("Error running fallback for destination [%s] in staged mode, "+
+			"cannot be used to store data (only available for dry-run)", destinationID)
+	}
+
+	return storage, nil
+}
+
 func (s *Service) GetFileStatuses(destinationsFilter map[string]bool) []*FileStatus {
 	files, err := filepath.Glob(s.fileMask)
 	if err != nil {
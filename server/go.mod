@@ -8,8 +8,10 @@ require (
 	cloud.google.com/go/firestore v1.1.1
 	cloud.google.com/go/storage v1.6.0
 	firebase.google.com/go/v4 v4.1.0
+	github.com/Shopify/sarama v1.27.2
 	github.com/aws/aws-sdk-go v1.34.0
 	github.com/docker/go-connections v0.4.0
+	github.com/dop251/goja v0.0.0-20230605162241-28ee0ee714f3
 	github.com/gin-gonic/gin v1.6.3
 	github.com/go-sql-driver/mysql v1.5.0
 	github.com/gomodule/redigo v1.8.2
@@ -20,9 +22,11 @@ require (
 	github.com/hashicorp/go-multierror v1.1.0
 	github.com/huandu/facebook/v2 v2.5.3
 	github.com/joncrlsn/dque v0.0.0-20200702023911-3e80e3146ce5
+	github.com/klauspost/compress v1.13.1
 	github.com/lib/pq v1.8.0
 	github.com/mailru/easyjson v0.7.7
 	github.com/mailru/go-clickhouse v1.3.0
+	github.com/mattn/go-sqlite3 v1.14.0
 	github.com/mitchellh/hashstructure/v2 v2.0.1
 	github.com/oschwald/geoip2-golang v1.4.0
 	github.com/panjf2000/ants/v2 v2.4.3
@@ -35,6 +39,7 @@ require (
 	github.com/stretchr/testify v1.7.0
 	github.com/testcontainers/testcontainers-go v0.10.0
 	github.com/ua-parser/uap-go v0.0.0-20200325213135-e1c09f13e2fe
+	github.com/xitongsys/parquet-go v1.6.2
 	go.etcd.io/etcd/client/v3 v3.5.0-alpha.0
 	go.opencensus.io v0.22.4 // indirect
 	go.uber.org/atomic v1.6.0
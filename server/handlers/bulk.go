@@ -0,0 +1,349 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/appconfig"
+	"github.com/jitsucom/jitsu/server/counters"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/middleware"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	acceptedStatus = "accepted"
+	rejectedStatus = "rejected"
+
+	gzipEncoding = "gzip"
+)
+
+var (
+	errBodyTooLarge  = errors.New("Request body is too large")
+	errTooManyEvents = errors.New("Request contains too many events")
+)
+
+//BulkEventResult is a result of processing one event from the bulk request
+//Index is a position of the event in the request (array index or NDJSON line number without empty lines)
+type BulkEventResult struct {
+	Index   int    `json:"index"`
+	EventID string `json:"event_id,omitempty"`
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+}
+
+//BulkResponse is a response of bulk endpoint with per-event results
+//clients should retry only rejected events
+type BulkResponse struct {
+	Status   string             `json:"status"`
+	Accepted int                `json:"accepted"`
+	Rejected int                `json:"rejected"`
+	Results  []*BulkEventResult `json:"results"`
+}
+
+//bulkItem is a parsed event or a parsing error
+type bulkItem struct {
+	event events.Event
+	err   error
+}
+
+//BulkHandler accepts JSON array or newline-delimited JSON (optionally gzipped) body
+//and processes every event as EventHandler.PostHandler does
+type BulkHandler struct {
+	eventHandler *EventHandler
+
+	maxBodySize         int64
+	maxDecompressedSize int64
+	maxLineSize         int
+	maxEvents           int
+}
+
+//NewBulkHandler return configured BulkHandler
+//maxBodySize and maxDecompressedSize limit raw and decompressed body size in bytes
+//maxLineSize limits one NDJSON line and maxEvents limits events count per request
+func NewBulkHandler(eventHandler *EventHandler, maxBodySize, maxDecompressedSize int64, maxLineSize, maxEvents int) *BulkHandler {
+	return &BulkHandler{
+		eventHandler:        eventHandler,
+		maxBodySize:         maxBodySize,
+		maxDecompressedSize: maxDecompressedSize,
+		maxLineSize:         maxLineSize,
+		maxEvents:           maxEvents,
+	}
+}
+
+func (bh *BulkHandler) Handler(c *gin.Context) {
+	iface, ok := c.Get(middleware.TokenName)
+	if !ok {
+		logging.SystemError("Token wasn't found in context")
+		return
+	}
+	token := iface.(string)
+
+	contentEncoding := strings.ToLower(strings.TrimSpace(c.GetHeader("Content-Encoding")))
+	if contentEncoding != "" && contentEncoding != gzipEncoding {
+		c.JSON(http.StatusUnsupportedMediaType, middleware.ErrorResponse{Message: fmt.Sprintf("Unsupported Content-Encoding [%s]. Only gzip is supported", contentEncoding)})
+		return
+	}
+
+	tokenID := appconfig.Instance.AuthorizationService.GetTokenID(token)
+	consumers := bh.eventHandler.destinationService.GetConsumers(tokenID)
+	if len(consumers) == 0 {
+		noConsumerMessage := fmt.Sprintf("No destination is configured for token [%s] (or only staged ones)", token)
+		logging.Warnf("%s. Bulk request has been rejected", noConsumerMessage)
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse{Message: noConsumerMessage})
+		return
+	}
+
+	//MaxBytesReader allows one extra byte: it is read by limitedReader for detecting that the body is too large
+	var reader io.Reader = &limitedReader{r: http.MaxBytesReader(c.Writer, c.Request.Body, bh.maxBodySize+1), n: bh.maxBodySize}
+	if contentEncoding == gzipEncoding {
+		gzipReader, err := gzip.NewReader(reader)
+		if err != nil {
+			bh.readingError(c, fmt.Errorf("Error reading gzip body: %v", err))
+			return
+		}
+		defer gzipReader.Close()
+		reader = &limitedReader{r: gzipReader, n: bh.maxDecompressedSize}
+	}
+
+	items, err := bh.parse(reader)
+	if err != nil {
+		bh.readingError(c, err)
+		return
+	}
+
+	if len(items) == 0 {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse{Message: "Body doesn't contain any events"})
+		return
+	}
+
+	destinationIDs := bh.eventHandler.getDestinationIDs(tokenID)
+	response := &BulkResponse{Status: "ok", Results: make([]*BulkEventResult, 0, len(items))}
+	for i, item := range items {
+		result := &BulkEventResult{Index: i}
+		response.Results = append(response.Results, result)
+
+		if item.err != nil {
+			result.Status = rejectedStatus
+			result.Error = item.err.Error()
+			response.Rejected++
+			continue
+		}
+
+		bh.eventHandler.consume(item.event, token, tokenID, destinationIDs, consumers, c.Request)
+
+		result.EventID = events.ExtractEventID(item.event)
+		result.Status = acceptedStatus
+		response.Accepted++
+	}
+
+	counters.SuccessSourceEvents(tokenID, response.Accepted)
+
+	c.JSON(http.StatusOK, response)
+}
+
+//readingError writes 413 if body exceeds the limits and 400 otherwise
+func (bh *BulkHandler) readingError(c *gin.Context, err error) {
+	logging.Errorf("Error reading bulk body: %v", err)
+	if err == errBodyTooLarge || err == errTooManyEvents {
+		c.JSON(http.StatusRequestEntityTooLarge, middleware.ErrorResponse{Message: "Failed to read body", Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusBadRequest, middleware.ErrorResponse{Message: "Failed to read body", Error: err.Error()})
+}
+
+//parse reads JSON array or NDJSON from the reader and returns parsed items
+//returns error only if the body can't be read or exceeds the limits. Malformed events are returned as items with error
+func (bh *BulkHandler) parse(reader io.Reader) ([]*bulkItem, error) {
+	bufReader := bufio.NewReader(reader)
+	firstByte, err := skipSpaces(bufReader)
+	if err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if firstByte == '[' {
+		return bh.parseArray(bufReader)
+	}
+
+	return bh.parseNDJSON(bufReader)
+}
+
+//parseArray decodes JSON array elements one by one
+//a syntax error stops parsing: events before it are returned as is and the rest of the body is returned as one rejected item
+func (bh *BulkHandler) parseArray(reader io.Reader) ([]*bulkItem, error) {
+	decoder := json.NewDecoder(reader)
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+
+	var items []*bulkItem
+	for decoder.More() {
+		raw := json.RawMessage{}
+		err := decoder.Decode(&raw)
+		if err != nil {
+			if isMalformedJSONErr(err) {
+				return bh.appendItem(items, &bulkItem{err: err})
+			}
+			return nil, err
+		}
+
+		items, err = bh.appendItem(items, parseBulkEvent(raw))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := decoder.Token(); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if isMalformedJSONErr(err) {
+			return bh.appendItem(items, &bulkItem{err: err})
+		}
+		return nil, err
+	}
+
+	return items, nil
+}
+
+//parseNDJSON reads events line by line. Empty lines are skipped, lines longer than maxLineSize are rejected
+func (bh *BulkHandler) parseNDJSON(reader *bufio.Reader) ([]*bulkItem, error) {
+	var items []*bulkItem
+	for {
+		line, tooLong, err := readLine(reader, bh.maxLineSize)
+		if err != nil {
+			if err == io.EOF {
+				return items, nil
+			}
+			return nil, err
+		}
+
+		item := &bulkItem{err: fmt.Errorf("Line exceeds max size %d bytes", bh.maxLineSize)}
+		if !tooLong {
+			line = bytes.TrimSpace(line)
+			if len(line) == 0 {
+				continue
+			}
+			item = parseBulkEvent(line)
+		}
+
+		items, err = bh.appendItem(items, item)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+//appendItem returns items with the item or errTooManyEvents if items already contain maxEvents items
+//every parsed item (including rejected ones) is counted in both JSON array and NDJSON bodies
+func (bh *BulkHandler) appendItem(items []*bulkItem, item *bulkItem) ([]*bulkItem, error) {
+	if len(items) >= bh.maxEvents {
+		return nil, errTooManyEvents
+	}
+
+	return append(items, item), nil
+}
+
+//parseBulkEvent return bulkItem with event or with parsing error
+//numbers are parsed as json.Number (like in gin binding with EnableDecoderUseNumber)
+func parseBulkEvent(raw []byte) *bulkItem {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	event := events.Event{}
+	if err := decoder.Decode(&event); err != nil {
+		return &bulkItem{err: err}
+	}
+
+	if len(event) == 0 {
+		return &bulkItem{err: errors.New("Event is empty")}
+	}
+
+	return &bulkItem{event: event}
+}
+
+//readLine return next line without line ending. If the line is longer than maxSize it is skipped and tooLong is true
+func readLine(reader *bufio.Reader, maxSize int) (line []byte, tooLong bool, err error) {
+	for {
+		chunk, isPrefix, err := reader.ReadLine()
+		if err != nil {
+			return nil, false, err
+		}
+
+		if !tooLong {
+			if len(line)+len(chunk) > maxSize {
+				tooLong = true
+				line = nil
+			} else {
+				line = append(line, chunk...)
+			}
+		}
+
+		if !isPrefix {
+			return line, tooLong, nil
+		}
+	}
+}
+
+//skipSpaces returns first non-space byte without consuming it
+func skipSpaces(reader *bufio.Reader) (byte, error) {
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+
+		if b != ' ' && b != '\t' && b != '\r' && b != '\n' {
+			return b, reader.UnreadByte()
+		}
+	}
+}
+
+//isMalformedJSONErr return true if error is caused by body content (not by reading)
+func isMalformedJSONErr(err error) bool {
+	if _, ok := err.(*json.SyntaxError); ok {
+		return true
+	}
+
+	return err == io.ErrUnexpectedEOF
+}
+
+//limitedReader returns errBodyTooLarge if the underlying reader has more than n bytes
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	if lr.n <= 0 {
+		//check if there is anything left: the underlying reader may return 0 bytes without error
+		probe := make([]byte, 1)
+		for {
+			n, err := lr.r.Read(probe)
+			if n > 0 {
+				return 0, errBodyTooLarge
+			}
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if int64(len(p)) > lr.n {
+		p = p[:lr.n]
+	}
+	n, err := lr.r.Read(p)
+	lr.n -= int64(n)
+	return n, err
+}
@@ -36,13 +36,25 @@ func testDestinationConnection(config *storages.DestinationConfig) error {
 			return err
 		}
 
-		postgres, err := adapters.NewPostgres(context.Background(), config.DataSource, nil, map[string]string{})
+		postgres, err := adapters.NewPostgres(context.Background(), config.DataSource, nil, map[string]string{}, nil)
 		if err != nil {
 			return err
 		}
 
 		postgres.Close()
 		return nil
+	case storages.MySQLType:
+		if err := config.DataSource.Validate(); err != nil {
+			return err
+		}
+
+		mySQL, err := adapters.NewMySQL(context.Background(), config.DataSource, nil, map[string]string{})
+		if err != nil {
+			return err
+		}
+
+		mySQL.Close()
+		return nil
 	case storages.ClickHouseType:
 		if err := config.ClickHouse.Validate(); err != nil {
 			return err
@@ -144,6 +156,41 @@ func testDestinationConnection(config *storages.DestinationConfig) error {
 			}
 		}
 		return nil
+	case storages.GCSType:
+		if err := config.Google.Validate(false); err != nil {
+			return err
+		}
+
+		googleStorage, err := adapters.NewGoogleCloudStorage(context.Background(), config.Google)
+		if err != nil {
+			return err
+		}
+		defer googleStorage.Close()
+
+		return googleStorage.ValidateWritePermission()
+	case storages.FileType:
+		if err := config.File.Validate(); err != nil {
+			return err
+		}
+
+		fileStorage, err := adapters.NewLocalFileStorage(config.File)
+		if err != nil {
+			return err
+		}
+		defer fileStorage.Close()
+
+		return fileStorage.ValidateWritePermission()
+	case storages.SQLiteType:
+		if err := config.SQLite.Validate(); err != nil {
+			return err
+		}
+
+		sqLite, err := adapters.NewSQLite(context.Background(), config.SQLite, nil, map[string]string{})
+		if err != nil {
+			return err
+		}
+
+		return sqLite.Close()
 	case storages.GoogleAnalyticsType:
 		if err := config.GoogleAnalytics.Validate(); err != nil {
 			return err
@@ -163,6 +210,30 @@ func testDestinationConnection(config *storages.DestinationConfig) error {
 		}
 
 		return nil
+	case storages.KafkaType:
+		if err := config.Kafka.Validate(); err != nil {
+			return err
+		}
+
+		//producer requests brokers metadata on creation
+		adapter, err := adapters.NewKafka(config.Kafka, nil)
+		if err != nil {
+			return err
+		}
+
+		return adapter.Close()
+	case storages.WebHookType:
+		if err := config.WebHook.Validate(); err != nil {
+			return err
+		}
+
+		//only templates are checked: webhook request depends on an event
+		adapter, err := adapters.NewWebHook(config.WebHook, nil)
+		if err != nil {
+			return err
+		}
+
+		return adapter.Close()
 	default:
 		return errors.New("unsupported destination type " + config.Type)
 	}
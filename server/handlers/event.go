@@ -75,8 +75,36 @@ func (eh *EventHandler) PostHandler(c *gin.Context) {
 	}
 	token := iface.(string)
 
+	tokenID := appconfig.Instance.AuthorizationService.GetTokenID(token)
+	consumers := eh.destinationService.GetConsumers(tokenID)
+	if len(consumers) == 0 {
+		noConsumerMessage := fmt.Sprintf("No destination is configured for token [%s] (or only staged ones)", token)
+		logging.Warnf("%s. Event: %s", noConsumerMessage, payload.Serialize())
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse{Message: noConsumerMessage})
+		return
+	}
+
+	eh.consume(payload, token, tokenID, eh.getDestinationIDs(tokenID), consumers, c.Request)
+
+	counters.SuccessSourceEvents(tokenID, 1)
+
+	c.JSON(http.StatusOK, middleware.OkResponse())
+}
+
+//getDestinationIDs return all destination ids (including staged ones) of the token
+func (eh *EventHandler) getDestinationIDs(tokenID string) []string {
+	var destinationIDs []string
+	for destinationID := range eh.destinationService.GetDestinationIDs(tokenID) {
+		destinationIDs = append(destinationIDs, destinationID)
+	}
+	return destinationIDs
+}
+
+//consume enriches event, puts it into events cache and passes it to consumers and users recognition
+//destinationIDs and consumers must be resolved by the caller once per request
+func (eh *EventHandler) consume(payload events.Event, token, tokenID string, destinationIDs []string, consumers []events.Consumer, r *http.Request) {
 	//** Context enrichment **
-	enrichment.ContextEnrichmentStep(payload, token, c.Request, eh.preprocessor)
+	enrichment.ContextEnrichmentStep(payload, token, r, eh.preprocessor)
 
 	//** Caching **
 	//clone payload for preventing concurrent changes while serialization
@@ -87,34 +115,19 @@ func (eh *EventHandler) PostHandler(c *gin.Context) {
 	if eventID == "" {
 		logging.SystemErrorf("Empty extracted eventn_ctx_event_id in: %s", payload.Serialize())
 	}
-	tokenID := appconfig.Instance.AuthorizationService.GetTokenID(token)
-	var destinationIDs []string
-	for destinationID := range eh.destinationService.GetDestinationIDs(tokenID) {
-		destinationIDs = append(destinationIDs, destinationID)
+	for _, destinationID := range destinationIDs {
 		eh.eventsCache.Put(destinationID, eventID, cachingEvent)
 	}
 
 	//** Multiplexing **
-	consumers := eh.destinationService.GetConsumers(tokenID)
-	if len(consumers) == 0 {
-		noConsumerMessage := fmt.Sprintf("No destination is configured for token [%s] (or only staged ones)", token)
-		logging.Warnf("%s. Event: %s", noConsumerMessage, payload.Serialize())
-		c.JSON(http.StatusBadRequest, middleware.ErrorResponse{Message: noConsumerMessage})
-		return
-	} else {
-		telemetry.Event()
+	telemetry.Event()
 
-		for _, consumer := range consumers {
-			consumer.Consume(payload, tokenID)
-		}
-
-		//Retrospective users recognition
-		eh.userRecognitionService.Event(payload, destinationIDs)
-
-		counters.SuccessSourceEvents(tokenID, 1)
+	for _, consumer := range consumers {
+		consumer.Consume(payload, tokenID)
 	}
 
-	c.JSON(http.StatusOK, middleware.OkResponse())
+	//Retrospective users recognition
+	eh.userRecognitionService.Event(payload, destinationIDs)
 }
 
 func (eh *EventHandler) GetHandler(c *gin.Context) {
@@ -1,11 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/jitsucom/jitsu/server/fallback"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/middleware"
 	"net/http"
+	"strconv"
 	"strings"
 )
 
@@ -21,6 +23,30 @@ type ReplayRequest struct {
 	FileFormat    string `json:"file_format"`
 }
 
+type UpdateEventRequest struct {
+	FileName string                 `json:"file_name"`
+	Index    *int                   `json:"index"`
+	Event    map[string]interface{} `json:"event"`
+}
+
+type PatchEventRequest struct {
+	FileName string                 `json:"file_name"`
+	Index    *int                   `json:"index"`
+	Patch    map[string]interface{} `json:"patch"`
+}
+
+type ReplayEventsRequest struct {
+	FileName      string   `json:"file_name"`
+	DestinationID string   `json:"destination_id"`
+	ErrorContains string   `json:"error_contains"`
+	EventIDs      []string `json:"event_ids"`
+	Force         bool     `json:"force"`
+}
+
+type PurgeRequest struct {
+	FileNames []string `json:"file_names"`
+}
+
 type FallbackHandler struct {
 	fallbackService *fallback.Service
 }
@@ -60,3 +86,139 @@ func (fh *FallbackHandler) ReplayHandler(c *gin.Context) {
 
 	c.JSON(http.StatusOK, middleware.OkResponse())
 }
+
+//EventsHandler returns page of fallback file events with error groups
+func (fh *FallbackHandler) EventsHandler(c *gin.Context) {
+	fileName := c.Query("file_name")
+	offset, err := parseIntQueryParameter(c, "offset")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse{Message: err.Error()})
+		return
+	}
+	limit, err := parseIntQueryParameter(c, "limit")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	filter := &fallback.EventsFilter{ErrorContains: c.Query("error"), EventIDs: map[string]bool{}}
+	if eventIDs := c.Query("event_ids"); eventIDs != "" {
+		for _, eventID := range strings.Split(eventIDs, ",") {
+			filter.EventIDs[strings.TrimSpace(eventID)] = true
+		}
+	}
+
+	page, err := fh.fallbackService.GetEvents(fileName, filter, offset, limit)
+	if err != nil {
+		logging.Errorf("Error getting events from fallback file [%s]: %v", fileName, err)
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse{Message: "Failed to get events from file: " + fileName, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+//UpdateEventHandler overwrites event in fallback file
+func (fh *FallbackHandler) UpdateEventHandler(c *gin.Context) {
+	req := &UpdateEventRequest{}
+	if err := c.BindJSON(req); err != nil {
+		logging.Errorf("Error parsing update event body: %v", err)
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse{Message: "Failed to parse body", Error: err.Error()})
+		return
+	}
+
+	if req.Index == nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse{Message: "index is required parameter"})
+		return
+	}
+
+	fh.respondEventModification(c, req.FileName, *req.Index, fh.fallbackService.UpdateEvent(req.FileName, *req.Index, req.Event))
+}
+
+//PatchEventHandler applies JSON merge patch to event in fallback file
+func (fh *FallbackHandler) PatchEventHandler(c *gin.Context) {
+	req := &PatchEventRequest{}
+	if err := c.BindJSON(req); err != nil {
+		logging.Errorf("Error parsing patch event body: %v", err)
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse{Message: "Failed to parse body", Error: err.Error()})
+		return
+	}
+
+	if req.Index == nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse{Message: "index is required parameter"})
+		return
+	}
+
+	fh.respondEventModification(c, req.FileName, *req.Index, fh.fallbackService.PatchEvent(req.FileName, *req.Index, req.Patch))
+}
+
+//ReplayEventsHandler replays fallback file events subset
+func (fh *FallbackHandler) ReplayEventsHandler(c *gin.Context) {
+	req := &ReplayEventsRequest{}
+	if err := c.BindJSON(req); err != nil {
+		logging.Errorf("Error parsing replay events body: %v", err)
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse{Message: "Failed to parse body", Error: err.Error()})
+		return
+	}
+
+	filter := &fallback.EventsFilter{ErrorContains: req.ErrorContains, EventIDs: map[string]bool{}}
+	for _, eventID := range req.EventIDs {
+		filter.EventIDs[eventID] = true
+	}
+
+	result, err := fh.fallbackService.ReplayEvents(req.FileName, req.DestinationID, filter, req.Force)
+	if err != nil {
+		logging.Errorf("Error replaying events from fallback file [%s]: %v", req.FileName, err)
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse{Message: "Failed to replay events from file: " + req.FileName, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+//PurgeHandler deletes fallback files
+func (fh *FallbackHandler) PurgeHandler(c *gin.Context) {
+	req := &PurgeRequest{}
+	if err := c.BindJSON(req); err != nil {
+		logging.Errorf("Error parsing purge body: %v", err)
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse{Message: "Failed to parse body", Error: err.Error()})
+		return
+	}
+
+	if err := fh.fallbackService.Purge(req.FileNames); err != nil {
+		logging.Errorf("Error purging fallback files %v: %v", req.FileNames, err)
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse{Message: "Failed to purge files", Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, middleware.OkResponse())
+}
+
+func (fh *FallbackHandler) respondEventModification(c *gin.Context, fileName string, index int, err error) {
+	if err != nil {
+		logging.Errorf("Error modifying event [%d] in fallback file [%s]: %v", index, fileName, err)
+		status := http.StatusBadRequest
+		if err == fallback.ErrEventNotFound {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, middleware.ErrorResponse{Message: "Failed to modify event in file: " + fileName, Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, middleware.OkResponse())
+}
+
+//parseIntQueryParameter returns 0 if parameter is absent or error if it isn't a number
+func parseIntQueryParameter(c *gin.Context, name string) (int, error) {
+	value := c.Query(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	result, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, errors.New(name + " must be an integer")
+	}
+
+	return result, nil
+}
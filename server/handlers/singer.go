@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/drivers"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/middleware"
+	"github.com/jitsucom/jitsu/server/singer"
+	"github.com/jitsucom/jitsu/server/uuid"
+)
+
+//SingerPropertiesRequest is a request for building singer properties file:
+//selected streams of the catalog. If catalog isn't provided, it is discovered with the source config
+type SingerPropertiesRequest struct {
+	Source  *drivers.SourceConfig     `json:"source"`
+	Catalog *singer.Catalog           `json:"catalog,omitempty"`
+	Streams []*singer.StreamSelection `json:"streams"`
+}
+
+//SingerDiscoverHandler runs singer tap in --discover mode and returns catalog with all available streams and their schemas
+func SingerDiscoverHandler(c *gin.Context) {
+	sourceConfig := &drivers.SourceConfig{}
+	if err := c.BindJSON(sourceConfig); err != nil {
+		logging.Errorf("Error parsing singer source body: %v", err)
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse{Message: "Failed to parse body", Error: err.Error()})
+		return
+	}
+
+	catalog, err := discoverSingerCatalog(sourceConfig)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse{Message: "Failed to discover singer catalog", Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, catalog)
+}
+
+//SingerPropertiesHandler returns singer properties with selected streams and replication keys
+//result might be used as 'properties' or 'catalog' singer source configuration parameter
+func SingerPropertiesHandler(c *gin.Context) {
+	req := &SingerPropertiesRequest{}
+	if err := c.BindJSON(req); err != nil {
+		logging.Errorf("Error parsing singer properties body: %v", err)
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse{Message: "Failed to parse body", Error: err.Error()})
+		return
+	}
+
+	if len(req.Streams) == 0 {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse{Message: "streams are required"})
+		return
+	}
+
+	catalog := req.Catalog
+	if catalog == nil {
+		if req.Source == nil {
+			c.JSON(http.StatusBadRequest, middleware.ErrorResponse{Message: "source or catalog is required"})
+			return
+		}
+
+		var err error
+		catalog, err = discoverSingerCatalog(req.Source)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, middleware.ErrorResponse{Message: "Failed to discover singer catalog", Error: err.Error()})
+			return
+		}
+	}
+
+	properties, err := catalog.Select(req.Streams)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse{Message: "Failed to select singer streams", Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, properties)
+}
+
+//discoverSingerCatalog creates singer driver and runs discover
+//driver json files are always written into a temporary dir with generated name which is removed afterwards:
+//the request source name isn't used so json files of the configured source aren't overwritten
+func discoverSingerCatalog(sourceConfig *drivers.SourceConfig) (*singer.Catalog, error) {
+	if sourceConfig.Type != drivers.SingerType {
+		return nil, errors.New("discover is supported only for singer sources")
+	}
+
+	discoverConfig := *sourceConfig
+	discoverConfig.Name = "discover_" + uuid.New()
+	if singer.Instance != nil {
+		defer os.RemoveAll(path.Join(singer.Instance.VenvDir, discoverConfig.Name))
+	}
+
+	driver, err := drivers.NewSinger(context.Background(), &discoverConfig, &drivers.Collection{
+		Name: drivers.DefaultSingerCollection,
+		Type: drivers.DefaultSingerCollection,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer driver.Close()
+
+	return driver.(*drivers.Singer).Discover()
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/drivers"
+	"github.com/jitsucom/jitsu/server/middleware"
+	"github.com/jitsucom/jitsu/server/singer"
+	"github.com/stretchr/testify/require"
+)
+
+const testSingerCatalog = `{"streams": [
+  {"tap_stream_id": "public-users", "stream": "users", "schema": {"type": "object", "properties": {"id": {"type": "integer"}}}},
+  {"tap_stream_id": "archive-users", "stream": "users", "schema": {"type": "object", "properties": {"id": {"type": "integer"}}}},
+  {"tap_stream_id": "orders", "stream": "orders", "schema": {"type": "object", "properties": {"updated_at": {"type": "string"}}}}
+]}`
+
+//initTestSingerBridge creates singer venv dir with installed fake tap which prints testSingerCatalog on --discover
+func initTestSingerBridge(t *testing.T) string {
+	venvDir, err := ioutil.TempDir("", "singer_venv")
+	require.NoError(t, err)
+
+	tapBinDir := path.Join(venvDir, "tap-test", "bin")
+	require.NoError(t, os.MkdirAll(tapBinDir, 0755))
+	require.NoError(t, ioutil.WriteFile(path.Join(tapBinDir, "tap-test"), []byte("#!/bin/sh\necho '"+testSingerCatalog+"'\n"), 0755))
+
+	require.NoError(t, singer.Init("python3", venvDir, false, nil))
+
+	return venvDir
+}
+
+func testSingerSourceConfig(name string) *drivers.SourceConfig {
+	return &drivers.SourceConfig{
+		Name:   name,
+		Type:   drivers.SingerType,
+		Config: map[string]interface{}{"tap": "tap-test", "config": map[string]interface{}{"key": "value"}},
+	}
+}
+
+func serveTestRequest(handler gin.HandlerFunc, body interface{}) *httptest.ResponseRecorder {
+	b, _ := json.Marshal(body)
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(b))
+	c.Request.Header.Set("Content-Type", "application/json")
+	handler(c)
+
+	return recorder
+}
+
+func TestDiscoverSingerCatalogUsesTemporaryDir(t *testing.T) {
+	venvDir := initTestSingerBridge(t)
+	defer func() {
+		singer.Instance = nil
+		os.RemoveAll(venvDir)
+	}()
+
+	//json files of the configured source
+	sourceConfigPath := path.Join(venvDir, "my_source", "tap-test", "config.json")
+	require.NoError(t, os.MkdirAll(path.Dir(sourceConfigPath), 0755))
+	require.NoError(t, ioutil.WriteFile(sourceConfigPath, []byte(`{"key": "configured"}`), 0644))
+
+	for _, name := range []string{"", "my_source", "../escaped"} {
+		sourceConfig := testSingerSourceConfig(name)
+		catalog, err := discoverSingerCatalog(sourceConfig)
+		require.NoError(t, err, name)
+		require.Len(t, catalog.Streams, 3, name)
+		require.Equal(t, name, sourceConfig.Name, "request source config mustn't be changed")
+	}
+
+	configContent, err := ioutil.ReadFile(sourceConfigPath)
+	require.NoError(t, err)
+	require.Equal(t, `{"key": "configured"}`, string(configContent), "configured source json files mustn't be overwritten")
+
+	files, err := ioutil.ReadDir(venvDir)
+	require.NoError(t, err)
+	var dirs []string
+	for _, f := range files {
+		dirs = append(dirs, f.Name())
+	}
+	require.ElementsMatch(t, []string{"tap-test", "my_source"}, dirs, "temporary discover dirs must be removed")
+
+	_, err = os.Stat(path.Join(venvDir, "..", "escaped"))
+	require.True(t, os.IsNotExist(err), "files mustn't be written outside of venv dir")
+
+	_, err = discoverSingerCatalog(&drivers.SourceConfig{Type: drivers.AirbyteType})
+	require.EqualError(t, err, "discover is supported only for singer sources")
+}
+
+func TestSingerDiscoverHandler(t *testing.T) {
+	venvDir := initTestSingerBridge(t)
+	defer func() {
+		singer.Instance = nil
+		os.RemoveAll(venvDir)
+	}()
+
+	recorder := serveTestRequest(SingerDiscoverHandler, testSingerSourceConfig("my_source"))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	catalog, err := singer.ParseCatalog(recorder.Body.Bytes())
+	require.NoError(t, err)
+	require.Len(t, catalog.Streams, 3)
+
+	recorder = serveTestRequest(SingerDiscoverHandler, &drivers.SourceConfig{Type: drivers.AirbyteType})
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestSingerPropertiesHandler(t *testing.T) {
+	catalog, err := singer.ParseCatalog([]byte(testSingerCatalog))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		request       *SingerPropertiesRequest
+		expectedCode  int
+		expectedError *middleware.ErrorResponse
+	}{
+		{
+			"streams are required",
+			&SingerPropertiesRequest{Catalog: catalog},
+			http.StatusBadRequest,
+			&middleware.ErrorResponse{Message: "streams are required"},
+		},
+		{
+			"source or catalog is required",
+			&SingerPropertiesRequest{Streams: []*singer.StreamSelection{{Stream: "orders"}}},
+			http.StatusBadRequest,
+			&middleware.ErrorResponse{Message: "source or catalog is required"},
+		},
+		{
+			"ambiguous stream name",
+			&SingerPropertiesRequest{Catalog: catalog, Streams: []*singer.StreamSelection{{Stream: "users"}}},
+			http.StatusBadRequest,
+			&middleware.ErrorResponse{Message: "Failed to select singer streams",
+				Error: "Stream name [users] is ambiguous. Please select one of streams by tap_stream_id: [public-users, archive-users]"},
+		},
+		{
+			"selected streams",
+			&SingerPropertiesRequest{Catalog: catalog, Streams: []*singer.StreamSelection{{Stream: "archive-users"}, {Stream: "orders", ReplicationKey: "updated_at"}}},
+			http.StatusOK,
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := serveTestRequest(SingerPropertiesHandler, tt.request)
+			require.Equal(t, tt.expectedCode, recorder.Code)
+
+			if tt.expectedError != nil {
+				errResponse := &middleware.ErrorResponse{}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), errResponse))
+				require.Equal(t, tt.expectedError, errResponse)
+				return
+			}
+
+			properties, err := singer.ParseCatalog(recorder.Body.Bytes())
+			require.NoError(t, err)
+			require.Len(t, properties.Streams, 3)
+			require.Equal(t, false, properties.Streams[0].Schema["selected"])
+			require.Equal(t, true, properties.Streams[1].Schema["selected"])
+			require.Equal(t, singer.FullTableReplication, properties.Streams[1].ReplicationMethod)
+			require.Equal(t, true, properties.Streams[2].Schema["selected"])
+			require.Equal(t, singer.IncrementalReplication, properties.Streams[2].ReplicationMethod)
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/middleware"
+	"github.com/jitsucom/jitsu/server/sources"
+	"github.com/jitsucom/jitsu/server/synchronization"
+)
+
+//ResyncRequest is a request for resync of selected intervals. All intervals are resynced if empty
+type ResyncRequest struct {
+	Intervals []string `json:"intervals,omitempty"`
+}
+
+//StateHandler handles get, overwrite, clear source collection synchronization state and resync requests
+type StateHandler struct {
+	taskService   *synchronization.TaskService
+	sourceService *sources.Service
+}
+
+func NewStateHandler(taskService *synchronization.TaskService, sourceService *sources.Service) *StateHandler {
+	return &StateHandler{taskService: taskService, sourceService: sourceService}
+}
+
+//GetHandler returns source collection state
+func (sh *StateHandler) GetHandler(c *gin.Context) {
+	sourceID, collectionID, ok := sh.extractSourceCollection(c)
+	if !ok {
+		return
+	}
+
+	state, err := sh.taskService.GetState(sourceID, collectionID)
+	if err != nil {
+		logging.Errorf("Error getting source [%s] collection [%s] state: %v", sourceID, collectionID, err)
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse{Message: "State gathering failed", Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, state)
+}
+
+//UpdateHandler overwrites source collection state with the state from the request body
+func (sh *StateHandler) UpdateHandler(c *gin.Context) {
+	sourceID, collectionID, ok := sh.extractSourceCollection(c)
+	if !ok {
+		return
+	}
+
+	state := &synchronization.StateDto{}
+	if err := c.BindJSON(state); err != nil {
+		logging.Errorf("Error parsing state body: %v", err)
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse{Message: "Failed to parse body", Error: err.Error()})
+		return
+	}
+
+	if err := sh.taskService.SaveState(sourceID, collectionID, state); err != nil {
+		sh.writeStateError(c, sourceID, collectionID, "State overwriting failed", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, middleware.OkResponse())
+}
+
+//DeleteHandler clears source collection state
+func (sh *StateHandler) DeleteHandler(c *gin.Context) {
+	sourceID, collectionID, ok := sh.extractSourceCollection(c)
+	if !ok {
+		return
+	}
+
+	if err := sh.taskService.ClearState(sourceID, collectionID); err != nil {
+		sh.writeStateError(c, sourceID, collectionID, "State clearing failed", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, middleware.OkResponse())
+}
+
+//ResyncHandler clears state of the selected intervals (or the whole state) and runs synchronization task
+func (sh *StateHandler) ResyncHandler(c *gin.Context) {
+	sourceID, collectionID, ok := sh.extractSourceCollection(c)
+	if !ok {
+		return
+	}
+
+	req := &ResyncRequest{}
+	if c.Request.ContentLength > 0 {
+		if err := c.BindJSON(req); err != nil {
+			logging.Errorf("Error parsing resync body: %v", err)
+			c.JSON(http.StatusBadRequest, middleware.ErrorResponse{Message: "Failed to parse body", Error: err.Error()})
+			return
+		}
+	}
+
+	taskID, err := sh.taskService.Resync(sourceID, collectionID, req.Intervals)
+	if err != nil {
+		if err == synchronization.ErrSourceCollectionIsSyncing && taskID != "" {
+			c.JSON(http.StatusOK, TaskIDResponse{ID: taskID})
+			return
+		}
+
+		sh.writeStateError(c, sourceID, collectionID, "Resync failed", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, TaskIDResponse{ID: taskID})
+}
+
+//extractSourceCollection returns source and collection query parameters or writes bad request response
+func (sh *StateHandler) extractSourceCollection(c *gin.Context) (string, string, bool) {
+	sourceID := c.Query("source")
+	if sourceID == "" {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse{Message: "'source' is required query parameter"})
+		return "", "", false
+	}
+
+	source, err := sh.sourceService.GetSource(sourceID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse{Message: "Error getting source", Error: err.Error()})
+		return "", "", false
+	}
+
+	collectionID := extractCollectionID(source.SourceType, c)
+	if collectionID == "" {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse{Message: "'collection' is required query parameter"})
+		return "", "", false
+	}
+
+	return sourceID, collectionID, true
+}
+
+//writeStateError writes conflict response if the collection is being synchronized otherwise bad request response
+func (sh *StateHandler) writeStateError(c *gin.Context, sourceID, collectionID, msg string, err error) {
+	if err == synchronization.ErrSourceCollectionIsSyncing || err == synchronization.ErrSourceCollectionIsStartingToSync {
+		c.JSON(http.StatusConflict, middleware.ErrorResponse{Message: msg, Error: err.Error()})
+		return
+	}
+
+	logging.Errorf("%s source [%s] collection [%s]: %v", msg, sourceID, collectionID, err)
+	c.JSON(http.StatusBadRequest, middleware.ErrorResponse{Message: msg, Error: err.Error()})
+}
@@ -224,7 +224,7 @@ func (sh *TaskHandler) SyncHandler(c *gin.Context) {
 }
 
 func extractCollectionID(sourceType string, c *gin.Context) string {
-	if sourceType == drivers.SingerType {
+	if sourceType == drivers.SingerType || sourceType == drivers.AirbyteType {
 		return drivers.DefaultSingerCollection
 	}
 	return c.Query("collection")
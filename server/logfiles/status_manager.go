@@ -9,16 +9,30 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 )
 
 const statusFileExtension = ".status"
 const statusFileMask = "*" + statusFileExtension
 
+const eventsStatusFileExtension = ".events_status"
+const eventsStatusFileMask = "*" + eventsStatusFileExtension
+
+const retryStatusFileExtension = ".retry_status"
+const retryStatusFileMask = "*" + retryStatusFileExtension
+
 type Status struct {
 	Uploaded bool   `json:"uploaded"`
 	Err      string `json:"error"`
 }
 
+//RetryStatus is a state of automatic retries of the file
+type RetryStatus struct {
+	Attempts      int       `json:"attempts"`
+	LastAttemptAt time.Time `json:"last_attempt_at"`
+	LastError     string    `json:"last_error,omitempty"`
+}
+
 type StatusManager struct {
 	sync.RWMutex
 
@@ -27,6 +41,12 @@ type StatusManager struct {
 	//map[fileLogName]map[storageName]map[tableName]Status
 	//incoming.tok=123 {"storage1":{tableName1": Status, "tableName2": Status}, "storage2":{tableName3": Status}}
 	fileStorageTableStatuses map[string]map[string]map[string]*Status
+	//map[fileLogName]map[eventKey]map[storageName]Status
+	//failed.dst=123 {"0":{"storage1": Status}, "5":{"storage1": Status, "storage2": Status}}
+	fileEventStorageStatuses map[string]map[string]map[string]*Status
+	//map[fileLogName]RetryStatus
+	//failed.dst=123 {"attempts": 2, "last_attempt_at": "2021-05-18T10:00:00Z", "last_error": "connection refused"}
+	fileRetryStatuses map[string]*RetryStatus
 }
 
 func NewStatusManager(logFilesDir string) (*StatusManager, error) {
@@ -61,13 +81,83 @@ func NewStatusManager(logFilesDir string) (*StatusManager, error) {
 		fileStorageTableStatuses[fileLogName] = storageTableStatuses
 	}
 
+	fileEventStorageStatuses, err := readEventsStatuses(path.Join(logFilesDir, eventsStatusFileMask))
+	if err != nil {
+		return nil, err
+	}
+
+	fileRetryStatuses, err := readRetryStatuses(path.Join(logFilesDir, retryStatusFileMask))
+	if err != nil {
+		return nil, err
+	}
+
 	return &StatusManager{
 		filesDir:                 logFilesDir,
 		statusFilesMask:          statusFilesMask,
 		fileStorageTableStatuses: fileStorageTableStatuses,
+		fileEventStorageStatuses: fileEventStorageStatuses,
+		fileRetryStatuses:        fileRetryStatuses,
 	}, nil
 }
 
+//readEventsStatuses returns per event statuses from all files by mask
+func readEventsStatuses(eventsStatusFilesMask string) (map[string]map[string]map[string]*Status, error) {
+	files, err := filepath.Glob(eventsStatusFilesMask)
+	if err != nil {
+		return nil, err
+	}
+
+	fileEventStorageStatuses := map[string]map[string]map[string]*Status{}
+	for _, filePath := range files {
+		fileLogName := strings.TrimSuffix(filepath.Base(filePath), eventsStatusFileExtension)
+
+		b, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			logging.Error("Error reading log events status file", filePath, err)
+			continue
+		}
+
+		eventStorageStatuses := map[string]map[string]*Status{}
+		if len(b) > 0 {
+			if err := json.Unmarshal(b, &eventStorageStatuses); err != nil {
+				logging.SystemError("Error unmarshalling log events status file", filePath, err)
+			}
+		}
+		fileEventStorageStatuses[fileLogName] = eventStorageStatuses
+	}
+
+	return fileEventStorageStatuses, nil
+}
+
+//readRetryStatuses returns retry statuses from all files by mask
+func readRetryStatuses(retryStatusFilesMask string) (map[string]*RetryStatus, error) {
+	files, err := filepath.Glob(retryStatusFilesMask)
+	if err != nil {
+		return nil, err
+	}
+
+	fileRetryStatuses := map[string]*RetryStatus{}
+	for _, filePath := range files {
+		fileLogName := strings.TrimSuffix(filepath.Base(filePath), retryStatusFileExtension)
+
+		b, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			logging.Error("Error reading log retry status file", filePath, err)
+			continue
+		}
+
+		retryStatus := &RetryStatus{}
+		if len(b) > 0 {
+			if err := json.Unmarshal(b, retryStatus); err != nil {
+				logging.SystemError("Error unmarshalling log retry status file", filePath, err)
+			}
+		}
+		fileRetryStatuses[fileLogName] = retryStatus
+	}
+
+	return fileRetryStatuses, nil
+}
+
 func (sm *StatusManager) GetTablesStatuses(fileName, storageName string) map[string]*Status {
 	sm.RLock()
 	defer sm.RUnlock()
@@ -109,13 +199,115 @@ func (sm *StatusManager) UpdateStatus(fileName, storage, table string, err error
 	sm.persist(fileName, statusesPerStorage)
 }
 
+//GetEventsStatuses returns statuses per storage of all events from the file
+//result map key is an event key (e.g. line number)
+func (sm *StatusManager) GetEventsStatuses(fileName string) map[string]map[string]*Status {
+	sm.RLock()
+	defer sm.RUnlock()
+
+	statuses, ok := sm.fileEventStorageStatuses[fileName]
+	if !ok {
+		return map[string]map[string]*Status{}
+	}
+
+	result := make(map[string]map[string]*Status, len(statuses))
+	for eventKey, storageStatuses := range statuses {
+		copied := make(map[string]*Status, len(storageStatuses))
+		for storage, status := range storageStatuses {
+			copied[storage] = status
+		}
+		result[eventKey] = copied
+	}
+
+	return result
+}
+
+//UpdateEventStatus saves storing status of a single event from the file (e.g. after replaying)
+func (sm *StatusManager) UpdateEventStatus(fileName, eventKey, storage string, err error) {
+	sm.Lock()
+	defer sm.Unlock()
+
+	statusesPerEvent, ok := sm.fileEventStorageStatuses[fileName]
+	if !ok {
+		statusesPerEvent = map[string]map[string]*Status{}
+		sm.fileEventStorageStatuses[fileName] = statusesPerEvent
+	}
+
+	statusPerStorage, ok := statusesPerEvent[eventKey]
+	if !ok {
+		statusPerStorage = map[string]*Status{}
+		statusesPerEvent[eventKey] = statusPerStorage
+	}
+
+	var errMsg string
+	if err != nil {
+		errMsg = err.Error()
+	}
+
+	statusPerStorage[storage] = &Status{
+		Uploaded: err == nil,
+		Err:      errMsg,
+	}
+
+	sm.persistEvents(fileName, statusesPerEvent)
+}
+
+//GetRetryStatus returns automatic retries state of the file (zero value if there were no attempts)
+func (sm *StatusManager) GetRetryStatus(fileName string) RetryStatus {
+	sm.RLock()
+	defer sm.RUnlock()
+
+	retryStatus, ok := sm.fileRetryStatuses[fileName]
+	if !ok {
+		return RetryStatus{}
+	}
+
+	return *retryStatus
+}
+
+//IncrementRetryAttempts saves failed automatic retry attempt of the file and returns updated state
+func (sm *StatusManager) IncrementRetryAttempts(fileName string, err error) RetryStatus {
+	sm.Lock()
+	defer sm.Unlock()
+
+	retryStatus, ok := sm.fileRetryStatuses[fileName]
+	if !ok {
+		retryStatus = &RetryStatus{}
+		sm.fileRetryStatuses[fileName] = retryStatus
+	}
+
+	retryStatus.Attempts++
+	retryStatus.LastAttemptAt = time.Now().UTC()
+	retryStatus.LastError = ""
+	if err != nil {
+		retryStatus.LastError = err.Error()
+	}
+
+	b, marshalErr := json.Marshal(retryStatus)
+	if marshalErr != nil {
+		logging.SystemErrorf("Error marshaling event log file retry status for [%s] file: %v", fileName, marshalErr)
+		return *retryStatus
+	}
+
+	filePath := path.Join(sm.filesDir, fileName+retryStatusFileExtension)
+	if writeErr := ioutil.WriteFile(filePath, b, 0644); writeErr != nil {
+		logging.SystemErrorf("Error writing event log retry status file [%s]: %v", filePath, writeErr)
+	}
+
+	return *retryStatus
+}
+
 func (sm *StatusManager) CleanUp(fileName string) {
 	sm.Lock()
 	defer sm.Unlock()
 
 	delete(sm.fileStorageTableStatuses, fileName)
+	delete(sm.fileEventStorageStatuses, fileName)
+	delete(sm.fileRetryStatuses, fileName)
 
 	os.Remove(path.Join(sm.filesDir, fileName+statusFileExtension))
+	os.Remove(path.Join(sm.filesDir, fileName+eventsStatusFileExtension))
+	os.Remove(path.Join(sm.filesDir, fileName+retryStatusFileExtension))
 }
 
 func (sm *StatusManager) persist(fileName string, statuses map[string]map[string]*Status) {
@@ -130,3 +322,16 @@ func (sm *StatusManager) persist(fileName string, statuses map[string]map[string
 		logging.SystemErrorf("Error writing event log status file [%s]: %v", filePath, err)
 	}
 }
+
+func (sm *StatusManager) persistEvents(fileName string, statuses map[string]map[string]*Status) {
+	b, err := json.Marshal(statuses)
+	if err != nil {
+		logging.SystemErrorf("Error marshaling event log file events statuses for [%s] file: %v", fileName, err)
+		return
+	}
+
+	filePath := path.Join(sm.filesDir, fileName+eventsStatusFileExtension)
+	if err := ioutil.WriteFile(filePath, b, 0644); err != nil {
+		logging.SystemErrorf("Error writing event log events status file [%s]: %v", filePath, err)
+	}
+}
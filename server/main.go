@@ -17,6 +17,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin/binding"
+	"github.com/jitsucom/jitsu/server/airbyte"
 	"github.com/jitsucom/jitsu/server/appconfig"
 	"github.com/jitsucom/jitsu/server/appstatus"
 	"github.com/jitsucom/jitsu/server/caching"
@@ -189,6 +190,10 @@ func main() {
 		logging.Fatal(err)
 	}
 
+	if err := airbyte.Init(viper.GetString("airbyte-bridge.config_dir"), appconfig.Instance.AirbyteLogsWriter); err != nil {
+		logging.Fatal(err)
+	}
+
 	enrichment.InitDefault()
 
 	safego.GlobalRecoverHandler = func(value interface{}) {
@@ -359,6 +364,10 @@ func main() {
 		logging.Fatal("Error creating fallback service:", err)
 	}
 
+	//Fallback files auto retry worker (according to destinations fallback.auto_retry policies)
+	fallbackRetryWorker := fallback.NewRetryWorker(fallbackService, time.Duration(viper.GetInt("server.fallback.auto_retry_check_sec"))*time.Second)
+	appconfig.Instance.ScheduleClosing(fallbackRetryWorker)
+
 	//version reminder banner in logs
 	if tag != "" && !viper.GetBool("server.disable_version_reminder") {
 		vn := appconfig.NewVersionReminder(ctx)
@@ -2,7 +2,9 @@ package main
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -398,6 +400,253 @@ func TestAPIEvent(t *testing.T) {
 	}
 }
 
+func TestBulkAPIEvent(t *testing.T) {
+	uuid.InitMock()
+	binding.EnableDecoderUseNumber = true
+
+	SetTestDefaultParams()
+	defer SetTestDefaultParams()
+	viper.Set("server.auth", `{"tokens":[{"id":"id1","server_secret":"s2stoken"},{"id":"id2","server_secret":"s2stoken_without_destinations"}]}`)
+	viper.Set("server.bulk.max_body_size_bytes", 1024)
+	viper.Set("server.bulk.max_decompressed_size_bytes", 2048)
+	viper.Set("server.bulk.max_line_size_bytes", 256)
+	viper.Set("server.bulk.max_events", 5)
+
+	tests := []struct {
+		Name             string
+		Body             string
+		Token            string
+		ContentEncoding  string
+		ExpectedHTTPCode int
+		ExpectedResponse string
+		ExpectedLogged   int
+	}{
+		{
+			"JSON array with wrong element type",
+			`[{"event_origin": "test"}, 1, {"user": {"id": 123}}]`,
+			"s2stoken",
+			"",
+			http.StatusOK,
+			`{"status":"ok","accepted":2,"rejected":1,"results":[{"index":0,"event_id":"mockeduuid","status":"accepted"},{"index":1,"status":"rejected","error":"json: cannot unmarshal number into Go value of type events.Event"},{"index":2,"event_id":"mockeduuid","status":"accepted"}]}`,
+			2,
+		},
+		{
+			"JSON array with malformed tail",
+			`[{"event_origin": "test"}, {"user": {"id": 123}}, {"a" 1}]`,
+			"s2stoken",
+			"",
+			http.StatusOK,
+			`{"status":"ok","accepted":2,"rejected":1,"results":[{"index":0,"event_id":"mockeduuid","status":"accepted"},{"index":1,"event_id":"mockeduuid","status":"accepted"},{"index":2,"status":"rejected","error":"invalid character '1' after object key"}]}`,
+			2,
+		},
+		{
+			"Truncated JSON array",
+			`[{"event_origin": "test"}`,
+			"s2stoken",
+			"",
+			http.StatusOK,
+			`{"status":"ok","accepted":1,"rejected":1,"results":[{"index":0,"event_id":"mockeduuid","status":"accepted"},{"index":1,"status":"rejected","error":"unexpected end of JSON input"}]}`,
+			1,
+		},
+		{
+			"Gzipped NDJSON with malformed and oversized lines",
+			"{\"event_origin\": \"test\"}\n\n{\"a\"\n{\"long\": \"" + strings.Repeat("x", 300) + "\"}\n{\"user\": {\"id\": 123}}\n",
+			"s2stoken",
+			"gzip",
+			http.StatusOK,
+			`{"status":"ok","accepted":2,"rejected":2,"results":[{"index":0,"event_id":"mockeduuid","status":"accepted"},{"index":1,"status":"rejected","error":"unexpected EOF"},{"index":2,"status":"rejected","error":"Line exceeds max size 256 bytes"},{"index":3,"event_id":"mockeduuid","status":"accepted"}]}`,
+			2,
+		},
+		{
+			"Oversized body",
+			`[{"big": "` + strings.Repeat("x", 2000) + `"}]`,
+			"s2stoken",
+			"",
+			http.StatusRequestEntityTooLarge,
+			`{"message":"Failed to read body","error":"Request body is too large"}`,
+			0,
+		},
+		{
+			"Oversized decompressed body",
+			strings.Repeat(" ", 3000) + `[{"event_origin": "test"}]`,
+			"s2stoken",
+			"gzip",
+			http.StatusRequestEntityTooLarge,
+			`{"message":"Failed to read body","error":"Request body is too large"}`,
+			0,
+		},
+		{
+			"Too many events",
+			strings.Repeat("{\"event_origin\": \"test\"}\n", 6),
+			"s2stoken",
+			"",
+			http.StatusRequestEntityTooLarge,
+			`{"message":"Failed to read body","error":"Request contains too many events"}`,
+			0,
+		},
+		{
+			"Too many events in JSON array",
+			"[" + strings.Repeat(`{"event_origin": "test"},`, 5) + `{"event_origin": "test"}]`,
+			"s2stoken",
+			"",
+			http.StatusRequestEntityTooLarge,
+			`{"message":"Failed to read body","error":"Request contains too many events"}`,
+			0,
+		},
+		{
+			"Too many events with rejected ones",
+			"[" + strings.Repeat(`{"event_origin": "test"},`, 5) + `{"a" 1}]`,
+			"s2stoken",
+			"",
+			http.StatusRequestEntityTooLarge,
+			`{"message":"Failed to read body","error":"Request contains too many events"}`,
+			0,
+		},
+		{
+			"Max events in JSON array",
+			"[" + strings.Repeat(`{"event_origin": "test"},`, 4) + `{"event_origin": "test"}]`,
+			"s2stoken",
+			"",
+			http.StatusOK,
+			`{"status":"ok","accepted":5,"rejected":0,"results":[` +
+				`{"index":0,"event_id":"mockeduuid","status":"accepted"},{"index":1,"event_id":"mockeduuid","status":"accepted"},{"index":2,"event_id":"mockeduuid","status":"accepted"},{"index":3,"event_id":"mockeduuid","status":"accepted"},{"index":4,"event_id":"mockeduuid","status":"accepted"}]}`,
+			5,
+		},
+		{
+			"Body of max size",
+			strings.Repeat(" ", 1024-len(`[{"event_origin": "test"}]`)) + `[{"event_origin": "test"}]`,
+			"s2stoken",
+			"",
+			http.StatusOK,
+			`{"status":"ok","accepted":1,"rejected":0,"results":[{"index":0,"event_id":"mockeduuid","status":"accepted"}]}`,
+			1,
+		},
+		{
+			"Bad gzip stream",
+			`[{"event_origin": "test"}]`,
+			"s2stoken",
+			"gzip-without-compression",
+			http.StatusBadRequest,
+			`{"message":"Failed to read body","error":"Error reading gzip body: gzip: invalid header"}`,
+			0,
+		},
+		{
+			"Unsupported content encoding",
+			`[{"event_origin": "test"}]`,
+			"s2stoken",
+			"gzip, deflate",
+			http.StatusUnsupportedMediaType,
+			`{"message":"Unsupported Content-Encoding [gzip, deflate]. Only gzip is supported","error":""}`,
+			0,
+		},
+		{
+			"Token without destinations",
+			`[{"event_origin": "test"}]`,
+			"s2stoken_without_destinations",
+			"",
+			http.StatusBadRequest,
+			`{"message":"No destination is configured for token [s2stoken_without_destinations] (or only staged ones)","error":""}`,
+			0,
+		},
+		{
+			"Empty body",
+			` `,
+			"s2stoken",
+			"",
+			http.StatusBadRequest,
+			`{"message":"Body doesn't contain any events","error":""}`,
+			0,
+		},
+	}
+
+	telemetry.InitTest()
+	httpAuthority, _ := test.GetLocalAuthority()
+
+	err := appconfig.Init(false, "")
+	require.NoError(t, err)
+	defer appconfig.Instance.Close()
+
+	inmemWriter := logging.InitInMemoryWriter()
+	destinationService := destinations.NewTestService(destinations.TokenizedConsumers{"id1": {"id1": logging.NewAsyncLogger(inmemWriter, false)}},
+		destinations.TokenizedStorages{}, destinations.TokenizedIDs{})
+	appconfig.Instance.ScheduleClosing(destinationService)
+
+	metaStorage := &meta.Dummy{}
+
+	dummyRecognitionService, _ := users.NewRecognitionService(metaStorage, nil, nil, "")
+	router := routers.SetupRouter("", metaStorage, destinationService, sources.NewTestService(), synchronization.NewTestTaskService(),
+		dummyRecognitionService, fallback.NewTestService(), coordination.NewInMemoryService([]string{}),
+		caching.NewEventsCache(metaStorage, 100))
+
+	server := &http.Server{
+		Addr:              httpAuthority,
+		Handler:           middleware.Cors(router, appconfig.Instance.AuthorizationService.GetClientOrigins),
+		ReadTimeout:       time.Second * 60,
+		ReadHeaderTimeout: time.Second * 60,
+		IdleTimeout:       time.Second * 65,
+	}
+	go func() {
+		log.Fatal(server.ListenAndServe())
+	}()
+
+	logging.Info("Started listen and serve " + httpAuthority)
+
+	//check ping endpoint
+	_, err = test.RenewGet("http://" + httpAuthority + "/ping")
+	require.NoError(t, err)
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			logging.InstanceMock.Data = [][]byte{}
+
+			body := []byte(tt.Body)
+			contentEncoding := tt.ContentEncoding
+			switch contentEncoding {
+			case "gzip":
+				buf := &bytes.Buffer{}
+				gzipWriter := gzip.NewWriter(buf)
+				_, err := gzipWriter.Write(body)
+				require.NoError(t, err)
+				require.NoError(t, gzipWriter.Close())
+				body = buf.Bytes()
+			case "gzip-without-compression":
+				contentEncoding = "gzip"
+			}
+
+			apiReq, err := http.NewRequest("POST", "http://"+httpAuthority+"/api/v1/s2s/events/bulk", bytes.NewBuffer(body))
+			require.NoError(t, err)
+			apiReq.Header.Add("x-auth-token", tt.Token)
+			apiReq.Header.Add("x-real-ip", "95.82.232.185")
+			if contentEncoding != "" {
+				apiReq.Header.Add("Content-Encoding", contentEncoding)
+			}
+			resp, err := http.DefaultClient.Do(apiReq)
+			require.NoError(t, err)
+
+			require.Equal(t, tt.ExpectedHTTPCode, resp.StatusCode, "HTTP cods aren't equal")
+			b, err := ioutil.ReadAll(resp.Body)
+			require.NoError(t, err)
+			resp.Body.Close()
+			require.Equal(t, tt.ExpectedResponse, string(b))
+
+			time.Sleep(200 * time.Millisecond)
+			data := logging.InstanceMock.Data
+			require.Equal(t, tt.ExpectedLogged, len(data))
+
+			//check that every accepted event has been enriched
+			for _, logged := range data {
+				event := map[string]interface{}{}
+				require.NoError(t, json.Unmarshal(logged, &event))
+				require.Equal(t, "95.82.232.185", event["source_ip"])
+				require.Equal(t, "s2stoken", event["api_key"])
+				require.Equal(t, "api", event["src"])
+				require.Equal(t, map[string]interface{}{"event_id": "mockeduuid"}, event["eventn_ctx"])
+				require.NotEmpty(t, event["_timestamp"])
+			}
+		})
+	}
+}
+
 func TestPostgresStreamInsert(t *testing.T) {
 	configTemplate := `{"destinations": {
   			"test": {
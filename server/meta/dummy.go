@@ -7,10 +7,16 @@ type Dummy struct {
 
 func (d *Dummy) GetSignature(sourceID, collection, interval string) (string, error)   { return "", nil }
 func (d *Dummy) SaveSignature(sourceID, collection, interval, signature string) error { return nil }
+func (d *Dummy) GetSignatures(sourceID, collection string) (map[string]string, error) {
+	return map[string]string{}, nil
+}
+func (d *Dummy) DeleteSignature(sourceID, collection, interval string) error { return nil }
+func (d *Dummy) DeleteSignatures(sourceID, collection string) error          { return nil }
 
 func (d *Dummy) SuccessEvents(id, namespace string, now time.Time, value int) error { return nil }
 func (d *Dummy) ErrorEvents(id, namespace string, now time.Time, value int) error   { return nil }
 func (d *Dummy) SkipEvents(id, namespace string, now time.Time, value int) error    { return nil }
+func (d *Dummy) FilterEvents(id, namespace string, now time.Time, value int) error  { return nil }
 func (d *Dummy) GetProjectEventsWithGranularity(projectID string, start, end time.Time, granularity Granularity) ([]EventsPerTime, error) {
 	return nil, nil
 }
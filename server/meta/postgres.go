@@ -0,0 +1,679 @@
+package meta
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/safego"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/lib/pq"
+)
+
+const (
+	defaultPostgresMetaSchema = "public"
+
+	anonymousEventsCleanupInterval = 10 * time.Minute
+)
+
+//postgres table [primary key] - description
+//
+//** Sources state**
+//jitsu_signatures [source_id, collection, sync_interval] - sync interval signatures
+//
+//** Events counters **
+//jitsu_events_index [namespace, project_id, id] - destination/source ids by projectID
+//jitsu_events_counters [namespace, id, status, event_hour] - events counters (success, errors, skip, filter) by hour
+//
+//** Last events cache**
+//jitsu_last_events [destination_id, event_id] - original event json, processed with schema json, error json and timestamp
+//
+//** Retrospective user recognition **
+//jitsu_anonymous_events [destination_id, anonymous_id, event_id] - anonymous event JSON with expiration time
+//
+//** Sources Synchronization **
+//jitsu_sync_tasks [id] - task fields [id, source, collection, priority, created_at, started_at, finished_at, status]
+//jitsu_sync_tasks_index [source_id, collection, task_id] - taskIDs and timestamps
+//jitsu_sync_task_logs [id] - log records by taskID and timestamps
+//jitsu_sync_tasks_priority_queue [task_id] - tasks to execute with priority
+var postgresMetaDDL = []string{
+	`CREATE TABLE IF NOT EXISTS %s.jitsu_signatures (
+		source_id text NOT NULL,
+		collection text NOT NULL,
+		sync_interval text NOT NULL,
+		signature text NOT NULL,
+		PRIMARY KEY (source_id, collection, sync_interval)
+	)`,
+	`CREATE TABLE IF NOT EXISTS %s.jitsu_events_index (
+		namespace text NOT NULL,
+		project_id text NOT NULL,
+		id text NOT NULL,
+		PRIMARY KEY (namespace, project_id, id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS %s.jitsu_events_counters (
+		namespace text NOT NULL,
+		id text NOT NULL,
+		status text NOT NULL,
+		event_hour timestamp NOT NULL,
+		value bigint NOT NULL,
+		PRIMARY KEY (namespace, id, status, event_hour)
+	)`,
+	`CREATE TABLE IF NOT EXISTS %s.jitsu_last_events (
+		destination_id text NOT NULL,
+		event_id text NOT NULL,
+		original text NOT NULL DEFAULT '',
+		success text NOT NULL DEFAULT '',
+		error text NOT NULL DEFAULT '',
+		created_at timestamp NOT NULL,
+		PRIMARY KEY (destination_id, event_id)
+	)`,
+	`CREATE INDEX IF NOT EXISTS jitsu_last_events_created_at_idx ON %s.jitsu_last_events (destination_id, created_at)`,
+	`CREATE TABLE IF NOT EXISTS %s.jitsu_anonymous_events (
+		destination_id text NOT NULL,
+		anonymous_id text NOT NULL,
+		event_id text NOT NULL,
+		payload text NOT NULL,
+		expires_at timestamp,
+		PRIMARY KEY (destination_id, anonymous_id, event_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS %s.jitsu_sync_tasks (
+		id text PRIMARY KEY,
+		source text NOT NULL DEFAULT '',
+		collection text NOT NULL DEFAULT '',
+		priority bigint NOT NULL DEFAULT 0,
+		created_at text NOT NULL DEFAULT '',
+		started_at text NOT NULL DEFAULT '',
+		finished_at text NOT NULL DEFAULT '',
+		status text NOT NULL DEFAULT ''
+	)`,
+	`CREATE TABLE IF NOT EXISTS %s.jitsu_sync_tasks_index (
+		source_id text NOT NULL,
+		collection text NOT NULL,
+		task_id text NOT NULL,
+		created_at timestamp NOT NULL,
+		PRIMARY KEY (source_id, collection, task_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS %s.jitsu_sync_task_logs (
+		id bigserial PRIMARY KEY,
+		task_id text NOT NULL,
+		logged_at timestamp NOT NULL,
+		log_time text NOT NULL,
+		message text NOT NULL,
+		level text NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS jitsu_sync_task_logs_task_id_idx ON %s.jitsu_sync_task_logs (task_id, logged_at)`,
+	`CREATE TABLE IF NOT EXISTS %s.jitsu_sync_tasks_priority_queue (
+		task_id text PRIMARY KEY,
+		priority bigint NOT NULL
+	)`,
+}
+
+//Postgres is a meta storage implementation on Postgres tables
+//all timestamps are stored in UTC with seconds precision (like Redis sorted sets scores)
+type Postgres struct {
+	dataSource                *sql.DB
+	schema                    string
+	anonymousEventsSecondsTTL int
+
+	closed bool
+}
+
+//NewPostgres returns configured Postgres meta storage with created tables
+func NewPostgres(config *adapters.DataSourceConfig, anonymousEventsMinutesTTL int) (*Postgres, error) {
+	if config.Schema == "" {
+		config.Schema = defaultPostgresMetaSchema
+	}
+	if config.Port.String() == "" {
+		config.Port = "5432"
+	}
+
+	if anonymousEventsMinutesTTL > 0 {
+		logging.Infof("Initializing postgres meta storage [%s:%s/%s] with anonymous events ttl: %d...", config.Host, config.Port.String(), config.Db, anonymousEventsMinutesTTL)
+	} else {
+		logging.Infof("Initializing postgres meta storage [%s:%s/%s]...", config.Host, config.Port.String(), config.Db)
+	}
+
+	connectionString := fmt.Sprintf("host=%s port=%s dbname=%s user=%s password=%s ",
+		config.Host, config.Port.String(), config.Db, config.Username, config.Password)
+	//concat provided connection parameters
+	for k, v := range config.Parameters {
+		connectionString += k + "=" + v + " "
+	}
+	dataSource, err := sql.Open("postgres", connectionString)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := dataSource.Ping(); err != nil {
+		dataSource.Close()
+		return nil, fmt.Errorf("Error testing connection to Postgres: %v", err)
+	}
+
+	//set default value
+	dataSource.SetConnMaxLifetime(10 * time.Minute)
+
+	p := &Postgres{dataSource: dataSource, schema: pq.QuoteIdentifier(config.Schema), anonymousEventsSecondsTTL: anonymousEventsMinutesTTL * 60}
+	if err := p.init(); err != nil {
+		dataSource.Close()
+		return nil, err
+	}
+
+	if p.anonymousEventsSecondsTTL > 0 {
+		p.startAnonymousEventsCleaner()
+	}
+
+	return p, nil
+}
+
+//init creates schema and tables if they don't exist
+func (p *Postgres) init() error {
+	if _, err := p.dataSource.Exec("CREATE SCHEMA IF NOT EXISTS " + p.schema); err != nil {
+		return fmt.Errorf("Error creating meta storage schema %s: %v", p.schema, err)
+	}
+
+	for _, ddl := range postgresMetaDDL {
+		if _, err := p.dataSource.Exec(fmt.Sprintf(ddl, p.schema)); err != nil {
+			return fmt.Errorf("Error creating meta storage table: %v", err)
+		}
+	}
+
+	return nil
+}
+
+//GetSignature returns sync interval signature from Postgres
+func (p *Postgres) GetSignature(sourceID, collection, interval string) (string, error) {
+	var signature string
+	err := p.dataSource.QueryRow(p.query(`SELECT signature FROM %s.jitsu_signatures WHERE source_id = $1 AND collection = $2 AND sync_interval = $3`),
+		sourceID, collection, interval).Scan(&signature)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	return signature, nil
+}
+
+//SaveSignature saves sync interval signature in Postgres
+func (p *Postgres) SaveSignature(sourceID, collection, interval, signature string) error {
+	_, err := p.dataSource.Exec(p.query(`INSERT INTO %s.jitsu_signatures (source_id, collection, sync_interval, signature) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (source_id, collection, sync_interval) DO UPDATE SET signature = EXCLUDED.signature`),
+		sourceID, collection, interval, signature)
+	return err
+}
+
+//GetSignatures returns all sync interval - signature pairs of the collection from Postgres
+func (p *Postgres) GetSignatures(sourceID, collection string) (map[string]string, error) {
+	rows, err := p.dataSource.Query(p.query(`SELECT sync_interval, signature FROM %s.jitsu_signatures WHERE source_id = $1 AND collection = $2`),
+		sourceID, collection)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	signatures := map[string]string{}
+	for rows.Next() {
+		var interval, signature string
+		if err := rows.Scan(&interval, &signature); err != nil {
+			return nil, err
+		}
+		signatures[interval] = signature
+	}
+
+	return signatures, rows.Err()
+}
+
+//DeleteSignature deletes sync interval signature from Postgres
+func (p *Postgres) DeleteSignature(sourceID, collection, interval string) error {
+	_, err := p.dataSource.Exec(p.query(`DELETE FROM %s.jitsu_signatures WHERE source_id = $1 AND collection = $2 AND sync_interval = $3`),
+		sourceID, collection, interval)
+	return err
+}
+
+//DeleteSignatures deletes all sync interval signatures of the collection from Postgres
+func (p *Postgres) DeleteSignatures(sourceID, collection string) error {
+	_, err := p.dataSource.Exec(p.query(`DELETE FROM %s.jitsu_signatures WHERE source_id = $1 AND collection = $2`),
+		sourceID, collection)
+	return err
+}
+
+//SuccessEvents ensures that id is in the index and increments success events counter
+func (p *Postgres) SuccessEvents(id, namespace string, now time.Time, value int) error {
+	err := p.ensureIDInIndex(id, namespace)
+	if err != nil {
+		return fmt.Errorf("Error ensuring id in index: %v", err)
+	}
+	return p.incrementEventsCount(id, namespace, "success", now, value)
+}
+
+//ErrorEvents increments error events counter
+func (p *Postgres) ErrorEvents(id, namespace string, now time.Time, value int) error {
+	return p.incrementEventsCount(id, namespace, "errors", now, value)
+}
+
+//SkipEvents increments skipp events counter
+func (p *Postgres) SkipEvents(id, namespace string, now time.Time, value int) error {
+	return p.incrementEventsCount(id, namespace, "skip", now, value)
+}
+
+//FilterEvents increments filtered events counter
+func (p *Postgres) FilterEvents(id, namespace string, now time.Time, value int) error {
+	return p.incrementEventsCount(id, namespace, "filter", now, value)
+}
+
+//GetProjectEventsWithGranularity returns project's sources success events amount with time criteria by granularity
+func (p *Postgres) GetProjectEventsWithGranularity(projectID string, start, end time.Time, granularity Granularity) ([]EventsPerTime, error) {
+	if granularity != HOUR && granularity != DAY {
+		return nil, fmt.Errorf("Unknown granularity: %s", granularity.String())
+	}
+
+	rows, err := p.dataSource.Query(p.query(`SELECT date_trunc($1, c.event_hour) AS chunk, SUM(c.value) FROM %[1]s.jitsu_events_counters c
+		JOIN %[1]s.jitsu_events_index i ON i.namespace = c.namespace AND i.id = c.id
+		WHERE i.namespace = $2 AND i.project_id = $3 AND c.status = 'success'
+		AND date_trunc($1, c.event_hour) >= date_trunc($1, $4::timestamp) AND date_trunc($1, c.event_hour) < $5
+		GROUP BY chunk ORDER BY chunk`),
+		granularity.String(), SourceNamespace, projectID, start.UTC(), end.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	eventsPerTime := []EventsPerTime{}
+	for rows.Next() {
+		var chunk time.Time
+		var events int
+		if err := rows.Scan(&chunk, &events); err != nil {
+			return nil, err
+		}
+
+		eventsPerTime = append(eventsPerTime, EventsPerTime{Key: chunk.UTC().Format(responseTimestampLayout), Events: events})
+	}
+
+	return eventsPerTime, rows.Err()
+}
+
+//AddEvent saves event JSON string into Postgres
+//returns destination's cached events count
+func (p *Postgres) AddEvent(destinationID, eventID, payload string, now time.Time) (int, error) {
+	_, err := p.dataSource.Exec(p.query(`INSERT INTO %s.jitsu_last_events (destination_id, event_id, original, created_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (destination_id, event_id) DO UPDATE SET original = EXCLUDED.original, created_at = EXCLUDED.created_at`),
+		destinationID, eventID, payload, toSeconds(now))
+	if err != nil {
+		return 0, err
+	}
+
+	return p.GetTotalEvents(destinationID)
+}
+
+//UpdateSucceedEvent updates event record in Postgres with success field = JSON of succeed event (if the record exists)
+func (p *Postgres) UpdateSucceedEvent(destinationID, eventID, success string) error {
+	_, err := p.dataSource.Exec(p.query(`UPDATE %s.jitsu_last_events SET success = $3, error = '' WHERE destination_id = $1 AND event_id = $2`),
+		destinationID, eventID, success)
+	return err
+}
+
+//UpdateErrorEvent updates event record in Postgres with error field = error string (if the record exists)
+func (p *Postgres) UpdateErrorEvent(destinationID, eventID, error string) error {
+	_, err := p.dataSource.Exec(p.query(`UPDATE %s.jitsu_last_events SET error = $3 WHERE destination_id = $1 AND event_id = $2`),
+		destinationID, eventID, error)
+	return err
+}
+
+//RemoveLastEvent removes the oldest destination's cached event
+func (p *Postgres) RemoveLastEvent(destinationID string) error {
+	result, err := p.dataSource.Exec(p.query(`DELETE FROM %[1]s.jitsu_last_events WHERE destination_id = $1 AND event_id = (
+		SELECT event_id FROM %[1]s.jitsu_last_events WHERE destination_id = $1 ORDER BY created_at, event_id LIMIT 1)`),
+		destinationID)
+	if err != nil {
+		return err
+	}
+
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		return fmt.Errorf("Destination [%s] doesn't have cached events", destinationID)
+	}
+
+	return nil
+}
+
+//GetEvents returns destination's last events with time criteria
+func (p *Postgres) GetEvents(destinationID string, start, end time.Time, n int) ([]Event, error) {
+	rows, err := p.dataSource.Query(p.query(`SELECT original, success, error FROM %s.jitsu_last_events
+		WHERE destination_id = $1 AND created_at >= $2 AND created_at <= $3 ORDER BY created_at, event_id LIMIT $4`),
+		destinationID, toSeconds(start), toSeconds(end), n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []Event{}
+	for rows.Next() {
+		event := Event{}
+		if err := rows.Scan(&event.Original, &event.Success, &event.Error); err != nil {
+			return nil, fmt.Errorf("Error deserializing destination [%s] event: %v", destinationID, err)
+		}
+
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+//GetTotalEvents returns total of cached events
+func (p *Postgres) GetTotalEvents(destinationID string) (int, error) {
+	var count int
+	err := p.dataSource.QueryRow(p.query(`SELECT count(*) FROM %s.jitsu_last_events WHERE destination_id = $1`), destinationID).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+//SaveAnonymousEvent saves event JSON by destination ID and user anonymous ID key
+//TTL (if configured) is prolonged for all events with the anonymous ID (like Redis EXPIRE on the whole hashtable)
+func (p *Postgres) SaveAnonymousEvent(destinationID, anonymousID, eventID, payload string) error {
+	_, err := p.dataSource.Exec(p.query(`INSERT INTO %s.jitsu_anonymous_events (destination_id, anonymous_id, event_id, payload) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (destination_id, anonymous_id, event_id) DO UPDATE SET payload = EXCLUDED.payload`),
+		destinationID, anonymousID, eventID, payload)
+	if err != nil {
+		return err
+	}
+
+	if p.anonymousEventsSecondsTTL > 0 {
+		expiresAt := time.Now().UTC().Add(time.Duration(p.anonymousEventsSecondsTTL) * time.Second)
+		_, err := p.dataSource.Exec(p.query(`UPDATE %s.jitsu_anonymous_events SET expires_at = $3 WHERE destination_id = $1 AND anonymous_id = $2`),
+			destinationID, anonymousID, expiresAt)
+		if err != nil {
+			logging.SystemErrorf("Error updating expiration of anonymous event destination: %s anonymous id: %s event id: %s: %v", destinationID, anonymousID, eventID, err)
+		}
+	}
+
+	return nil
+}
+
+//GetAnonymousEvents returns events JSON per event ID map (without expired events)
+func (p *Postgres) GetAnonymousEvents(destinationID, anonymousID string) (map[string]string, error) {
+	rows, err := p.dataSource.Query(p.query(`SELECT event_id, payload FROM %s.jitsu_anonymous_events
+		WHERE destination_id = $1 AND anonymous_id = $2 AND (expires_at IS NULL OR expires_at > $3)`),
+		destinationID, anonymousID, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	eventsMap := map[string]string{}
+	for rows.Next() {
+		var eventID, payload string
+		if err := rows.Scan(&eventID, &payload); err != nil {
+			return nil, err
+		}
+
+		eventsMap[eventID] = payload
+	}
+
+	return eventsMap, rows.Err()
+}
+
+//DeleteAnonymousEvent deletes event with eventID
+func (p *Postgres) DeleteAnonymousEvent(destinationID, anonymousID, eventID string) error {
+	_, err := p.dataSource.Exec(p.query(`DELETE FROM %s.jitsu_anonymous_events WHERE destination_id = $1 AND anonymous_id = $2 AND event_id = $3`),
+		destinationID, anonymousID, eventID)
+	return err
+}
+
+//CreateTask saves task into Postgres and add Task ID in index
+func (p *Postgres) CreateTask(sourceID, collection string, task *Task, createdAt time.Time) error {
+	err := p.UpsertTask(task)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.dataSource.Exec(p.query(`INSERT INTO %s.jitsu_sync_tasks_index (source_id, collection, task_id, created_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (source_id, collection, task_id) DO UPDATE SET created_at = EXCLUDED.created_at`),
+		sourceID, collection, task.ID, toSeconds(createdAt))
+	if err != nil {
+		logging.SystemErrorf("Task [%s] was saved but failed to save in index: %v", task.ID, err)
+		return err
+	}
+
+	return nil
+}
+
+//UpsertTask overwrite task in Postgres (save or update)
+func (p *Postgres) UpsertTask(task *Task) error {
+	_, err := p.dataSource.Exec(p.query(`INSERT INTO %s.jitsu_sync_tasks (id, source, collection, priority, created_at, started_at, finished_at, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (id) DO UPDATE SET source = EXCLUDED.source, collection = EXCLUDED.collection, priority = EXCLUDED.priority,
+		created_at = EXCLUDED.created_at, started_at = EXCLUDED.started_at, finished_at = EXCLUDED.finished_at, status = EXCLUDED.status`),
+		task.ID, task.Source, task.Collection, task.Priority, task.CreatedAt, task.StartedAt, task.FinishedAt, task.Status)
+	return err
+}
+
+//GetAllTasks returns all source's tasks by collection and time criteria
+func (p *Postgres) GetAllTasks(sourceID, collection string, start, end time.Time, limit int) ([]Task, error) {
+	query := p.query(`SELECT t.id, t.source, t.collection, t.priority, t.created_at, t.started_at, t.finished_at, t.status
+		FROM %[1]s.jitsu_sync_tasks_index i JOIN %[1]s.jitsu_sync_tasks t ON t.id = i.task_id
+		WHERE i.source_id = $1 AND i.collection = $2 AND i.created_at >= $3 AND i.created_at <= $4 ORDER BY i.created_at, i.task_id`)
+	args := []interface{}{sourceID, collection, toSeconds(start), toSeconds(end)}
+	if limit > 0 {
+		query += " LIMIT $5"
+		args = append(args, limit)
+	}
+
+	rows, err := p.dataSource.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []Task
+	for rows.Next() {
+		task := Task{}
+		if err := scanTask(rows, &task); err != nil {
+			return nil, fmt.Errorf("Error deserializing task: %v", err)
+		}
+
+		tasks = append(tasks, task)
+	}
+
+	return tasks, rows.Err()
+}
+
+//GetLastTask returns last sync task
+func (p *Postgres) GetLastTask(sourceID, collection string) (*Task, error) {
+	var taskID string
+	err := p.dataSource.QueryRow(p.query(`SELECT task_id FROM %s.jitsu_sync_tasks_index WHERE source_id = $1 AND collection = $2
+		ORDER BY created_at DESC, task_id DESC LIMIT 1`), sourceID, collection).Scan(&taskID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTaskNotFound
+		}
+
+		return nil, err
+	}
+
+	task, err := p.GetTask(taskID)
+	if err != nil {
+		if err == ErrTaskNotFound {
+			logging.SystemErrorf("Task with id: %s exists in tasks index but doesn't exist in tasks table", taskID)
+		}
+
+		return nil, err
+	}
+
+	return task, nil
+}
+
+//GetTask returns task by task ID or ErrTaskNotFound
+func (p *Postgres) GetTask(taskID string) (*Task, error) {
+	task := &Task{}
+	row := p.dataSource.QueryRow(p.query(`SELECT id, source, collection, priority, created_at, started_at, finished_at, status
+		FROM %s.jitsu_sync_tasks WHERE id = $1`), taskID)
+	if err := scanTask(row, task); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTaskNotFound
+		}
+
+		return nil, fmt.Errorf("Error deserializing task entity [%s]: %v", taskID, err)
+	}
+
+	return task, nil
+}
+
+//AppendTaskLog appends log record into task logs table
+func (p *Postgres) AppendTaskLog(taskID string, now time.Time, message, level string) error {
+	_, err := p.dataSource.Exec(p.query(`INSERT INTO %s.jitsu_sync_task_logs (task_id, logged_at, log_time, message, level) VALUES ($1, $2, $3, $4, $5)`),
+		taskID, toSeconds(now), now.Format(timestamp.Layout), message, level)
+	return err
+}
+
+//GetTaskLogs returns task logs with time criteria
+func (p *Postgres) GetTaskLogs(taskID string, start, end time.Time) ([]TaskLogRecord, error) {
+	rows, err := p.dataSource.Query(p.query(`SELECT log_time, message, level FROM %s.jitsu_sync_task_logs
+		WHERE task_id = $1 AND logged_at >= $2 AND logged_at <= $3 ORDER BY logged_at, id`),
+		taskID, toSeconds(start), toSeconds(end))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var taskLogs []TaskLogRecord
+	for rows.Next() {
+		tlr := TaskLogRecord{}
+		if err := rows.Scan(&tlr.Time, &tlr.Message, &tlr.Level); err != nil {
+			return nil, fmt.Errorf("Error deserializing task [%s] log record: %v", taskID, err)
+		}
+
+		taskLogs = append(taskLogs, tlr)
+	}
+
+	return taskLogs, rows.Err()
+}
+
+//PushTask saves task into priority queue
+func (p *Postgres) PushTask(task *Task) error {
+	_, err := p.dataSource.Exec(p.query(`INSERT INTO %s.jitsu_sync_tasks_priority_queue (task_id, priority) VALUES ($1, $2)
+		ON CONFLICT (task_id) DO UPDATE SET priority = EXCLUDED.priority`), task.ID, task.Priority)
+	return err
+}
+
+//PollTask return task with max priority from the Queue or nil if the queue is empty
+//concurrent pollers don't get the same task (SKIP LOCKED)
+func (p *Postgres) PollTask() (*Task, error) {
+	var taskID string
+	err := p.dataSource.QueryRow(p.query(`DELETE FROM %[1]s.jitsu_sync_tasks_priority_queue WHERE task_id = (
+		SELECT task_id FROM %[1]s.jitsu_sync_tasks_priority_queue ORDER BY priority DESC, task_id DESC LIMIT 1 FOR UPDATE SKIP LOCKED)
+		RETURNING task_id`)).Scan(&taskID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	task, err := p.GetTask(taskID)
+	if err != nil && err == ErrTaskNotFound {
+		logging.SystemErrorf("Task with id: %s exists in priority queue but doesn't exist in tasks table", taskID)
+	}
+
+	return task, err
+}
+
+//IsTaskInQueue returns task ID and true if a task is already in queue
+func (p *Postgres) IsTaskInQueue(sourceID, collection string) (string, bool, error) {
+	prefix := fmt.Sprintf("%s_%s_", sourceID, collection)
+	var taskID string
+	err := p.dataSource.QueryRow(p.query(`SELECT task_id FROM %s.jitsu_sync_tasks_priority_queue WHERE left(task_id, length($1)) = $1 LIMIT 1`),
+		prefix).Scan(&taskID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", false, nil
+		}
+
+		return "", false, err
+	}
+
+	return taskID, true, nil
+}
+
+func (p *Postgres) Type() string {
+	return PostgresType
+}
+
+func (p *Postgres) Close() error {
+	p.closed = true
+	return p.dataSource.Close()
+}
+
+//ensureIDInIndex add id to corresponding index by projectID
+//namespaces: [destination, source]
+func (p *Postgres) ensureIDInIndex(id, namespace string) error {
+	if namespace != DestinationNamespace && namespace != SourceNamespace {
+		return fmt.Errorf("Unknown namespace: %v", namespace)
+	}
+
+	//get projectID from id or empty
+	var projectID string
+	splitted := strings.Split(id, ".")
+	if len(splitted) > 1 {
+		projectID = splitted[0]
+	}
+
+	_, err := p.dataSource.Exec(p.query(`INSERT INTO %s.jitsu_events_index (namespace, project_id, id) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`),
+		namespace, projectID, id)
+	return err
+}
+
+//incrementEventsCount increment events counter in the hour bucket
+//namespaces: [destination, source]
+//status: [success, error, skip, filter]
+func (p *Postgres) incrementEventsCount(id, namespace, status string, now time.Time, value int) error {
+	_, err := p.dataSource.Exec(p.query(`INSERT INTO %s.jitsu_events_counters (namespace, id, status, event_hour, value) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (namespace, id, status, event_hour) DO UPDATE SET value = jitsu_events_counters.value + EXCLUDED.value`),
+		namespace, id, status, now.UTC().Truncate(time.Hour), value)
+	return err
+}
+
+//startAnonymousEventsCleaner runs goroutine for deleting expired anonymous events
+func (p *Postgres) startAnonymousEventsCleaner() {
+	safego.RunWithRestart(func() {
+		ticker := time.NewTicker(anonymousEventsCleanupInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if p.closed {
+				break
+			}
+
+			_, err := p.dataSource.Exec(p.query(`DELETE FROM %s.jitsu_anonymous_events WHERE expires_at IS NOT NULL AND expires_at <= $1`),
+				time.Now().UTC())
+			if err != nil {
+				logging.SystemErrorf("Error deleting expired anonymous events: %v", err)
+			}
+		}
+	})
+}
+
+//query returns query with the meta storage schema
+func (p *Postgres) query(query string) string {
+	return fmt.Sprintf(query, p.schema)
+}
+
+//rowScanner is an interface of sql.Row and sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTask(row rowScanner, task *Task) error {
+	return row.Scan(&task.ID, &task.Source, &task.Collection, &task.Priority, &task.CreatedAt, &task.StartedAt, &task.FinishedAt, &task.Status)
+}
+
+//toSeconds returns UTC time truncated to seconds for comparison like Unix timestamps in Redis
+func toSeconds(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Second)
+}
@@ -42,9 +42,11 @@ type Redis struct {
 //hourly_events:destination#destinationID:day#yyyymmdd:success [hour] - hashtable with success events counter by hour
 //hourly_events:destination#destinationID:day#yyyymmdd:errors  [hour] - hashtable with error events counter by hour
 //hourly_events:destination#destinationID:day#yyyymmdd:skip    [hour] - hashtable with skipped events counter by hour
+//hourly_events:destination#destinationID:day#yyyymmdd:filter  [hour] - hashtable with filtered events counter by hour
 //daily_events:destination#destinationID:month#yyyymm:success  [day] - hashtable with success events counter by day
 //daily_events:destination#destinationID:month#yyyymm:errors   [day] - hashtable with error events counter by day
 //daily_events:destination#destinationID:month#yyyymm:skip     [day] - hashtable with skipped events counter by day
+//daily_events:destination#destinationID:month#yyyymm:filter   [day] - hashtable with filtered events counter by day
 //
 // * per source *
 //sources_index:project#projectID [sourceID1, sourceID2] - set of source ids
@@ -149,6 +151,53 @@ func (r *Redis) SaveSignature(sourceID, collection, interval, signature string)
 	return nil
 }
 
+//GetSignatures returns all sync interval - signature pairs of the collection from Redis
+func (r *Redis) GetSignatures(sourceID, collection string) (map[string]string, error) {
+	key := "source#" + sourceID + ":collection#" + collection + ":chunks"
+	connection := r.pool.Get()
+	defer connection.Close()
+	signatures, err := redis.StringMap(connection.Do("HGETALL", key))
+	noticeError(err)
+	if err != nil && err != redis.ErrNil {
+		return nil, err
+	}
+
+	if signatures == nil {
+		signatures = map[string]string{}
+	}
+
+	return signatures, nil
+}
+
+//DeleteSignature deletes sync interval signature from Redis
+func (r *Redis) DeleteSignature(sourceID, collection, interval string) error {
+	key := "source#" + sourceID + ":collection#" + collection + ":chunks"
+	field := interval
+	connection := r.pool.Get()
+	defer connection.Close()
+	_, err := connection.Do("HDEL", key, field)
+	noticeError(err)
+	if err != nil && err != redis.ErrNil {
+		return err
+	}
+
+	return nil
+}
+
+//DeleteSignatures deletes all sync interval signatures of the collection from Redis
+func (r *Redis) DeleteSignatures(sourceID, collection string) error {
+	key := "source#" + sourceID + ":collection#" + collection + ":chunks"
+	connection := r.pool.Get()
+	defer connection.Close()
+	_, err := connection.Do("DEL", key)
+	noticeError(err)
+	if err != nil && err != redis.ErrNil {
+		return err
+	}
+
+	return nil
+}
+
 //SuccessEvents ensures that id is in the index and increments success events counter
 func (r *Redis) SuccessEvents(id, namespace string, now time.Time, value int) error {
 	err := r.ensureIDInIndex(id, namespace)
@@ -168,6 +217,11 @@ func (r *Redis) SkipEvents(id, namespace string, now time.Time, value int) error
 	return r.incrementEventsCount(id, namespace, "skip", now, value)
 }
 
+//FilterEvents increments filtered events counter
+func (r *Redis) FilterEvents(id, namespace string, now time.Time, value int) error {
+	return r.incrementEventsCount(id, namespace, "filter", now, value)
+}
+
 //AddEvent saves event JSON string into Redis and ensures that event ID is in index by destination ID
 //returns index length
 func (r *Redis) AddEvent(destinationID, eventID, payload string, now time.Time) (int, error) {
@@ -800,7 +854,7 @@ func (r *Redis) ensureIDInIndex(id, namespace string) error {
 
 //incrementEventsCount increment events counter
 //namespaces: [destination, source]
-//status: [success, error, skip]
+//status: [success, error, skip, filter]
 func (r *Redis) incrementEventsCount(id, namespace, status string, now time.Time, value int) error {
 	conn := r.pool.Get()
 	defer conn.Close()
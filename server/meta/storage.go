@@ -1,14 +1,17 @@
 package meta
 
 import (
+	"fmt"
+	"github.com/jitsucom/jitsu/server/adapters"
 	"github.com/spf13/viper"
 	"io"
 	"time"
 )
 
 const (
-	DummyType = "Dummy"
-	RedisType = "Redis"
+	DummyType    = "Dummy"
+	RedisType    = "Redis"
+	PostgresType = "Postgres"
 
 	DayGranularity  = "day"
 	HourGranularity = "hour"
@@ -21,12 +24,16 @@ type Storage interface {
 	//signatures
 	GetSignature(sourceID, collection, interval string) (string, error)
 	SaveSignature(sourceID, collection, interval, signature string) error
+	GetSignatures(sourceID, collection string) (map[string]string, error)
+	DeleteSignature(sourceID, collection, interval string) error
+	DeleteSignatures(sourceID, collection string) error
 
 	//** Counters **
 	//events counters
 	SuccessEvents(id, namespace string, now time.Time, value int) error
 	ErrorEvents(id, namespace string, now time.Time, value int) error
 	SkipEvents(id, namespace string, now time.Time, value int) error
+	FilterEvents(id, namespace string, now time.Time, value int) error
 	GetProjectEventsWithGranularity(projectID string, start, end time.Time, granularity Granularity) ([]EventsPerTime, error)
 
 	//** Cache **
@@ -63,11 +70,26 @@ type Storage interface {
 	Type() string
 }
 
+//NewStorage returns Postgres storage if meta.storage.postgres is configured
+//Redis storage if meta.storage.redis is configured
+//or Dummy storage if meta.storage isn't configured
 func NewStorage(meta *viper.Viper) (Storage, error) {
 	if meta == nil {
 		return &Dummy{}, nil
 	}
 
+	if meta.IsSet("postgres") {
+		config := &adapters.DataSourceConfig{}
+		if err := meta.UnmarshalKey("postgres", config); err != nil {
+			return nil, fmt.Errorf("Error parsing meta.storage.postgres configuration: %v", err)
+		}
+		if err := config.Validate(); err != nil {
+			return nil, fmt.Errorf("Error validating meta.storage.postgres configuration: %v", err)
+		}
+
+		return NewPostgres(config, meta.GetInt("postgres.ttl_minutes.anonymous_events"))
+	}
+
 	host := meta.GetString("redis.host")
 	port := meta.GetInt("redis.port")
 	password := meta.GetString("redis.password")
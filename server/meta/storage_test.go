@@ -0,0 +1,358 @@
+package meta
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/test"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/jitsucom/jitsu/server/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisStorage(t *testing.T) {
+	ctx := context.Background()
+	container, err := test.NewRedisContainer(ctx)
+	if err != nil {
+		t.Fatalf("failed to initialize container: %v", err)
+	}
+	defer container.Close()
+
+	storage, err := NewRedis(container.Host, container.Port, "", 0)
+	require.NoError(t, err)
+	defer storage.Close()
+
+	testStorage(t, storage)
+}
+
+func TestPostgresStorage(t *testing.T) {
+	ctx := context.Background()
+	container, err := test.NewPostgresContainer(ctx)
+	if err != nil {
+		t.Fatalf("failed to initialize container: %v", err)
+	}
+	defer container.Close()
+
+	storage, err := NewPostgres(&adapters.DataSourceConfig{
+		Host:       container.Host,
+		Port:       json.Number(strconv.Itoa(container.Port)),
+		Db:         container.Database,
+		Schema:     "jitsu_meta",
+		Username:   container.Username,
+		Password:   container.Password,
+		Parameters: map[string]string{"sslmode": "disable"},
+	}, 0)
+	require.NoError(t, err)
+	defer storage.Close()
+
+	testStorage(t, storage)
+}
+
+//testStorage is a conformance test suite which every Storage implementation must pass
+//all identifiers are unique per run because containers might be reused in CI
+func testStorage(t *testing.T, storage Storage) {
+	t.Run("Signatures", func(t *testing.T) { testSignatures(t, storage) })
+	t.Run("Counters", func(t *testing.T) { testCounters(t, storage) })
+	t.Run("EventsCache", func(t *testing.T) { testEventsCache(t, storage) })
+	t.Run("AnonymousEvents", func(t *testing.T) { testAnonymousEvents(t, storage) })
+	t.Run("Tasks", func(t *testing.T) { testTasks(t, storage) })
+	t.Run("TaskLogs", func(t *testing.T) { testTaskLogs(t, storage) })
+	t.Run("TaskQueue", func(t *testing.T) { testTaskQueue(t, storage) })
+}
+
+func testSignatures(t *testing.T, storage Storage) {
+	sourceID := "source_" + uuid.New()
+
+	signature, err := storage.GetSignature(sourceID, "collection", "2021-03")
+	require.NoError(t, err)
+	require.Equal(t, "", signature)
+
+	require.NoError(t, storage.SaveSignature(sourceID, "collection", "2021-03", "signature1"))
+	require.NoError(t, storage.SaveSignature(sourceID, "collection", "2021-04", "signature2"))
+	require.NoError(t, storage.SaveSignature(sourceID, "collection", "2021-03", "signature3"))
+
+	signature, err = storage.GetSignature(sourceID, "collection", "2021-03")
+	require.NoError(t, err)
+	require.Equal(t, "signature3", signature)
+
+	signature, err = storage.GetSignature(sourceID, "collection", "2021-04")
+	require.NoError(t, err)
+	require.Equal(t, "signature2", signature)
+
+	signature, err = storage.GetSignature(sourceID, "another_collection", "2021-03")
+	require.NoError(t, err)
+	require.Equal(t, "", signature)
+
+	signatures, err := storage.GetSignatures(sourceID, "collection")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"2021-03": "signature3", "2021-04": "signature2"}, signatures)
+
+	require.NoError(t, storage.DeleteSignature(sourceID, "collection", "2021-03"))
+	signatures, err = storage.GetSignatures(sourceID, "collection")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"2021-04": "signature2"}, signatures)
+
+	require.NoError(t, storage.SaveSignature(sourceID, "another_collection", "2021-03", "signature4"))
+	require.NoError(t, storage.DeleteSignatures(sourceID, "collection"))
+	signatures, err = storage.GetSignatures(sourceID, "collection")
+	require.NoError(t, err)
+	require.Empty(t, signatures)
+
+	signature, err = storage.GetSignature(sourceID, "another_collection", "2021-03")
+	require.NoError(t, err)
+	require.Equal(t, "signature4", signature)
+}
+
+func testCounters(t *testing.T, storage Storage) {
+	projectID := "project" + uuid.New()
+	anotherProjectID := "project" + uuid.New()
+	source1 := projectID + ".source1"
+	source2 := projectID + ".source2"
+	hour := func(h, m int) time.Time { return time.Date(2021, 3, 17, h, m, 0, 0, time.UTC) }
+
+	require.NoError(t, storage.SuccessEvents(source1, SourceNamespace, hour(9, 59), 1))
+	require.NoError(t, storage.SuccessEvents(source1, SourceNamespace, hour(10, 5), 5))
+	require.NoError(t, storage.SuccessEvents(source2, SourceNamespace, hour(10, 40), 3))
+	require.NoError(t, storage.SuccessEvents(source1, SourceNamespace, hour(12, 59), 2))
+	require.NoError(t, storage.SuccessEvents(source1, SourceNamespace, hour(13, 0), 100))
+	require.NoError(t, storage.SuccessEvents(source1, SourceNamespace, time.Date(2021, 3, 18, 1, 0, 0, 0, time.UTC), 7))
+	//aren't counted: another project, destination namespace, not success statuses
+	require.NoError(t, storage.SuccessEvents(anotherProjectID+".source3", SourceNamespace, hour(11, 0), 1000))
+	require.NoError(t, storage.SuccessEvents(projectID+".destination1", DestinationNamespace, hour(11, 0), 1000))
+	require.NoError(t, storage.ErrorEvents(source1, SourceNamespace, hour(11, 0), 1000))
+	require.NoError(t, storage.SkipEvents(source1, SourceNamespace, hour(11, 0), 1000))
+	require.NoError(t, storage.FilterEvents(source1, SourceNamespace, hour(11, 0), 1000))
+
+	require.Error(t, storage.SuccessEvents(source1, "unknown", hour(11, 0), 1))
+
+	perHour, err := storage.GetProjectEventsWithGranularity(projectID, hour(10, 30), hour(13, 0), HOUR)
+	require.NoError(t, err)
+	require.Equal(t, []EventsPerTime{
+		{Key: "2021-03-17T10:00:00+0000", Events: 8},
+		{Key: "2021-03-17T12:00:00+0000", Events: 2},
+	}, perHour)
+
+	perDay, err := storage.GetProjectEventsWithGranularity(projectID, time.Date(2021, 3, 16, 0, 0, 0, 0, time.UTC), time.Date(2021, 3, 18, 0, 0, 0, 0, time.UTC), DAY)
+	require.NoError(t, err)
+	require.Equal(t, []EventsPerTime{{Key: "2021-03-17T00:00:00+0000", Events: 111}}, perDay)
+
+	empty, err := storage.GetProjectEventsWithGranularity("project"+uuid.New(), hour(0, 0), hour(23, 0), HOUR)
+	require.NoError(t, err)
+	require.Empty(t, empty)
+
+	_, err = storage.GetProjectEventsWithGranularity(projectID, hour(0, 0), hour(23, 0), UNKNOWN)
+	require.Error(t, err)
+}
+
+func testEventsCache(t *testing.T, storage Storage) {
+	destinationID := "destination_" + uuid.New()
+	start := time.Date(2021, 3, 17, 10, 0, 0, 0, time.UTC)
+
+	for i := 1; i <= 3; i++ {
+		count, err := storage.AddEvent(destinationID, fmt.Sprintf("event%d", i), fmt.Sprintf(`{"id":%d}`, i), start.Add(time.Duration(i-1)*time.Second))
+		require.NoError(t, err)
+		require.Equal(t, i, count)
+	}
+
+	require.NoError(t, storage.UpdateSucceedEvent(destinationID, "event1", `{"success":1}`))
+	require.NoError(t, storage.UpdateErrorEvent(destinationID, "event2", "error2"))
+	require.NoError(t, storage.UpdateErrorEvent(destinationID, "event3", "error3"))
+	require.NoError(t, storage.UpdateSucceedEvent(destinationID, "event3", `{"success":3}`))
+	//events which aren't in cache aren't created
+	require.NoError(t, storage.UpdateSucceedEvent(destinationID, "unknown1", `{"success":0}`))
+	require.NoError(t, storage.UpdateErrorEvent(destinationID, "unknown2", "error"))
+
+	total, err := storage.GetTotalEvents(destinationID)
+	require.NoError(t, err)
+	require.Equal(t, 3, total)
+
+	events, err := storage.GetEvents(destinationID, start, start.Add(time.Hour), 100)
+	require.NoError(t, err)
+	require.Equal(t, []Event{
+		{Original: `{"id":1}`, Success: `{"success":1}`},
+		{Original: `{"id":2}`, Error: "error2"},
+		{Original: `{"id":3}`, Success: `{"success":3}`},
+	}, events)
+
+	events, err = storage.GetEvents(destinationID, start, start.Add(time.Second), 100)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	events, err = storage.GetEvents(destinationID, start, start.Add(time.Hour), 1)
+	require.NoError(t, err)
+	require.Equal(t, []Event{{Original: `{"id":1}`, Success: `{"success":1}`}}, events)
+
+	require.NoError(t, storage.RemoveLastEvent(destinationID))
+
+	total, err = storage.GetTotalEvents(destinationID)
+	require.NoError(t, err)
+	require.Equal(t, 2, total)
+
+	events, err = storage.GetEvents(destinationID, start, start.Add(time.Hour), 100)
+	require.NoError(t, err)
+	require.Equal(t, []Event{
+		{Original: `{"id":2}`, Error: "error2"},
+		{Original: `{"id":3}`, Success: `{"success":3}`},
+	}, events)
+
+	total, err = storage.GetTotalEvents("destination_" + uuid.New())
+	require.NoError(t, err)
+	require.Equal(t, 0, total)
+}
+
+func testAnonymousEvents(t *testing.T, storage Storage) {
+	destinationID := "destination_" + uuid.New()
+	anonymousID := uuid.New()
+
+	require.NoError(t, storage.SaveAnonymousEvent(destinationID, anonymousID, "event1", `{"id":1}`))
+	require.NoError(t, storage.SaveAnonymousEvent(destinationID, anonymousID, "event2", `{"id":2}`))
+	require.NoError(t, storage.SaveAnonymousEvent(destinationID, uuid.New(), "event3", `{"id":3}`))
+
+	events, err := storage.GetAnonymousEvents(destinationID, anonymousID)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"event1": `{"id":1}`, "event2": `{"id":2}`}, events)
+
+	require.NoError(t, storage.DeleteAnonymousEvent(destinationID, anonymousID, "event1"))
+
+	events, err = storage.GetAnonymousEvents(destinationID, anonymousID)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"event2": `{"id":2}`}, events)
+
+	events, err = storage.GetAnonymousEvents(destinationID, uuid.New())
+	require.NoError(t, err)
+	require.Empty(t, events)
+}
+
+func testTasks(t *testing.T, storage Storage) {
+	sourceID := "source" + uuid.New()
+	createdAt := time.Date(2021, 3, 17, 10, 0, 0, 0, time.UTC)
+	task1 := newTestTask(sourceID, "collection", 1, createdAt)
+	task2 := newTestTask(sourceID, "collection", 1, createdAt.Add(time.Second))
+
+	_, err := storage.GetLastTask(sourceID, "collection")
+	require.Equal(t, ErrTaskNotFound, err)
+
+	require.NoError(t, storage.CreateTask(sourceID, "collection", task1, createdAt))
+	require.NoError(t, storage.CreateTask(sourceID, "collection", task2, createdAt.Add(time.Second)))
+
+	actual, err := storage.GetTask(task1.ID)
+	require.NoError(t, err)
+	require.Equal(t, task1, actual)
+
+	_, err = storage.GetTask("unknown" + uuid.New())
+	require.Equal(t, ErrTaskNotFound, err)
+
+	actual, err = storage.GetLastTask(sourceID, "collection")
+	require.NoError(t, err)
+	require.Equal(t, task2, actual)
+
+	task1.Status = "RUNNING"
+	task1.StartedAt = createdAt.Add(time.Minute).Format(timestamp.Layout)
+	require.NoError(t, storage.UpsertTask(task1))
+
+	actual, err = storage.GetTask(task1.ID)
+	require.NoError(t, err)
+	require.Equal(t, task1, actual)
+
+	tasks, err := storage.GetAllTasks(sourceID, "collection", createdAt, createdAt.Add(time.Hour), 0)
+	require.NoError(t, err)
+	require.Equal(t, []Task{*task1, *task2}, tasks)
+
+	tasks, err = storage.GetAllTasks(sourceID, "collection", createdAt, createdAt.Add(time.Hour), 1)
+	require.NoError(t, err)
+	require.Equal(t, []Task{*task1}, tasks)
+
+	tasks, err = storage.GetAllTasks(sourceID, "collection", createdAt.Add(time.Second), createdAt.Add(time.Hour), 0)
+	require.NoError(t, err)
+	require.Equal(t, []Task{*task2}, tasks)
+
+	tasks, err = storage.GetAllTasks(sourceID, "another_collection", createdAt, createdAt.Add(time.Hour), 0)
+	require.NoError(t, err)
+	require.Empty(t, tasks)
+}
+
+func testTaskLogs(t *testing.T, storage Storage) {
+	taskID := "task" + uuid.New()
+	now := time.Date(2021, 3, 17, 10, 0, 0, 0, time.UTC)
+
+	require.NoError(t, storage.AppendTaskLog(taskID, now, "message1", "info"))
+	require.NoError(t, storage.AppendTaskLog(taskID, now.Add(time.Second), "message2", "error"))
+
+	logs, err := storage.GetTaskLogs(taskID, now, now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, []TaskLogRecord{
+		{Time: now.Format(timestamp.Layout), Message: "message1", Level: "info"},
+		{Time: now.Add(time.Second).Format(timestamp.Layout), Message: "message2", Level: "error"},
+	}, logs)
+
+	logs, err = storage.GetTaskLogs(taskID, now, now)
+	require.NoError(t, err)
+	require.Equal(t, []TaskLogRecord{{Time: now.Format(timestamp.Layout), Message: "message1", Level: "info"}}, logs)
+
+	logs, err = storage.GetTaskLogs("task"+uuid.New(), now, now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Empty(t, logs)
+}
+
+func testTaskQueue(t *testing.T, storage Storage) {
+	//queue is global: drain tasks from previous runs
+	for {
+		task, err := storage.PollTask()
+		if err != ErrTaskNotFound {
+			require.NoError(t, err)
+		}
+		if task == nil && err == nil {
+			break
+		}
+	}
+
+	sourceID := "source" + uuid.New()
+	createdAt := time.Date(2021, 3, 17, 10, 0, 0, 0, time.UTC)
+	lowPriority := newTestTask(sourceID, "collection", 1, createdAt)
+	highPriority := newTestTask(sourceID, "another_collection", 10, createdAt)
+	for _, task := range []*Task{lowPriority, highPriority} {
+		require.NoError(t, storage.UpsertTask(task))
+		require.NoError(t, storage.PushTask(task))
+	}
+
+	taskID, exists, err := storage.IsTaskInQueue(sourceID, "collection")
+	require.NoError(t, err)
+	require.True(t, exists)
+	require.Equal(t, lowPriority.ID, taskID)
+
+	_, exists, err = storage.IsTaskInQueue(sourceID, "unknown")
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	task, err := storage.PollTask()
+	require.NoError(t, err)
+	require.Equal(t, highPriority, task)
+
+	task, err = storage.PollTask()
+	require.NoError(t, err)
+	require.Equal(t, lowPriority, task)
+
+	task, err = storage.PollTask()
+	require.NoError(t, err)
+	require.Nil(t, task)
+
+	_, exists, err = storage.IsTaskInQueue(sourceID, "collection")
+	require.NoError(t, err)
+	require.False(t, exists)
+}
+
+func newTestTask(sourceID, collection string, priority int64, createdAt time.Time) *Task {
+	return &Task{
+		ID:         sourceID + "_" + collection + "_" + uuid.New(),
+		Source:     sourceID,
+		Collection: collection,
+		Priority:   priority,
+		CreatedAt:  createdAt.Format(timestamp.Layout),
+		Status:     "SCHEDULED",
+	}
+}
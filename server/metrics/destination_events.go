@@ -6,10 +6,13 @@ import (
 )
 
 var eventLabels = []string{"source_id", "project_id", "destination_id"}
+var destinationLabels = []string{"project_id", "destination_id"}
 
 var (
-	successEvents *prometheus.CounterVec
-	errorsEvents  *prometheus.CounterVec
+	successEvents  *prometheus.CounterVec
+	errorsEvents   *prometheus.CounterVec
+	skippedEvents  *prometheus.CounterVec
+	filteredEvents *prometheus.CounterVec
 )
 
 func initEvents() {
@@ -23,6 +26,16 @@ func initEvents() {
 		Subsystem: "destinations",
 		Name:      "errors",
 	}, eventLabels)
+	skippedEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "eventnative",
+		Subsystem: "destinations",
+		Name:      "skipped",
+	}, destinationLabels)
+	filteredEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "eventnative",
+		Subsystem: "destinations",
+		Name:      "filtered",
+	}, destinationLabels)
 }
 
 func SuccessTokenEvent(tokenID, destinationName string) {
@@ -62,3 +75,19 @@ func ErrorSourceEvents(sourceName, destinationName string, value int) {
 		errorsEvents.WithLabelValues("source_"+sourceID, projectID, destinationID).Add(float64(value))
 	}
 }
+
+//SkipEvents increments counter of events which were skipped by table name template or transform script
+func SkipEvents(destinationName string, value int) {
+	if Enabled {
+		projectID, destinationID := extractLabels(destinationName)
+		skippedEvents.WithLabelValues(projectID, destinationID).Add(float64(value))
+	}
+}
+
+//FilterEvents increments counter of events which were filtered out by destination filters
+func FilterEvents(destinationName string, value int) {
+	if Enabled {
+		projectID, destinationID := extractLabels(destinationName)
+		filteredEvents.WithLabelValues(projectID, destinationID).Add(float64(value))
+	}
+}
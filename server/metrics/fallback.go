@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var fallbackRetryLabels = []string{"project_id", "destination_id", "status"}
+
+var (
+	fallbackRetries          *prometheus.CounterVec
+	fallbackRetriesExhausted *prometheus.CounterVec
+	fallbackPendingRetries   *prometheus.GaugeVec
+)
+
+func initFallback() {
+	fallbackRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "eventnative",
+		Subsystem: "fallback",
+		Name:      "retries",
+	}, fallbackRetryLabels)
+	fallbackRetriesExhausted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "eventnative",
+		Subsystem: "fallback",
+		Name:      "retries_exhausted",
+	}, destinationLabels)
+	fallbackPendingRetries = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "eventnative",
+		Subsystem: "fallback",
+		Name:      "pending_retries",
+	}, destinationLabels)
+}
+
+//SuccessFallbackRetry increments counter of successful fallback files automatic retry attempts
+func SuccessFallbackRetry(destinationName string) {
+	if Enabled {
+		projectID, destinationID := extractLabels(destinationName)
+		fallbackRetries.WithLabelValues(projectID, destinationID, "success").Inc()
+	}
+}
+
+//ErrorFallbackRetry increments counter of failed fallback files automatic retry attempts
+func ErrorFallbackRetry(destinationName string) {
+	if Enabled {
+		projectID, destinationID := extractLabels(destinationName)
+		fallbackRetries.WithLabelValues(projectID, destinationID, "error").Inc()
+	}
+}
+
+//ExhaustedFallbackRetries increments counter of fallback files which won't be retried automatically anymore
+func ExhaustedFallbackRetries(destinationName string) {
+	if Enabled {
+		projectID, destinationID := extractLabels(destinationName)
+		fallbackRetriesExhausted.WithLabelValues(projectID, destinationID).Inc()
+	}
+}
+
+//PendingFallbackRetries sets amount of fallback files which are waiting for automatic retry per destination
+func PendingFallbackRetries(pendingPerDestination map[string]int) {
+	if Enabled {
+		fallbackPendingRetries.Reset()
+		for destinationName, value := range pendingPerDestination {
+			projectID, destinationID := extractLabels(destinationName)
+			fallbackPendingRetries.WithLabelValues(projectID, destinationID).Set(float64(value))
+		}
+	}
+}
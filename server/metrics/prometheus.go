@@ -17,6 +17,7 @@ func Init(enabled bool) {
 		initRedis()
 		initUsersRecognitionQueue()
 		initStreamEventsQueue()
+		initFallback()
 	} else {
 		logging.Warnf("Metrics isn't enabled")
 	}
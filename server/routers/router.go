@@ -45,8 +45,11 @@ func SetupRouter(adminToken string, metaStorage meta.Storage, destinations *dest
 
 	jsEventHandler := handlers.NewEventHandler(destinations, events.NewJsPreprocessor(), eventsCache, usersRecognitionService)
 	apiEventHandler := handlers.NewEventHandler(destinations, events.NewAPIPreprocessor(), eventsCache, usersRecognitionService)
+	bulkHandler := handlers.NewBulkHandler(apiEventHandler, viper.GetInt64("server.bulk.max_body_size_bytes"), viper.GetInt64("server.bulk.max_decompressed_size_bytes"),
+		viper.GetInt("server.bulk.max_line_size_bytes"), viper.GetInt("server.bulk.max_events"))
 
 	taskHandler := handlers.NewTaskHandler(taskService, sourcesService)
+	stateHandler := handlers.NewStateHandler(taskService, sourcesService)
 	fallbackHandler := handlers.NewFallbackHandler(fallbackService)
 	dryRunHandler := handlers.NewDryRunHandler(destinations, events.NewJsPreprocessor())
 	statisticsHandler := handlers.NewStatisticsHandler(metaStorage)
@@ -56,10 +59,13 @@ func SetupRouter(adminToken string, metaStorage meta.Storage, destinations *dest
 	{
 		apiV1.POST("/event", middleware.TokenFuncAuth(jsEventHandler.PostHandler, appconfig.Instance.AuthorizationService.GetClientOrigins, ""))
 		apiV1.POST("/s2s/event", middleware.TokenTwoFuncAuth(apiEventHandler.PostHandler, appconfig.Instance.AuthorizationService.GetServerOrigins, appconfig.Instance.AuthorizationService.GetClientOrigins, "The token isn't a server token. Please use s2s integration token"))
+		apiV1.POST("/s2s/events/bulk", middleware.TokenTwoFuncAuth(bulkHandler.Handler, appconfig.Instance.AuthorizationService.GetServerOrigins, appconfig.Instance.AuthorizationService.GetClientOrigins, "The token isn't a server token. Please use s2s integration token"))
 		apiV1.POST("/events/dry-run", middleware.TokenTwoFuncAuth(dryRunHandler.Handle, appconfig.Instance.AuthorizationService.GetServerOrigins, appconfig.Instance.AuthorizationService.GetClientOrigins, ""))
 
 		apiV1.POST("/destinations/test", adminTokenMiddleware.AdminAuth(handlers.DestinationsHandler))
 		apiV1.POST("/sources/test", adminTokenMiddleware.AdminAuth(handlers.SourcesHandler))
+		apiV1.POST("/singer/discover", adminTokenMiddleware.AdminAuth(handlers.SingerDiscoverHandler))
+		apiV1.POST("/singer/properties", adminTokenMiddleware.AdminAuth(handlers.SingerPropertiesHandler))
 
 		apiV1.GET("/statistics", adminTokenMiddleware.AdminAuth(statisticsHandler.GetHandler))
 
@@ -71,11 +77,28 @@ func SetupRouter(adminToken string, metaStorage meta.Storage, destinations *dest
 			tasksRoute.GET("/:taskID/logs", adminTokenMiddleware.AdminAuth(taskHandler.TaskLogsHandler))
 		}
 
+		stateRoute := apiV1.Group("/state")
+		{
+			stateRoute.GET("", adminTokenMiddleware.AdminAuth(stateHandler.GetHandler))
+			stateRoute.PUT("", adminTokenMiddleware.AdminAuth(stateHandler.UpdateHandler))
+			stateRoute.DELETE("", adminTokenMiddleware.AdminAuth(stateHandler.DeleteHandler))
+			stateRoute.POST("/resync", adminTokenMiddleware.AdminAuth(stateHandler.ResyncHandler))
+		}
+
 		apiV1.GET("/cluster", adminTokenMiddleware.AdminAuth(handlers.NewClusterHandler(clusterManager).Handler))
 		apiV1.GET("/events/cache", adminTokenMiddleware.AdminAuth(jsEventHandler.GetHandler))
 
 		apiV1.GET("/fallback", adminTokenMiddleware.AdminAuth(fallbackHandler.GetHandler))
 		apiV1.POST("/replay", adminTokenMiddleware.AdminAuth(fallbackHandler.ReplayHandler))
+		apiV1.POST("/fallback/purge", adminTokenMiddleware.AdminAuth(fallbackHandler.PurgeHandler))
+
+		fallbackEventsRoute := apiV1.Group("/fallback/events")
+		{
+			fallbackEventsRoute.GET("", adminTokenMiddleware.AdminAuth(fallbackHandler.EventsHandler))
+			fallbackEventsRoute.PUT("", adminTokenMiddleware.AdminAuth(fallbackHandler.UpdateEventHandler))
+			fallbackEventsRoute.PATCH("", adminTokenMiddleware.AdminAuth(fallbackHandler.PatchEventHandler))
+			fallbackEventsRoute.POST("/replay", adminTokenMiddleware.AdminAuth(fallbackHandler.ReplayEventsHandler))
+		}
 	}
 
 	router.POST("/api.:ignored", middleware.TokenFuncAuth(jsEventHandler.PostHandler, appconfig.Instance.AuthorizationService.GetClientOrigins, ""))
@@ -10,6 +10,8 @@ type Fields map[string]Field
 type BatchHeader struct {
 	TableName string
 	Fields    Fields
+	//PKFields are primary key fields of the source collection. They override destination primary_key_fields
+	PKFields map[string]bool
 }
 
 //Return true if there is at least one field
@@ -31,6 +33,14 @@ func (f Fields) Merge(other Fields) {
 					f[otherName] = currentField
 				}
 			}
+			//add new array elements type occurrences
+			for t := range otherField.elementTypeOccurrence {
+				if currentField.elementTypeOccurrence == nil {
+					currentField.elementTypeOccurrence = map[typing.DataType]bool{}
+					f[otherName] = currentField
+				}
+				currentField.elementTypeOccurrence[t] = true
+			}
 		} else {
 			f[otherName] = otherField
 		}
@@ -58,6 +68,8 @@ func (f Fields) Header() (header []string) {
 type Field struct {
 	dataType       *typing.DataType
 	typeOccurrence map[typing.DataType]bool
+	//elementTypeOccurrence are types of array elements (only for arrays values)
+	elementTypeOccurrence map[typing.DataType]bool
 }
 
 func NewField(t typing.DataType) Field {
@@ -67,6 +79,13 @@ func NewField(t typing.DataType) Field {
 	}
 }
 
+//NewArrayField returns typing.ARRAY field with the type of array elements
+func NewArrayField(elementType typing.DataType) Field {
+	field := NewField(typing.ARRAY)
+	field.elementTypeOccurrence = map[typing.DataType]bool{elementType: true}
+	return field
+}
+
 //GetType get field type based on occurrence in one file
 //lazily get common ancestor type (typing.GetCommonAncestorType)
 func (f Field) GetType() typing.DataType {
@@ -93,3 +112,26 @@ func (f Field) GetType() typing.DataType {
 	f.dataType = &common
 	return common
 }
+
+//GetArrayElementType returns common ancestor type of array elements types occurrences
+//typing.UNKNOWN occurrences (empty arrays) are skipped
+//returns typing.STRING if there weren't any arrays elements types (e.g. typing.ARRAY type from mappings)
+func (f Field) GetArrayElementType() typing.DataType {
+	var types []typing.DataType
+	for t := range f.elementTypeOccurrence {
+		if t != typing.UNKNOWN {
+			types = append(types, t)
+		}
+	}
+
+	if len(types) == 0 {
+		return typing.STRING
+	}
+
+	common := types[0]
+	for i := 1; i < len(types); i++ {
+		common = typing.GetCommonAncestorType(common, types[i])
+	}
+
+	return common
+}
@@ -8,6 +8,7 @@ import (
 )
 
 func TestFieldMerge(t *testing.T) {
+	arrayType := typing.ARRAY
 	tests := []struct {
 		name     string
 		current  Fields
@@ -44,6 +45,23 @@ func TestFieldMerge(t *testing.T) {
 				"col5": NewField(typing.TIMESTAMP),
 			},
 		},
+		{
+			"Arrays elements types merged ok",
+			Fields{"col1": NewArrayField(typing.INT64), "col2": NewField(typing.STRING)},
+			Fields{"col1": NewArrayField(typing.FLOAT64), "col2": NewArrayField(typing.BOOL)},
+			Fields{
+				"col1": Field{
+					dataType:              &arrayType,
+					typeOccurrence:        map[typing.DataType]bool{typing.ARRAY: true},
+					elementTypeOccurrence: map[typing.DataType]bool{typing.INT64: true, typing.FLOAT64: true},
+				},
+				"col2": Field{
+					dataType:              nil,
+					typeOccurrence:        map[typing.DataType]bool{typing.STRING: true, typing.ARRAY: true},
+					elementTypeOccurrence: map[typing.DataType]bool{typing.BOOL: true},
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -116,3 +134,18 @@ func TestColumnGetType(t *testing.T) {
 		})
 	}
 }
+
+func TestFieldGetArrayElementType(t *testing.T) {
+	require.Equal(t, typing.STRING, NewField(typing.ARRAY).GetArrayElementType())
+	require.Equal(t, typing.STRING, NewArrayField(typing.UNKNOWN).GetArrayElementType())
+	require.Equal(t, typing.INT64, NewArrayField(typing.INT64).GetArrayElementType())
+
+	fields := Fields{"col1": NewArrayField(typing.INT64)}
+	fields.Merge(Fields{"col1": NewArrayField(typing.UNKNOWN)})
+	require.Equal(t, typing.INT64, fields["col1"].GetArrayElementType())
+	fields.Merge(Fields{"col1": NewArrayField(typing.FLOAT64)})
+	require.Equal(t, typing.FLOAT64, fields["col1"].GetArrayElementType())
+
+	fields.Merge(Fields{"col1": NewArrayField(typing.TIMESTAMP)})
+	require.Equal(t, typing.STRING, fields["col1"].GetArrayElementType())
+}
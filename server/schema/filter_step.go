@@ -0,0 +1,254 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/jitsucom/jitsu/server/jsonutils"
+)
+
+//FiltersConfig is a configuration of declarative events filtering
+//event passes if it matches all include conditions and doesn't match any exclude condition
+type FiltersConfig struct {
+	Include []*FilterConditionConfig `mapstructure:"include" json:"include,omitempty" yaml:"include,omitempty"`
+	Exclude []*FilterConditionConfig `mapstructure:"exclude" json:"exclude,omitempty" yaml:"exclude,omitempty"`
+}
+
+//FilterConditionConfig is a condition on JSON path value
+//all configured operators must be satisfied
+type FilterConditionConfig struct {
+	Path   string        `mapstructure:"path" json:"path,omitempty" yaml:"path,omitempty"`
+	Equals interface{}   `mapstructure:"equals" json:"equals,omitempty" yaml:"equals,omitempty"`
+	In     []interface{} `mapstructure:"in" json:"in,omitempty" yaml:"in,omitempty"`
+	Regex  string        `mapstructure:"regex" json:"regex,omitempty" yaml:"regex,omitempty"`
+	Exists *bool         `mapstructure:"exists" json:"exists,omitempty" yaml:"exists,omitempty"`
+	Gt     *float64      `mapstructure:"gt" json:"gt,omitempty" yaml:"gt,omitempty"`
+	Gte    *float64      `mapstructure:"gte" json:"gte,omitempty" yaml:"gte,omitempty"`
+	Lt     *float64      `mapstructure:"lt" json:"lt,omitempty" yaml:"lt,omitempty"`
+	Lte    *float64      `mapstructure:"lte" json:"lte,omitempty" yaml:"lte,omitempty"`
+}
+
+func (fcc *FilterConditionConfig) String() string {
+	b, _ := json.Marshal(fcc)
+	return string(b)
+}
+
+//FilterStep checks events against include/exclude conditions
+type FilterStep struct {
+	include []*filterCondition
+	exclude []*filterCondition
+}
+
+type filterCondition struct {
+	config *FilterConditionConfig
+	path   *jsonutils.JSONPath
+	regex  *regexp.Regexp
+}
+
+//NewFilterStep return FilterStep with parsed conditions
+//or nil if config is nil or doesn't have conditions
+func NewFilterStep(config *FiltersConfig) (*FilterStep, error) {
+	if config == nil || (len(config.Include) == 0 && len(config.Exclude) == 0) {
+		return nil, nil
+	}
+
+	include, err := parseFilterConditions(config.Include)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing include filters: %v", err)
+	}
+
+	exclude, err := parseFilterConditions(config.Exclude)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing exclude filters: %v", err)
+	}
+
+	return &FilterStep{include: include, exclude: exclude}, nil
+}
+
+//Passed return true if object matches all include conditions and doesn't match any of exclude conditions
+func (fs *FilterStep) Passed(object map[string]interface{}) bool {
+	for _, condition := range fs.include {
+		if !condition.match(object) {
+			return false
+		}
+	}
+
+	for _, condition := range fs.exclude {
+		if condition.match(object) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func parseFilterConditions(configs []*FilterConditionConfig) ([]*filterCondition, error) {
+	var conditions []*filterCondition
+	for _, config := range configs {
+		if config == nil {
+			continue
+		}
+		if config.Path == "" {
+			return nil, fmt.Errorf("path is required parameter: %s", config.String())
+		}
+		if config.Equals == nil && len(config.In) == 0 && config.Regex == "" && config.Exists == nil &&
+			config.Gt == nil && config.Gte == nil && config.Lt == nil && config.Lte == nil {
+			return nil, fmt.Errorf("at least one of equals, in, regex, exists, gt, gte, lt, lte is required: %s", config.String())
+		}
+
+		condition := &filterCondition{config: config, path: jsonutils.NewJSONPath(config.Path)}
+		if config.Regex != "" {
+			regex, err := regexp.Compile(config.Regex)
+			if err != nil {
+				return nil, fmt.Errorf("error compiling regex [%s]: %v", config.Regex, err)
+			}
+			condition.regex = regex
+		}
+
+		conditions = append(conditions, condition)
+	}
+
+	return conditions, nil
+}
+
+//match return true if value under condition JSON path satisfies all configured operators
+func (fc *filterCondition) match(object map[string]interface{}) bool {
+	value, exists := fc.path.Get(object)
+	if fc.config.Exists != nil && *fc.config.Exists != exists {
+		return false
+	}
+
+	//other operators require value
+	if fc.config.Equals == nil && len(fc.config.In) == 0 && fc.regex == nil &&
+		fc.config.Gt == nil && fc.config.Gte == nil && fc.config.Lt == nil && fc.config.Lte == nil {
+		return true
+	}
+	if !exists || value == nil {
+		return false
+	}
+
+	if fc.config.Equals != nil && !filterValuesEqual(value, fc.config.Equals) {
+		return false
+	}
+
+	if len(fc.config.In) > 0 {
+		found := false
+		for _, expected := range fc.config.In {
+			if filterValuesEqual(value, expected) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if fc.regex != nil {
+		str, ok := filterScalarString(value)
+		if !ok || !fc.regex.MatchString(str) {
+			return false
+		}
+	}
+
+	if fc.config.Gt != nil || fc.config.Gte != nil || fc.config.Lt != nil || fc.config.Lte != nil {
+		number, ok := filterNumber(value)
+		if !ok {
+			return false
+		}
+		if fc.config.Gt != nil && !(number > *fc.config.Gt) {
+			return false
+		}
+		if fc.config.Gte != nil && !(number >= *fc.config.Gte) {
+			return false
+		}
+		if fc.config.Lt != nil && !(number < *fc.config.Lt) {
+			return false
+		}
+		if fc.config.Lte != nil && !(number <= *fc.config.Lte) {
+			return false
+		}
+	}
+
+	return true
+}
+
+//filterValuesEqual compares values as numbers if both are numeric (e.g. json.Number from event and int from config)
+//otherwise as strings
+func filterValuesEqual(value, expected interface{}) bool {
+	valueStr, valueIsStr := value.(string)
+	expectedStr, expectedIsStr := expected.(string)
+	if valueIsStr && expectedIsStr {
+		return valueStr == expectedStr
+	}
+
+	if valueNumber, ok := filterNumber(value); ok {
+		if expectedNumber, ok := filterNumber(expected); ok {
+			return valueNumber == expectedNumber
+		}
+	}
+
+	valueStr, ok := filterScalarString(value)
+	if !ok {
+		return false
+	}
+	expectedStr, ok = filterScalarString(expected)
+	if !ok {
+		return false
+	}
+
+	return valueStr == expectedStr
+}
+
+//filterNumber return float64 from numeric value or from numeric string
+func filterNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+//filterScalarString return string representation of scalar values
+//objects and arrays aren't supported
+func filterScalarString(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case nil, map[string]interface{}, []interface{}:
+		return "", false
+	case string:
+		return v, true
+	default:
+		return fmt.Sprint(v), true
+	}
+}
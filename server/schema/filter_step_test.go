@@ -0,0 +1,137 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterStepPassed(t *testing.T) {
+	yes := true
+	no := false
+	hundred := 100.0
+	ten := 10.0
+
+	event := map[string]interface{}{
+		"event_type": "pageview",
+		"amount":     json.Number("150.5"),
+		"count":      json.Number("10"),
+		"user": map[string]interface{}{
+			"email": "john@test.com",
+			"id":    "007",
+		},
+		"flag": true,
+	}
+
+	tests := []struct {
+		name     string
+		config   *FiltersConfig
+		expected bool
+	}{
+		{
+			"Include equals",
+			&FiltersConfig{Include: []*FilterConditionConfig{{Path: "/event_type", Equals: "pageview"}}},
+			true,
+		},
+		{
+			"Include equals doesn't match",
+			&FiltersConfig{Include: []*FilterConditionConfig{{Path: "/event_type", Equals: "click"}}},
+			false,
+		},
+		{
+			"Include equals number and bool",
+			&FiltersConfig{Include: []*FilterConditionConfig{{Path: "/count", Equals: 10}, {Path: "/flag", Equals: true}}},
+			true,
+		},
+		{
+			"Strings are compared as strings",
+			&FiltersConfig{Include: []*FilterConditionConfig{{Path: "/user/id", Equals: "7"}}},
+			false,
+		},
+		{
+			"Include in list",
+			&FiltersConfig{Include: []*FilterConditionConfig{{Path: "/event_type", In: []interface{}{"click", "pageview"}}}},
+			true,
+		},
+		{
+			"Exclude regex",
+			&FiltersConfig{Exclude: []*FilterConditionConfig{{Path: "/user/email", Regex: "@test\\.com$"}}},
+			false,
+		},
+		{
+			"Exclude regex doesn't match",
+			&FiltersConfig{Exclude: []*FilterConditionConfig{{Path: "/user/email", Regex: "@example\\.com$"}}},
+			true,
+		},
+		{
+			"Include exists",
+			&FiltersConfig{Include: []*FilterConditionConfig{{Path: "/user/email", Exists: &yes}}},
+			true,
+		},
+		{
+			"Include not exists",
+			&FiltersConfig{Include: []*FilterConditionConfig{{Path: "/user/phone", Exists: &no}}},
+			true,
+		},
+		{
+			"Exclude exists",
+			&FiltersConfig{Exclude: []*FilterConditionConfig{{Path: "/user", Exists: &yes}}},
+			false,
+		},
+		{
+			"Include numeric range",
+			&FiltersConfig{Include: []*FilterConditionConfig{{Path: "/amount", Gt: &hundred, Lte: &hundred}}},
+			false,
+		},
+		{
+			"Include numeric comparison",
+			&FiltersConfig{Include: []*FilterConditionConfig{{Path: "/amount", Gt: &hundred}, {Path: "/count", Gte: &ten}}},
+			true,
+		},
+		{
+			"Numeric comparison of absent field",
+			&FiltersConfig{Include: []*FilterConditionConfig{{Path: "/unknown", Lt: &hundred}}},
+			false,
+		},
+		{
+			"Numeric comparison of non-numeric field",
+			&FiltersConfig{Include: []*FilterConditionConfig{{Path: "/event_type", Lt: &hundred}}},
+			false,
+		},
+		{
+			"Include and exclude",
+			&FiltersConfig{
+				Include: []*FilterConditionConfig{{Path: "/event_type", Equals: "pageview"}},
+				Exclude: []*FilterConditionConfig{{Path: "/amount", Gt: &hundred}},
+			},
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, err := NewFilterStep(tt.config)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, step.Passed(event))
+		})
+	}
+}
+
+func TestNewFilterStep(t *testing.T) {
+	step, err := NewFilterStep(nil)
+	require.NoError(t, err)
+	require.Nil(t, step)
+
+	step, err = NewFilterStep(&FiltersConfig{})
+	require.NoError(t, err)
+	require.Nil(t, step)
+
+	_, err = NewFilterStep(&FiltersConfig{Include: []*FilterConditionConfig{{Equals: "a"}}})
+	require.EqualError(t, err, `Error parsing include filters: path is required parameter: {"equals":"a"}`)
+
+	_, err = NewFilterStep(&FiltersConfig{Exclude: []*FilterConditionConfig{{Path: "/a"}}})
+	require.EqualError(t, err, `Error parsing exclude filters: at least one of equals, in, regex, exists, gt, gte, lt, lte is required: {"path":"/a"}`)
+
+	_, err = NewFilterStep(&FiltersConfig{Exclude: []*FilterConditionConfig{{Path: "/a", Regex: "("}}})
+	require.EqualError(t, err, "Error parsing exclude filters: error compiling regex [(]: error parsing regexp: missing closing ): `(`")
+}
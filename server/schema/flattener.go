@@ -35,8 +35,17 @@ type Flattener interface {
 	FlattenObject(map[string]interface{}) (map[string]interface{}, error)
 }
 
+//NativeTypesConfig dto for data_layout.native_types configuration
+//arrays are kept as native arrays and objects (JSON paths) are kept as JSON columns instead of flattening
+type NativeTypesConfig struct {
+	Arrays  bool     `mapstructure:"arrays" json:"arrays,omitempty" yaml:"arrays,omitempty"`
+	Objects []string `mapstructure:"objects" json:"objects,omitempty" yaml:"objects,omitempty"`
+}
+
 type FlattenerImpl struct {
 	omitNilValues bool
+	keepArrays    bool
+	keepObjects   map[string]bool
 
 	specialCharsReplacer *strings.Replacer
 }
@@ -47,6 +56,29 @@ func NewFlattener() Flattener {
 	}
 }
 
+//NewNativeTypesFlattener returns Flattener which keeps arrays and configured nested objects as is
+//returns err if objects paths are invalid
+func NewNativeTypesFlattener(config *NativeTypesConfig) (Flattener, error) {
+	keepObjects := map[string]bool{}
+	for _, path := range config.Objects {
+		//flat field name of the path: /key1/key2 -> key1_key2
+		var parts []string
+		for _, part := range strings.Split(strings.Trim(path, "/"), "/") {
+			if part == "" {
+				return nil, fmt.Errorf("Error parsing native types object path [%s]: empty path part", path)
+			}
+			parts = append(parts, Reformat(part))
+		}
+		keepObjects[strings.Join(parts, "_")] = true
+	}
+
+	return &FlattenerImpl{
+		omitNilValues: true,
+		keepArrays:    config.Arrays,
+		keepObjects:   keepObjects,
+	}, nil
+}
+
 //FlattenObject flatten object e.g. from {"key1":{"key2":123}} to {"key1_key2":123}
 //from {"$key1":1} to {"_key1":1}
 //from {"(key1)":1} to {"_key1_":1}
@@ -68,6 +100,11 @@ func (f *FlattenerImpl) flatten(key string, value interface{}, destination map[s
 	t := reflect.ValueOf(value)
 	switch t.Kind() {
 	case reflect.Slice:
+		if f.keepArrays {
+			destination[key] = value
+			return nil
+		}
+
 		b, err := json.Marshal(value)
 		if err != nil {
 			return fmt.Errorf("Error marshaling array with key %s: %v", key, err)
@@ -75,6 +112,11 @@ func (f *FlattenerImpl) flatten(key string, value interface{}, destination map[s
 		destination[key] = string(b)
 	case reflect.Map:
 		unboxed := value.(map[string]interface{})
+		if f.keepObjects[key] {
+			destination[key] = unboxed
+			return nil
+		}
+
 		for k, v := range unboxed {
 			newKey := k
 			if key != "" {
@@ -68,3 +68,30 @@ func TestFlattenObject(t *testing.T) {
 		})
 	}
 }
+
+func TestFlattenObjectNativeTypes(t *testing.T) {
+	flattener, err := NewNativeTypesFlattener(&NativeTypesConfig{Arrays: true, Objects: []string{"/key8/sub_key3", "Key$9"}})
+	require.NoError(t, err)
+
+	actualFlattenJSON, err := flattener.FlattenObject(map[string]interface{}{
+		"key1": "value1",
+		"key5": []interface{}{1, 2, 3, 4},
+		"key8": map[string]interface{}{
+			"sub_key1": "event",
+			"sub_key3": map[string]interface{}{
+				"sub_sub_key1": []string{"1,", "2."}},
+		},
+		"key$9": map[string]interface{}{"Sub-Key": true},
+	})
+	require.NoError(t, err)
+	test.ObjectsEqual(t, map[string]interface{}{
+		"key1":          "value1",
+		"key5":          []interface{}{1, 2, 3, 4},
+		"key8_sub_key1": "event",
+		"key8_sub_key3": map[string]interface{}{"sub_sub_key1": []string{"1,", "2."}},
+		"key_9":         map[string]interface{}{"Sub-Key": true},
+	}, actualFlattenJSON, "Wrong flattened json")
+
+	_, err = NewNativeTypesFlattener(&NativeTypesConfig{Objects: []string{"/key1//key2"}})
+	require.Error(t, err)
+}
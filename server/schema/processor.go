@@ -11,22 +11,52 @@ import (
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/maputils"
+	"github.com/jitsucom/jitsu/server/metrics"
 	"io"
 )
 
-var ErrSkipObject = errors.New("Table name template return empty string. This object will be skipped.")
+var (
+	ErrSkipObject            = errors.New("Table name template return empty string. This object will be skipped.")
+	ErrSkipObjectByTransform = errors.New("Transform script returned no events. This object will be skipped.")
+	ErrFilteredObject        = errors.New("Event has been filtered out by destination filters.")
+)
+
+//Envelope is a processed flatten object with table representation
+type Envelope struct {
+	Header *BatchHeader
+	Event  events.Event
+}
 
 type Processor struct {
 	identifier           string
 	tableNameExtractor   *TableNameExtractor
 	lookupEnrichmentStep *enrichment.LookupEnrichmentStep
+	filterStep           *FilterStep
+	transformStep        *TransformStep
+	unnestStep           *UnnestStep
 	mappingStep          *MappingStep
+	childMappingStep     *MappingStep
 	breakOnError         bool
 }
 
+//ProcessorOptions contains optional Processor steps (every step might be nil)
+type ProcessorOptions struct {
+	FilterStep    *FilterStep
+	TransformStep *TransformStep
+	UnnestStep    *UnnestStep
+}
+
+//NewProcessor return configured Processor
+//options are optional (might be nil)
 func NewProcessor(destinationID, tableNameFuncExpression string, fieldMapper Mapper, enrichmentRules []enrichment.Rule,
-	flattener Flattener, typeResolver TypeResolver, breakOnError bool) (*Processor, error) {
+	flattener Flattener, typeResolver TypeResolver, breakOnError bool, options *ProcessorOptions) (*Processor, error) {
+	if options == nil {
+		options = &ProcessorOptions{}
+	}
+
 	mappingStep := NewMappingStep(fieldMapper, flattener, typeResolver)
+	//mappings are configured for parent tables: child objects are only flattened
+	childMappingStep := NewMappingStep(&DummyMapper{}, flattener, typeResolver)
 	tableNameExtractor, err := NewTableNameExtractor(tableNameFuncExpression)
 	if err != nil {
 		return nil, err
@@ -36,18 +66,23 @@ func NewProcessor(destinationID, tableNameFuncExpression string, fieldMapper Map
 		identifier:           destinationID,
 		tableNameExtractor:   tableNameExtractor,
 		lookupEnrichmentStep: enrichment.NewLookupEnrichmentStep(enrichmentRules),
+		filterStep:           options.FilterStep,
+		transformStep:        options.TransformStep,
+		unnestStep:           options.UnnestStep,
 		mappingStep:          mappingStep,
+		childMappingStep:     childMappingStep,
 		breakOnError:         breakOnError,
 	}, nil
 }
 
-//ProcessEvent return table representation, processed flatten object
-func (p *Processor) ProcessEvent(event map[string]interface{}) (*BatchHeader, events.Event, error) {
+//ProcessEvent return table representations and processed flatten objects
+//(transform step can produce 0, 1 or many objects from one event, unnest step produces child tables objects)
+func (p *Processor) ProcessEvent(event map[string]interface{}) ([]Envelope, error) {
 	return p.processObject(event, map[string]bool{})
 }
 
 //ProcessFilePayload process file payload lines divided with \n. Line by line where 1 line = 1 json
-//Return array of processed objects per table like {"table1": []objects, "table2": []objects},
+//Return array of processed objects per table like {"table1": []objects, "table2": []objects} (including unnested child tables),
 //All failed events are moved to separate collection for sending to fallback
 func (p *Processor) ProcessFilePayload(fileName string, payload []byte, alreadyUploadedTables map[string]bool,
 	parseFunc func([]byte) (map[string]interface{}, error)) (map[string]*ProcessedFile, []*events.FailedEvent, error) {
@@ -64,15 +99,19 @@ func (p *Processor) ProcessFilePayload(fileName string, payload []byte, alreadyU
 			return nil, nil, err
 		}
 
-		batchHeader, processedObject, err := p.processObject(object, alreadyUploadedTables)
+		envelopes, err := p.processObject(object, alreadyUploadedTables)
 		if err != nil {
 			//handle skip object functionality
-			if err == ErrSkipObject {
+			if err == ErrSkipObject || err == ErrSkipObjectByTransform {
 				if !appconfig.Instance.DisableSkipEventsWarn {
 					logging.Warnf("[%s] Event [%s]: %v", p.identifier, events.ExtractEventID(object), err)
 				}
 
 				counters.SkipEvents(p.identifier, 1)
+				metrics.SkipEvents(p.identifier, 1)
+			} else if err == ErrFilteredObject {
+				counters.FilterEvents(p.identifier, 1)
+				metrics.FilterEvents(p.identifier, 1)
 			} else if p.breakOnError {
 				return nil, nil, err
 			} else {
@@ -87,14 +126,14 @@ func (p *Processor) ProcessFilePayload(fileName string, payload []byte, alreadyU
 			}
 		}
 
-		//don't process empty and skipped object (Exists func nil-protected)
-		if batchHeader.Exists() {
-			f, ok := filePerTable[batchHeader.TableName]
+		//skipped and failed objects don't have envelopes
+		for _, envelope := range envelopes {
+			f, ok := filePerTable[envelope.Header.TableName]
 			if !ok {
-				filePerTable[batchHeader.TableName] = &ProcessedFile{FileName: fileName, BatchHeader: batchHeader, payload: []map[string]interface{}{processedObject}}
+				filePerTable[envelope.Header.TableName] = &ProcessedFile{FileName: fileName, BatchHeader: envelope.Header, payload: []map[string]interface{}{envelope.Event}}
 			} else {
-				f.BatchHeader.Fields.Merge(batchHeader.Fields)
-				f.payload = append(f.payload, processedObject)
+				f.BatchHeader.Fields.Merge(envelope.Header.Fields)
+				f.payload = append(f.payload, envelope.Event)
 			}
 		}
 
@@ -114,51 +153,108 @@ func (p *Processor) ProcessObjects(objects []map[string]interface{}) (map[string
 	unitPerTable := map[string]*ProcessedFile{}
 
 	for _, object := range objects {
-		batchHeader, processedObject, err := p.processObject(object, map[string]bool{})
+		envelopes, err := p.processObject(object, map[string]bool{})
 		if err != nil {
+			//object has been filtered out or dropped by transform script
+			if err == ErrFilteredObject || err == ErrSkipObjectByTransform {
+				continue
+			}
 			return nil, err
 		}
 
-		//don't process empty object
-		if !batchHeader.Exists() {
-			continue
-		}
-
-		unit, ok := unitPerTable[batchHeader.TableName]
-		if !ok {
-			unitPerTable[batchHeader.TableName] = &ProcessedFile{BatchHeader: batchHeader, payload: []map[string]interface{}{processedObject}}
-		} else {
-			unit.BatchHeader.Fields.Merge(batchHeader.Fields)
-			unit.payload = append(unit.payload, processedObject)
+		for _, envelope := range envelopes {
+			unit, ok := unitPerTable[envelope.Header.TableName]
+			if !ok {
+				unitPerTable[envelope.Header.TableName] = &ProcessedFile{BatchHeader: envelope.Header, payload: []map[string]interface{}{envelope.Event}}
+			} else {
+				unit.BatchHeader.Fields.Merge(envelope.Header.Fields)
+				unit.payload = append(unit.payload, envelope.Event)
+			}
 		}
 	}
 
 	return unitPerTable, nil
 }
 
-//Check if table name in skipTables => return empty Table for skipping or
-//Return table representation of object and flatten, mapped object
-//1. extract table name
-//2. execute enrichment.LookupEnrichmentStep and MappingStep
-//or ErrSkipObject/another error
-func (p *Processor) processObject(object map[string]interface{}, alreadyUploadedTables map[string]bool) (*BatchHeader, map[string]interface{}, error) {
-	tableName, err := p.tableNameExtractor.Extract(object)
-	if err != nil {
-		return nil, nil, err
-	}
-	if tableName == "" {
-		return nil, nil, ErrSkipObject
+//Return table representations of objects and flatten, mapped objects:
+//1. check FilterStep conditions (if configured)
+//2. execute TransformStep (if configured) which returns 0, 1 or many objects
+//3. extract table name of every object, skip objects with table names from alreadyUploadedTables
+//4. execute enrichment.LookupEnrichmentStep
+//5. execute UnnestStep (if configured) which extracts arrays into child tables objects
+//6. execute MappingStep (mappings are applied only to the parent object: arrays have been already extracted)
+//or ErrFilteredObject/ErrSkipObject/ErrSkipObjectByTransform/another error
+func (p *Processor) processObject(object map[string]interface{}, alreadyUploadedTables map[string]bool) ([]Envelope, error) {
+	if p.filterStep != nil && !p.filterStep.Passed(object) {
+		return nil, ErrFilteredObject
 	}
 
-	//object has been already processed (storage:table pair might be already processed)
-	_, ok := alreadyUploadedTables[tableName]
-	if ok {
-		return &BatchHeader{}, nil, nil
+	objects := []map[string]interface{}{maputils.CopyMap(object)}
+	if p.transformStep != nil {
+		transformed, err := p.transformStep.Execute(object)
+		if err != nil {
+			return nil, err
+		}
+		if len(transformed) == 0 {
+			return nil, ErrSkipObjectByTransform
+		}
+		objects = transformed
 	}
 
-	objectCopy := maputils.CopyMap(object)
+	var envelopes []Envelope
+	skipped := 0
+	for _, obj := range objects {
+		tableName, err := p.tableNameExtractor.Extract(obj)
+		if err != nil {
+			return nil, err
+		}
+		if tableName == "" {
+			skipped++
+			continue
+		}
+
+		//object has been already processed (storage:table pair might be already processed)
+		if _, ok := alreadyUploadedTables[tableName]; ok {
+			continue
+		}
+
+		p.lookupEnrichmentStep.Execute(obj)
 
-	p.lookupEnrichmentStep.Execute(objectCopy)
+		var children []*ChildObjects
+		if p.unnestStep != nil {
+			children = p.unnestStep.Execute(obj)
+		}
+
+		batchHeader, processedObject, err := p.mappingStep.Execute(tableName, obj)
+		if err != nil {
+			return nil, err
+		}
+
+		//don't process empty object
+		if batchHeader.Exists() {
+			envelopes = append(envelopes, Envelope{Header: batchHeader, Event: processedObject})
+		}
+
+		for _, child := range children {
+			childTableName := tableName + "_" + child.TableSuffix
+			if _, ok := alreadyUploadedTables[childTableName]; ok {
+				continue
+			}
+
+			for _, childObject := range child.Objects {
+				childHeader, processedChild, err := p.childMappingStep.Execute(childTableName, childObject)
+				if err != nil {
+					return nil, fmt.Errorf("Error processing [%s] child object: %v", childTableName, err)
+				}
+
+				envelopes = append(envelopes, Envelope{Header: childHeader, Event: processedChild})
+			}
+		}
+	}
+
+	if skipped == len(objects) {
+		return nil, ErrSkipObject
+	}
 
-	return p.mappingStep.Execute(tableName, objectCopy)
+	return envelopes, nil
 }
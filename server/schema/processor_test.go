@@ -1,6 +1,7 @@
 package schema
 
 import (
+	"encoding/json"
 	"github.com/spf13/viper"
 	"io/ioutil"
 	"testing"
@@ -137,7 +138,7 @@ func TestProcessFilePayload(t *testing.T) {
 			[]events.FailedEvent{},
 		},
 	}
-	p, err := NewProcessor("test", `{{if .event_type}}{{if eq .event_type "skipped"}}{{else}}{{.event_type}}_{{._timestamp.Format "2006_01"}}{{end}}{{else}}{{.event_type}}_{{._timestamp.Format "2006_01"}}{{end}}`, &DummyMapper{}, []enrichment.Rule{}, NewFlattener(), NewTypeResolver(), false)
+	p, err := NewProcessor("test", `{{if .event_type}}{{if eq .event_type "skipped"}}{{else}}{{.event_type}}_{{._timestamp.Format "2006_01"}}{{end}}{{else}}{{.event_type}}_{{._timestamp.Format "2006_01"}}{{end}}`, &DummyMapper{}, []enrichment.Rule{}, NewFlattener(), NewTypeResolver(), false, nil)
 	require.NoError(t, err)
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -274,22 +275,175 @@ func TestProcessFact(t *testing.T) {
 	})
 	require.NoError(t, err)
 
-	p, err := NewProcessor("test", `events_{{._timestamp.Format "2006_01"}}`, fieldMapper, []enrichment.Rule{uaRule, ipRule}, NewFlattener(), NewTypeResolver(), false)
+	p, err := NewProcessor("test", `events_{{._timestamp.Format "2006_01"}}`, fieldMapper, []enrichment.Rule{uaRule, ipRule}, NewFlattener(), NewTypeResolver(), false, nil)
 
 	require.NoError(t, err)
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			batchHeader, actual, err := p.ProcessEvent(tt.input)
+			envelopes, err := p.ProcessEvent(tt.input)
 
 			if tt.expectedErr != "" {
 				require.Error(t, err)
 				require.Equal(t, tt.expectedErr, err.Error())
 			} else {
 				require.NoError(t, err)
+				require.Len(t, envelopes, 1)
 
-				test.ObjectsEqual(t, tt.expectedBatchHeader, batchHeader, "BatchHeader results aren't equal")
-				test.ObjectsEqual(t, tt.expectedObject, actual, "Processed objects aren't equal")
+				test.ObjectsEqual(t, tt.expectedBatchHeader, envelopes[0].Header, "BatchHeader results aren't equal")
+				test.ObjectsEqual(t, tt.expectedObject, envelopes[0].Event, "Processed objects aren't equal")
 			}
 		})
 	}
 }
+
+func TestProcessEventWithTransform(t *testing.T) {
+	viper.Set("server.log.path", "")
+
+	err := appconfig.Init(false, "")
+	require.NoError(t, err)
+
+	testTime, _ := time.Parse(timestamp.Layout, "2020-08-02T18:23:58.057807Z")
+
+	transformStep, err := NewTransformStep(&TransformConfig{Script: `
+function transform(event) {
+    if (event.event_type === "drop") {
+        return null;
+    }
+    if (event.event_type === "order") {
+        return event.items.map(function(item) {
+            return {_timestamp: event._timestamp, event_type: "order_item", sku: item.sku, total: item.price * item.qty};
+        });
+    }
+    event.computed = event.event_type + "_computed";
+    return event;
+}`})
+	require.NoError(t, err)
+
+	p, err := NewProcessor("test", `{{.event_type}}`, &DummyMapper{}, []enrichment.Rule{}, NewFlattener(), NewTypeResolver(), false, &ProcessorOptions{TransformStep: transformStep})
+	require.NoError(t, err)
+
+	envelopes, err := p.ProcessEvent(map[string]interface{}{"_timestamp": "2020-08-02T18:23:58.057807Z", "event_type": "drop"})
+	require.Equal(t, ErrSkipObjectByTransform, err)
+	require.Empty(t, envelopes)
+
+	envelopes, err = p.ProcessEvent(map[string]interface{}{"_timestamp": "2020-08-02T18:23:58.057807Z", "event_type": "pageview"})
+	require.NoError(t, err)
+	require.Len(t, envelopes, 1)
+	require.Equal(t, "pageview", envelopes[0].Header.TableName)
+	test.ObjectsEqual(t, events.Event{"_timestamp": testTime, "event_type": "pageview", "computed": "pageview_computed"}, envelopes[0].Event)
+
+	envelopes, err = p.ProcessEvent(map[string]interface{}{
+		"_timestamp": "2020-08-02T18:23:58.057807Z",
+		"event_type": "order",
+		"items": []interface{}{
+			map[string]interface{}{"sku": "a", "price": json.Number("2.5"), "qty": json.Number("2")},
+			map[string]interface{}{"sku": "b", "price": json.Number("10"), "qty": json.Number("1")},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, envelopes, 2)
+	for _, envelope := range envelopes {
+		require.Equal(t, "order_item", envelope.Header.TableName)
+	}
+	test.ObjectsEqual(t, events.Event{"_timestamp": testTime, "event_type": "order_item", "sku": "a", "total": int64(5)}, envelopes[0].Event)
+	test.ObjectsEqual(t, events.Event{"_timestamp": testTime, "event_type": "order_item", "sku": "b", "total": int64(10)}, envelopes[1].Event)
+}
+
+func TestProcessEventWithFilters(t *testing.T) {
+	viper.Set("server.log.path", "")
+
+	err := appconfig.Init(false, "")
+	require.NoError(t, err)
+
+	filterStep, err := NewFilterStep(&FiltersConfig{Exclude: []*FilterConditionConfig{{Path: "/event_type", In: []interface{}{"test", "debug"}}}})
+	require.NoError(t, err)
+
+	p, err := NewProcessor("test", `{{.event_type}}`, &DummyMapper{}, []enrichment.Rule{}, NewFlattener(), NewTypeResolver(), false, &ProcessorOptions{FilterStep: filterStep})
+	require.NoError(t, err)
+
+	envelopes, err := p.ProcessEvent(map[string]interface{}{"_timestamp": "2020-08-02T18:23:58.057807Z", "event_type": "debug"})
+	require.Equal(t, ErrFilteredObject, err)
+	require.Empty(t, envelopes)
+
+	envelopes, err = p.ProcessEvent(map[string]interface{}{"_timestamp": "2020-08-02T18:23:58.057807Z", "event_type": "pageview"})
+	require.NoError(t, err)
+	require.Len(t, envelopes, 1)
+
+	//filtered objects are ignored in sync processing
+	units, err := p.ProcessObjects([]map[string]interface{}{
+		{"_timestamp": "2020-08-02T18:23:58.057807Z", "event_type": "test"},
+		{"_timestamp": "2020-08-02T18:23:58.057807Z", "event_type": "pageview"},
+	})
+	require.NoError(t, err)
+	require.Len(t, units, 1)
+	require.Equal(t, 1, units["pageview"].GetPayloadLen())
+}
+
+func TestProcessFilePayloadWithUnnest(t *testing.T) {
+	viper.Set("server.log.path", "")
+
+	err := appconfig.Init(false, "")
+	require.NoError(t, err)
+
+	testTime, _ := time.Parse(timestamp.Layout, "2020-08-02T18:23:58.057807Z")
+
+	unnestStep, err := NewUnnestStep([]string{"/products", "/order/Tags"})
+	require.NoError(t, err)
+
+	p, err := NewProcessor("test", `events`, &DummyMapper{}, []enrichment.Rule{}, NewFlattener(), NewTypeResolver(), false, &ProcessorOptions{UnnestStep: unnestStep})
+	require.NoError(t, err)
+
+	payload := []byte(`{"_timestamp": "2020-08-02T18:23:58.057807Z", "eventn_ctx": {"event_id": "e1"}, "products": [{"sku": "a", "price": 2.5}, {"sku": "b", "price": 10}], "order": {"id": 1, "Tags": ["x"]}}
+{"_timestamp": "2020-08-02T18:23:58.057807Z", "eventn_ctx": {"event_id": "e2"}, "products": [], "order": {"id": 2, "Tags": "not array"}}
+`)
+	actual, failed, err := p.ProcessFilePayload("testfile", payload, map[string]bool{}, parsers.ParseJSON)
+	require.NoError(t, err)
+	require.Empty(t, failed)
+	require.Len(t, actual, 3)
+
+	//arrays are removed from the parent table
+	test.ObjectsEqual(t, []map[string]interface{}{
+		{"_timestamp": testTime, "eventn_ctx_event_id": "e1", "order_id": int64(1)},
+		{"_timestamp": testTime, "eventn_ctx_event_id": "e2", "order_id": int64(2), "order_tags": "not array"},
+	}, actual["events"].GetPayload())
+
+	test.ObjectsEqual(t, []map[string]interface{}{
+		{"_timestamp": testTime, "eventn_ctx_event_id": "e1_0", "parent_eventn_ctx_event_id": "e1", "array_index": int64(0), "sku": "a", "price": 2.5},
+		{"_timestamp": testTime, "eventn_ctx_event_id": "e1_1", "parent_eventn_ctx_event_id": "e1", "array_index": int64(1), "sku": "b", "price": int64(10)},
+	}, actual["events_products"].GetPayload())
+	require.Equal(t, typing.INT64, actual["events_products"].BatchHeader.Fields["array_index"].GetType())
+
+	//elements which aren't objects are written into 'value' field
+	test.ObjectsEqual(t, []map[string]interface{}{
+		{"_timestamp": testTime, "eventn_ctx_event_id": "e1_0", "parent_eventn_ctx_event_id": "e1", "array_index": int64(0), "value": "x"},
+	}, actual["events_order_tags"].GetPayload())
+
+	//already uploaded child tables are skipped
+	actual, _, err = p.ProcessFilePayload("testfile", payload, map[string]bool{"events_products": true}, parsers.ParseJSON)
+	require.NoError(t, err)
+	require.Len(t, actual, 2)
+	require.NotContains(t, actual, "events_products")
+
+	_, err = NewUnnestStep([]string{"/products//items"})
+	require.Error(t, err)
+}
+
+func TestUnnestStepWithoutParentEventID(t *testing.T) {
+	unnestStep, err := NewUnnestStep([]string{"/products"})
+	require.NoError(t, err)
+
+	object := map[string]interface{}{"event_type": "purchase", "products": []interface{}{"a", "b"}}
+	children := unnestStep.Execute(object)
+	require.Len(t, children, 1)
+	require.Len(t, children[0].Objects, 2)
+	require.Equal(t, map[string]interface{}{"event_type": "purchase"}, object)
+
+	ids := map[interface{}]bool{}
+	for i, child := range children[0].Objects {
+		require.NotEmpty(t, child[events.EventnCtxEventID])
+		require.NotContains(t, child, ParentEventIDField)
+		require.Equal(t, int64(i), child[ArrayIndexField])
+		ids[child[events.EventnCtxEventID]] = true
+	}
+	require.Len(t, ids, 2, "child ids must be unique")
+}
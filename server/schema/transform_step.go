@@ -0,0 +1,194 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/jitsucom/jitsu/server/typing"
+)
+
+const (
+	transformFunctionName   = "transform"
+	defaultTransformTimeout = 100 * time.Millisecond
+)
+
+//TransformConfig is a configuration of JavaScript transform step
+//Script must declare function transform(event) which returns an object, an array of objects or null (skip the event)
+type TransformConfig struct {
+	Script    string `mapstructure:"script" json:"script,omitempty" yaml:"script,omitempty"`
+	TimeoutMs int    `mapstructure:"timeout_ms" json:"timeout_ms,omitempty" yaml:"timeout_ms,omitempty"`
+}
+
+//Validate return err if script is empty
+func (tc *TransformConfig) Validate() error {
+	if tc == nil {
+		return errors.New("transform config is required")
+	}
+	if tc.Script == "" {
+		return errors.New("transform script is required parameter")
+	}
+	if tc.TimeoutMs < 0 {
+		return errors.New("transform timeout_ms can't be negative")
+	}
+
+	return nil
+}
+
+//TransformStep executes JavaScript transform function against every event in a sandboxed runtime
+//(without I/O and Node.js modules) with a timeout per event
+//goja.Runtime isn't goroutine-safe so runtimes are reused via sync.Pool
+type TransformStep struct {
+	program  *goja.Program
+	timeout  time.Duration
+	runtimes sync.Pool
+}
+
+//transformRuntime is a goja.Runtime with evaluated script and resolved transform function
+type transformRuntime struct {
+	vm        *goja.Runtime
+	transform goja.Callable
+}
+
+//NewTransformStep return TransformStep with compiled script
+//or nil if config is nil
+func NewTransformStep(config *TransformConfig) (*TransformStep, error) {
+	if config == nil {
+		return nil, nil
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	program, err := goja.Compile(transformFunctionName, config.Script, false)
+	if err != nil {
+		return nil, fmt.Errorf("Error compiling transform script: %v", err)
+	}
+
+	timeout := defaultTransformTimeout
+	if config.TimeoutMs > 0 {
+		timeout = time.Duration(config.TimeoutMs) * time.Millisecond
+	}
+
+	ts := &TransformStep{program: program, timeout: timeout}
+
+	//check that script is runnable and has transform function
+	runtime, err := ts.newRuntime()
+	if err != nil {
+		return nil, err
+	}
+	ts.runtimes.Put(runtime)
+
+	return ts, nil
+}
+
+//Execute run transform function with object copy and return 0, 1 or many result objects
+//or error if script failed, timeout was exceeded or returned value has unsupported type
+func (ts *TransformStep) Execute(object map[string]interface{}) (result []map[string]interface{}, err error) {
+	runtime, err := ts.getRuntime()
+	if err != nil {
+		return nil, err
+	}
+
+	//panic handler
+	defer func() {
+		if r := recover(); r != nil {
+			result = nil
+			err = fmt.Errorf("Error executing transform script: %v", r)
+		}
+	}()
+
+	timer := time.AfterFunc(ts.timeout, func() {
+		runtime.vm.Interrupt(fmt.Sprintf("timeout %s exceeded", ts.timeout))
+	})
+	value, err := runtime.transform(goja.Undefined(), runtime.vm.ToValue(toScriptValue(object)))
+	//runtime might be interrupted after successful execution, don't reuse it in this case
+	if timer.Stop() {
+		ts.runtimes.Put(runtime)
+	}
+
+	if err != nil {
+		if interrupted, ok := err.(*goja.InterruptedError); ok {
+			return nil, fmt.Errorf("Transform script was interrupted: %v", interrupted.Value())
+		}
+		return nil, fmt.Errorf("Error executing transform script: %v", err)
+	}
+
+	return fromScriptValue(value)
+}
+
+func (ts *TransformStep) getRuntime() (*transformRuntime, error) {
+	if runtime, ok := ts.runtimes.Get().(*transformRuntime); ok {
+		return runtime, nil
+	}
+
+	return ts.newRuntime()
+}
+
+func (ts *TransformStep) newRuntime() (*transformRuntime, error) {
+	vm := goja.New()
+	if _, err := vm.RunProgram(ts.program); err != nil {
+		return nil, fmt.Errorf("Error running transform script: %v", err)
+	}
+
+	transform, ok := goja.AssertFunction(vm.Get(transformFunctionName))
+	if !ok {
+		return nil, fmt.Errorf("Transform script must declare function %s(event)", transformFunctionName)
+	}
+
+	return &transformRuntime{vm: vm, transform: transform}, nil
+}
+
+//toScriptValue return deep copy of value with json.Number values converted into int64 or float64
+//so the script can't modify the original object and works with JavaScript numbers
+func toScriptValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		cp := make(map[string]interface{}, len(v))
+		for key, nested := range v {
+			cp[key] = toScriptValue(nested)
+		}
+		return cp
+	case []interface{}:
+		cp := make([]interface{}, len(v))
+		for i, nested := range v {
+			cp[i] = toScriptValue(nested)
+		}
+		return cp
+	default:
+		return typing.ReformatValue(v)
+	}
+}
+
+//fromScriptValue return objects from transform function result:
+//null or undefined - 0 objects, object - 1 object, array of objects - many objects
+func fromScriptValue(value goja.Value) ([]map[string]interface{}, error) {
+	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+		return nil, nil
+	}
+
+	switch exported := value.Export().(type) {
+	case map[string]interface{}:
+		return []map[string]interface{}{exported}, nil
+	case []interface{}:
+		var objects []map[string]interface{}
+		for i, element := range exported {
+			if element == nil {
+				continue
+			}
+			object, ok := element.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("Transform script returned array with non-object element [%d]: %v", i, element)
+			}
+			objects = append(objects, object)
+		}
+		return objects, nil
+	case []map[string]interface{}:
+		return exported, nil
+	default:
+		return nil, fmt.Errorf("Transform script must return an object, an array of objects or null. Returned: %v", exported)
+	}
+}
@@ -0,0 +1,109 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransformStepExecute(t *testing.T) {
+	tests := []struct {
+		name        string
+		script      string
+		input       map[string]interface{}
+		expected    []map[string]interface{}
+		expectedErr string
+	}{
+		{
+			"Return the same event",
+			`function transform(event) { return event }`,
+			map[string]interface{}{"a": "b", "num": json.Number("1"), "nested": map[string]interface{}{"c": json.Number("1.5")}},
+			[]map[string]interface{}{{"a": "b", "num": int64(1), "nested": map[string]interface{}{"c": 1.5}}},
+			"",
+		},
+		{
+			"Return undefined",
+			`function transform(event) {}`,
+			map[string]interface{}{"a": "b"},
+			nil,
+			"",
+		},
+		{
+			"Return array with nulls",
+			`function transform(event) { return [null, {id: 1}, {id: 2}] }`,
+			map[string]interface{}{"a": "b"},
+			[]map[string]interface{}{{"id": int64(1)}, {"id": int64(2)}},
+			"",
+		},
+		{
+			"Return string",
+			`function transform(event) { return "abc" }`,
+			map[string]interface{}{"a": "b"},
+			nil,
+			"Transform script must return an object, an array of objects or null. Returned: abc",
+		},
+		{
+			"Return array of strings",
+			`function transform(event) { return [{}, "abc"] }`,
+			map[string]interface{}{"a": "b"},
+			nil,
+			"Transform script returned array with non-object element [1]: abc",
+		},
+		{
+			"Throw error",
+			`function transform(event) { throw new Error("bad event") }`,
+			map[string]interface{}{"a": "b"},
+			nil,
+			"Error executing transform script: Error: bad event at transform (transform:1:35(3))",
+		},
+		{
+			"Infinite loop",
+			`function transform(event) { while (true) {} }`,
+			map[string]interface{}{"a": "b"},
+			nil,
+			"Transform script was interrupted: timeout 10ms exceeded",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, err := NewTransformStep(&TransformConfig{Script: tt.script, TimeoutMs: 10})
+			require.NoError(t, err)
+
+			actual, err := step.Execute(tt.input)
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestTransformStepDoesntModifyInput(t *testing.T) {
+	step, err := NewTransformStep(&TransformConfig{Script: `function transform(event) { event.a = "changed"; event.nested.b = "changed"; return event }`})
+	require.NoError(t, err)
+
+	input := map[string]interface{}{"a": "a", "nested": map[string]interface{}{"b": "b"}}
+	actual, err := step.Execute(input)
+	require.NoError(t, err)
+	require.Equal(t, []map[string]interface{}{{"a": "changed", "nested": map[string]interface{}{"b": "changed"}}}, actual)
+	require.Equal(t, map[string]interface{}{"a": "a", "nested": map[string]interface{}{"b": "b"}}, input)
+}
+
+func TestNewTransformStep(t *testing.T) {
+	step, err := NewTransformStep(nil)
+	require.NoError(t, err)
+	require.Nil(t, step)
+
+	_, err = NewTransformStep(&TransformConfig{})
+	require.EqualError(t, err, "transform script is required parameter")
+
+	_, err = NewTransformStep(&TransformConfig{Script: `var a = 1`})
+	require.EqualError(t, err, "Transform script must declare function transform(event)")
+
+	_, err = NewTransformStep(&TransformConfig{Script: `function transform(event) {`})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Error compiling transform script")
+}
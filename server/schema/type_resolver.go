@@ -61,6 +61,12 @@ func (tr *TypeResolverImpl) Resolve(object map[string]interface{}) (Fields, erro
 			object[k] = converted
 		}
 
+		//arrays are kept only with native types configuration (see NewNativeTypesFlattener)
+		if resultColumnType == typing.ARRAY {
+			fields[k] = NewArrayField(typing.ArrayElementType(v))
+			continue
+		}
+
 		fields[k] = NewField(resultColumnType)
 	}
 
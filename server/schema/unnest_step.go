@@ -0,0 +1,116 @@
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/jsonutils"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/jitsucom/jitsu/server/uuid"
+)
+
+const (
+	//ParentEventIDField is a child table field with eventn_ctx_event_id of the parent event
+	ParentEventIDField = "parent_" + events.EventnCtxEventID
+	//ArrayIndexField is a child table field with index of the element in the parent event array
+	ArrayIndexField = "array_index"
+	//arrayValueField is a child table field with the element value if the element isn't an object
+	arrayValueField = "value"
+)
+
+//UnnestStep extracts configured arrays from events: every array element becomes an object of the child table
+//<parent table>_<flattened array path> (e.g. events_products)
+//Arrays are extracted before MappingStep: paths are paths of the source event and mappings
+//aren't applied to arrays (child objects are only flattened)
+type UnnestStep struct {
+	arrays []*unnestArray
+}
+
+type unnestArray struct {
+	path        *jsonutils.JSONPath
+	tableSuffix string
+}
+
+//ChildObjects is a result of unnesting of one array: child table name suffix and child objects
+type ChildObjects struct {
+	TableSuffix string
+	Objects     []map[string]interface{}
+}
+
+//NewUnnestStep return UnnestStep with parsed arrays paths
+//or nil if paths are empty
+func NewUnnestStep(paths []string) (*UnnestStep, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	var arrays []*unnestArray
+	for _, path := range paths {
+		//child table name suffix: /key1/key2 -> key1_key2 (the same as Flattener does)
+		var parts []string
+		for _, part := range strings.Split(strings.Trim(path, "/"), "/") {
+			if part == "" {
+				return nil, fmt.Errorf("Error parsing unnest path [%s]: empty path part", path)
+			}
+			parts = append(parts, Reformat(part))
+		}
+
+		arrays = append(arrays, &unnestArray{path: jsonutils.NewJSONPath(path), tableSuffix: strings.Join(parts, "_")})
+	}
+
+	return &UnnestStep{arrays: arrays}, nil
+}
+
+//Execute removes configured arrays from the object and returns their elements as child objects
+//every child object has:
+//unique eventn_ctx_event_id (<parent eventn_ctx_event_id>_<index> or generated uuid if the parent doesn't have eventn_ctx_event_id),
+//parent eventn_ctx_event_id (if exists), array index, parent _timestamp and element fields (or 'value' field if the element isn't an object)
+//values which aren't arrays are left as is
+func (us *UnnestStep) Execute(object map[string]interface{}) []*ChildObjects {
+	var result []*ChildObjects
+	for _, array := range us.arrays {
+		value, ok := array.path.Get(object)
+		if !ok || value == nil || reflect.TypeOf(value).Kind() != reflect.Slice {
+			continue
+		}
+		array.path.GetAndRemove(object)
+
+		parentEventID := events.ExtractEventID(object)
+		parentTimestamp, hasTimestamp := object[timestamp.Key]
+
+		elements := reflect.ValueOf(value)
+		children := &ChildObjects{TableSuffix: array.tableSuffix}
+		for i := 0; i < elements.Len(); i++ {
+			child := map[string]interface{}{}
+			element := elements.Index(i).Interface()
+			if elementObject, ok := element.(map[string]interface{}); ok {
+				for k, v := range elementObject {
+					child[k] = v
+				}
+			} else {
+				child[arrayValueField] = element
+			}
+
+			if parentEventID != "" {
+				child[events.EventnCtxEventID] = fmt.Sprintf("%s_%d", parentEventID, i)
+				child[ParentEventIDField] = parentEventID
+			} else {
+				child[events.EventnCtxEventID] = uuid.New()
+			}
+			child[ArrayIndexField] = int64(i)
+			if hasTimestamp {
+				child[timestamp.Key] = parentTimestamp
+			}
+
+			children.Objects = append(children.Objects, child)
+		}
+
+		if len(children.Objects) > 0 {
+			result = append(result, children)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,179 @@
+package singer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+//Singer catalog replication methods
+const (
+	FullTableReplication   = "FULL_TABLE"
+	IncrementalReplication = "INCREMENTAL"
+)
+
+//Catalog is a result of tap --discover command. It is used as properties/catalog file with selected streams
+type Catalog struct {
+	Streams []*CatalogStream `json:"streams"`
+}
+
+type CatalogStream struct {
+	TapStreamID       string                 `json:"tap_stream_id"`
+	Stream            string                 `json:"stream"`
+	TableName         string                 `json:"table_name,omitempty"`
+	Schema            map[string]interface{} `json:"schema"`
+	KeyProperties     []string               `json:"key_properties,omitempty"`
+	ReplicationKey    string                 `json:"replication_key,omitempty"`
+	ReplicationMethod string                 `json:"replication_method,omitempty"`
+	Metadata          []*Metadata            `json:"metadata,omitempty"`
+}
+
+type Metadata struct {
+	Breadcrumb []string               `json:"breadcrumb"`
+	Metadata   map[string]interface{} `json:"metadata"`
+}
+
+//StreamSelection is a selected stream (by tap_stream_id or stream name) with optional replication key
+//if replication key is set, the stream is synchronized incrementally
+type StreamSelection struct {
+	Stream         string `json:"stream"`
+	ReplicationKey string `json:"replication_key,omitempty"`
+}
+
+//ParseCatalog returns Catalog from tap --discover output
+func ParseCatalog(output []byte) (*Catalog, error) {
+	catalog := &Catalog{}
+	if err := json.Unmarshal(output, catalog); err != nil {
+		return nil, fmt.Errorf("Error unmarshalling singer catalog: %v", err)
+	}
+
+	if catalog.Streams == nil {
+		return nil, fmt.Errorf("Singer catalog doesn't contain 'streams' field: %s", string(output))
+	}
+
+	return catalog, nil
+}
+
+//Select returns a copy of the catalog where only selected streams are marked as selected (in schema and in root metadata)
+//replication key and replication method are set according to selections. Returns err if selected stream or replication key doesn't exist
+func (c *Catalog) Select(selections []*StreamSelection) (*Catalog, error) {
+	selected := map[*CatalogStream]*StreamSelection{}
+	for _, selection := range selections {
+		if selection.Stream == "" {
+			return nil, errors.New("Selected stream name can't be empty")
+		}
+
+		stream, err := c.findStream(selection.Stream)
+		if err != nil {
+			return nil, err
+		}
+		selected[stream] = selection
+	}
+
+	properties := &Catalog{Streams: []*CatalogStream{}}
+	for _, stream := range c.Streams {
+		selection, ok := selected[stream]
+
+		propertiesStream := stream.copy()
+		propertiesStream.Schema["selected"] = ok
+
+		rootMetadata := map[string]interface{}{"selected": ok}
+		if ok {
+			if selection.ReplicationKey != "" {
+				if !stream.hasProperty(selection.ReplicationKey) {
+					return nil, fmt.Errorf("Replication key [%s] doesn't exist in stream [%s] schema", selection.ReplicationKey, selection.Stream)
+				}
+
+				propertiesStream.ReplicationKey = selection.ReplicationKey
+				propertiesStream.ReplicationMethod = IncrementalReplication
+				rootMetadata["replication-key"] = selection.ReplicationKey
+			} else {
+				propertiesStream.ReplicationKey = ""
+				propertiesStream.ReplicationMethod = FullTableReplication
+			}
+			rootMetadata["replication-method"] = propertiesStream.ReplicationMethod
+		}
+
+		propertiesStream.setRootMetadata(rootMetadata)
+		properties.Streams = append(properties.Streams, propertiesStream)
+	}
+
+	return properties, nil
+}
+
+//findStream returns the stream with tap_stream_id equal to name or the only stream with such stream name
+//returns err if the stream doesn't exist or several streams have the same stream name (e.g. tables from different schemas)
+func (c *Catalog) findStream(name string) (*CatalogStream, error) {
+	var streamsByName []*CatalogStream
+	for _, stream := range c.Streams {
+		if stream.TapStreamID == name {
+			return stream, nil
+		}
+		if stream.Stream == name {
+			streamsByName = append(streamsByName, stream)
+		}
+	}
+
+	switch len(streamsByName) {
+	case 0:
+		return nil, fmt.Errorf("Stream [%s] doesn't exist in the catalog", name)
+	case 1:
+		return streamsByName[0], nil
+	default:
+		var tapStreamIDs []string
+		for _, stream := range streamsByName {
+			tapStreamIDs = append(tapStreamIDs, stream.TapStreamID)
+		}
+		return nil, fmt.Errorf("Stream name [%s] is ambiguous. Please select one of streams by tap_stream_id: [%s]", name, strings.Join(tapStreamIDs, ", "))
+	}
+}
+
+//copy returns stream copy with copied schema top level fields and metadata
+func (cs *CatalogStream) copy() *CatalogStream {
+	streamCopy := *cs
+
+	streamCopy.Schema = map[string]interface{}{}
+	for k, v := range cs.Schema {
+		streamCopy.Schema[k] = v
+	}
+
+	streamCopy.Metadata = make([]*Metadata, 0, len(cs.Metadata))
+	for _, metadata := range cs.Metadata {
+		metadataCopy := &Metadata{Breadcrumb: metadata.Breadcrumb, Metadata: map[string]interface{}{}}
+		for k, v := range metadata.Metadata {
+			metadataCopy.Metadata[k] = v
+		}
+		streamCopy.Metadata = append(streamCopy.Metadata, metadataCopy)
+	}
+
+	return &streamCopy
+}
+
+//hasProperty returns true if schema doesn't describe properties or contains the property
+func (cs *CatalogStream) hasProperty(name string) bool {
+	properties, ok := cs.Schema["properties"].(map[string]interface{})
+	if !ok {
+		return true
+	}
+
+	_, ok = properties[name]
+	return ok
+}
+
+//setRootMetadata puts values into metadata with empty breadcrumb (stream level metadata)
+func (cs *CatalogStream) setRootMetadata(values map[string]interface{}) {
+	for _, metadata := range cs.Metadata {
+		if len(metadata.Breadcrumb) == 0 {
+			for k, v := range values {
+				metadata.Metadata[k] = v
+			}
+			if _, ok := values["replication-key"]; !ok {
+				delete(metadata.Metadata, "replication-key")
+			}
+			return
+		}
+	}
+
+	cs.Metadata = append(cs.Metadata, &Metadata{Breadcrumb: []string{}, Metadata: values})
+}
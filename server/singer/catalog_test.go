@@ -0,0 +1,99 @@
+package singer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testDiscoverOutput = `{"streams": [
+  {"tap_stream_id": "users", "stream": "users", "key_properties": ["id"],
+   "schema": {"type": "object", "properties": {"id": {"type": "integer"}, "updated_at": {"type": "string", "format": "date-time"}}},
+   "metadata": [{"breadcrumb": [], "metadata": {"valid-replication-keys": ["updated_at"]}},
+                {"breadcrumb": ["properties", "id"], "metadata": {"inclusion": "automatic"}}]},
+  {"tap_stream_id": "orders", "stream": "orders",
+   "schema": {"type": "object", "properties": {"id": {"type": "integer"}}}}
+]}`
+
+func TestParseCatalog(t *testing.T) {
+	catalog, err := ParseCatalog([]byte(testDiscoverOutput))
+	require.NoError(t, err)
+	require.Len(t, catalog.Streams, 2)
+	require.Equal(t, []string{"id"}, catalog.Streams[0].KeyProperties)
+
+	_, err = ParseCatalog([]byte(`{"type": "object"}`))
+	require.Error(t, err)
+
+	_, err = ParseCatalog([]byte(`INFO starting discovery`))
+	require.Error(t, err)
+}
+
+func TestCatalogSelect(t *testing.T) {
+	catalog, err := ParseCatalog([]byte(testDiscoverOutput))
+	require.NoError(t, err)
+
+	properties, err := catalog.Select([]*StreamSelection{{Stream: "users", ReplicationKey: "updated_at"}})
+	require.NoError(t, err)
+	require.Len(t, properties.Streams, 2)
+
+	users := properties.Streams[0]
+	require.Equal(t, true, users.Schema["selected"])
+	require.Equal(t, "updated_at", users.ReplicationKey)
+	require.Equal(t, IncrementalReplication, users.ReplicationMethod)
+	require.Len(t, users.Metadata, 2)
+	require.Equal(t, map[string]interface{}{
+		"valid-replication-keys": []interface{}{"updated_at"},
+		"selected":               true,
+		"replication-key":        "updated_at",
+		"replication-method":     IncrementalReplication,
+	}, users.Metadata[0].Metadata)
+
+	orders := properties.Streams[1]
+	require.Equal(t, false, orders.Schema["selected"])
+	require.Equal(t, []*Metadata{{Breadcrumb: []string{}, Metadata: map[string]interface{}{"selected": false}}}, orders.Metadata)
+
+	//source catalog isn't changed
+	require.NotContains(t, catalog.Streams[0].Schema, "selected")
+	require.Len(t, catalog.Streams[0].Metadata[0].Metadata, 1)
+	require.Empty(t, catalog.Streams[1].Metadata)
+
+	properties, err = catalog.Select([]*StreamSelection{{Stream: "orders"}})
+	require.NoError(t, err)
+	require.Equal(t, FullTableReplication, properties.Streams[1].ReplicationMethod)
+	require.Equal(t, "", properties.Streams[1].ReplicationKey)
+
+	_, err = catalog.Select([]*StreamSelection{{Stream: "unknown"}})
+	require.EqualError(t, err, "Stream [unknown] doesn't exist in the catalog")
+
+	_, err = catalog.Select([]*StreamSelection{{Stream: "orders", ReplicationKey: "updated_at"}})
+	require.EqualError(t, err, "Replication key [updated_at] doesn't exist in stream [orders] schema")
+}
+
+func TestCatalogSelectByTapStreamID(t *testing.T) {
+	catalog, err := ParseCatalog([]byte(`{"streams": [
+  {"tap_stream_id": "public-users", "stream": "users", "schema": {"type": "object"}},
+  {"tap_stream_id": "archive-users", "stream": "users", "schema": {"type": "object"}},
+  {"tap_stream_id": "users", "stream": "accounts", "schema": {"type": "object"}}
+]}`))
+	require.NoError(t, err)
+
+	//tap_stream_id has priority over stream name
+	properties, err := catalog.Select([]*StreamSelection{{Stream: "users"}})
+	require.NoError(t, err)
+	require.Equal(t, false, properties.Streams[0].Schema["selected"])
+	require.Equal(t, false, properties.Streams[1].Schema["selected"])
+	require.Equal(t, true, properties.Streams[2].Schema["selected"])
+
+	properties, err = catalog.Select([]*StreamSelection{{Stream: "archive-users"}})
+	require.NoError(t, err)
+	require.Equal(t, false, properties.Streams[0].Schema["selected"])
+	require.Equal(t, true, properties.Streams[1].Schema["selected"])
+	require.Equal(t, false, properties.Streams[2].Schema["selected"])
+
+	_, err = catalog.Select([]*StreamSelection{{Stream: "accounts"}})
+	require.NoError(t, err)
+
+	catalog.Streams = catalog.Streams[:2]
+	_, err = catalog.Select([]*StreamSelection{{Stream: "users"}})
+	require.EqualError(t, err, "Stream name [users] is ambiguous. Please select one of streams by tap_stream_id: [public-users, archive-users]")
+}
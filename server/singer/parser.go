@@ -41,6 +41,8 @@ type StreamRepresentation struct {
 	BatchHeader *schema.BatchHeader
 	KeyFields   []string
 	Objects     []map[string]interface{}
+	//Overwrite is true if objects of the previous synchronization must be deleted (airbyte overwrite destination sync mode)
+	Overwrite bool
 }
 
 func StreamParseOutput(stdout io.ReadCloser, consumer PortionConsumer, logger logging.TaskLogger) error {
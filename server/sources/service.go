@@ -44,6 +44,11 @@ func NewTestService() *Service {
 	return &Service{}
 }
 
+//NewTestServiceWithSources is used only for tests
+func NewTestServiceWithSources(sources map[string]*Unit) *Service {
+	return &Service{sources: sources, configured: true}
+}
+
 //NewService returns initialized Service instance
 //or error if occurred
 func NewService(ctx context.Context, sources *viper.Viper, sourcesURL string, destinationsService *destinations.Service, metaStorage meta.Storage,
@@ -0,0 +1,157 @@
+package storages
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+//error classes of fallback events which can be used in auto retry policy
+const (
+	ConnectionErrorClass = "connection"
+	TimeoutErrorClass    = "timeout"
+	RateLimitErrorClass  = "rate_limit"
+	ServerErrorClass     = "server"
+	OtherErrorClass      = "other"
+
+	defaultAutoRetryMaxAttempts     = 5
+	defaultAutoRetryInitialDelaySec = 60
+	defaultAutoRetryMaxDelaySec     = 3600
+	defaultAutoRetryMultiplier      = 2.0
+)
+
+//transient error classes are used by default
+var defaultAutoRetryErrorClasses = []string{ConnectionErrorClass, TimeoutErrorClass, RateLimitErrorClass, ServerErrorClass}
+
+//error message substrings (lower case) per error class
+//classes are checked in this order: the first matched class is used
+var errorClassesPatterns = []struct {
+	class    string
+	patterns []string
+}{
+	{TimeoutErrorClass, []string{"timeout", "timed out", "deadline exceeded"}},
+	{ConnectionErrorClass, []string{"connection refused", "connection reset", "connection closed", "bad connection", "broken pipe",
+		"no such host", "network is unreachable", "dial tcp", "eof"}},
+	{RateLimitErrorClass, []string{"rate limit", "ratelimit", "too many requests", "quota exceeded"}},
+	{ServerErrorClass, []string{"internal server error", "bad gateway", "service unavailable", "internal error"}},
+}
+
+//FallbackConfig is a destination fallback files configuration
+type FallbackConfig struct {
+	AutoRetry *AutoRetryConfig `mapstructure:"auto_retry" json:"auto_retry,omitempty" yaml:"auto_retry,omitempty"`
+}
+
+//AutoRetryConfig is a policy of automatic fallback files replaying:
+//next attempt is made after exponentially increased delay if all file errors belong to allowed error classes
+type AutoRetryConfig struct {
+	Enabled         bool     `mapstructure:"enabled" json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	MaxAttempts     int      `mapstructure:"max_attempts" json:"max_attempts,omitempty" yaml:"max_attempts,omitempty"`
+	InitialDelaySec int      `mapstructure:"initial_delay_sec" json:"initial_delay_sec,omitempty" yaml:"initial_delay_sec,omitempty"`
+	MaxDelaySec     int      `mapstructure:"max_delay_sec" json:"max_delay_sec,omitempty" yaml:"max_delay_sec,omitempty"`
+	Multiplier      float64  `mapstructure:"multiplier" json:"multiplier,omitempty" yaml:"multiplier,omitempty"`
+	ErrorClasses    []string `mapstructure:"error_classes" json:"error_classes,omitempty" yaml:"error_classes,omitempty"`
+}
+
+//GetAutoRetry returns auto retry configuration or nil if it isn't enabled
+func (fc *FallbackConfig) GetAutoRetry() *AutoRetryConfig {
+	if fc == nil || !fc.AutoRetry.IsEnabled() {
+		return nil
+	}
+
+	return fc.AutoRetry
+}
+
+func (arc *AutoRetryConfig) IsEnabled() bool {
+	return arc != nil && arc.Enabled
+}
+
+func (arc *AutoRetryConfig) Validate() error {
+	if !arc.IsEnabled() {
+		return nil
+	}
+
+	if arc.MaxAttempts < 0 || arc.InitialDelaySec < 0 || arc.MaxDelaySec < 0 || arc.Multiplier < 0 {
+		return fmt.Errorf("max_attempts, initial_delay_sec, max_delay_sec and multiplier must be positive")
+	}
+
+	if arc.Multiplier > 0 && arc.Multiplier < 1 {
+		return fmt.Errorf("multiplier must be greater than or equal to 1: %v", arc.Multiplier)
+	}
+
+	for _, errorClass := range arc.ErrorClasses {
+		switch errorClass {
+		case ConnectionErrorClass, TimeoutErrorClass, RateLimitErrorClass, ServerErrorClass, OtherErrorClass:
+		default:
+			return fmt.Errorf("unknown error class: %s. Available error classes: [%s, %s, %s, %s, %s]", errorClass,
+				ConnectionErrorClass, TimeoutErrorClass, RateLimitErrorClass, ServerErrorClass, OtherErrorClass)
+		}
+	}
+
+	return nil
+}
+
+//GetMaxAttempts returns configured max attempts or default value (5)
+func (arc *AutoRetryConfig) GetMaxAttempts() int {
+	if arc.MaxAttempts > 0 {
+		return arc.MaxAttempts
+	}
+
+	return defaultAutoRetryMaxAttempts
+}
+
+//Delay returns delay before the next attempt when attemptsMade attempts have been already made:
+//initial_delay_sec * multiplier^attemptsMade but not more than max_delay_sec
+func (arc *AutoRetryConfig) Delay(attemptsMade int) time.Duration {
+	initialDelaySec := arc.InitialDelaySec
+	if initialDelaySec == 0 {
+		initialDelaySec = defaultAutoRetryInitialDelaySec
+	}
+	maxDelaySec := arc.MaxDelaySec
+	if maxDelaySec == 0 {
+		maxDelaySec = defaultAutoRetryMaxDelaySec
+	}
+	multiplier := arc.Multiplier
+	if multiplier == 0 {
+		multiplier = defaultAutoRetryMultiplier
+	}
+
+	delaySec := math.Min(float64(initialDelaySec)*math.Pow(multiplier, float64(attemptsMade)), float64(maxDelaySec))
+	return time.Duration(delaySec) * time.Second
+}
+
+//IsRetryable returns true if all error messages belong to allowed error classes
+//(connection, timeout, rate_limit and server by default)
+func (arc *AutoRetryConfig) IsRetryable(errMsgs ...string) bool {
+	errorClasses := arc.ErrorClasses
+	if len(errorClasses) == 0 {
+		errorClasses = defaultAutoRetryErrorClasses
+	}
+
+	allowed := map[string]bool{}
+	for _, errorClass := range errorClasses {
+		allowed[errorClass] = true
+	}
+
+	for _, errMsg := range errMsgs {
+		if !allowed[ClassifyError(errMsg)] {
+			return false
+		}
+	}
+
+	return true
+}
+
+//ClassifyError returns error class by error message
+func ClassifyError(errMsg string) string {
+	lowerErrMsg := strings.ToLower(errMsg)
+	for _, errorClassPatterns := range errorClassesPatterns {
+		for _, pattern := range errorClassPatterns.patterns {
+			if strings.Contains(lowerErrMsg, pattern) {
+				return errorClassPatterns.class
+			}
+		}
+	}
+
+	return OtherErrorClass
+}
@@ -0,0 +1,47 @@
+package storages
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestAutoRetryDelay(t *testing.T) {
+	defaultConfig := &AutoRetryConfig{Enabled: true}
+	require.Equal(t, 60*time.Second, defaultConfig.Delay(0))
+	require.Equal(t, 120*time.Second, defaultConfig.Delay(1))
+	require.Equal(t, 480*time.Second, defaultConfig.Delay(3))
+	require.Equal(t, time.Hour, defaultConfig.Delay(10))
+	require.Equal(t, 5, defaultConfig.GetMaxAttempts())
+
+	config := &AutoRetryConfig{Enabled: true, MaxAttempts: 3, InitialDelaySec: 10, MaxDelaySec: 100, Multiplier: 3}
+	require.Equal(t, 10*time.Second, config.Delay(0))
+	require.Equal(t, 90*time.Second, config.Delay(2))
+	require.Equal(t, 100*time.Second, config.Delay(3))
+	require.Equal(t, 3, config.GetMaxAttempts())
+}
+
+func TestAutoRetryIsRetryable(t *testing.T) {
+	defaultConfig := &AutoRetryConfig{Enabled: true}
+	require.True(t, defaultConfig.IsRetryable("dial tcp 127.0.0.1:5432: connect: connection refused", "context deadline exceeded"))
+	require.True(t, defaultConfig.IsRetryable("googleapi: Error 429: Too Many Requests"))
+	require.False(t, defaultConfig.IsRetryable("connection refused", `pq: invalid input syntax for type numeric: "abc"`))
+
+	connectionOnly := &AutoRetryConfig{Enabled: true, ErrorClasses: []string{ConnectionErrorClass}}
+	require.True(t, connectionOnly.IsRetryable("read: connection reset by peer"))
+	require.False(t, connectionOnly.IsRetryable("i/o timeout"))
+
+	all := &AutoRetryConfig{Enabled: true, ErrorClasses: []string{ConnectionErrorClass, OtherErrorClass}}
+	require.True(t, all.IsRetryable("unexpected EOF", "column type mismatch"))
+}
+
+func TestAutoRetryValidate(t *testing.T) {
+	var notConfigured *FallbackConfig
+	require.Nil(t, notConfigured.GetAutoRetry())
+	require.Nil(t, (&FallbackConfig{AutoRetry: &AutoRetryConfig{Enabled: false}}).GetAutoRetry())
+
+	require.NoError(t, (&AutoRetryConfig{Enabled: false, ErrorClasses: []string{"unknown"}}).Validate())
+	require.EqualError(t, (&AutoRetryConfig{Enabled: true, ErrorClasses: []string{"unknown"}}).Validate(),
+		"unknown error class: unknown. Available error classes: [connection, timeout, rate_limit, server, other]")
+	require.EqualError(t, (&AutoRetryConfig{Enabled: true, Multiplier: 0.5}).Validate(), "multiplier must be greater than or equal to 1: 0.5")
+}
@@ -71,7 +71,7 @@ func NewBigQuery(config *Config) (Storage, error) {
 		return nil, err
 	}
 
-	tableHelper := NewTableHelper(bigQueryAdapter, config.monitorKeeper, config.pkFields, adapters.SchemaToBigQueryString, config.streamMode, config.maxColumns)
+	tableHelper := NewTableHelper(bigQueryAdapter, config.monitorKeeper, config.pkFields, adapters.SchemaToBigQueryString, config.streamMode, config.maxColumns, config.overflow, config.schemaEvolution, queryLogger)
 
 	bq := &BigQuery{
 		name:           config.name,
@@ -85,7 +85,7 @@ func NewBigQuery(config *Config) (Storage, error) {
 	}
 
 	if config.streamMode {
-		bq.streamingWorker = newStreamingWorker(config.eventQueue, config.processor, bq, config.eventsCache, config.loggerFactory.CreateStreamingArchiveLogger(config.name), tableHelper)
+		bq.streamingWorker = newStreamingWorker(config.eventQueue, config.processor, bq, config.eventsCache, config.loggerFactory.CreateStreamingArchiveLogger(config.name), config.streamBatch, tableHelper)
 		bq.streamingWorker.start()
 	}
 
@@ -102,6 +102,7 @@ func (bq *BigQuery) Insert(dataSchema *adapters.Table, event events.Event) (err
 	if err != nil {
 		return err
 	}
+	bq.tableHelper.AdaptObjects(dbTable, event)
 
 	err = bq.bqAdapter.Insert(dbTable, event)
 
@@ -116,6 +117,7 @@ func (bq *BigQuery) Insert(dataSchema *adapters.Table, event events.Event) (err
 		if err != nil {
 			return err
 		}
+		bq.tableHelper.AdaptObjects(dbTable, event)
 
 		return bq.bqAdapter.Insert(dbTable, event)
 	}
@@ -123,18 +125,18 @@ func (bq *BigQuery) Insert(dataSchema *adapters.Table, event events.Event) (err
 	return nil
 }
 
-//Store call StoreWithParseFunc with parsers.ParseJSON func
+//Store call StoreWithFallback with parsers.ParseJSON func
 func (bq *BigQuery) Store(fileName string, payload []byte, alreadyUploadedTables map[string]bool) (map[string]*StoreResult, int, error) {
-	return bq.StoreWithParseFunc(fileName, payload, alreadyUploadedTables, parsers.ParseJSON)
+	return StoreWithFallback(bq, fileName, payload, alreadyUploadedTables, parsers.ParseJSON)
 }
 
 //StoreWithParseFunc store file from byte payload to BigQuery with processing
-//return result per table, failed events count and err if occurred
+//return result per table, failed events (they aren't written into fallback, see StoreWithFallback) and err if occurred
 func (bq *BigQuery) StoreWithParseFunc(fileName string, payload []byte, alreadyUploadedTables map[string]bool,
-	parseFunc func([]byte) (map[string]interface{}, error)) (map[string]*StoreResult, int, error) {
+	parseFunc func([]byte) (map[string]interface{}, error)) (map[string]*StoreResult, []*events.FailedEvent, error) {
 	flatData, failedEvents, err := bq.processor.ProcessFilePayload(fileName, payload, alreadyUploadedTables, parseFunc)
 	if err != nil {
-		return nil, linesCount(payload), err
+		return nil, nil, err
 	}
 
 	//update cache with failed events
@@ -142,15 +144,11 @@ func (bq *BigQuery) StoreWithParseFunc(fileName string, payload []byte, alreadyU
 		bq.eventsCache.Error(bq.Name(), failedEvent.EventID, failedEvent.Error)
 	}
 
-	storeFailedEvents := true
 	tableResults := map[string]*StoreResult{}
 	for _, fdata := range flatData {
 		table := bq.tableHelper.MapTableSchema(fdata.BatchHeader)
 		err := bq.storeTable(fdata, table)
 		tableResults[table.Name] = &StoreResult{Err: err, RowsCount: fdata.GetPayloadLen()}
-		if err != nil {
-			storeFailedEvents = false
-		}
 
 		//events cache
 		for _, object := range fdata.GetPayload() {
@@ -162,12 +160,7 @@ func (bq *BigQuery) StoreWithParseFunc(fileName string, payload []byte, alreadyU
 		}
 	}
 
-	//store failed events to fallback only if other events have been inserted ok
-	if storeFailedEvents {
-		bq.Fallback(failedEvents...)
-	}
-
-	return tableResults, len(failedEvents), nil
+	return tableResults, failedEvents, nil
 }
 
 //check table schema
@@ -177,6 +170,7 @@ func (bq *BigQuery) storeTable(fdata *schema.ProcessedFile, table *adapters.Tabl
 	if err != nil {
 		return err
 	}
+	bq.tableHelper.AdaptObjects(dbTable, fdata.GetPayload()...)
 
 	b := fdata.GetPayloadBytes(schema.JSONMarshallerInstance)
 	if err := bq.gcsAdapter.UploadBytes(fdata.FileName, b); err != nil {
@@ -226,6 +220,12 @@ func (bq *BigQuery) IsStaging() bool {
 }
 
 func (bq *BigQuery) Close() (multiErr error) {
+	if bq.streamingWorker != nil {
+		if err := bq.streamingWorker.Close(); err != nil {
+			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing streaming worker: %v", bq.Name(), err))
+		}
+	}
+
 	if bq.gcsAdapter != nil {
 		if err := bq.gcsAdapter.Close(); err != nil {
 			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing google cloud storage client: %v", bq.Name(), err))
@@ -236,12 +236,6 @@ func (bq *BigQuery) Close() (multiErr error) {
 		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing BigQuery client: %v", bq.Name(), err))
 	}
 
-	if bq.streamingWorker != nil {
-		if err := bq.streamingWorker.Close(); err != nil {
-			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing streaming worker: %v", bq.Name(), err))
-		}
-	}
-
 	if err := bq.fallbackLogger.Close(); err != nil {
 		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing fallback logger: %v", bq.Name(), err))
 	}
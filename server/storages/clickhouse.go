@@ -34,7 +34,16 @@ func NewClickHouse(config *Config) (Storage, error) {
 		return nil, err
 	}
 
-	tableStatementFactory, err := adapters.NewTableStatementFactory(chConfig)
+	if chConfig.Engine != nil && chConfig.Engine.IsCollapsing() && len(config.pkFields) == 0 {
+		return nil, fmt.Errorf("primary_key_fields must be configured if clickhouse.engine.type is %s", adapters.CollapsingEngine)
+	}
+
+	var pkFields []string
+	for pkField := range config.pkFields {
+		pkFields = append(pkFields, pkField)
+	}
+
+	tableStatementFactory, err := adapters.NewTableStatementFactory(chConfig, pkFields)
 	if err != nil {
 		return nil, err
 	}
@@ -62,7 +71,7 @@ func NewClickHouse(config *Config) (Storage, error) {
 		}
 
 		chAdapters = append(chAdapters, adapter)
-		tableHelpers = append(tableHelpers, NewTableHelper(adapter, config.monitorKeeper, config.pkFields, adapters.SchemaToClickhouse, config.streamMode, config.maxColumns))
+		tableHelpers = append(tableHelpers, NewTableHelper(adapter, config.monitorKeeper, config.pkFields, adapters.SchemaToClickhouse, config.streamMode, config.maxColumns, config.overflow, config.schemaEvolution, queryLogger))
 	}
 
 	ch := &ClickHouse{
@@ -88,7 +97,7 @@ func NewClickHouse(config *Config) (Storage, error) {
 	}
 
 	if config.streamMode {
-		ch.streamingWorker = newStreamingWorker(config.eventQueue, config.processor, ch, config.eventsCache, config.loggerFactory.CreateStreamingArchiveLogger(config.name), tableHelpers...)
+		ch.streamingWorker = newStreamingWorker(config.eventQueue, config.processor, ch, config.eventsCache, config.loggerFactory.CreateStreamingArchiveLogger(config.name), config.streamBatch, tableHelpers...)
 		ch.streamingWorker.start()
 	}
 
@@ -116,6 +125,7 @@ func (ch *ClickHouse) Insert(dataSchema *adapters.Table, event events.Event) (er
 	if err != nil {
 		return err
 	}
+	tableHelper.AdaptObjects(dbSchema, event)
 
 	err = adapter.Insert(dbSchema, event)
 
@@ -130,6 +140,7 @@ func (ch *ClickHouse) Insert(dataSchema *adapters.Table, event events.Event) (er
 		if err != nil {
 			return err
 		}
+		tableHelper.AdaptObjects(dbSchema, event)
 
 		return adapter.Insert(dbSchema, event)
 	}
@@ -137,18 +148,55 @@ func (ch *ClickHouse) Insert(dataSchema *adapters.Table, event events.Event) (er
 	return nil
 }
 
-//Store call StoreWithParseFunc with parsers.ParseJSON func
+//InsertBatch inserts objects in ClickHouse in one statement (1 retry if error)
+//insertion isn't atomic: if the block has been sent, *adapters.PartialInsertError is returned without retry
+func (ch *ClickHouse) InsertBatch(dataSchema *adapters.Table, objects []map[string]interface{}) (err error) {
+	adapter, tableHelper := ch.getAdapters()
+
+	dbSchema, err := tableHelper.EnsureTable(ch.Name(), dataSchema)
+	if err != nil {
+		return err
+	}
+	tableHelper.AdaptObjects(dbSchema, objects...)
+
+	err = adapter.BulkInsert(dbSchema, objects)
+
+	//part of objects might have been inserted: retry might duplicate them
+	if _, ok := err.(*adapters.PartialInsertError); ok {
+		return err
+	}
+
+	//renew current db schema and retry
+	if err != nil {
+		dbSchema, err := tableHelper.RefreshTableSchema(ch.Name(), dataSchema)
+		if err != nil {
+			return err
+		}
+
+		dbSchema, err = tableHelper.EnsureTable(ch.Name(), dataSchema)
+		if err != nil {
+			return err
+		}
+		tableHelper.AdaptObjects(dbSchema, objects...)
+
+		return adapter.BulkInsert(dbSchema, objects)
+	}
+
+	return nil
+}
+
+//Store call StoreWithFallback with parsers.ParseJSON func
 func (ch *ClickHouse) Store(fileName string, payload []byte, alreadyUploadedTables map[string]bool) (map[string]*StoreResult, int, error) {
-	return ch.StoreWithParseFunc(fileName, payload, alreadyUploadedTables, parsers.ParseJSON)
+	return StoreWithFallback(ch, fileName, payload, alreadyUploadedTables, parsers.ParseJSON)
 }
 
 //StoreWithParseFunc store file payload to ClickHouse with processing
-//return result per table, failed events count and err if occurred
+//return result per table, failed events (they aren't written into fallback, see StoreWithFallback) and err if occurred
 func (ch *ClickHouse) StoreWithParseFunc(fileName string, payload []byte, alreadyUploadedTables map[string]bool,
-	parseFunc func([]byte) (map[string]interface{}, error)) (map[string]*StoreResult, int, error) {
+	parseFunc func([]byte) (map[string]interface{}, error)) (map[string]*StoreResult, []*events.FailedEvent, error) {
 	flatData, failedEvents, err := ch.processor.ProcessFilePayload(fileName, payload, alreadyUploadedTables, parseFunc)
 	if err != nil {
-		return nil, linesCount(payload), err
+		return nil, nil, err
 	}
 
 	//update cache with failed events
@@ -156,16 +204,12 @@ func (ch *ClickHouse) StoreWithParseFunc(fileName string, payload []byte, alread
 		ch.eventsCache.Error(ch.Name(), failedEvent.EventID, failedEvent.Error)
 	}
 
-	storeFailedEvents := true
 	tableResults := map[string]*StoreResult{}
 	for _, fdata := range flatData {
 		adapter, tableHelper := ch.getAdapters()
 		table := tableHelper.MapTableSchema(fdata.BatchHeader)
 		err := ch.storeTable(adapter, tableHelper, fdata, table)
 		tableResults[table.Name] = &StoreResult{Err: err, RowsCount: fdata.GetPayloadLen()}
-		if err != nil {
-			storeFailedEvents = false
-		}
 
 		//events cache
 		for _, object := range fdata.GetPayload() {
@@ -177,12 +221,7 @@ func (ch *ClickHouse) StoreWithParseFunc(fileName string, payload []byte, alread
 		}
 	}
 
-	//store failed events to fallback only if other events have been inserted ok
-	if storeFailedEvents {
-		ch.Fallback(failedEvents...)
-	}
-
-	return tableResults, len(failedEvents), nil
+	return tableResults, failedEvents, nil
 }
 
 //check table schema
@@ -192,6 +231,7 @@ func (ch *ClickHouse) storeTable(adapter *adapters.ClickHouse, tableHelper *Tabl
 	if err != nil {
 		return err
 	}
+	tableHelper.AdaptObjects(dbSchema, fdata.GetPayload()...)
 
 	if err := adapter.BulkInsert(dbSchema, fdata.GetPayload()); err != nil {
 		return err
@@ -235,6 +275,7 @@ func (ch *ClickHouse) SyncStore(overriddenDataSchema *schema.BatchHeader, object
 		if err != nil {
 			return rowsCount, err
 		}
+		tableHelper.AdaptObjects(dbSchema, data...)
 		if err = adapter.BulkUpdate(dbSchema, data, deleteConditions); err != nil {
 			return rowsCount, err
 		}
@@ -256,6 +297,7 @@ func (ch *ClickHouse) SyncStore(overriddenDataSchema *schema.BatchHeader, object
 		if err != nil {
 			return rowsCount, err
 		}
+		tableHelper.AdaptObjects(dbSchema, fdata.GetPayload()...)
 		err = adapter.BulkUpdate(dbSchema, fdata.GetPayload(), deleteConditions)
 		if err != nil {
 			return rowsCount, err
@@ -265,9 +307,30 @@ func (ch *ClickHouse) SyncStore(overriddenDataSchema *schema.BatchHeader, object
 	return rowsCount, nil
 }
 
+//Update inserts a new version of the object (is used in users recognition)
+//rows with the same primary key fields are deduplicated by ClickHouse engine (ReplacingMergeTree or CollapsingMergeTree)
 func (ch *ClickHouse) Update(object map[string]interface{}) error {
-	_, err := ch.SyncStore(nil, []map[string]interface{}{object}, "")
-	return err
+	flatData, err := ch.processor.ProcessObjects([]map[string]interface{}{object})
+	if err != nil {
+		return err
+	}
+
+	for _, fdata := range flatData {
+		adapter, tableHelper := ch.getAdapters()
+		table := tableHelper.MapTableSchema(fdata.BatchHeader)
+
+		dbSchema, err := tableHelper.EnsureTable(ch.Name(), table)
+		if err != nil {
+			return err
+		}
+		tableHelper.AdaptObjects(dbSchema, fdata.GetPayload()...)
+
+		if err := adapter.Update(dbSchema, fdata.GetPayload()); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 func (ch *ClickHouse) GetUsersRecognition() *UserRecognitionConfiguration {
@@ -287,18 +350,18 @@ func (ch *ClickHouse) IsStaging() bool {
 
 //Close adapters.ClickHouse
 func (ch *ClickHouse) Close() (multiErr error) {
-	for i, adapter := range ch.adapters {
-		if err := adapter.Close(); err != nil {
-			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing clickhouse datasource[%d]: %v", ch.Name(), i, err))
-		}
-	}
-
 	if ch.streamingWorker != nil {
 		if err := ch.streamingWorker.Close(); err != nil {
 			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing streaming worker: %v", ch.Name(), err))
 		}
 	}
 
+	for i, adapter := range ch.adapters {
+		if err := adapter.Close(); err != nil {
+			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing clickhouse datasource[%d]: %v", ch.Name(), i, err))
+		}
+	}
+
 	if err := ch.fallbackLogger.Close(); err != nil {
 		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing fallback logger: %v", ch.Name(), err))
 	}
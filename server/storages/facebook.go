@@ -39,7 +39,7 @@ func NewFacebook(config *Config) (Storage, error) {
 	requestDebugLogger := config.loggerFactory.CreateSQLQueryLogger(config.name)
 	fbAdapter := adapters.NewFacebookConversion(fbConfig, requestDebugLogger)
 
-	tableHelper := NewTableHelper(fbAdapter, config.monitorKeeper, config.pkFields, adapters.SchemaToFacebookConversion, config.streamMode, 0)
+	tableHelper := NewTableHelper(fbAdapter, config.monitorKeeper, config.pkFields, adapters.SchemaToFacebookConversion, config.streamMode, 0, nil, SchemaEvolutionFail, nil)
 
 	fb := &Facebook{
 		name:           config.name,
@@ -51,7 +51,7 @@ func NewFacebook(config *Config) (Storage, error) {
 		staged:         config.destination.Staged,
 	}
 
-	fb.streamingWorker = newStreamingWorker(config.eventQueue, config.processor, fb, config.eventsCache, config.loggerFactory.CreateStreamingArchiveLogger(config.name), tableHelper)
+	fb.streamingWorker = newStreamingWorker(config.eventQueue, config.processor, fb, config.eventsCache, config.loggerFactory.CreateStreamingArchiveLogger(config.name), config.streamBatch, tableHelper)
 	fb.streamingWorker.start()
 
 	return fb, nil
@@ -69,8 +69,8 @@ func (fb *Facebook) Store(fileName string, payload []byte, alreadyUploadedTables
 	return nil, 0, errors.New("Facebook Conversion doesn't support Store() func")
 }
 
-func (fb *Facebook) StoreWithParseFunc(fileName string, payload []byte, skipTables map[string]bool, parseFunc func([]byte) (map[string]interface{}, error)) (map[string]*StoreResult, int, error) {
-	return nil, 0, errors.New("Facebook Conversion doesn't support StoreWithParseFunc() func")
+func (fb *Facebook) StoreWithParseFunc(fileName string, payload []byte, skipTables map[string]bool, parseFunc func([]byte) (map[string]interface{}, error)) (map[string]*StoreResult, []*events.FailedEvent, error) {
+	return nil, nil, errors.New("Facebook Conversion doesn't support StoreWithParseFunc() func")
 }
 
 func (fb *Facebook) SyncStore(overriddenDataSchema *schema.BatchHeader, objects []map[string]interface{}, timeIntervalValue string) (int, error) {
@@ -105,16 +105,16 @@ func (fb *Facebook) IsStaging() bool {
 }
 
 func (fb *Facebook) Close() (multiErr error) {
-	if err := fb.fbAdapter.Close(); err != nil {
-		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing Facebook client: %v", fb.Name(), err))
-	}
-
 	if fb.streamingWorker != nil {
 		if err := fb.streamingWorker.Close(); err != nil {
 			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing streaming worker: %v", fb.Name(), err))
 		}
 	}
 
+	if err := fb.fbAdapter.Close(); err != nil {
+		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing Facebook client: %v", fb.Name(), err))
+	}
+
 	if err := fb.fallbackLogger.Close(); err != nil {
 		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing fallback logger: %v", fb.Name(), err))
 	}
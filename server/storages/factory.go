@@ -32,9 +32,14 @@ type DestinationConfig struct {
 	DataLayout       *DataLayout              `mapstructure:"data_layout" json:"data_layout,omitempty" yaml:"data_layout,omitempty"`
 	UsersRecognition *UsersRecognition        `mapstructure:"users_recognition" json:"users_recognition,omitempty" yaml:"users_recognition,omitempty"`
 	Enrichment       []*enrichment.RuleConfig `mapstructure:"enrichment" json:"enrichment,omitempty" yaml:"enrichment,omitempty"`
+	Filters          *schema.FiltersConfig    `mapstructure:"filters" json:"filters,omitempty" yaml:"filters,omitempty"`
+	Transform        *schema.TransformConfig  `mapstructure:"transform" json:"transform,omitempty" yaml:"transform,omitempty"`
 	Log              *logging.SQLDebugConfig  `mapstructure:"log" json:"log,omitempty" yaml:"log,omitempty"`
 	BreakOnError     bool                     `mapstructure:"break_on_error" json:"break_on_error,omitempty" yaml:"break_on_error,omitempty"`
 	Staged           bool                     `mapstructure:"staged" json:"staged,omitempty" yaml:"staged,omitempty"`
+	Fallback         *FallbackConfig          `mapstructure:"fallback" json:"fallback,omitempty" yaml:"fallback,omitempty"`
+	SchemaEvolution  string                   `mapstructure:"schema_evolution" json:"schema_evolution,omitempty" yaml:"schema_evolution,omitempty"`
+	StreamBatch      *StreamBatchConfig       `mapstructure:"stream_batch" json:"stream_batch,omitempty" yaml:"stream_batch,omitempty"`
 
 	DataSource      *adapters.DataSourceConfig            `mapstructure:"datasource" json:"datasource,omitempty" yaml:"datasource,omitempty"`
 	S3              *adapters.S3Config                    `mapstructure:"s3" json:"s3,omitempty" yaml:"s3,omitempty"`
@@ -43,15 +48,23 @@ type DestinationConfig struct {
 	ClickHouse      *adapters.ClickHouseConfig            `mapstructure:"clickhouse" json:"clickhouse,omitempty" yaml:"clickhouse,omitempty"`
 	Snowflake       *adapters.SnowflakeConfig             `mapstructure:"snowflake" json:"snowflake,omitempty" yaml:"snowflake,omitempty"`
 	Facebook        *adapters.FacebookConversionAPIConfig `mapstructure:"facebook" json:"facebook,omitempty" yaml:"facebook,omitempty"`
+	Kafka           *adapters.KafkaConfig                 `mapstructure:"kafka" json:"kafka,omitempty" yaml:"kafka,omitempty"`
+	WebHook         *adapters.WebHookConfig               `mapstructure:"webhook" json:"webhook,omitempty" yaml:"webhook,omitempty"`
+	File            *adapters.FileConfig                  `mapstructure:"file" json:"file,omitempty" yaml:"file,omitempty"`
+	SQLite          *adapters.SQLiteConfig                `mapstructure:"sqlite" json:"sqlite,omitempty" yaml:"sqlite,omitempty"`
+	Postgres        *adapters.PostgresConfig              `mapstructure:"postgres" json:"postgres,omitempty" yaml:"postgres,omitempty"`
 }
 
 type DataLayout struct {
-	MappingType       schema.FieldMappingType `mapstructure:"mapping_type" json:"mapping_type,omitempty" yaml:"mapping_type,omitempty"`
-	Mapping           []string                `mapstructure:"mapping" json:"mapping,omitempty" yaml:"mapping,omitempty"`
-	Mappings          *schema.Mapping         `mapstructure:"mappings" json:"mappings,omitempty" yaml:"mappings,omitempty"`
-	MaxColumns        int                     `mapstructure:"max_columns" json:"max_columns,omitempty" yaml:"max_columns,omitempty"`
-	TableNameTemplate string                  `mapstructure:"table_name_template" json:"table_name_template,omitempty" yaml:"table_name_template,omitempty"`
-	PrimaryKeyFields  []string                `mapstructure:"primary_key_fields" json:"primary_key_fields,omitempty" yaml:"primary_key_fields,omitempty"`
+	MappingType       schema.FieldMappingType   `mapstructure:"mapping_type" json:"mapping_type,omitempty" yaml:"mapping_type,omitempty"`
+	Mapping           []string                  `mapstructure:"mapping" json:"mapping,omitempty" yaml:"mapping,omitempty"`
+	Mappings          *schema.Mapping           `mapstructure:"mappings" json:"mappings,omitempty" yaml:"mappings,omitempty"`
+	MaxColumns        int                       `mapstructure:"max_columns" json:"max_columns,omitempty" yaml:"max_columns,omitempty"`
+	TableNameTemplate string                    `mapstructure:"table_name_template" json:"table_name_template,omitempty" yaml:"table_name_template,omitempty"`
+	PrimaryKeyFields  []string                  `mapstructure:"primary_key_fields" json:"primary_key_fields,omitempty" yaml:"primary_key_fields,omitempty"`
+	Overflow          *OverflowConfig           `mapstructure:"overflow" json:"overflow,omitempty" yaml:"overflow,omitempty"`
+	NativeTypes       *schema.NativeTypesConfig `mapstructure:"native_types" json:"native_types,omitempty" yaml:"native_types,omitempty"`
+	Unnest            []string                  `mapstructure:"unnest" json:"unnest,omitempty" yaml:"unnest,omitempty"`
 }
 
 type UsersRecognition struct {
@@ -86,12 +99,15 @@ type Config struct {
 	processor        *schema.Processor
 	streamMode       bool
 	maxColumns       int
+	overflow         *Overflow
 	monitorKeeper    MonitorKeeper
 	eventQueue       *events.PersistentQueue
 	eventsCache      *caching.EventsCache
 	loggerFactory    *logging.Factory
 	pkFields         map[string]bool
 	sqlTypeCasts     map[string]string
+	schemaEvolution  string
+	streamBatch      *StreamBatchConfig
 }
 
 type Factory interface {
@@ -198,6 +214,37 @@ func (f *FactoryImpl) Create(name string, destination DestinationConfig) (Storag
 		enrichmentRules = append(enrichmentRules, rule)
 	}
 
+	// ** Filters **
+	filterStep, err := schema.NewFilterStep(destination.Filters)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error creating filters: %v", err)
+	}
+	if filterStep != nil {
+		logging.Infof("[%s] Configured filters: include %d condition(s), exclude %d condition(s)", name, len(destination.Filters.Include), len(destination.Filters.Exclude))
+	}
+
+	// ** Transform script **
+	transformStep, err := schema.NewTransformStep(destination.Transform)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error creating transform step: %v", err)
+	}
+	if transformStep != nil {
+		logging.Infof("[%s] Configured transform script", name)
+	}
+
+	// ** Unnest arrays into child tables **
+	var unnestPaths []string
+	if destination.DataLayout != nil {
+		unnestPaths = destination.DataLayout.Unnest
+	}
+	unnestStep, err := schema.NewUnnestStep(unnestPaths)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error creating unnest step: %v", err)
+	}
+	if unnestStep != nil {
+		logging.Infof("[%s] Configured unnest arrays into child tables: [%s]", name, strings.Join(unnestPaths, ", "))
+	}
+
 	// ** Mapping rules **
 	if len(oldStyleMappings) > 0 {
 		logging.Warnf("\n\t ** [%s] DEPRECATED mapping configuration. Read more about new configuration schema: https://jitsu.com/docs/configuration/schema-and-mappings **\n", name)
@@ -213,6 +260,52 @@ func (f *FactoryImpl) Create(name string, destination DestinationConfig) (Storag
 		return nil, nil, err
 	}
 
+	// ** Fallback auto retry **
+	if destination.Fallback != nil {
+		if err := destination.Fallback.AutoRetry.Validate(); err != nil {
+			return nil, nil, fmt.Errorf("Error validating fallback auto_retry configuration: %v", err)
+		}
+
+		if autoRetry := destination.Fallback.GetAutoRetry(); autoRetry != nil {
+			logging.Infof("[%s] Configured fallback auto retry: max attempts %d", name, autoRetry.GetMaxAttempts())
+		}
+	}
+
+	// ** Stream mode micro-batching **
+	if err := destination.StreamBatch.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("Error validating stream_batch configuration: %v", err)
+	}
+	if destination.StreamBatch.IsEnabled() {
+		if destination.Mode == StreamMode {
+			logging.Infof("[%s] Configured stream micro-batching: max events %d, max latency %s", name, destination.StreamBatch.GetMaxEvents(), destination.StreamBatch.GetMaxLatency())
+		} else {
+			logging.Warnf("[%s] stream_batch configuration is used only in %s mode and will be skipped", name, StreamMode)
+		}
+	}
+
+	// ** Schema evolution **
+	if err := ValidateSchemaEvolution(destination.SchemaEvolution, destination.Type); err != nil {
+		return nil, nil, err
+	}
+	if destination.SchemaEvolution == "" {
+		destination.SchemaEvolution = SchemaEvolutionFail
+	} else {
+		logging.Infof("[%s] uses schema_evolution policy: %s", name, destination.SchemaEvolution)
+	}
+
+	// ** Overflow column **
+	var overflowConfig *OverflowConfig
+	if destination.DataLayout != nil {
+		overflowConfig = destination.DataLayout.Overflow
+	}
+	overflow, err := NewOverflow(overflowConfig)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error creating overflow column configuration: %v", err)
+	}
+	if overflow != nil {
+		logging.Infof("[%s] Configured overflow column: %s", name, overflow.column)
+	}
+
 	//retrospective users recognition
 	var usersRecognitionConfiguration *UserRecognitionConfiguration
 	var globalConfigurationLogMsg string
@@ -238,23 +331,36 @@ func (f *FactoryImpl) Create(name string, destination DestinationConfig) (Storag
 	//duplication data error warning
 	//if global enabled or overridden enabled - check primary key fields
 	//don't process user recognition in this case
-	if (f.globalConfiguration.IsEnabled() || destination.UsersRecognition.IsEnabled()) && (destination.Type == PostgresType || destination.Type == RedshiftType) && len(pkFields) == 0 {
+	if (f.globalConfiguration.IsEnabled() || destination.UsersRecognition.IsEnabled()) && (destination.Type == PostgresType || destination.Type == RedshiftType || destination.Type == MySQLType || destination.Type == SQLiteType) && len(pkFields) == 0 {
 		logging.Errorf("[%s] retrospective users recognition is disabled: primary_key_fields must be configured (otherwise data duplication will occurred)", name)
 		usersRecognitionConfiguration = &UserRecognitionConfiguration{Enabled: false}
 	}
 
 	//Fields shouldn't been flattened in Facebook destination (requests has non-flat structure)
+	//and in Kafka and WebHook destinations (messages and templates use original JSON structure)
 	var flattener schema.Flattener
 	var typeResolver schema.TypeResolver
-	if destination.Type == FacebookType {
+	if destination.Type == FacebookType || destination.Type == KafkaType || destination.Type == WebHookType {
 		flattener = schema.NewDummyFlattener()
 		typeResolver = schema.NewDummyTypeResolver()
+		if unnestStep != nil {
+			logging.Warnf("[%s] data_layout.unnest isn't supported in %s destination and will be skipped", name, destination.Type)
+			unnestStep = nil
+		}
+	} else if destination.DataLayout != nil && destination.DataLayout.NativeTypes != nil {
+		flattener, err = schema.NewNativeTypesFlattener(destination.DataLayout.NativeTypes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Error creating native types flattener: %v", err)
+		}
+		typeResolver = schema.NewTypeResolver()
+		logging.Infof("[%s] keeps arrays as native columns: %t, nested objects as JSON columns: [%s]", name, destination.DataLayout.NativeTypes.Arrays, strings.Join(destination.DataLayout.NativeTypes.Objects, ", "))
 	} else {
 		flattener = schema.NewFlattener()
 		typeResolver = schema.NewTypeResolver()
 	}
 
-	processor, err := schema.NewProcessor(name, tableName, fieldMapper, enrichmentRules, flattener, typeResolver, destination.BreakOnError)
+	processor, err := schema.NewProcessor(name, tableName, fieldMapper, enrichmentRules, flattener, typeResolver, destination.BreakOnError,
+		&schema.ProcessorOptions{FilterStep: filterStep, TransformStep: transformStep, UnnestStep: unnestStep})
 	if err != nil {
 		return nil, nil, err
 	}
@@ -295,12 +401,15 @@ func (f *FactoryImpl) Create(name string, destination DestinationConfig) (Storag
 		processor:        processor,
 		streamMode:       destination.Mode == StreamMode,
 		maxColumns:       maxColumns,
+		overflow:         overflow,
 		monitorKeeper:    f.monitorKeeper,
 		eventQueue:       eventQueue,
 		eventsCache:      f.eventsCache,
 		loggerFactory:    destinationLoggerFactory,
 		pkFields:         pkFields,
 		sqlTypeCasts:     sqlTypeCasts,
+		schemaEvolution:  destination.SchemaEvolution,
+		streamBatch:      destination.StreamBatch,
 	}
 
 	var storageProxy StorageProxy
@@ -311,16 +420,28 @@ func (f *FactoryImpl) Create(name string, destination DestinationConfig) (Storag
 		storageProxy = newProxy(NewBigQuery, storageConfig)
 	case PostgresType:
 		storageProxy = newProxy(NewPostgres, storageConfig)
+	case MySQLType:
+		storageProxy = newProxy(NewMySQL, storageConfig)
 	case ClickHouseType:
 		storageProxy = newProxy(NewClickHouse, storageConfig)
 	case S3Type:
 		storageProxy = newProxy(NewS3, storageConfig)
+	case GCSType:
+		storageProxy = newProxy(NewGoogleCloudStorage, storageConfig)
+	case FileType:
+		storageProxy = newProxy(NewFile, storageConfig)
+	case SQLiteType:
+		storageProxy = newProxy(NewSQLite, storageConfig)
 	case SnowflakeType:
 		storageProxy = newProxy(NewSnowflake, storageConfig)
 	case GoogleAnalyticsType:
 		storageProxy = newProxy(NewGoogleAnalytics, storageConfig)
 	case FacebookType:
 		storageProxy = newProxy(NewFacebook, storageConfig)
+	case KafkaType:
+		storageProxy = newProxy(NewKafka, storageConfig)
+	case WebHookType:
+		storageProxy = newProxy(NewWebHook, storageConfig)
 	default:
 		if eventQueue != nil {
 			eventQueue.Close()
@@ -386,8 +507,12 @@ func isSQLType(destinationType string) bool {
 	return destinationType == RedshiftType ||
 		destinationType == BigQueryType ||
 		destinationType == PostgresType ||
+		destinationType == MySQLType ||
 		destinationType == ClickHouseType ||
 		destinationType == SnowflakeType ||
-		//S3 can be SQL (S3 as intermediate layer)
-		destinationType == S3Type
+		destinationType == SQLiteType ||
+		//S3, GCS and File can be SQL (as intermediate layer)
+		destinationType == S3Type ||
+		destinationType == GCSType ||
+		destinationType == FileType
 }
@@ -0,0 +1,76 @@
+package storages
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/parsers"
+	"github.com/jitsucom/jitsu/server/schema"
+)
+
+//defaultFileKeyTemplate stores every table in a separate directory
+const defaultFileKeyTemplate = "{table}/{file}"
+
+//File Stores files to the local directory in batch mode
+//every batch (rotated log file) is stored as a new file per table
+type File struct {
+	*fileStorage
+}
+
+//NewFile returns configured File storage
+func NewFile(config *Config) (Storage, error) {
+	if config.streamMode {
+		if config.eventQueue != nil {
+			config.eventQueue.Close()
+		}
+		return nil, fmt.Errorf("File destination doesn't support %s mode", StreamMode)
+	}
+	fileConfig := config.destination.File
+	if err := fileConfig.Validate(); err != nil {
+		return nil, err
+	}
+
+	fileAdapter, err := adapters.NewLocalFileStorage(fileConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	keyTemplate := fileConfig.KeyTemplate
+	if keyTemplate == "" {
+		keyTemplate = defaultFileKeyTemplate
+	}
+
+	f := &File{
+		fileStorage: newFileStorage(config, fileAdapter, newFileLayout(fileConfig.Format, fileConfig.Compression, keyTemplate)),
+	}
+
+	return f, nil
+}
+
+func (f *File) Consume(event events.Event, tokenID string) {
+	logging.Errorf("[%s] File storage doesn't support streaming mode", f.Name())
+}
+
+func (f *File) DryRun(payload events.Event) ([]adapters.TableField, error) {
+	return nil, errors.New("File storage does not support dry run functionality")
+}
+
+//Store call StoreWithFallback with parsers.ParseJSON func
+func (f *File) Store(fileName string, payload []byte, alreadyUploadedTables map[string]bool) (map[string]*StoreResult, int, error) {
+	return StoreWithFallback(f, fileName, payload, alreadyUploadedTables, parsers.ParseJSON)
+}
+
+func (f *File) SyncStore(overriddenDataSchema *schema.BatchHeader, objects []map[string]interface{}, timeIntervalValue string) (int, error) {
+	return 0, errors.New("File storage doesn't support sync store")
+}
+
+func (f *File) Update(object map[string]interface{}) error {
+	return errors.New("File storage doesn't support updates")
+}
+
+func (f *File) Type() string {
+	return FileType
+}
@@ -0,0 +1,392 @@
+package storages
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/schema"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/jitsucom/jitsu/server/typing"
+	"github.com/klauspost/compress/zstd"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+const (
+	defaultKeyTemplate = "{file}"
+	logFileExtension   = ".log"
+)
+
+//key template placeholders
+var timeKeyPlaceholders = []string{"{yyyy-mm-dd}", "{yyyy}", "{mm}", "{dd}", "{hour}"}
+
+//fileLayout splits processed data into objects (files) by key template
+//and encodes every object in the format (json, csv or parquet) with compression (gzip or zstd)
+//key template placeholders:
+//{table} - table name, {file} - name of the processed file (with the format extension),
+//{yyyy-mm-dd}, {yyyy}, {mm}, {dd}, {hour} - event _timestamp (UTC) parts
+//e.g. {table}/dt={yyyy-mm-dd}/{hour}/{file}
+type fileLayout struct {
+	format      string
+	compression string
+	keyTemplate string
+}
+
+//layoutObject is a single object (file) of fileLayout
+type layoutObject struct {
+	key       string
+	payload   []byte
+	rowsCount int
+}
+
+func newFileLayout(format, compression, keyTemplate string) *fileLayout {
+	if format == "" {
+		format = adapters.JSONFileFormat
+	}
+	if keyTemplate == "" {
+		keyTemplate = defaultKeyTemplate
+	}
+
+	return &fileLayout{format: format, compression: compression, keyTemplate: keyTemplate}
+}
+
+//objects returns encoded objects of the processed data grouped by keys
+//if the key template doesn't contain {table} placeholder and there are several tables in the batch (multipleTables = true),
+//the table name is added to the {file} value, so objects of different tables never overwrite each other
+func (fl *fileLayout) objects(fileName string, fdata *schema.ProcessedFile, multipleTables bool) ([]*layoutObject, error) {
+	tableName := fdata.BatchHeader.TableName
+	appendTable := multipleTables && !strings.Contains(fl.keyTemplate, "{table}")
+	file := fl.fileName(fileName, tableName, appendTable)
+
+	now := time.Now().UTC()
+	var keys []string
+	objectsByKey := map[string][]map[string]interface{}{}
+	for _, object := range fdata.GetPayload() {
+		eventTime := now
+		if t, ok := object[timestamp.Key].(time.Time); ok {
+			eventTime = t.UTC()
+		}
+
+		key := fl.key(tableName, file, eventTime)
+		if _, ok := objectsByKey[key]; !ok {
+			keys = append(keys, key)
+		}
+		objectsByKey[key] = append(objectsByKey[key], object)
+	}
+
+	var result []*layoutObject
+	for _, key := range keys {
+		objects := objectsByKey[key]
+		payload, err := fl.encode(fdata.BatchHeader.Fields, objects)
+		if err != nil {
+			return nil, fmt.Errorf("Error encoding object [%s] in %s format: %v", key, fl.format, err)
+		}
+
+		result = append(result, &layoutObject{key: key, payload: payload, rowsCount: len(objects)})
+	}
+
+	return result, nil
+}
+
+//upload encodes table data according to the layout and uploads objects to the stage (S3 or GCS)
+func (fl *fileLayout) upload(stage adapters.Stage, fileName string, fdata *schema.ProcessedFile, multipleTables bool) error {
+	objects, err := fl.objects(fileName, fdata, multipleTables)
+	if err != nil {
+		return err
+	}
+
+	for _, object := range objects {
+		if err := stage.UploadBytes(object.key, object.payload); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//key returns rendered key template
+func (fl *fileLayout) key(tableName, file string, t time.Time) string {
+	if !fl.hasTimePlaceholders() {
+		return strings.NewReplacer("{table}", tableName, "{file}", file).Replace(fl.keyTemplate)
+	}
+
+	return strings.NewReplacer(
+		"{table}", tableName,
+		"{file}", file,
+		"{yyyy-mm-dd}", t.Format(timestamp.DashDayLayout),
+		"{yyyy}", t.Format("2006"),
+		"{mm}", t.Format("01"),
+		"{dd}", t.Format("02"),
+		"{hour}", t.Format("15"),
+	).Replace(fl.keyTemplate)
+}
+
+func (fl *fileLayout) hasTimePlaceholders() bool {
+	for _, placeholder := range timeKeyPlaceholders {
+		if strings.Contains(fl.keyTemplate, placeholder) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//fileName returns file name with format and compression extensions (instead of .log extension)
+//json files without compression keep the original name
+func (fl *fileLayout) fileName(fileName, tableName string, appendTable bool) string {
+	extension := ""
+	if strings.HasSuffix(fileName, logFileExtension) {
+		extension = logFileExtension
+	}
+	base := strings.TrimSuffix(fileName, extension)
+	if appendTable {
+		base += "_" + tableName
+	}
+
+	switch fl.format {
+	case adapters.CSVFileFormat:
+		extension = ".csv"
+	case adapters.ParquetFileFormat:
+		//parquet is compressed internally
+		return base + ".parquet"
+	}
+
+	switch fl.compression {
+	case adapters.GZIPCompression:
+		extension += ".gz"
+	case adapters.ZSTDCompression:
+		extension += ".zst"
+	}
+
+	return base + extension
+}
+
+//encode returns objects serialized in the format with compression
+func (fl *fileLayout) encode(fields schema.Fields, objects []map[string]interface{}) ([]byte, error) {
+	switch fl.format {
+	case adapters.CSVFileFormat:
+		payload, err := encodeCSV(fields, objects)
+		if err != nil {
+			return nil, err
+		}
+		return compress(fl.compression, payload)
+	case adapters.ParquetFileFormat:
+		return encodeParquet(fields, objects, fl.compression)
+	default:
+		payload, err := encodeJSON(objects)
+		if err != nil {
+			return nil, err
+		}
+		return compress(fl.compression, payload)
+	}
+}
+
+//encodeJSON returns objects as newline-delimited JSON
+func encodeJSON(objects []map[string]interface{}) ([]byte, error) {
+	buf := bytes.Buffer{}
+	for i, object := range objects {
+		b, err := schema.JSONMarshallerInstance.Marshal(nil, object)
+		if err != nil {
+			return nil, err
+		}
+
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		buf.Write(b)
+	}
+
+	return buf.Bytes(), nil
+}
+
+//encodeCSV returns objects as CSV with header (sorted fields names)
+func encodeCSV(fields schema.Fields, objects []map[string]interface{}) ([]byte, error) {
+	header := sortedFieldsNames(fields)
+
+	buf := bytes.Buffer{}
+	csvWriter := csv.NewWriter(&buf)
+	if err := csvWriter.Write(header); err != nil {
+		return nil, err
+	}
+
+	for _, object := range objects {
+		record := make([]string, len(header))
+		for i, name := range header {
+			value, err := csvValue(object[name])
+			if err != nil {
+				return nil, fmt.Errorf("Error serializing field [%s]: %v", name, err)
+			}
+			record[i] = value
+		}
+
+		if err := csvWriter.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	csvWriter.Flush()
+	if err := csvWriter.Error(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func csvValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case time.Time:
+		return timestamp.ToISOFormat(v.UTC()), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case int64, int, bool:
+		return fmt.Sprint(v), nil
+	default:
+		b, err := json.Marshal(v)
+		return string(b), err
+	}
+}
+
+//encodeParquet returns objects as parquet file with optional columns typed by fields types
+//compression is applied to parquet pages (snappy is used by default)
+func encodeParquet(fields schema.Fields, objects []map[string]interface{}, compression string) ([]byte, error) {
+	names := sortedFieldsNames(fields)
+	metadata := make([]string, len(names))
+	types := make([]typing.DataType, len(names))
+	for i, name := range names {
+		types[i] = fields[name].GetType()
+		metadata[i] = "name=" + name + ", " + parquetType(types[i]) + ", repetitiontype=OPTIONAL"
+	}
+
+	buf := &bytes.Buffer{}
+	parquetWriter, err := writer.NewCSVWriterFromWriter(metadata, buf, 1)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating parquet writer: %v", err)
+	}
+
+	switch compression {
+	case adapters.GZIPCompression:
+		parquetWriter.CompressionType = parquet.CompressionCodec_GZIP
+	case adapters.ZSTDCompression:
+		parquetWriter.CompressionType = parquet.CompressionCodec_ZSTD
+	default:
+		parquetWriter.CompressionType = parquet.CompressionCodec_SNAPPY
+	}
+
+	for _, object := range objects {
+		record := make([]interface{}, len(names))
+		for i, name := range names {
+			value, err := parquetValue(types[i], object[name])
+			if err != nil {
+				return nil, fmt.Errorf("Error converting field [%s]: %v", name, err)
+			}
+			record[i] = value
+		}
+
+		if err := parquetWriter.Write(record); err != nil {
+			return nil, fmt.Errorf("Error writing parquet record: %v", err)
+		}
+	}
+
+	if err := parquetWriter.WriteStop(); err != nil {
+		return nil, fmt.Errorf("Error finishing parquet file: %v", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+//parquetType returns parquet type metadata by data type
+func parquetType(dataType typing.DataType) string {
+	switch dataType {
+	case typing.BOOL:
+		return "type=BOOLEAN"
+	case typing.INT64:
+		return "type=INT64"
+	case typing.FLOAT64:
+		return "type=DOUBLE"
+	case typing.TIMESTAMP:
+		return "type=INT64, convertedtype=TIMESTAMP_MILLIS"
+	default:
+		return "type=BYTE_ARRAY, convertedtype=UTF8"
+	}
+}
+
+//parquetValue returns value converted to parquet column go type
+func parquetValue(dataType typing.DataType, value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	switch dataType {
+	case typing.BOOL:
+		if v, ok := value.(bool); ok {
+			return v, nil
+		}
+	case typing.INT64:
+		switch v := value.(type) {
+		case int64:
+			return v, nil
+		case int:
+			return int64(v), nil
+		}
+	case typing.FLOAT64:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case int64:
+			return float64(v), nil
+		case int:
+			return float64(v), nil
+		}
+	case typing.TIMESTAMP:
+		if v, ok := value.(time.Time); ok {
+			return v.UnixNano() / int64(time.Millisecond), nil
+		}
+	default:
+		return csvValue(value)
+	}
+
+	return nil, fmt.Errorf("value %v (%T) doesn't match column type %s", value, value, dataType.String())
+}
+
+//compress returns payload compressed by gzip or zstd or payload as is if compression is empty
+func compress(compression string, payload []byte) ([]byte, error) {
+	switch compression {
+	case adapters.GZIPCompression:
+		buf := bytes.Buffer{}
+		gzipWriter := gzip.NewWriter(&buf)
+		if _, err := gzipWriter.Write(payload); err != nil {
+			return nil, err
+		}
+		if err := gzipWriter.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case adapters.ZSTDCompression:
+		encoder, err := zstd.NewWriter(nil)
+		if err != nil {
+			return nil, err
+		}
+		defer encoder.Close()
+		return encoder.EncodeAll(payload, nil), nil
+	default:
+		return payload, nil
+	}
+}
+
+func sortedFieldsNames(fields schema.Fields) []string {
+	names := fields.Header()
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,138 @@
+package storages
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/appconfig"
+	"github.com/jitsucom/jitsu/server/enrichment"
+	"github.com/jitsucom/jitsu/server/parsers"
+	"github.com/jitsucom/jitsu/server/schema"
+	"github.com/klauspost/compress/zstd"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+const testLayoutFileName = "incoming.tok=abc-2021-05-18T11-00-00.000.log"
+
+const testLayoutPayload = `{"event_type":"pageview","_timestamp":"2021-05-18T10:15:00.000000Z","amount":10,"user":{"id":"u1"}}
+{"event_type":"pageview","_timestamp":"2021-05-18T11:20:00.000000Z","amount":12.5,"user":{"id":"u2"}}
+{"event_type":"click","_timestamp":"2021-05-18T10:30:00.000000Z","flag":true}
+`
+
+func processTestLayoutPayload(t *testing.T) map[string]*schema.ProcessedFile {
+	viper.Set("server.log.path", "")
+	require.NoError(t, appconfig.Init(false, ""))
+
+	processor, err := schema.NewProcessor("test", `{{.event_type}}`, &schema.DummyMapper{}, []enrichment.Rule{},
+		schema.NewFlattener(), schema.NewTypeResolver(), false, nil)
+	require.NoError(t, err)
+
+	flatData, failedEvents, err := processor.ProcessFilePayload(testLayoutFileName, []byte(testLayoutPayload), map[string]bool{}, parsers.ParseJSON)
+	require.NoError(t, err)
+	require.Empty(t, failedEvents)
+	require.Len(t, flatData, 2)
+
+	return flatData
+}
+
+func TestFileLayoutKeys(t *testing.T) {
+	flatData := processTestLayoutPayload(t)
+
+	tests := []struct {
+		name           string
+		layout         *fileLayout
+		table          string
+		multipleTables bool
+		expectedKeys   []string
+	}{
+		{
+			"Default layout of a single table keeps file name",
+			newFileLayout("", "", ""),
+			"pageview",
+			false,
+			[]string{testLayoutFileName},
+		},
+		{
+			"Default layout of several tables adds table name",
+			newFileLayout("", "", ""),
+			"click",
+			true,
+			[]string{"incoming.tok=abc-2021-05-18T11-00-00.000_click.log"},
+		},
+		{
+			"Hive partitions by event time",
+			newFileLayout(adapters.CSVFileFormat, adapters.GZIPCompression, "{table}/dt={yyyy-mm-dd}/{hour}/{file}"),
+			"pageview",
+			true,
+			[]string{
+				"pageview/dt=2021-05-18/10/incoming.tok=abc-2021-05-18T11-00-00.000.csv.gz",
+				"pageview/dt=2021-05-18/11/incoming.tok=abc-2021-05-18T11-00-00.000.csv.gz",
+			},
+		},
+		{
+			"Parquet with date parts",
+			newFileLayout(adapters.ParquetFileFormat, adapters.ZSTDCompression, "events/{yyyy}/{mm}/{dd}/{table}_{file}"),
+			"click",
+			true,
+			[]string{"events/2021/05/18/click_incoming.tok=abc-2021-05-18T11-00-00.000.parquet"},
+		},
+		{
+			"JSON with zstd",
+			newFileLayout(adapters.JSONFileFormat, adapters.ZSTDCompression, "{table}/{file}"),
+			"click",
+			true,
+			[]string{"click/incoming.tok=abc-2021-05-18T11-00-00.000.log.zst"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects, err := tt.layout.objects(testLayoutFileName, flatData[tt.table], tt.multipleTables)
+			require.NoError(t, err)
+
+			var keys []string
+			for _, object := range objects {
+				keys = append(keys, object.key)
+			}
+			require.ElementsMatch(t, tt.expectedKeys, keys)
+		})
+	}
+}
+
+func TestFileLayoutEncoding(t *testing.T) {
+	flatData := processTestLayoutPayload(t)
+
+	objects, err := newFileLayout(adapters.JSONFileFormat, adapters.GZIPCompression, "").objects(testLayoutFileName, flatData["click"], false)
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+	require.Equal(t, 1, objects[0].rowsCount)
+	gzipReader, err := gzip.NewReader(bytes.NewReader(objects[0].payload))
+	require.NoError(t, err)
+	decompressed, err := ioutil.ReadAll(gzipReader)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"_timestamp":"2021-05-18T10:30:00Z","event_type":"click","flag":true}`, string(decompressed))
+
+	objects, err = newFileLayout(adapters.CSVFileFormat, adapters.ZSTDCompression, "{table}/{file}").objects(testLayoutFileName, flatData["pageview"], false)
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+	zstdReader, err := zstd.NewReader(nil)
+	require.NoError(t, err)
+	defer zstdReader.Close()
+	decompressed, err = zstdReader.DecodeAll(objects[0].payload, nil)
+	require.NoError(t, err)
+	require.Equal(t, "_timestamp,amount,event_type,user_id\n"+
+		"2021-05-18T10:15:00.000000Z,10,pageview,u1\n"+
+		"2021-05-18T11:20:00.000000Z,12.5,pageview,u2\n", string(decompressed))
+
+	objects, err = newFileLayout(adapters.ParquetFileFormat, "", "").objects(testLayoutFileName, flatData["pageview"], false)
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+	require.Equal(t, 2, objects[0].rowsCount)
+	payload := objects[0].payload
+	require.True(t, len(payload) > 8, "parquet file is too small")
+	require.Equal(t, "PAR1", string(payload[:4]))
+	require.Equal(t, "PAR1", string(payload[len(payload)-4:]))
+}
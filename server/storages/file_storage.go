@@ -0,0 +1,102 @@
+package storages
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/caching"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/schema"
+)
+
+//fileStorage is a common part of batch destinations which store processed files as objects (S3, GoogleCloudStorage, File)
+//objects are encoded and named according to fileLayout and uploaded to the stage
+type fileStorage struct {
+	name           string
+	stage          adapters.Stage
+	fileLayout     *fileLayout
+	processor      *schema.Processor
+	fallbackLogger *logging.AsyncLogger
+	eventsCache    *caching.EventsCache
+	staged         bool
+}
+
+func newFileStorage(config *Config, stage adapters.Stage, fileLayout *fileLayout) *fileStorage {
+	return &fileStorage{
+		name:           config.name,
+		stage:          stage,
+		fileLayout:     fileLayout,
+		processor:      config.processor,
+		fallbackLogger: config.loggerFactory.CreateFailedLogger(config.name),
+		eventsCache:    config.eventsCache,
+		staged:         config.destination.Staged,
+	}
+}
+
+//StoreWithParseFunc file from byte payload to the stage with processing
+//return result per table, failed events (they aren't written into fallback, see StoreWithFallback) and err if occurred
+func (fs *fileStorage) StoreWithParseFunc(fileName string, payload []byte, alreadyUploadedTables map[string]bool,
+	parseFunc func([]byte) (map[string]interface{}, error)) (map[string]*StoreResult, []*events.FailedEvent, error) {
+	flatData, failedEvents, err := fs.processor.ProcessFilePayload(fileName, payload, alreadyUploadedTables, parseFunc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	//update cache with failed events
+	for _, failedEvent := range failedEvents {
+		fs.eventsCache.Error(fs.Name(), failedEvent.EventID, failedEvent.Error)
+	}
+
+	tableResults := map[string]*StoreResult{}
+	for _, fdata := range flatData {
+		err := fs.fileLayout.upload(fs.stage, fileName, fdata, len(flatData) > 1)
+
+		tableResults[fdata.BatchHeader.TableName] = &StoreResult{Err: err, RowsCount: fdata.GetPayloadLen()}
+		if err != nil {
+			logging.Errorf("[%s] Error storing file %s: %v", fs.Name(), fileName, err)
+		}
+
+		//events cache
+		for _, object := range fdata.GetPayload() {
+			if err != nil {
+				fs.eventsCache.Error(fs.Name(), events.ExtractEventID(object), err.Error())
+			}
+		}
+	}
+
+	return tableResults, failedEvents, nil
+}
+
+//Fallback log event with error to fallback logger
+func (fs *fileStorage) Fallback(failedEvents ...*events.FailedEvent) {
+	for _, failedEvent := range failedEvents {
+		fs.fallbackLogger.ConsumeAny(failedEvent)
+	}
+}
+
+func (fs *fileStorage) GetUsersRecognition() *UserRecognitionConfiguration {
+	return disabledRecognitionConfiguration
+}
+
+func (fs *fileStorage) Name() string {
+	return fs.name
+}
+
+func (fs *fileStorage) IsStaging() bool {
+	return fs.staged
+}
+
+//Close closes the stage and fallback logger
+func (fs *fileStorage) Close() (multiErr error) {
+	if err := fs.stage.Close(); err != nil {
+		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing stage: %v", fs.Name(), err))
+	}
+
+	if err := fs.fallbackLogger.Close(); err != nil {
+		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing fallback logger: %v", fs.Name(), err))
+	}
+
+	return
+}
@@ -0,0 +1,68 @@
+package storages
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/appconfig"
+	"github.com/jitsucom/jitsu/server/caching"
+	"github.com/jitsucom/jitsu/server/enrichment"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/jitsucom/jitsu/server/schema"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStore(t *testing.T) {
+	viper.Set("server.log.path", "")
+	require.NoError(t, appconfig.Init(false, ""))
+
+	dir, err := ioutil.TempDir("", "file_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	processor, err := schema.NewProcessor("file_test", `{{.event_type}}`, &schema.DummyMapper{}, []enrichment.Rule{},
+		schema.NewFlattener(), schema.NewTypeResolver(), false, nil)
+	require.NoError(t, err)
+
+	storage, err := NewFile(&Config{
+		ctx:           context.Background(),
+		name:          "file_test",
+		destination:   &DestinationConfig{File: &adapters.FileConfig{Path: filepath.Join(dir, "events"), Format: adapters.CSVFileFormat}},
+		processor:     processor,
+		loggerFactory: logging.NewFactory(filepath.Join(dir, "logs"), 5, false, nil, nil),
+		eventsCache:   caching.NewEventsCache(&meta.Dummy{}, 100),
+	})
+	require.NoError(t, err)
+	defer storage.Close()
+
+	tableResults, failedCount, err := storage.Store(testLayoutFileName, []byte(testLayoutPayload), map[string]bool{})
+	require.NoError(t, err)
+	require.Equal(t, 0, failedCount)
+	require.Len(t, tableResults, 2)
+	for tableName, result := range tableResults {
+		require.NoError(t, result.Err, tableName)
+	}
+
+	//every table is stored in a separate directory by default
+	files, err := adapters.NewLocalFileStorage(&adapters.FileConfig{Path: filepath.Join(dir, "events")})
+	require.NoError(t, err)
+	keys, err := files.ListBucket("")
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{
+		"click/incoming.tok=abc-2021-05-18T11-00-00.000.csv",
+		"pageview/incoming.tok=abc-2021-05-18T11-00-00.000.csv",
+	}, keys)
+
+	content, err := files.GetObject("click/incoming.tok=abc-2021-05-18T11-00-00.000.csv")
+	require.NoError(t, err)
+	require.Equal(t, "_timestamp,event_type,flag\n2021-05-18T10:30:00.000000Z,click,true\n", string(content))
+
+	_, err = files.GetObject("../logs")
+	require.Error(t, err)
+}
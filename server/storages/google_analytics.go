@@ -39,7 +39,7 @@ func NewGoogleAnalytics(config *Config) (Storage, error) {
 	requestDebugLogger := config.loggerFactory.CreateSQLQueryLogger(config.name)
 	gaAdapter := adapters.NewGoogleAnalytics(gaConfig, requestDebugLogger)
 
-	tableHelper := NewTableHelper(gaAdapter, config.monitorKeeper, config.pkFields, adapters.SchemaToGoogleAnalytics, config.streamMode, 0)
+	tableHelper := NewTableHelper(gaAdapter, config.monitorKeeper, config.pkFields, adapters.SchemaToGoogleAnalytics, config.streamMode, 0, nil, SchemaEvolutionFail, nil)
 
 	ga := &GoogleAnalytics{
 		name:           config.name,
@@ -51,7 +51,7 @@ func NewGoogleAnalytics(config *Config) (Storage, error) {
 		staged:         config.destination.Staged,
 	}
 
-	ga.streamingWorker = newStreamingWorker(config.eventQueue, config.processor, ga, config.eventsCache, config.loggerFactory.CreateStreamingArchiveLogger(config.name), tableHelper)
+	ga.streamingWorker = newStreamingWorker(config.eventQueue, config.processor, ga, config.eventsCache, config.loggerFactory.CreateStreamingArchiveLogger(config.name), config.streamBatch, tableHelper)
 	ga.streamingWorker.start()
 
 	return ga, nil
@@ -69,8 +69,8 @@ func (ga *GoogleAnalytics) Store(fileName string, payload []byte, alreadyUploade
 	return nil, 0, errors.New("GoogleAnalytics doesn't support Store() func")
 }
 
-func (ga *GoogleAnalytics) StoreWithParseFunc(fileName string, payload []byte, skipTables map[string]bool, parseFunc func([]byte) (map[string]interface{}, error)) (map[string]*StoreResult, int, error) {
-	return nil, 0, errors.New("GoogleAnalytics doesn't support StoreWithParseFunc() func")
+func (ga *GoogleAnalytics) StoreWithParseFunc(fileName string, payload []byte, skipTables map[string]bool, parseFunc func([]byte) (map[string]interface{}, error)) (map[string]*StoreResult, []*events.FailedEvent, error) {
+	return nil, nil, errors.New("GoogleAnalytics doesn't support StoreWithParseFunc() func")
 }
 
 func (ga *GoogleAnalytics) SyncStore(overriddenDataSchema *schema.BatchHeader, objects []map[string]interface{}, timeIntervalValue string) (int, error) {
@@ -105,16 +105,16 @@ func (ga *GoogleAnalytics) IsStaging() bool {
 }
 
 func (ga *GoogleAnalytics) Close() (multiErr error) {
-	if err := ga.gaAdapter.Close(); err != nil {
-		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing GoogleAnalytics client: %v", ga.Name(), err))
-	}
-
 	if ga.streamingWorker != nil {
 		if err := ga.streamingWorker.Close(); err != nil {
 			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing streaming worker: %v", ga.Name(), err))
 		}
 	}
 
+	if err := ga.gaAdapter.Close(); err != nil {
+		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing GoogleAnalytics client: %v", ga.Name(), err))
+	}
+
 	if err := ga.fallbackLogger.Close(); err != nil {
 		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing fallback logger: %v", ga.Name(), err))
 	}
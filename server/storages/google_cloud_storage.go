@@ -0,0 +1,66 @@
+package storages
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/parsers"
+	"github.com/jitsucom/jitsu/server/schema"
+)
+
+//GoogleCloudStorage Stores files to google cloud storage in batch mode
+type GoogleCloudStorage struct {
+	*fileStorage
+}
+
+func NewGoogleCloudStorage(config *Config) (Storage, error) {
+	if config.streamMode {
+		if config.eventQueue != nil {
+			config.eventQueue.Close()
+		}
+		return nil, fmt.Errorf("Google cloud storage destination doesn't support %s mode", StreamMode)
+	}
+	gConfig := config.destination.Google
+	if err := gConfig.Validate(false); err != nil {
+		return nil, err
+	}
+
+	gcsAdapter, err := adapters.NewGoogleCloudStorage(config.ctx, gConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	gcs := &GoogleCloudStorage{
+		fileStorage: newFileStorage(config, gcsAdapter, newFileLayout(gConfig.Format, gConfig.Compression, gConfig.KeyTemplate)),
+	}
+
+	return gcs, nil
+}
+
+func (gcs *GoogleCloudStorage) Consume(event events.Event, tokenID string) {
+	logging.Errorf("[%s] Google cloud storage doesn't support streaming mode", gcs.Name())
+}
+
+func (gcs *GoogleCloudStorage) DryRun(payload events.Event) ([]adapters.TableField, error) {
+	return nil, errors.New("Google cloud storage does not support dry run functionality")
+}
+
+//Store call StoreWithFallback with parsers.ParseJSON func
+func (gcs *GoogleCloudStorage) Store(fileName string, payload []byte, alreadyUploadedTables map[string]bool) (map[string]*StoreResult, int, error) {
+	return StoreWithFallback(gcs, fileName, payload, alreadyUploadedTables, parsers.ParseJSON)
+}
+
+func (gcs *GoogleCloudStorage) SyncStore(overriddenDataSchema *schema.BatchHeader, objects []map[string]interface{}, timeIntervalValue string) (int, error) {
+	return 0, errors.New("Google cloud storage doesn't support sync store")
+}
+
+func (gcs *GoogleCloudStorage) Update(object map[string]interface{}) error {
+	return errors.New("Google cloud storage doesn't support updates")
+}
+
+func (gcs *GoogleCloudStorage) Type() string {
+	return GCSType
+}
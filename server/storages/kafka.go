@@ -0,0 +1,183 @@
+package storages
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/caching"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/parsers"
+	"github.com/jitsucom/jitsu/server/schema"
+)
+
+//Kafka produces events into Kafka topics in stream and batch modes
+//topic name is a result of data_layout.table_name_template
+type Kafka struct {
+	name            string
+	kafkaAdapter    *adapters.Kafka
+	tableHelper     *TableHelper
+	processor       *schema.Processor
+	streamingWorker *StreamingWorker
+	fallbackLogger  *logging.AsyncLogger
+	eventsCache     *caching.EventsCache
+	staged          bool
+}
+
+//NewKafka return Kafka instance
+//start streaming worker goroutine in stream mode
+func NewKafka(config *Config) (Storage, error) {
+	kafkaConfig := config.destination.Kafka
+	if err := kafkaConfig.Validate(); err != nil {
+		return nil, err
+	}
+
+	requestDebugLogger := config.loggerFactory.CreateSQLQueryLogger(config.name)
+	kafkaAdapter, err := adapters.NewKafka(kafkaConfig, requestDebugLogger)
+	if err != nil {
+		return nil, err
+	}
+
+	tableHelper := NewTableHelper(kafkaAdapter, config.monitorKeeper, config.pkFields, adapters.SchemaToKafka, config.streamMode, 0, nil, SchemaEvolutionFail, nil)
+
+	k := &Kafka{
+		name:           config.name,
+		kafkaAdapter:   kafkaAdapter,
+		tableHelper:    tableHelper,
+		processor:      config.processor,
+		fallbackLogger: config.loggerFactory.CreateFailedLogger(config.name),
+		eventsCache:    config.eventsCache,
+		staged:         config.destination.Staged,
+	}
+
+	if config.streamMode {
+		k.streamingWorker = newStreamingWorker(config.eventQueue, config.processor, k, config.eventsCache, config.loggerFactory.CreateStreamingArchiveLogger(config.name), config.streamBatch, tableHelper)
+		k.streamingWorker.start()
+	}
+
+	return k, nil
+}
+
+func (k *Kafka) DryRun(payload events.Event) ([]adapters.TableField, error) {
+	return dryRun(payload, k.processor, k.tableHelper)
+}
+
+//Insert produce event into the topic with table name
+func (k *Kafka) Insert(table *adapters.Table, event events.Event) (err error) {
+	return k.kafkaAdapter.Send(table.Name, event)
+}
+
+//Store call StoreWithFallback with parsers.ParseJSON func
+func (k *Kafka) Store(fileName string, payload []byte, alreadyUploadedTables map[string]bool) (map[string]*StoreResult, int, error) {
+	return StoreWithFallback(k, fileName, payload, alreadyUploadedTables, parsers.ParseJSON)
+}
+
+//StoreWithParseFunc process file payload and produce all events per topic
+//return result per table, failed events (they aren't written into fallback, see StoreWithFallback) and err if occurred
+func (k *Kafka) StoreWithParseFunc(fileName string, payload []byte, alreadyUploadedTables map[string]bool,
+	parseFunc func([]byte) (map[string]interface{}, error)) (map[string]*StoreResult, []*events.FailedEvent, error) {
+	flatData, failedEvents, err := k.processor.ProcessFilePayload(fileName, payload, alreadyUploadedTables, parseFunc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	//update cache with failed events
+	for _, failedEvent := range failedEvents {
+		k.eventsCache.Error(k.Name(), failedEvent.EventID, failedEvent.Error)
+	}
+
+	tableResults := map[string]*StoreResult{}
+	for _, fdata := range flatData {
+		table := k.tableHelper.MapTableSchema(fdata.BatchHeader)
+		err := k.kafkaAdapter.SendBatch(table.Name, fdata.GetPayload())
+
+		tableResults[table.Name] = &StoreResult{Err: err, RowsCount: fdata.GetPayloadLen()}
+		if err != nil {
+			logging.Errorf("[%s] Error producing file %s to topic [%s]: %v", k.Name(), fileName, table.Name, err)
+		}
+
+		//events cache
+		for _, object := range fdata.GetPayload() {
+			if err != nil {
+				k.eventsCache.Error(k.Name(), events.ExtractEventID(object), err.Error())
+			} else {
+				k.eventsCache.Succeed(k.Name(), events.ExtractEventID(object), object, table)
+			}
+		}
+	}
+
+	return tableResults, failedEvents, nil
+}
+
+//SyncStore process objects and produce them per topic
+//topic name can be overridden by overriddenDataSchema
+func (k *Kafka) SyncStore(overriddenDataSchema *schema.BatchHeader, objects []map[string]interface{}, timeIntervalValue string) (rowsCount int, err error) {
+	flatData, err := k.processor.ProcessObjects(objects)
+	if err != nil {
+		return len(objects), err
+	}
+
+	for _, fdata := range flatData {
+		rowsCount += fdata.GetPayloadLen()
+	}
+
+	for _, fdata := range flatData {
+		topic := fdata.BatchHeader.TableName
+		if overriddenDataSchema != nil && overriddenDataSchema.TableName != "" {
+			topic = overriddenDataSchema.TableName
+		}
+
+		if err := k.kafkaAdapter.SendBatch(topic, fdata.GetPayload()); err != nil {
+			return rowsCount, err
+		}
+	}
+
+	return rowsCount, nil
+}
+
+func (k *Kafka) Update(object map[string]interface{}) error {
+	return errors.New("Kafka doesn't support updates")
+}
+
+func (k *Kafka) GetUsersRecognition() *UserRecognitionConfiguration {
+	return disabledRecognitionConfiguration
+}
+
+//Fallback log event with error to fallback logger
+func (k *Kafka) Fallback(failedEvents ...*events.FailedEvent) {
+	for _, failedEvent := range failedEvents {
+		k.fallbackLogger.ConsumeAny(failedEvent)
+	}
+}
+
+func (k *Kafka) Name() string {
+	return k.name
+}
+
+func (k *Kafka) Type() string {
+	return KafkaType
+}
+
+func (k *Kafka) IsStaging() bool {
+	return k.staged
+}
+
+func (k *Kafka) Close() (multiErr error) {
+	if k.streamingWorker != nil {
+		if err := k.streamingWorker.Close(); err != nil {
+			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing streaming worker: %v", k.Name(), err))
+		}
+	}
+
+	if err := k.kafkaAdapter.Close(); err != nil {
+		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing Kafka producer: %v", k.Name(), err))
+	}
+
+	if err := k.fallbackLogger.Close(); err != nil {
+		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing fallback logger: %v", k.Name(), err))
+	}
+
+	return
+}
@@ -0,0 +1,304 @@
+package storages
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/caching"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/parsers"
+	"github.com/jitsucom/jitsu/server/schema"
+)
+
+//MySQL stores files to MySQL in two modes:
+//batch: (1 file = 1 statement)
+//stream: (1 object = 1 statement)
+type MySQL struct {
+	name                          string
+	adapter                       *adapters.MySQL
+	tableHelper                   *TableHelper
+	processor                     *schema.Processor
+	streamingWorker               *StreamingWorker
+	fallbackLogger                *logging.AsyncLogger
+	eventsCache                   *caching.EventsCache
+	usersRecognitionConfiguration *UserRecognitionConfiguration
+	staged                        bool
+}
+
+//NewMySQL returns configured MySQL storage
+func NewMySQL(config *Config) (Storage, error) {
+	mySQLConfig := config.destination.DataSource
+	if err := mySQLConfig.Validate(); err != nil {
+		return nil, err
+	}
+	//enrich with default parameters
+	if mySQLConfig.Port.String() == "" {
+		mySQLConfig.Port = json.Number("3306")
+		logging.Warnf("[%s] port wasn't provided. Will be used default one: %s", config.name, mySQLConfig.Port.String())
+	}
+
+	queryLogger := config.loggerFactory.CreateSQLQueryLogger(config.name)
+	adapter, err := adapters.NewMySQL(config.ctx, mySQLConfig, queryLogger, config.sqlTypeCasts)
+	if err != nil {
+		return nil, err
+	}
+
+	tableHelper := NewTableHelper(adapter, config.monitorKeeper, config.pkFields, adapters.SchemaToMySQL, config.streamMode, config.maxColumns, config.overflow, config.schemaEvolution, queryLogger)
+
+	m := &MySQL{
+		name:                          config.name,
+		adapter:                       adapter,
+		tableHelper:                   tableHelper,
+		processor:                     config.processor,
+		fallbackLogger:                config.loggerFactory.CreateFailedLogger(config.name),
+		eventsCache:                   config.eventsCache,
+		usersRecognitionConfiguration: config.usersRecognition,
+		staged:                        config.destination.Staged,
+	}
+
+	if config.streamMode {
+		m.streamingWorker = newStreamingWorker(config.eventQueue, config.processor, m, config.eventsCache, config.loggerFactory.CreateStreamingArchiveLogger(config.name), config.streamBatch, tableHelper)
+		m.streamingWorker.start()
+	}
+
+	return m, nil
+}
+
+func (m *MySQL) DryRun(payload events.Event) ([]adapters.TableField, error) {
+	return dryRun(payload, m.processor, m.tableHelper)
+}
+
+//Store call StoreWithFallback with parsers.ParseJSON func
+func (m *MySQL) Store(fileName string, payload []byte, alreadyUploadedTables map[string]bool) (map[string]*StoreResult, int, error) {
+	return StoreWithFallback(m, fileName, payload, alreadyUploadedTables, parsers.ParseJSON)
+}
+
+//StoreWithParseFunc file payload to MySQL with processing
+//return result per table, failed events (they aren't written into fallback, see StoreWithFallback) and err if occurred
+func (m *MySQL) StoreWithParseFunc(fileName string, payload []byte, alreadyUploadedTables map[string]bool,
+	parseFunc func([]byte) (map[string]interface{}, error)) (map[string]*StoreResult, []*events.FailedEvent, error) {
+	flatData, failedEvents, err := m.processor.ProcessFilePayload(fileName, payload, alreadyUploadedTables, parseFunc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	//update cache with failed events
+	for _, failedEvent := range failedEvents {
+		m.eventsCache.Error(m.Name(), failedEvent.EventID, failedEvent.Error)
+	}
+
+	tableResults := map[string]*StoreResult{}
+	for _, fdata := range flatData {
+		table := m.tableHelper.MapTableSchema(fdata.BatchHeader)
+		err := m.storeTable(fdata, table)
+		tableResults[table.Name] = &StoreResult{Err: err, RowsCount: fdata.GetPayloadLen()}
+
+		//events cache
+		for _, object := range fdata.GetPayload() {
+			if err != nil {
+				m.eventsCache.Error(m.Name(), events.ExtractEventID(object), err.Error())
+			} else {
+				m.eventsCache.Succeed(m.Name(), events.ExtractEventID(object), object, table)
+			}
+		}
+	}
+
+	return tableResults, failedEvents, nil
+}
+
+//check table schema
+//and store data into one table
+func (m *MySQL) storeTable(fdata *schema.ProcessedFile, table *adapters.Table) error {
+	dbSchema, err := m.tableHelper.EnsureTable(m.Name(), table)
+	if err != nil {
+		return err
+	}
+	m.tableHelper.AdaptObjects(dbSchema, fdata.GetPayload()...)
+
+	start := time.Now()
+	if err := m.adapter.BulkInsert(dbSchema, fdata.GetPayload()); err != nil {
+		return err
+	}
+	logging.Debugf("[%s] Inserted [%d] rows in [%.2f] seconds", m.Name(), len(fdata.GetPayload()), time.Now().Sub(start).Seconds())
+
+	return nil
+}
+
+//Fallback log event with error to fallback logger
+func (m *MySQL) Fallback(failedEvents ...*events.FailedEvent) {
+	for _, failedEvent := range failedEvents {
+		m.fallbackLogger.ConsumeAny(failedEvent)
+	}
+}
+
+//SyncStore is used in two cases:
+//1. store chunk payload to MySQL with processing
+//2. store recognized users events
+//return rows count and err if can't store
+//or rows count and nil if stored
+func (m *MySQL) SyncStore(overriddenDataSchema *schema.BatchHeader, objects []map[string]interface{}, timeIntervalValue string) (rowsCount int, err error) {
+	flatData, err := m.processor.ProcessObjects(objects)
+	if err != nil {
+		return len(objects), err
+	}
+
+	for _, fdata := range flatData {
+		rowsCount += fdata.GetPayloadLen()
+	}
+
+	deleteConditions := adapters.DeleteByTimeChunkCondition(timeIntervalValue)
+
+	//table schema overridden
+	if overriddenDataSchema != nil && len(overriddenDataSchema.Fields) > 0 {
+		var data []map[string]interface{}
+		//ignore table multiplexing from mapping step
+		for _, fdata := range flatData {
+			data = append(data, fdata.GetPayload()...)
+			//enrich overridden schema with new fields (some system fields or e.g. after lookup step)
+			overriddenDataSchema.Fields.Add(fdata.BatchHeader.Fields)
+		}
+
+		table := m.tableHelper.MapTableSchema(overriddenDataSchema)
+
+		dbSchema, err := m.tableHelper.EnsureTable(m.Name(), table)
+		if err != nil {
+			return rowsCount, err
+		}
+		m.tableHelper.AdaptObjects(dbSchema, data...)
+		if err = m.adapter.BulkUpdate(dbSchema, data, deleteConditions); err != nil {
+			return rowsCount, err
+		}
+
+		return rowsCount, nil
+	}
+
+	//plain flow
+	for _, fdata := range flatData {
+		table := m.tableHelper.MapTableSchema(fdata.BatchHeader)
+
+		//overridden table name and source primary key fields
+		if overriddenDataSchema != nil {
+			if overriddenDataSchema.TableName != "" {
+				table.Name = overriddenDataSchema.TableName
+			}
+			if len(overriddenDataSchema.PKFields) > 0 {
+				table.PKFields = overriddenDataSchema.PKFields
+			}
+		}
+
+		dbSchema, err := m.tableHelper.EnsureTable(m.Name(), table)
+		if err != nil {
+			return rowsCount, err
+		}
+		m.tableHelper.AdaptObjects(dbSchema, fdata.GetPayload()...)
+		start := time.Now()
+		if err = m.adapter.BulkUpdate(dbSchema, fdata.GetPayload(), deleteConditions); err != nil {
+			return rowsCount, err
+		}
+		logging.Debugf("[%s] Inserted [%d] rows in [%.2f] seconds", m.Name(), len(fdata.GetPayload()), time.Now().Sub(start).Seconds())
+	}
+
+	return rowsCount, nil
+}
+
+func (m *MySQL) Update(object map[string]interface{}) error {
+	_, err := m.SyncStore(nil, []map[string]interface{}{object}, "")
+	return err
+}
+
+//Insert event in MySQL (1 retry if error)
+func (m *MySQL) Insert(table *adapters.Table, event events.Event) (err error) {
+	dbTable, err := m.tableHelper.EnsureTable(m.Name(), table)
+	if err != nil {
+		return err
+	}
+	m.tableHelper.AdaptObjects(dbTable, event)
+
+	err = m.adapter.Insert(dbTable, event)
+
+	//renew current db schema and retry
+	if err != nil {
+		dbTable, err := m.tableHelper.RefreshTableSchema(m.Name(), table)
+		if err != nil {
+			return err
+		}
+
+		dbTable, err = m.tableHelper.EnsureTable(m.Name(), table)
+		if err != nil {
+			return err
+		}
+		m.tableHelper.AdaptObjects(dbTable, event)
+
+		return m.adapter.Insert(dbTable, event)
+	}
+
+	return nil
+}
+
+//InsertBatch inserts objects in MySQL in one transaction (1 retry if error)
+func (m *MySQL) InsertBatch(table *adapters.Table, objects []map[string]interface{}) (err error) {
+	dbTable, err := m.tableHelper.EnsureTable(m.Name(), table)
+	if err != nil {
+		return err
+	}
+	m.tableHelper.AdaptObjects(dbTable, objects...)
+
+	err = m.adapter.BulkInsert(dbTable, objects)
+
+	//renew current db schema and retry
+	if err != nil {
+		dbTable, err := m.tableHelper.RefreshTableSchema(m.Name(), table)
+		if err != nil {
+			return err
+		}
+
+		dbTable, err = m.tableHelper.EnsureTable(m.Name(), table)
+		if err != nil {
+			return err
+		}
+		m.tableHelper.AdaptObjects(dbTable, objects...)
+
+		return m.adapter.BulkInsert(dbTable, objects)
+	}
+
+	return nil
+}
+
+func (m *MySQL) GetUsersRecognition() *UserRecognitionConfiguration {
+	return m.usersRecognitionConfiguration
+}
+
+//Close adapters.MySQL
+func (m *MySQL) Close() (multiErr error) {
+	if m.streamingWorker != nil {
+		if err := m.streamingWorker.Close(); err != nil {
+			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing streaming worker: %v", m.Name(), err))
+		}
+	}
+
+	if err := m.adapter.Close(); err != nil {
+		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing mysql datasource: %v", m.Name(), err))
+	}
+
+	if err := m.fallbackLogger.Close(); err != nil {
+		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing fallback logger: %v", m.Name(), err))
+	}
+
+	return
+}
+
+func (m *MySQL) Name() string {
+	return m.name
+}
+
+func (m *MySQL) Type() string {
+	return MySQLType
+}
+
+func (m *MySQL) IsStaging() bool {
+	return m.staged
+}
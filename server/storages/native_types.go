@@ -0,0 +1,157 @@
+package storages
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/typing"
+)
+
+//arrayElementGoTypes are Go types of native array columns elements values per element type
+//arrays of other elements types (e.g. nested objects) are written as arrays of strings
+var arrayElementGoTypes = map[typing.DataType]reflect.Type{
+	typing.STRING:    reflect.TypeOf(""),
+	typing.INT64:     reflect.TypeOf(int64(0)),
+	typing.FLOAT64:   reflect.TypeOf(float64(0)),
+	typing.BOOL:      reflect.TypeOf(false),
+	typing.TIMESTAMP: reflect.TypeOf(time.Time{}),
+}
+
+//adaptNestedValues converts nested objects and arrays (they are kept by schema.Flattener with native types configuration)
+//into values of db columns: arrays of native array columns become typed arrays of the column elements type
+//other nested values (including arrays in JSON or string columns) are serialized into JSON strings
+func (th *TableHelper) adaptNestedValues(dbSchema *adapters.Table, object map[string]interface{}) {
+	for name, value := range object {
+		valueType, err := typing.TypeFromValue(value)
+		if err != nil || (valueType != typing.JSON && valueType != typing.ARRAY) {
+			continue
+		}
+
+		if valueType == typing.ARRAY && th.isArrayColumn(dbSchema, name) {
+			elementType := th.arrayElementType(dbSchema.Columns[name].SQLType)
+			typedArray, err := toTypedArray(elementType, value)
+			if err != nil {
+				logging.Errorf("Error converting [%s] field array elements into %s: %v", name, elementType, err)
+				continue
+			}
+
+			object[name] = typedArray
+			continue
+		}
+
+		jsonValue, err := typing.Convert(typing.STRING, value)
+		if err != nil {
+			logging.Errorf("Error serializing [%s] field value into JSON: %v", name, err)
+			continue
+		}
+
+		object[name] = jsonValue
+	}
+}
+
+//arrayColumnType returns SQL type of native array column with elements of the type
+//e.g. bigint[] in Postgres, Array(Int64) in ClickHouse, REPEATED INTEGER in BigQuery
+//nested objects elements are JSON strings. Destinations without typed arrays use ARRAY type mapping as is (e.g. Snowflake variant)
+func (th *TableHelper) arrayColumnType(elementType typing.DataType) (string, bool) {
+	arrayType, ok := th.columnTypesMapping[typing.ARRAY]
+	if !ok {
+		return "", false
+	}
+
+	if _, ok := arrayElementGoTypes[elementType]; !ok {
+		elementType = typing.STRING
+	}
+	elementSQLType, ok := th.columnTypesMapping[elementType]
+	if !ok {
+		return arrayType, true
+	}
+
+	prefix, _, suffix, ok := splitArrayType(arrayType)
+	if !ok {
+		return arrayType, true
+	}
+
+	return prefix + elementSQLType + suffix, true
+}
+
+//isArrayColumn returns true if db column is a native array column
+func (th *TableHelper) isArrayColumn(dbSchema *adapters.Table, name string) bool {
+	dbColumn, ok := dbSchema.Columns[name]
+	if !ok {
+		return false
+	}
+
+	columnType, ok := th.sqlTypeToDataType(dbColumn.SQLType)
+	return ok && columnType == typing.ARRAY
+}
+
+//arrayElementType returns type of native array SQL type elements (e.g. INT64 for bigint[])
+//returns typing.STRING if elements type isn't supported in native arrays values (see arrayElementGoTypes)
+func (th *TableHelper) arrayElementType(sqlType string) typing.DataType {
+	_, elementSQLType, _, ok := splitArrayType(sqlType)
+	if !ok {
+		return typing.STRING
+	}
+
+	elementType, ok := th.sqlTypeToDataType(elementSQLType)
+	if _, supported := arrayElementGoTypes[elementType]; !ok || !supported {
+		return typing.STRING
+	}
+
+	return elementType
+}
+
+//splitArrayType splits native array SQL type into the wrapper parts and SQL type of elements:
+//bigint[] -> "", bigint, []; Array(Int64) -> Array(, Int64, ); REPEATED INTEGER -> REPEATED, INTEGER, ""
+//returns false if the SQL type isn't a native array type
+func splitArrayType(sqlType string) (prefix, elementSQLType, suffix string, ok bool) {
+	sqlType = strings.TrimSpace(sqlType)
+	t := strings.ToLower(sqlType)
+	switch {
+	case strings.HasSuffix(t, "[]"):
+		return "", sqlType[:len(sqlType)-len("[]")], "[]", true
+	case strings.HasPrefix(t, "array(") && strings.HasSuffix(t, ")"):
+		return sqlType[:len("array(")], sqlType[len("array(") : len(sqlType)-len(")")], ")", true
+	case strings.HasPrefix(t, "repeated "):
+		return sqlType[:len("repeated ")], sqlType[len("repeated "):], "", true
+	default:
+		return "", "", "", false
+	}
+}
+
+//toTypedArray returns array with elements converted into the element type ([]string, []int64, []float64, []bool or []time.Time)
+//nil elements are zero values, nested objects and arrays are JSON strings
+//returns err if an element can't be converted (e.g. string into int64)
+func toTypedArray(elementType typing.DataType, array interface{}) (interface{}, error) {
+	goType, ok := arrayElementGoTypes[elementType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported array elements type %s", elementType)
+	}
+
+	arrayValue := reflect.ValueOf(array)
+	result := reflect.MakeSlice(reflect.SliceOf(goType), 0, arrayValue.Len())
+	for i := 0; i < arrayValue.Len(); i++ {
+		element := typing.ReformatValue(arrayValue.Index(i).Interface())
+		if element == nil {
+			result = reflect.Append(result, reflect.Zero(goType))
+			continue
+		}
+
+		converted, err := typing.Convert(elementType, element)
+		if err != nil {
+			return nil, fmt.Errorf("element [%d]: %v", i, err)
+		}
+
+		convertedValue := reflect.ValueOf(converted)
+		if !convertedValue.Type().ConvertibleTo(goType) {
+			return nil, fmt.Errorf("element [%d]: %v can't be converted into %s", i, converted, goType)
+		}
+		result = reflect.Append(result, convertedValue.Convert(goType))
+	}
+
+	return result.Interface(), nil
+}
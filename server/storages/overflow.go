@@ -0,0 +1,179 @@
+package storages
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/schema"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	"github.com/jitsucom/jitsu/server/typing"
+)
+
+const defaultOverflowColumn = "_unmapped_data"
+
+//OverflowConfig dto for data_layout.overflow configuration
+//fields beyond max_columns limit and fields matching paths are packed into one JSON column instead of separate columns
+type OverflowConfig struct {
+	Enabled bool     `mapstructure:"enabled" json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	Column  string   `mapstructure:"column" json:"column,omitempty" yaml:"column,omitempty"`
+	Paths   []string `mapstructure:"paths" json:"paths,omitempty" yaml:"paths,omitempty"`
+}
+
+//Overflow keeps overflow column name and flattened configured paths
+type Overflow struct {
+	column   string
+	prefixes []string
+}
+
+//NewOverflow returns configured Overflow or nil if overflow isn't enabled
+//returns err if paths are invalid
+func NewOverflow(config *OverflowConfig) (*Overflow, error) {
+	if config == nil || !config.Enabled {
+		return nil, nil
+	}
+
+	column := config.Column
+	if column == "" {
+		column = defaultOverflowColumn
+	}
+
+	var prefixes []string
+	for _, path := range config.Paths {
+		//flat field name of the path: /key1/key2 -> key1_key2 (the same as schema.Flattener does)
+		var parts []string
+		for _, part := range strings.Split(strings.Trim(path, "/"), "/") {
+			if part == "" {
+				return nil, fmt.Errorf("Error parsing overflow path [%s]: empty path part", path)
+			}
+			parts = append(parts, schema.Reformat(part))
+		}
+		prefixes = append(prefixes, strings.Join(parts, "_"))
+	}
+
+	return &Overflow{column: column, prefixes: prefixes}, nil
+}
+
+//matches returns true if flat field name is a configured path or a nested field of a configured path
+func (o *Overflow) matches(fieldName string) bool {
+	for _, prefix := range o.prefixes {
+		if fieldName == prefix || strings.HasPrefix(fieldName, prefix+"_") {
+			return true
+		}
+	}
+
+	return false
+}
+
+//applyOverflow removes columns which should be packed into the overflow column from dataSchema
+//(columns matching configured paths and new columns beyond max_columns limit) and adds the overflow column instead
+//primary key and system columns are the last candidates for packing
+func (th *TableHelper) applyOverflow(dbSchema, dataSchema *adapters.Table) {
+	if th.overflow == nil {
+		return
+	}
+
+	overflowed := false
+	var newColumns []string
+	for name := range dataSchema.Columns {
+		if name == th.overflow.column {
+			continue
+		}
+
+		if th.overflow.matches(name) {
+			delete(dataSchema.Columns, name)
+			overflowed = true
+			continue
+		}
+
+		if _, ok := dbSchema.Columns[name]; !ok {
+			newColumns = append(newColumns, name)
+		}
+	}
+
+	if th.maxColumns > 0 {
+		_, overflowColumnExists := dbSchema.Columns[th.overflow.column]
+		freeColumns := th.maxColumns - len(dbSchema.Columns)
+		if overflowed && !overflowColumnExists {
+			freeColumns--
+		}
+
+		if len(newColumns) > freeColumns {
+			if !overflowed && !overflowColumnExists {
+				freeColumns--
+			}
+			if freeColumns < 0 {
+				freeColumns = 0
+			}
+
+			sort.Slice(newColumns, func(i, j int) bool {
+				iPriority, jPriority := th.isPriorityColumn(newColumns[i]), th.isPriorityColumn(newColumns[j])
+				if iPriority != jPriority {
+					return iPriority
+				}
+				return newColumns[i] < newColumns[j]
+			})
+
+			for _, name := range newColumns[freeColumns:] {
+				delete(dataSchema.Columns, name)
+			}
+			logging.Debugf("Table %s: %d new columns exceed max_columns limit %d and are packed into %s column", dataSchema.Name, len(newColumns)-freeColumns, th.maxColumns, th.overflow.column)
+			overflowed = true
+		}
+	}
+
+	if overflowed {
+		dataSchema.Columns[th.overflow.column] = adapters.Column{SQLType: th.columnTypesMapping[typing.JSON]}
+	}
+}
+
+//isPriorityColumn returns true if column is a primary key or a system column
+func (th *TableHelper) isPriorityColumn(name string) bool {
+	if _, ok := th.pkFields[name]; ok {
+		return true
+	}
+
+	return name == events.EventnCtxEventID || name == timestamp.Key
+}
+
+//packOverflow moves object fields which aren't dbSchema columns (or match configured paths) into the overflow column as JSON
+func (th *TableHelper) packOverflow(dbSchema *adapters.Table, object map[string]interface{}) {
+	if th.overflow == nil {
+		return
+	}
+	if _, ok := dbSchema.Columns[th.overflow.column]; !ok {
+		return
+	}
+
+	packed := map[string]interface{}{}
+	for name, value := range object {
+		if name == th.overflow.column {
+			continue
+		}
+
+		if _, ok := dbSchema.Columns[name]; !ok || th.overflow.matches(name) {
+			packed[name] = value
+			delete(object, name)
+		}
+	}
+
+	if len(packed) == 0 {
+		return
+	}
+
+	b, err := json.Marshal(packed)
+	if err != nil {
+		logging.Errorf("Error marshaling %s overflow column value: %v", th.overflow.column, err)
+		//put fields back
+		for name, value := range packed {
+			object[name] = value
+		}
+		return
+	}
+
+	object[th.overflow.column] = string(b)
+}
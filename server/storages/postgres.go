@@ -12,10 +12,11 @@ import (
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/parsers"
 	"github.com/jitsucom/jitsu/server/schema"
+	"github.com/jitsucom/jitsu/server/timestamp"
 )
 
 //Store files to Postgres in two modes:
-//batch: (1 file = 1 statement)
+//batch: (1 file = 1 statement or COPY for large files)
 //stream: (1 object = 1 statement)
 type Postgres struct {
 	name                          string
@@ -48,8 +49,18 @@ func NewPostgres(config *Config) (Storage, error) {
 		pgConfig.Parameters["connect_timeout"] = "600"
 	}
 
+	if err := config.destination.Postgres.Validate(); err != nil {
+		return nil, err
+	}
+	if err := config.destination.Postgres.ValidatePrimaryKeyFields(config.pkFields); err != nil {
+		return nil, err
+	}
+	if partitioning := config.destination.Postgres.GetPartitioning(); partitioning != "" {
+		logging.Infof("[%s] tables with %s field are partitioned: %s", config.name, timestamp.Key, partitioning)
+	}
+
 	queryLogger := config.loggerFactory.CreateSQLQueryLogger(config.name)
-	adapter, err := adapters.NewPostgres(config.ctx, pgConfig, queryLogger, config.sqlTypeCasts)
+	adapter, err := adapters.NewPostgres(config.ctx, pgConfig, queryLogger, config.sqlTypeCasts, config.destination.Postgres)
 	if err != nil {
 		return nil, err
 	}
@@ -61,7 +72,7 @@ func NewPostgres(config *Config) (Storage, error) {
 		return nil, err
 	}
 
-	tableHelper := NewTableHelper(adapter, config.monitorKeeper, config.pkFields, adapters.SchemaToPostgres, config.streamMode, config.maxColumns)
+	tableHelper := NewTableHelper(adapter, config.monitorKeeper, config.pkFields, adapters.SchemaToPostgres, config.streamMode, config.maxColumns, config.overflow, config.schemaEvolution, queryLogger)
 
 	p := &Postgres{
 		name:                          config.name,
@@ -75,7 +86,7 @@ func NewPostgres(config *Config) (Storage, error) {
 	}
 
 	if config.streamMode {
-		p.streamingWorker = newStreamingWorker(config.eventQueue, config.processor, p, config.eventsCache, config.loggerFactory.CreateStreamingArchiveLogger(config.name), tableHelper)
+		p.streamingWorker = newStreamingWorker(config.eventQueue, config.processor, p, config.eventsCache, config.loggerFactory.CreateStreamingArchiveLogger(config.name), config.streamBatch, tableHelper)
 		p.streamingWorker.start()
 	}
 
@@ -86,18 +97,18 @@ func (p *Postgres) DryRun(payload events.Event) ([]adapters.TableField, error) {
 	return dryRun(payload, p.processor, p.tableHelper)
 }
 
-//Store call StoreWithParseFunc with parsers.ParseJSON func
+//Store call StoreWithFallback with parsers.ParseJSON func
 func (p *Postgres) Store(fileName string, payload []byte, alreadyUploadedTables map[string]bool) (map[string]*StoreResult, int, error) {
-	return p.StoreWithParseFunc(fileName, payload, alreadyUploadedTables, parsers.ParseJSON)
+	return StoreWithFallback(p, fileName, payload, alreadyUploadedTables, parsers.ParseJSON)
 }
 
 //StoreWithParseFunc file payload to Postgres with processing
-//return result per table, failed events count and err if occurred
+//return result per table, failed events (they aren't written into fallback, see StoreWithFallback) and err if occurred
 func (p *Postgres) StoreWithParseFunc(fileName string, payload []byte, alreadyUploadedTables map[string]bool,
-	parseFunc func([]byte) (map[string]interface{}, error)) (map[string]*StoreResult, int, error) {
+	parseFunc func([]byte) (map[string]interface{}, error)) (map[string]*StoreResult, []*events.FailedEvent, error) {
 	flatData, failedEvents, err := p.processor.ProcessFilePayload(fileName, payload, alreadyUploadedTables, parseFunc)
 	if err != nil {
-		return nil, linesCount(payload), err
+		return nil, nil, err
 	}
 
 	//update cache with failed events
@@ -105,15 +116,11 @@ func (p *Postgres) StoreWithParseFunc(fileName string, payload []byte, alreadyUp
 		p.eventsCache.Error(p.Name(), failedEvent.EventID, failedEvent.Error)
 	}
 
-	storeFailedEvents := true
 	tableResults := map[string]*StoreResult{}
 	for _, fdata := range flatData {
 		table := p.tableHelper.MapTableSchema(fdata.BatchHeader)
 		err := p.storeTable(fdata, table)
 		tableResults[table.Name] = &StoreResult{Err: err, RowsCount: fdata.GetPayloadLen()}
-		if err != nil {
-			storeFailedEvents = false
-		}
 
 		//events cache
 		for _, object := range fdata.GetPayload() {
@@ -125,12 +132,7 @@ func (p *Postgres) StoreWithParseFunc(fileName string, payload []byte, alreadyUp
 		}
 	}
 
-	//store failed events to fallback only if other events have been inserted ok
-	if storeFailedEvents {
-		p.Fallback(failedEvents...)
-	}
-
-	return tableResults, len(failedEvents), nil
+	return tableResults, failedEvents, nil
 }
 
 //check table schema
@@ -140,6 +142,7 @@ func (p *Postgres) storeTable(fdata *schema.ProcessedFile, table *adapters.Table
 	if err != nil {
 		return err
 	}
+	p.tableHelper.AdaptObjects(dbSchema, fdata.GetPayload()...)
 
 	start := time.Now()
 	if err := p.adapter.BulkInsert(dbSchema, fdata.GetPayload()); err != nil {
@@ -190,6 +193,7 @@ func (p *Postgres) SyncStore(overriddenDataSchema *schema.BatchHeader, objects [
 		if err != nil {
 			return rowsCount, err
 		}
+		p.tableHelper.AdaptObjects(dbSchema, data...)
 		if err = p.adapter.BulkUpdate(dbSchema, data, deleteConditions); err != nil {
 			return rowsCount, err
 		}
@@ -201,15 +205,21 @@ func (p *Postgres) SyncStore(overriddenDataSchema *schema.BatchHeader, objects [
 	for _, fdata := range flatData {
 		table := p.tableHelper.MapTableSchema(fdata.BatchHeader)
 
-		//overridden table name
-		if overriddenDataSchema != nil && overriddenDataSchema.TableName != "" {
-			table.Name = overriddenDataSchema.TableName
+		//overridden table name and source primary key fields
+		if overriddenDataSchema != nil {
+			if overriddenDataSchema.TableName != "" {
+				table.Name = overriddenDataSchema.TableName
+			}
+			if len(overriddenDataSchema.PKFields) > 0 {
+				table.PKFields = overriddenDataSchema.PKFields
+			}
 		}
 
 		dbSchema, err := p.tableHelper.EnsureTable(p.Name(), table)
 		if err != nil {
 			return rowsCount, err
 		}
+		p.tableHelper.AdaptObjects(dbSchema, fdata.GetPayload()...)
 		start := time.Now()
 		if err = p.adapter.BulkUpdate(dbSchema, fdata.GetPayload(), deleteConditions); err != nil {
 			return rowsCount, err
@@ -231,6 +241,7 @@ func (p *Postgres) Insert(table *adapters.Table, event events.Event) (err error)
 	if err != nil {
 		return err
 	}
+	p.tableHelper.AdaptObjects(dbTable, event)
 
 	err = p.adapter.Insert(dbTable, event)
 
@@ -245,6 +256,7 @@ func (p *Postgres) Insert(table *adapters.Table, event events.Event) (err error)
 		if err != nil {
 			return err
 		}
+		p.tableHelper.AdaptObjects(dbTable, event)
 
 		return p.adapter.Insert(dbTable, event)
 	}
@@ -252,22 +264,51 @@ func (p *Postgres) Insert(table *adapters.Table, event events.Event) (err error)
 	return nil
 }
 
+//InsertBatch inserts objects in Postgres in one transaction (1 retry if error)
+func (p *Postgres) InsertBatch(table *adapters.Table, objects []map[string]interface{}) (err error) {
+	dbTable, err := p.tableHelper.EnsureTable(p.Name(), table)
+	if err != nil {
+		return err
+	}
+	p.tableHelper.AdaptObjects(dbTable, objects...)
+
+	err = p.adapter.BulkInsert(dbTable, objects)
+
+	//renew current db schema and retry
+	if err != nil {
+		dbTable, err := p.tableHelper.RefreshTableSchema(p.Name(), table)
+		if err != nil {
+			return err
+		}
+
+		dbTable, err = p.tableHelper.EnsureTable(p.Name(), table)
+		if err != nil {
+			return err
+		}
+		p.tableHelper.AdaptObjects(dbTable, objects...)
+
+		return p.adapter.BulkInsert(dbTable, objects)
+	}
+
+	return nil
+}
+
 func (p *Postgres) GetUsersRecognition() *UserRecognitionConfiguration {
 	return p.usersRecognitionConfiguration
 }
 
 //Close adapters.Postgres
 func (p *Postgres) Close() (multiErr error) {
-	if err := p.adapter.Close(); err != nil {
-		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing postgres datasource: %v", p.Name(), err))
-	}
-
 	if p.streamingWorker != nil {
 		if err := p.streamingWorker.Close(); err != nil {
 			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing streaming worker: %v", p.Name(), err))
 		}
 	}
 
+	if err := p.adapter.Close(); err != nil {
+		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing postgres datasource: %v", p.Name(), err))
+	}
+
 	if err := p.fallbackLogger.Close(); err != nil {
 		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing fallback logger: %v", p.Name(), err))
 	}
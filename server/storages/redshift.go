@@ -73,7 +73,7 @@ func NewAwsRedshift(config *Config) (Storage, error) {
 		return nil, err
 	}
 
-	tableHelper := NewTableHelper(redshiftAdapter, config.monitorKeeper, config.pkFields, adapters.SchemaToRedshift, config.streamMode, config.maxColumns)
+	tableHelper := NewTableHelper(redshiftAdapter, config.monitorKeeper, config.pkFields, adapters.SchemaToRedshift, config.streamMode, config.maxColumns, config.overflow, config.schemaEvolution, queryLogger)
 
 	ar := &AwsRedshift{
 		name:                          config.name,
@@ -88,7 +88,7 @@ func NewAwsRedshift(config *Config) (Storage, error) {
 	}
 
 	if config.streamMode {
-		ar.streamingWorker = newStreamingWorker(config.eventQueue, config.processor, ar, config.eventsCache, config.loggerFactory.CreateStreamingArchiveLogger(config.name), tableHelper)
+		ar.streamingWorker = newStreamingWorker(config.eventQueue, config.processor, ar, config.eventsCache, config.loggerFactory.CreateStreamingArchiveLogger(config.name), config.streamBatch, tableHelper)
 		ar.streamingWorker.start()
 	}
 
@@ -105,6 +105,7 @@ func (ar *AwsRedshift) Insert(table *adapters.Table, event events.Event) (err er
 	if err != nil {
 		return err
 	}
+	ar.tableHelper.AdaptObjects(dbTable, event)
 
 	err = ar.redshiftAdapter.Insert(dbTable, event)
 
@@ -119,6 +120,7 @@ func (ar *AwsRedshift) Insert(table *adapters.Table, event events.Event) (err er
 		if err != nil {
 			return err
 		}
+		ar.tableHelper.AdaptObjects(dbTable, event)
 
 		return ar.redshiftAdapter.Insert(dbTable, event)
 	}
@@ -126,18 +128,18 @@ func (ar *AwsRedshift) Insert(table *adapters.Table, event events.Event) (err er
 	return nil
 }
 
-//Store call StoreWithParseFunc with parsers.ParseJSON func
+//Store call StoreWithFallback with parsers.ParseJSON func
 func (ar *AwsRedshift) Store(fileName string, payload []byte, alreadyUploadedTables map[string]bool) (map[string]*StoreResult, int, error) {
-	return ar.StoreWithParseFunc(fileName, payload, alreadyUploadedTables, parsers.ParseJSON)
+	return StoreWithFallback(ar, fileName, payload, alreadyUploadedTables, parsers.ParseJSON)
 }
 
 //StoreWithParseFunc file payload to AwsRedshift with processing
-//return result per table, failed events count and err if occurred
+//return result per table, failed events (they aren't written into fallback, see StoreWithFallback) and err if occurred
 func (ar *AwsRedshift) StoreWithParseFunc(fileName string, payload []byte, alreadyUploadedTables map[string]bool,
-	parseFunc func([]byte) (map[string]interface{}, error)) (map[string]*StoreResult, int, error) {
+	parseFunc func([]byte) (map[string]interface{}, error)) (map[string]*StoreResult, []*events.FailedEvent, error) {
 	flatData, failedEvents, err := ar.processor.ProcessFilePayload(fileName, payload, alreadyUploadedTables, parseFunc)
 	if err != nil {
-		return nil, linesCount(payload), err
+		return nil, nil, err
 	}
 
 	//update cache with failed events
@@ -145,15 +147,11 @@ func (ar *AwsRedshift) StoreWithParseFunc(fileName string, payload []byte, alrea
 		ar.eventsCache.Error(ar.Name(), failedEvent.EventID, failedEvent.Error)
 	}
 
-	storeFailedEvents := true
 	tableResults := map[string]*StoreResult{}
 	for _, fdata := range flatData {
 		table := ar.tableHelper.MapTableSchema(fdata.BatchHeader)
 		err := ar.storeTable(fdata, table)
 		tableResults[table.Name] = &StoreResult{Err: err, RowsCount: fdata.GetPayloadLen()}
-		if err != nil {
-			storeFailedEvents = false
-		}
 
 		//events cache
 		for _, object := range fdata.GetPayload() {
@@ -165,12 +163,7 @@ func (ar *AwsRedshift) StoreWithParseFunc(fileName string, payload []byte, alrea
 		}
 	}
 
-	//store failed events to fallback only if other events have been inserted ok
-	if storeFailedEvents {
-		ar.Fallback(failedEvents...)
-	}
-
-	return tableResults, len(failedEvents), nil
+	return tableResults, failedEvents, nil
 }
 
 //check table schema
@@ -180,6 +173,7 @@ func (ar *AwsRedshift) storeTable(fdata *schema.ProcessedFile, table *adapters.T
 	if err != nil {
 		return err
 	}
+	ar.tableHelper.AdaptObjects(dbTable, fdata.GetPayload()...)
 
 	b := fdata.GetPayloadBytes(schema.JSONMarshallerInstance)
 	if err := ar.s3Adapter.UploadBytes(fdata.FileName, b); err != nil {
@@ -209,23 +203,26 @@ func (ar *AwsRedshift) SyncStore(overriddenDataSchema *schema.BatchHeader, objec
 }
 
 func (ar *AwsRedshift) Update(object map[string]interface{}) error {
-	batchHeader, processedObject, err := ar.processor.ProcessEvent(object)
+	envelopes, err := ar.processor.ProcessEvent(object)
 	if err != nil {
 		return err
 	}
 
-	table := ar.tableHelper.MapTableSchema(batchHeader)
+	for _, envelope := range envelopes {
+		table := ar.tableHelper.MapTableSchema(envelope.Header)
 
-	dbSchema, err := ar.tableHelper.EnsureTable(ar.Name(), table)
-	if err != nil {
-		return err
-	}
+		dbSchema, err := ar.tableHelper.EnsureTable(ar.Name(), table)
+		if err != nil {
+			return err
+		}
+		ar.tableHelper.AdaptObjects(dbSchema, envelope.Event)
 
-	start := time.Now()
-	if err = ar.redshiftAdapter.Update(dbSchema, processedObject, events.EventnCtxEventID, events.ExtractEventID(object)); err != nil {
-		return err
+		start := time.Now()
+		if err = ar.redshiftAdapter.Update(dbSchema, envelope.Event, events.EventnCtxEventID, events.ExtractEventID(envelope.Event)); err != nil {
+			return err
+		}
+		logging.Debugf("[%s] Updated 1 row in [%.2f] seconds", ar.Name(), time.Now().Sub(start).Seconds())
 	}
-	logging.Debugf("[%s] Updated 1 row in [%.2f] seconds", ar.Name(), time.Now().Sub(start).Seconds())
 
 	return nil
 }
@@ -247,16 +244,16 @@ func (ar *AwsRedshift) IsStaging() bool {
 }
 
 func (ar *AwsRedshift) Close() (multiErr error) {
-	if err := ar.redshiftAdapter.Close(); err != nil {
-		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing redshift datasource: %v", ar.Name(), err))
-	}
-
 	if ar.streamingWorker != nil {
 		if err := ar.streamingWorker.Close(); err != nil {
 			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing streaming worker: %v", ar.Name(), err))
 		}
 	}
 
+	if err := ar.redshiftAdapter.Close(); err != nil {
+		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing redshift datasource: %v", ar.Name(), err))
+	}
+
 	if err := ar.fallbackLogger.Close(); err != nil {
 		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing fallback logger: %v", ar.Name(), err))
 	}
@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"github.com/jitsucom/jitsu/server/adapters"
-	"github.com/jitsucom/jitsu/server/caching"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/jitsucom/jitsu/server/parsers"
@@ -13,12 +12,7 @@ import (
 
 //S3 Stores files to aws s3 in batch mode
 type S3 struct {
-	name           string
-	s3Adapter      *adapters.S3
-	processor      *schema.Processor
-	fallbackLogger *logging.AsyncLogger
-	eventsCache    *caching.EventsCache
-	staged         bool
+	*fileStorage
 }
 
 func NewS3(config *Config) (Storage, error) {
@@ -39,12 +33,7 @@ func NewS3(config *Config) (Storage, error) {
 	}
 
 	s3 := &S3{
-		name:           config.name,
-		s3Adapter:      s3Adapter,
-		processor:      config.processor,
-		fallbackLogger: config.loggerFactory.CreateFailedLogger(config.name),
-		eventsCache:    config.eventsCache,
-		staged:         config.destination.Staged,
+		fileStorage: newFileStorage(config, s3Adapter, newFileLayout(s3Config.Format, s3Config.Compression, s3Config.KeyTemplate)),
 	}
 
 	return s3, nil
@@ -58,58 +47,9 @@ func (s3 *S3) DryRun(payload events.Event) ([]adapters.TableField, error) {
 	return nil, errors.New("s3 does not support dry run functionality")
 }
 
-//Store call StoreWithParseFunc with parsers.ParseJSON func
+//Store call StoreWithFallback with parsers.ParseJSON func
 func (s3 *S3) Store(fileName string, payload []byte, alreadyUploadedTables map[string]bool) (map[string]*StoreResult, int, error) {
-	return s3.StoreWithParseFunc(fileName, payload, alreadyUploadedTables, parsers.ParseJSON)
-}
-
-//Store file from byte payload to s3 with processing
-//return result per table, failed events count and err if occurred
-func (s3 *S3) StoreWithParseFunc(fileName string, payload []byte, alreadyUploadedTables map[string]bool,
-	parseFunc func([]byte) (map[string]interface{}, error)) (map[string]*StoreResult, int, error) {
-	flatData, failedEvents, err := s3.processor.ProcessFilePayload(fileName, payload, alreadyUploadedTables, parseFunc)
-	if err != nil {
-		return nil, linesCount(payload), err
-	}
-
-	//update cache with failed events
-	for _, failedEvent := range failedEvents {
-		s3.eventsCache.Error(s3.Name(), failedEvent.EventID, failedEvent.Error)
-	}
-
-	storeFailedEvents := true
-	tableResults := map[string]*StoreResult{}
-	for _, fdata := range flatData {
-		b := fdata.GetPayloadBytes(schema.JSONMarshallerInstance)
-		err := s3.s3Adapter.UploadBytes(fileName, b)
-
-		tableResults[fdata.BatchHeader.TableName] = &StoreResult{Err: err, RowsCount: fdata.GetPayloadLen()}
-		if err != nil {
-			logging.Errorf("[%s] Error storing file %s: %v", s3.Name(), fileName, err)
-			storeFailedEvents = false
-		}
-
-		//events cache
-		for _, object := range fdata.GetPayload() {
-			if err != nil {
-				s3.eventsCache.Error(s3.Name(), events.ExtractEventID(object), err.Error())
-			}
-		}
-	}
-
-	//store failed events to fallback only if other events have been inserted ok
-	if storeFailedEvents {
-		s3.Fallback(failedEvents...)
-	}
-
-	return tableResults, len(failedEvents), nil
-}
-
-//Fallback log event with error to fallback logger
-func (s3 *S3) Fallback(failedEvents ...*events.FailedEvent) {
-	for _, failedEvent := range failedEvents {
-		s3.fallbackLogger.ConsumeAny(failedEvent)
-	}
+	return StoreWithFallback(s3, fileName, payload, alreadyUploadedTables, parsers.ParseJSON)
 }
 
 func (s3 *S3) SyncStore(overriddenDataSchema *schema.BatchHeader, objects []map[string]interface{}, timeIntervalValue string) (int, error) {
@@ -120,25 +60,6 @@ func (s3 *S3) Update(object map[string]interface{}) error {
 	return errors.New("S3 doesn't support updates")
 }
 
-func (s3 *S3) GetUsersRecognition() *UserRecognitionConfiguration {
-	return disabledRecognitionConfiguration
-}
-
-func (s3 *S3) Name() string {
-	return s3.name
-}
-
 func (s3 *S3) Type() string {
 	return S3Type
 }
-
-func (s3 *S3) IsStaging() bool {
-	return s3.staged
-}
-
-func (s3 *S3) Close() error {
-	if err := s3.fallbackLogger.Close(); err != nil {
-		return fmt.Errorf("[%s] Error closing fallback logger: %v", s3.Name(), err)
-	}
-	return nil
-}
@@ -0,0 +1,98 @@
+package storages
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/appconfig"
+	"github.com/jitsucom/jitsu/server/caching"
+	"github.com/jitsucom/jitsu/server/enrichment"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/jitsucom/jitsu/server/schema"
+	"github.com/jitsucom/jitsu/server/test"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestS3StoreWithLayout(t *testing.T) {
+	viper.Set("server.log.path", "")
+	require.NoError(t, appconfig.Init(false, ""))
+
+	ctx := context.Background()
+	container, err := test.NewMinioContainer(ctx)
+	require.NoError(t, err)
+	defer container.Close()
+
+	logsDir, err := ioutil.TempDir("", "s3_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(logsDir)
+
+	processor, err := schema.NewProcessor("s3_test", `{{.event_type}}`, &schema.DummyMapper{}, []enrichment.Rule{},
+		schema.NewFlattener(), schema.NewTypeResolver(), false, nil)
+	require.NoError(t, err)
+
+	storage, err := NewS3(&Config{
+		ctx:  ctx,
+		name: "s3_test",
+		destination: &DestinationConfig{S3: &adapters.S3Config{
+			AccessKeyID:    container.AccessKey,
+			SecretKey:      container.SecretKey,
+			Bucket:         container.Bucket,
+			Region:         container.Region,
+			Endpoint:       container.Endpoint(),
+			ForcePathStyle: true,
+			Folder:         "layout",
+			Format:         adapters.JSONFileFormat,
+			Compression:    adapters.GZIPCompression,
+			KeyTemplate:    "{table}/dt={yyyy-mm-dd}/{hour}/{file}",
+		}},
+		processor:     processor,
+		loggerFactory: logging.NewFactory(logsDir, 5, false, nil, nil),
+		eventsCache:   caching.NewEventsCache(&meta.Dummy{}, 100),
+	})
+	require.NoError(t, err)
+	defer storage.Close()
+
+	tableResults, failedCount, err := storage.Store(testLayoutFileName, []byte(testLayoutPayload), map[string]bool{})
+	require.NoError(t, err)
+	require.Equal(t, 0, failedCount)
+	require.Len(t, tableResults, 2)
+	for tableName, result := range tableResults {
+		require.NoError(t, result.Err, tableName)
+	}
+
+	client := container.Client()
+	listOutput, err := client.ListObjectsV2(&s3.ListObjectsV2Input{Bucket: aws.String(container.Bucket), Prefix: aws.String("layout/")})
+	require.NoError(t, err)
+
+	var keys []string
+	for _, object := range listOutput.Contents {
+		keys = append(keys, *object.Key)
+	}
+	require.ElementsMatch(t, []string{
+		"layout/click/dt=2021-05-18/10/incoming.tok=abc-2021-05-18T11-00-00.000.log.gz",
+		"layout/pageview/dt=2021-05-18/10/incoming.tok=abc-2021-05-18T11-00-00.000.log.gz",
+		"layout/pageview/dt=2021-05-18/11/incoming.tok=abc-2021-05-18T11-00-00.000.log.gz",
+	}, keys)
+
+	object, err := client.GetObject(&s3.GetObjectInput{Bucket: aws.String(container.Bucket),
+		Key: aws.String("layout/pageview/dt=2021-05-18/11/incoming.tok=abc-2021-05-18T11-00-00.000.log.gz")})
+	require.NoError(t, err)
+	defer object.Body.Close()
+	compressed, err := ioutil.ReadAll(object.Body)
+	require.NoError(t, err)
+
+	gzipReader, err := gzip.NewReader(bytes.NewReader(compressed))
+	require.NoError(t, err)
+	decompressed, err := ioutil.ReadAll(gzipReader)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"_timestamp":"2021-05-18T11:20:00Z","amount":12.5,"event_type":"pageview","user_id":"u2"}`, string(decompressed))
+}
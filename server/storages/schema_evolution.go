@@ -0,0 +1,290 @@
+package storages
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/typing"
+)
+
+const (
+	//SchemaEvolutionFail keeps column types as is: inserts of conflicting values fail (default)
+	SchemaEvolutionFail = "fail"
+	//SchemaEvolutionWiden changes column type to the common supertype of the column type and the value type
+	SchemaEvolutionWiden = "widen"
+	//SchemaEvolutionQuarantine writes conflicting values into the sidecar <column>_conflict string column
+	SchemaEvolutionQuarantine = "quarantine"
+
+	conflictColumnSuffix = "_conflict"
+)
+
+//mappingLookupOrder is an order of reverse lookup in the destination types mapping
+var mappingLookupOrder = []typing.DataType{typing.STRING, typing.BOOL, typing.INT64, typing.FLOAT64, typing.TIMESTAMP, typing.JSON,
+	typing.ARRAY, typing.DATE, typing.DECIMAL, typing.UNKNOWN}
+
+//widenDestinationTypes are destination types which support column type changes (adapters.ColumnTypeManager)
+var widenDestinationTypes = map[string]bool{PostgresType: true, MySQLType: true, ClickHouseType: true}
+
+//numberTypeRegex matches NUMBER(precision, scale) and NUMERIC(precision, scale) types (e.g. Snowflake integers are NUMBER(38,0))
+var numberTypeRegex = regexp.MustCompile(`^(?:number|numeric|decimal)\(\s*\d+\s*,\s*(\d+)\s*\)$`)
+
+//tableConflicts are cached column types conflicts resolutions of the table schema version
+type tableConflicts struct {
+	version int64
+	//column name:db SQL type:data SQL type - resolution
+	resolutions map[string]*conflictResolution
+}
+
+//conflictResolution is a result of schema evolution policy applying to a column types conflict
+type conflictResolution struct {
+	//alterColumn is a column with the common supertype (widen)
+	alterColumn *adapters.Column
+	//conflictColumn is a name of the sidecar string column (quarantine)
+	conflictColumn string
+}
+
+//ValidateSchemaEvolution returns err if policy isn't one of fail, widen, quarantine
+//or if the destination type doesn't support the policy (widen requires column type changes support)
+func ValidateSchemaEvolution(policy, destinationType string) error {
+	switch policy {
+	case "", SchemaEvolutionFail, SchemaEvolutionQuarantine:
+		return nil
+	case SchemaEvolutionWiden:
+		if !widenDestinationTypes[destinationType] {
+			return fmt.Errorf("schema_evolution policy %s isn't supported in %s destination. Supported destinations: [%s, %s, %s]", policy, destinationType, PostgresType, MySQLType, ClickHouseType)
+		}
+		return nil
+	default:
+		return fmt.Errorf("Unknown schema_evolution policy: %s. Available policies: [%s, %s, %s]", policy, SchemaEvolutionFail, SchemaEvolutionWiden, SchemaEvolutionQuarantine)
+	}
+}
+
+//resolveConflicts compares db column types with data column types and applies schema evolution policy:
+//quarantine - adds <column>_conflict string columns into dataSchema
+//widen - returns columns which types should be changed to the common supertype
+//resolutions are cached per table schema version (see getConflictResolution)
+func (th *TableHelper) resolveConflicts(dbSchema, dataSchema *adapters.Table) adapters.Columns {
+	alterColumns := adapters.Columns{}
+	for name, column := range dataSchema.Columns {
+		dbColumn, ok := dbSchema.Columns[name]
+		if !ok || strings.EqualFold(dbColumn.SQLType, column.SQLType) {
+			continue
+		}
+
+		resolution := th.getConflictResolution(dbSchema, name, dbColumn.SQLType, column.SQLType)
+		if resolution.alterColumn != nil {
+			alterColumns[name] = *resolution.alterColumn
+		}
+		if resolution.conflictColumn != "" {
+			dataSchema.Columns[resolution.conflictColumn] = adapters.Column{SQLType: th.columnTypesMapping[typing.STRING]}
+		}
+	}
+
+	return alterColumns
+}
+
+//getConflictResolution returns cached resolution of the column types conflict or resolves it (see resolveConflict)
+//cached resolutions are dropped when the table schema version is changed
+func (th *TableHelper) getConflictResolution(dbSchema *adapters.Table, columnName, dbSQLType, dataSQLType string) *conflictResolution {
+	key := columnName + ":" + dbSQLType + ":" + dataSQLType
+
+	th.conflictsMutex.Lock()
+	defer th.conflictsMutex.Unlock()
+
+	conflicts, ok := th.resolvedConflicts[dbSchema.Name]
+	if !ok || conflicts.version != dbSchema.Version {
+		conflicts = &tableConflicts{version: dbSchema.Version, resolutions: map[string]*conflictResolution{}}
+		th.resolvedConflicts[dbSchema.Name] = conflicts
+	}
+
+	resolution, ok := conflicts.resolutions[key]
+	if !ok {
+		resolution = th.resolveConflict(dbSchema.Name, columnName, dbSQLType, dataSQLType)
+		conflicts.resolutions[key] = resolution
+	}
+
+	return resolution
+}
+
+//resolveConflict applies schema evolution policy to the column types conflict
+//every decision is written into the DDL log
+func (th *TableHelper) resolveConflict(tableName, columnName, dbSQLType, dataSQLType string) *conflictResolution {
+	resolution := &conflictResolution{}
+	dbType, ok := th.sqlTypeToDataType(dbSQLType)
+	if !ok {
+		return resolution
+	}
+	dataType, ok := th.sqlTypeToDataType(dataSQLType)
+	if !ok {
+		return resolution
+	}
+
+	//native arrays types conflicts are conflicts of their elements types
+	arrays := dbType == typing.ARRAY && dataType == typing.ARRAY
+	if arrays {
+		dbType, dataType = th.arrayElementType(dbSQLType), th.arrayElementType(dataSQLType)
+	}
+
+	commonType := typing.GetCommonAncestorType(dbType, dataType)
+	if commonType == dbType {
+		return resolution
+	}
+
+	switch th.schemaEvolution {
+	case SchemaEvolutionWiden:
+		sqlType, ok := th.columnTypesMapping[commonType]
+		if arrays {
+			sqlType, ok = th.arrayColumnType(commonType)
+		}
+		if !ok {
+			th.logDecision(tableName, columnName, SchemaEvolutionFail, fmt.Sprintf("%s column can't be widened: unknown column type mapping for %s", dbSQLType, commonType))
+			return resolution
+		}
+		if _, ok := th.manager.(adapters.ColumnTypeManager); !ok {
+			th.logDecision(tableName, columnName, SchemaEvolutionFail, fmt.Sprintf("%s column can't be widened to %s: destination doesn't support column type changes", dbSQLType, sqlType))
+			return resolution
+		}
+
+		resolution.alterColumn = &adapters.Column{SQLType: sqlType}
+		th.logDecision(tableName, columnName, SchemaEvolutionWiden, fmt.Sprintf("%s (%s) -> %s (%s)", dbSQLType, dbType, sqlType, commonType))
+	case SchemaEvolutionQuarantine:
+		resolution.conflictColumn = columnName + conflictColumnSuffix
+		th.logDecision(tableName, columnName, SchemaEvolutionQuarantine, fmt.Sprintf("%s values which don't fit %s column are written into %s", dataType, dbSQLType, resolution.conflictColumn))
+	default:
+		th.logDecision(tableName, columnName, SchemaEvolutionFail, fmt.Sprintf("%s values don't fit %s column", dataType, dbSQLType))
+	}
+
+	return resolution
+}
+
+//quarantineConflicts moves values which don't fit db column types into <column>_conflict columns (as strings)
+//works only with 'quarantine' schema evolution policy and columns which have <column>_conflict sidecar in dbSchema
+func (th *TableHelper) quarantineConflicts(dbSchema *adapters.Table, object map[string]interface{}) {
+	if th.schemaEvolution != SchemaEvolutionQuarantine {
+		return
+	}
+
+	for name, value := range object {
+		if value == nil || strings.HasSuffix(name, conflictColumnSuffix) {
+			continue
+		}
+
+		conflictColumnName := name + conflictColumnSuffix
+		if _, ok := dbSchema.Columns[conflictColumnName]; !ok {
+			continue
+		}
+
+		dbColumn, ok := dbSchema.Columns[name]
+		if !ok {
+			continue
+		}
+
+		dbType, ok := th.sqlTypeToDataType(dbColumn.SQLType)
+		if !ok {
+			continue
+		}
+
+		valueType, err := typing.TypeFromValue(value)
+		if err != nil {
+			continue
+		}
+
+		//native arrays values are compared by their elements types (empty arrays fit any array column)
+		if dbType == typing.ARRAY && valueType == typing.ARRAY {
+			dbType, valueType = th.arrayElementType(dbColumn.SQLType), typing.ArrayElementType(value)
+			if valueType == typing.UNKNOWN {
+				continue
+			}
+		}
+
+		if typing.GetCommonAncestorType(dbType, valueType) == dbType {
+			continue
+		}
+
+		stringValue, err := typing.Convert(typing.STRING, value)
+		if err != nil {
+			stringValue = fmt.Sprint(value)
+		}
+
+		object[conflictColumnName] = stringValue
+		delete(object, name)
+	}
+}
+
+//sqlTypeToDataType returns typing.DataType of the SQL type
+//tries mapping of the destination first (types created by Jitsu) and then common SQL types names (e.g. types from mappings)
+//returns false if type is unknown
+func (th *TableHelper) sqlTypeToDataType(sqlType string) (typing.DataType, bool) {
+	//several types might be mapped into the same SQL type (e.g. STRING, JSON and UNKNOWN): the first one wins
+	for _, dataType := range mappingLookupOrder {
+		if mappedType, ok := th.columnTypesMapping[dataType]; ok && strings.EqualFold(mappedType, sqlType) {
+			if dataType == typing.UNKNOWN {
+				return typing.STRING, true
+			}
+			return dataType, true
+		}
+	}
+
+	t := strings.ToLower(strings.TrimSpace(sqlType))
+	//ClickHouse wrappers
+	for _, wrapper := range []string{"nullable(", "lowcardinality("} {
+		if strings.HasPrefix(t, wrapper) && strings.HasSuffix(t, ")") {
+			t = strings.TrimSuffix(strings.TrimPrefix(t, wrapper), ")")
+		}
+	}
+
+	if submatches := numberTypeRegex.FindStringSubmatch(t); len(submatches) == 2 {
+		if submatches[1] == "0" {
+			return typing.INT64, true
+		}
+		return typing.FLOAT64, true
+	}
+
+	switch {
+	case strings.HasSuffix(t, "[]") || strings.HasPrefix(t, "array(") || strings.HasPrefix(t, "repeated "):
+		return typing.ARRAY, true
+	case t == "boolean" || t == "bool" || t == "tinyint(1)":
+		return typing.BOOL, true
+	case strings.HasPrefix(t, "bigint") || (strings.HasPrefix(t, "int") && t != "interval") || strings.HasPrefix(t, "smallint") ||
+		strings.HasPrefix(t, "uint") || strings.HasPrefix(t, "tinyint") || strings.HasPrefix(t, "mediumint"):
+		return typing.INT64, true
+	case strings.HasPrefix(t, "double") || strings.HasPrefix(t, "real") || strings.HasPrefix(t, "float") ||
+		strings.HasPrefix(t, "numeric") || strings.HasPrefix(t, "decimal") || strings.HasPrefix(t, "number"):
+		return typing.FLOAT64, true
+	case t == "date":
+		return typing.DATE, true
+	case strings.HasPrefix(t, "timestamp") || strings.HasPrefix(t, "datetime"):
+		return typing.TIMESTAMP, true
+	case t == "json" || t == "jsonb" || t == "variant" || t == "super":
+		return typing.JSON, true
+	case strings.HasPrefix(t, "text") || strings.HasPrefix(t, "varchar") || strings.HasPrefix(t, "character") ||
+		strings.HasPrefix(t, "string") || strings.HasPrefix(t, "char"):
+		return typing.STRING, true
+	default:
+		return typing.UNKNOWN, false
+	}
+}
+
+//logDecision writes schema evolution decision into the DDL log (as SQL comment) and into the application log
+//every decision is written once per table column and types pair
+func (th *TableHelper) logDecision(tableName, columnName, decision, details string) {
+	key := tableName + "." + columnName + ":" + decision + ":" + details
+
+	th.decisionsMutex.Lock()
+	_, logged := th.loggedDecisions[key]
+	th.loggedDecisions[key] = true
+	th.decisionsMutex.Unlock()
+
+	if logged {
+		return
+	}
+
+	msg := fmt.Sprintf("schema_evolution [%s] table: %s column: %s %s", decision, tableName, columnName, details)
+	if th.queryLogger != nil {
+		th.queryLogger.LogDDL("-- " + msg)
+	}
+	logging.Info(msg)
+}
@@ -82,7 +82,7 @@ func NewSnowflake(config *Config) (Storage, error) {
 		return nil, err
 	}
 
-	tableHelper := NewTableHelper(snowflakeAdapter, config.monitorKeeper, config.pkFields, adapters.SchemaToSnowflake, config.streamMode, config.maxColumns)
+	tableHelper := NewTableHelper(snowflakeAdapter, config.monitorKeeper, config.pkFields, adapters.SchemaToSnowflake, config.streamMode, config.maxColumns, config.overflow, config.schemaEvolution, queryLogger)
 
 	snowflake := &Snowflake{
 		name:             config.name,
@@ -96,7 +96,7 @@ func NewSnowflake(config *Config) (Storage, error) {
 	}
 
 	if config.streamMode {
-		snowflake.streamingWorker = newStreamingWorker(config.eventQueue, config.processor, snowflake, config.eventsCache, config.loggerFactory.CreateStreamingArchiveLogger(config.name), tableHelper)
+		snowflake.streamingWorker = newStreamingWorker(config.eventQueue, config.processor, snowflake, config.eventsCache, config.loggerFactory.CreateStreamingArchiveLogger(config.name), config.streamBatch, tableHelper)
 		snowflake.streamingWorker.start()
 	}
 
@@ -148,6 +148,7 @@ func (s *Snowflake) Insert(table *adapters.Table, event events.Event) (err error
 	if err != nil {
 		return err
 	}
+	s.tableHelper.AdaptObjects(dbTable, event)
 
 	err = s.snowflakeAdapter.Insert(dbTable, event)
 
@@ -162,6 +163,7 @@ func (s *Snowflake) Insert(table *adapters.Table, event events.Event) (err error
 		if err != nil {
 			return err
 		}
+		s.tableHelper.AdaptObjects(dbTable, event)
 
 		return s.snowflakeAdapter.Insert(dbTable, event)
 	}
@@ -169,18 +171,18 @@ func (s *Snowflake) Insert(table *adapters.Table, event events.Event) (err error
 	return nil
 }
 
-//Store call StoreWithParseFunc with parsers.ParseJSON func
+//Store call StoreWithFallback with parsers.ParseJSON func
 func (s *Snowflake) Store(fileName string, payload []byte, alreadyUploadedTables map[string]bool) (map[string]*StoreResult, int, error) {
-	return s.StoreWithParseFunc(fileName, payload, alreadyUploadedTables, parsers.ParseJSON)
+	return StoreWithFallback(s, fileName, payload, alreadyUploadedTables, parsers.ParseJSON)
 }
 
 //Store file from byte payload to stage with processing
-//return result per table, failed events count and err if occurred
+//return result per table, failed events (they aren't written into fallback, see StoreWithFallback) and err if occurred
 func (s *Snowflake) StoreWithParseFunc(fileName string, payload []byte, alreadyUploadedTables map[string]bool,
-	parseFunc func([]byte) (map[string]interface{}, error)) (map[string]*StoreResult, int, error) {
+	parseFunc func([]byte) (map[string]interface{}, error)) (map[string]*StoreResult, []*events.FailedEvent, error) {
 	flatData, failedEvents, err := s.processor.ProcessFilePayload(fileName, payload, alreadyUploadedTables, parseFunc)
 	if err != nil {
-		return nil, linesCount(payload), err
+		return nil, nil, err
 	}
 
 	//update cache with failed events
@@ -188,15 +190,11 @@ func (s *Snowflake) StoreWithParseFunc(fileName string, payload []byte, alreadyU
 		s.eventsCache.Error(s.Name(), failedEvent.EventID, failedEvent.Error)
 	}
 
-	storeFailedEvents := true
 	tableResults := map[string]*StoreResult{}
 	for _, fdata := range flatData {
 		table := s.tableHelper.MapTableSchema(fdata.BatchHeader)
 		err := s.storeTable(fdata, table)
 		tableResults[table.Name] = &StoreResult{Err: err, RowsCount: fdata.GetPayloadLen()}
-		if err != nil {
-			storeFailedEvents = false
-		}
 
 		//events cache
 		for _, object := range fdata.GetPayload() {
@@ -208,12 +206,7 @@ func (s *Snowflake) StoreWithParseFunc(fileName string, payload []byte, alreadyU
 		}
 	}
 
-	//store failed events to fallback only if other events have been inserted ok
-	if storeFailedEvents {
-		s.Fallback(failedEvents...)
-	}
-
-	return tableResults, len(failedEvents), nil
+	return tableResults, failedEvents, nil
 }
 
 //check table schema
@@ -224,12 +217,16 @@ func (s *Snowflake) storeTable(fdata *schema.ProcessedFile, table *adapters.Tabl
 		return err
 	}
 
+	s.tableHelper.AdaptObjects(dbTable, fdata.GetPayload()...)
+	//CSV header must contain only table columns (fields might have been moved into _conflict or overflow columns)
+	fdata.BatchHeader.Fields = fieldsOf(fdata.BatchHeader.Fields, fdata.GetPayload())
+
 	b, header := fdata.GetPayloadBytesWithHeader(schema.CsvMarshallerInstance)
 	if err := s.stageAdapter.UploadBytes(fdata.FileName, b); err != nil {
 		return err
 	}
 
-	if err := s.snowflakeAdapter.Copy(fdata.FileName, dbTable.Name, header); err != nil {
+	if err := s.snowflakeAdapter.Copy(fdata.FileName, dbTable, header); err != nil {
 		return fmt.Errorf("Error copying file [%s] from stage to snowflake: %v", fdata.FileName, err)
 	}
 
@@ -272,6 +269,12 @@ func (s *Snowflake) IsStaging() bool {
 }
 
 func (s *Snowflake) Close() (multiErr error) {
+	if s.streamingWorker != nil {
+		if err := s.streamingWorker.Close(); err != nil {
+			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing streaming worker: %v", s.Name(), err))
+		}
+	}
+
 	if err := s.snowflakeAdapter.Close(); err != nil {
 		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing snowflake datasource: %v", s.Name(), err))
 	}
@@ -282,12 +285,6 @@ func (s *Snowflake) Close() (multiErr error) {
 		}
 	}
 
-	if s.streamingWorker != nil {
-		if err := s.streamingWorker.Close(); err != nil {
-			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing streaming worker: %v", s.Name(), err))
-		}
-	}
-
 	if err := s.fallbackLogger.Close(); err != nil {
 		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing fallback logger: %v", s.Name(), err))
 	}
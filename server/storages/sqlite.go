@@ -0,0 +1,298 @@
+package storages
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/caching"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/parsers"
+	"github.com/jitsucom/jitsu/server/schema"
+)
+
+//SQLite stores files to embedded SQLite database file in two modes:
+//batch: (1 file = 1 statement)
+//stream: (1 object = 1 statement)
+type SQLite struct {
+	name                          string
+	adapter                       *adapters.SQLite
+	tableHelper                   *TableHelper
+	processor                     *schema.Processor
+	streamingWorker               *StreamingWorker
+	fallbackLogger                *logging.AsyncLogger
+	eventsCache                   *caching.EventsCache
+	usersRecognitionConfiguration *UserRecognitionConfiguration
+	staged                        bool
+}
+
+//NewSQLite returns configured SQLite storage
+func NewSQLite(config *Config) (Storage, error) {
+	sqLiteConfig := config.destination.SQLite
+	if err := sqLiteConfig.Validate(); err != nil {
+		return nil, err
+	}
+
+	queryLogger := config.loggerFactory.CreateSQLQueryLogger(config.name)
+	adapter, err := adapters.NewSQLite(config.ctx, sqLiteConfig, queryLogger, config.sqlTypeCasts)
+	if err != nil {
+		return nil, err
+	}
+
+	tableHelper := NewTableHelper(adapter, config.monitorKeeper, config.pkFields, adapters.SchemaToSQLite, config.streamMode, config.maxColumns, config.overflow, config.schemaEvolution, queryLogger)
+
+	s := &SQLite{
+		name:                          config.name,
+		adapter:                       adapter,
+		tableHelper:                   tableHelper,
+		processor:                     config.processor,
+		fallbackLogger:                config.loggerFactory.CreateFailedLogger(config.name),
+		eventsCache:                   config.eventsCache,
+		usersRecognitionConfiguration: config.usersRecognition,
+		staged:                        config.destination.Staged,
+	}
+
+	if config.streamMode {
+		s.streamingWorker = newStreamingWorker(config.eventQueue, config.processor, s, config.eventsCache, config.loggerFactory.CreateStreamingArchiveLogger(config.name), config.streamBatch, tableHelper)
+		s.streamingWorker.start()
+	}
+
+	return s, nil
+}
+
+func (s *SQLite) DryRun(payload events.Event) ([]adapters.TableField, error) {
+	return dryRun(payload, s.processor, s.tableHelper)
+}
+
+//Store call StoreWithFallback with parsers.ParseJSON func
+func (s *SQLite) Store(fileName string, payload []byte, alreadyUploadedTables map[string]bool) (map[string]*StoreResult, int, error) {
+	return StoreWithFallback(s, fileName, payload, alreadyUploadedTables, parsers.ParseJSON)
+}
+
+//StoreWithParseFunc file payload to SQLite with processing
+//return result per table, failed events (they aren't written into fallback, see StoreWithFallback) and err if occurred
+func (s *SQLite) StoreWithParseFunc(fileName string, payload []byte, alreadyUploadedTables map[string]bool,
+	parseFunc func([]byte) (map[string]interface{}, error)) (map[string]*StoreResult, []*events.FailedEvent, error) {
+	flatData, failedEvents, err := s.processor.ProcessFilePayload(fileName, payload, alreadyUploadedTables, parseFunc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	//update cache with failed events
+	for _, failedEvent := range failedEvents {
+		s.eventsCache.Error(s.Name(), failedEvent.EventID, failedEvent.Error)
+	}
+
+	tableResults := map[string]*StoreResult{}
+	for _, fdata := range flatData {
+		table := s.tableHelper.MapTableSchema(fdata.BatchHeader)
+		err := s.storeTable(fdata, table)
+		tableResults[table.Name] = &StoreResult{Err: err, RowsCount: fdata.GetPayloadLen()}
+
+		//events cache
+		for _, object := range fdata.GetPayload() {
+			if err != nil {
+				s.eventsCache.Error(s.Name(), events.ExtractEventID(object), err.Error())
+			} else {
+				s.eventsCache.Succeed(s.Name(), events.ExtractEventID(object), object, table)
+			}
+		}
+	}
+
+	return tableResults, failedEvents, nil
+}
+
+//check table schema
+//and store data into one table
+func (s *SQLite) storeTable(fdata *schema.ProcessedFile, table *adapters.Table) error {
+	dbSchema, err := s.tableHelper.EnsureTable(s.Name(), table)
+	if err != nil {
+		return err
+	}
+	s.tableHelper.AdaptObjects(dbSchema, fdata.GetPayload()...)
+
+	start := time.Now()
+	if err := s.adapter.BulkInsert(dbSchema, fdata.GetPayload()); err != nil {
+		return err
+	}
+	logging.Debugf("[%s] Inserted [%d] rows in [%.2f] seconds", s.Name(), len(fdata.GetPayload()), time.Now().Sub(start).Seconds())
+
+	return nil
+}
+
+//Fallback log event with error to fallback logger
+func (s *SQLite) Fallback(failedEvents ...*events.FailedEvent) {
+	for _, failedEvent := range failedEvents {
+		s.fallbackLogger.ConsumeAny(failedEvent)
+	}
+}
+
+//SyncStore is used in two cases:
+//1. store chunk payload to SQLite with processing
+//2. store recognized users events
+//return rows count and err if can't store
+//or rows count and nil if stored
+func (s *SQLite) SyncStore(overriddenDataSchema *schema.BatchHeader, objects []map[string]interface{}, timeIntervalValue string) (rowsCount int, err error) {
+	flatData, err := s.processor.ProcessObjects(objects)
+	if err != nil {
+		return len(objects), err
+	}
+
+	for _, fdata := range flatData {
+		rowsCount += fdata.GetPayloadLen()
+	}
+
+	deleteConditions := adapters.DeleteByTimeChunkCondition(timeIntervalValue)
+
+	//table schema overridden
+	if overriddenDataSchema != nil && len(overriddenDataSchema.Fields) > 0 {
+		var data []map[string]interface{}
+		//ignore table multiplexing from mapping step
+		for _, fdata := range flatData {
+			data = append(data, fdata.GetPayload()...)
+			//enrich overridden schema with new fields (some system fields or e.g. after lookup step)
+			overriddenDataSchema.Fields.Add(fdata.BatchHeader.Fields)
+		}
+
+		table := s.tableHelper.MapTableSchema(overriddenDataSchema)
+
+		dbSchema, err := s.tableHelper.EnsureTable(s.Name(), table)
+		if err != nil {
+			return rowsCount, err
+		}
+		s.tableHelper.AdaptObjects(dbSchema, data...)
+		if err = s.adapter.BulkUpdate(dbSchema, data, deleteConditions); err != nil {
+			return rowsCount, err
+		}
+
+		return rowsCount, nil
+	}
+
+	//plain flow
+	for _, fdata := range flatData {
+		table := s.tableHelper.MapTableSchema(fdata.BatchHeader)
+
+		//overridden table name and source primary key fields
+		if overriddenDataSchema != nil {
+			if overriddenDataSchema.TableName != "" {
+				table.Name = overriddenDataSchema.TableName
+			}
+			if len(overriddenDataSchema.PKFields) > 0 {
+				table.PKFields = overriddenDataSchema.PKFields
+			}
+		}
+
+		dbSchema, err := s.tableHelper.EnsureTable(s.Name(), table)
+		if err != nil {
+			return rowsCount, err
+		}
+		s.tableHelper.AdaptObjects(dbSchema, fdata.GetPayload()...)
+		start := time.Now()
+		if err = s.adapter.BulkUpdate(dbSchema, fdata.GetPayload(), deleteConditions); err != nil {
+			return rowsCount, err
+		}
+		logging.Debugf("[%s] Inserted [%d] rows in [%.2f] seconds", s.Name(), len(fdata.GetPayload()), time.Now().Sub(start).Seconds())
+	}
+
+	return rowsCount, nil
+}
+
+func (s *SQLite) Update(object map[string]interface{}) error {
+	_, err := s.SyncStore(nil, []map[string]interface{}{object}, "")
+	return err
+}
+
+//Insert event in SQLite (1 retry if error)
+func (s *SQLite) Insert(table *adapters.Table, event events.Event) (err error) {
+	dbTable, err := s.tableHelper.EnsureTable(s.Name(), table)
+	if err != nil {
+		return err
+	}
+	s.tableHelper.AdaptObjects(dbTable, event)
+
+	err = s.adapter.Insert(dbTable, event)
+
+	//renew current db schema and retry
+	if err != nil {
+		dbTable, err := s.tableHelper.RefreshTableSchema(s.Name(), table)
+		if err != nil {
+			return err
+		}
+
+		dbTable, err = s.tableHelper.EnsureTable(s.Name(), table)
+		if err != nil {
+			return err
+		}
+		s.tableHelper.AdaptObjects(dbTable, event)
+
+		return s.adapter.Insert(dbTable, event)
+	}
+
+	return nil
+}
+
+//InsertBatch inserts objects in SQLite in one transaction (1 retry if error)
+func (s *SQLite) InsertBatch(table *adapters.Table, objects []map[string]interface{}) (err error) {
+	dbTable, err := s.tableHelper.EnsureTable(s.Name(), table)
+	if err != nil {
+		return err
+	}
+	s.tableHelper.AdaptObjects(dbTable, objects...)
+
+	err = s.adapter.BulkInsert(dbTable, objects)
+
+	//renew current db schema and retry
+	if err != nil {
+		dbTable, err := s.tableHelper.RefreshTableSchema(s.Name(), table)
+		if err != nil {
+			return err
+		}
+
+		dbTable, err = s.tableHelper.EnsureTable(s.Name(), table)
+		if err != nil {
+			return err
+		}
+		s.tableHelper.AdaptObjects(dbTable, objects...)
+
+		return s.adapter.BulkInsert(dbTable, objects)
+	}
+
+	return nil
+}
+
+func (s *SQLite) GetUsersRecognition() *UserRecognitionConfiguration {
+	return s.usersRecognitionConfiguration
+}
+
+//Close adapters.SQLite
+func (s *SQLite) Close() (multiErr error) {
+	if s.streamingWorker != nil {
+		if err := s.streamingWorker.Close(); err != nil {
+			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing streaming worker: %v", s.Name(), err))
+		}
+	}
+
+	if err := s.adapter.Close(); err != nil {
+		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing sqlite datasource: %v", s.Name(), err))
+	}
+
+	if err := s.fallbackLogger.Close(); err != nil {
+		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing fallback logger: %v", s.Name(), err))
+	}
+
+	return
+}
+
+func (s *SQLite) Name() string {
+	return s.name
+}
+
+func (s *SQLite) Type() string {
+	return SQLiteType
+}
+
+func (s *SQLite) IsStaging() bool {
+	return s.staged
+}
@@ -0,0 +1,80 @@
+package storages
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+)
+
+const (
+	defaultStreamBatchMaxEvents    = 1000
+	defaultStreamBatchMaxLatencyMs = 1000
+)
+
+//StreamBatchConfig is a stream mode micro-batching configuration:
+//dequeued events are grouped by table and inserted in bulk when max_events events are collected
+//or max_latency_ms milliseconds have passed since the first event of the batch
+type StreamBatchConfig struct {
+	Enabled      bool `mapstructure:"enabled" json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	MaxEvents    int  `mapstructure:"max_events" json:"max_events,omitempty" yaml:"max_events,omitempty"`
+	MaxLatencyMs int  `mapstructure:"max_latency_ms" json:"max_latency_ms,omitempty" yaml:"max_latency_ms,omitempty"`
+}
+
+func (sbc *StreamBatchConfig) IsEnabled() bool {
+	return sbc != nil && sbc.Enabled
+}
+
+func (sbc *StreamBatchConfig) Validate() error {
+	if !sbc.IsEnabled() {
+		return nil
+	}
+
+	if sbc.MaxEvents < 0 || sbc.MaxLatencyMs < 0 {
+		return fmt.Errorf("max_events and max_latency_ms can't be negative (0 means default value)")
+	}
+
+	return nil
+}
+
+//GetMaxEvents returns configured max events count in a batch or default value (1000)
+func (sbc *StreamBatchConfig) GetMaxEvents() int {
+	if sbc.MaxEvents > 0 {
+		return sbc.MaxEvents
+	}
+
+	return defaultStreamBatchMaxEvents
+}
+
+//GetMaxLatency returns configured max time of collecting a batch or default value (1 second)
+func (sbc *StreamBatchConfig) GetMaxLatency() time.Duration {
+	if sbc.MaxLatencyMs > 0 {
+		return time.Duration(sbc.MaxLatencyMs) * time.Millisecond
+	}
+
+	return defaultStreamBatchMaxLatencyMs * time.Millisecond
+}
+
+//insertWithSplit inserts objects with insertFunc (all objects are inserted or none of them if insertFunc returns an error).
+//If insertion fails, objects are split into halves which are inserted separately (recursively)
+//so only failed objects get an error. Retryable errors (e.g. connection errors) and *adapters.PartialInsertError
+//(some objects might have been inserted, so splitting would duplicate them) aren't split: all objects get the error
+//return errors per object (nil if the object has been inserted)
+func insertWithSplit(objects []map[string]interface{}, insertFunc func([]map[string]interface{}) error) []error {
+	err := insertFunc(objects)
+	if err == nil {
+		return make([]error, len(objects))
+	}
+
+	_, partiallyInserted := err.(*adapters.PartialInsertError)
+	if len(objects) == 1 || partiallyInserted || isRetryableError(err) {
+		errs := make([]error, len(objects))
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+
+	middle := len(objects) / 2
+	return append(insertWithSplit(objects[:middle], insertFunc), insertWithSplit(objects[middle:], insertFunc)...)
+}
@@ -0,0 +1,112 @@
+package storages
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamBatchConfig(t *testing.T) {
+	var notConfigured *StreamBatchConfig
+	require.False(t, notConfigured.IsEnabled())
+	require.NoError(t, notConfigured.Validate())
+
+	defaultConfig := &StreamBatchConfig{Enabled: true}
+	require.Equal(t, 1000, defaultConfig.GetMaxEvents())
+	require.Equal(t, time.Second, defaultConfig.GetMaxLatency())
+
+	config := &StreamBatchConfig{Enabled: true, MaxEvents: 5000, MaxLatencyMs: 200}
+	require.Equal(t, 5000, config.GetMaxEvents())
+	require.Equal(t, 200*time.Millisecond, config.GetMaxLatency())
+
+	require.EqualError(t, (&StreamBatchConfig{Enabled: true, MaxEvents: -1}).Validate(), "max_events and max_latency_ms can't be negative (0 means default value)")
+	require.NoError(t, (&StreamBatchConfig{Enabled: true, MaxEvents: 0, MaxLatencyMs: 0}).Validate())
+}
+
+func TestInsertWithSplit(t *testing.T) {
+	var objects []map[string]interface{}
+	for i := 0; i < 10; i++ {
+		objects = append(objects, map[string]interface{}{"id": i})
+	}
+
+	tests := []struct {
+		name          string
+		insertErr     error
+		badIDs        map[int]bool
+		expectedCalls int
+	}{
+		{
+			"all objects are inserted",
+			nil,
+			map[int]bool{},
+			1,
+		},
+		{
+			"one bad object",
+			errors.New("pq: invalid input syntax for type numeric"),
+			map[int]bool{7: true},
+			7,
+		},
+		{
+			"several bad objects",
+			errors.New("pq: invalid input syntax for type numeric"),
+			map[int]bool{0: true, 1: true, 9: true},
+			13,
+		},
+		{
+			"retryable error isn't split",
+			errors.New("dial tcp 127.0.0.1:5432: connect: connection refused"),
+			map[int]bool{3: true},
+			1,
+		},
+		{
+			"partial insert error isn't split",
+			&adapters.PartialInsertError{Err: errors.New("code: 252, message: Too many parts")},
+			map[int]bool{3: true},
+			1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var inserted []int
+			calls := 0
+			errs := insertWithSplit(objects, func(batch []map[string]interface{}) error {
+				calls++
+				for _, object := range batch {
+					if tt.badIDs[object["id"].(int)] {
+						return tt.insertErr
+					}
+				}
+				for _, object := range batch {
+					inserted = append(inserted, object["id"].(int))
+				}
+				return nil
+			})
+
+			require.Len(t, errs, len(objects))
+			require.Equal(t, tt.expectedCalls, calls)
+
+			_, partiallyInserted := tt.insertErr.(*adapters.PartialInsertError)
+			if tt.insertErr != nil && (partiallyInserted || isRetryableError(tt.insertErr)) {
+				for _, err := range errs {
+					require.Equal(t, tt.insertErr, err)
+				}
+				require.Empty(t, inserted)
+				return
+			}
+
+			for i, err := range errs {
+				if tt.badIDs[i] {
+					require.Equal(t, tt.insertErr, err, "object %d must be failed", i)
+				} else {
+					require.NoError(t, err, "object %d must be inserted", i)
+					require.Contains(t, inserted, i)
+				}
+			}
+			require.Len(t, inserted, len(objects)-len(tt.badIDs))
+		})
+	}
+}
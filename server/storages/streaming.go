@@ -12,42 +12,98 @@ import (
 	"github.com/jitsucom/jitsu/server/schema"
 	"math/rand"
 	"strings"
+	"sync"
 	"time"
 )
 
+const (
+	//streamingRetryDelay is a delay between insertion attempts of events which have failed with retryable errors
+	streamingRetryDelay = 20 * time.Second
+	//maxStreamingRetries is a max number of insertion retries (~10 minutes). After that events are stored in fallback
+	maxStreamingRetries = 30
+)
+
 type StreamingStorage interface {
 	Storage
 	Insert(dataSchema *adapters.Table, event events.Event) (err error)
 }
 
+//BulkStreamingStorage is a StreamingStorage which can insert several objects into one table at once (all or nothing)
+//it is used by StreamingWorker in micro-batching mode. Batches of other storages are inserted object by object
+type BulkStreamingStorage interface {
+	StreamingStorage
+	InsertBatch(dataSchema *adapters.Table, objects []map[string]interface{}) (err error)
+}
+
 //StreamingWorker reads events from queue and using events.StreamingStorage writes them
+//one by one or in micro-batches (see StreamBatchConfig)
 type StreamingWorker struct {
 	eventQueue       *events.PersistentQueue
 	processor        *schema.Processor
 	streamingStorage StreamingStorage
 	eventsCache      *caching.EventsCache
 	archiveLogger    *logging.AsyncLogger
+	batchConfig      *StreamBatchConfig
 	tableHelper      []*TableHelper
 
-	closed bool
+	closed    bool
+	done      chan struct{}
+	closeOnce sync.Once
+	//batching is done when the micro-batching goroutine has inserted the last batch
+	batching sync.WaitGroup
+}
+
+//streamingEvent is a processed event which is ready for inserting
+type streamingEvent struct {
+	fact      events.Event
+	tokenID   string
+	eventID   string
+	retries   int
+	envelopes []schema.Envelope
+	//inserted envelopes (including inserted by previous attempts)
+	inserted []bool
+	//tables of envelopes inserted by the current attempt
+	tables []*adapters.Table
+	//the first insertion error
+	err error
+}
+
+//insertedEnvelopes returns indexes of inserted envelopes
+func (se *streamingEvent) insertedEnvelopes() []int {
+	var indexes []int
+	for i, inserted := range se.inserted {
+		if inserted {
+			indexes = append(indexes, i)
+		}
+	}
+
+	return indexes
 }
 
 func newStreamingWorker(eventQueue *events.PersistentQueue, processor *schema.Processor, streamingStorage StreamingStorage,
-	eventsCache *caching.EventsCache, archiveLogger *logging.AsyncLogger, tableHelper ...*TableHelper) *StreamingWorker {
+	eventsCache *caching.EventsCache, archiveLogger *logging.AsyncLogger, batchConfig *StreamBatchConfig, tableHelper ...*TableHelper) *StreamingWorker {
 	return &StreamingWorker{
 		eventQueue:       eventQueue,
 		processor:        processor,
 		streamingStorage: streamingStorage,
 		eventsCache:      eventsCache,
 		archiveLogger:    archiveLogger,
+		batchConfig:      batchConfig,
 		tableHelper:      tableHelper,
+		done:             make(chan struct{}),
 	}
 }
 
 //Run goroutine to:
 //1. read from queue
 //2. Insert in events.StreamingStorage
+//or run micro-batching goroutines if it is enabled
 func (sw *StreamingWorker) start() {
+	if sw.batchConfig.IsEnabled() {
+		sw.startBatching()
+		return
+	}
+
 	safego.RunWithRestart(func() {
 		for {
 			if sw.streamingStorage.IsStaging() {
@@ -57,98 +113,335 @@ func (sw *StreamingWorker) start() {
 				break
 			}
 
-			fact, dequeuedTime, tokenID, err := sw.eventQueue.DequeueBlock()
-			if err != nil {
-				if err == events.ErrQueueClosed && sw.closed {
-					continue
-				}
-				logging.SystemErrorf("[%s] Error reading event from queue: %v", sw.streamingStorage.Name(), err)
+			event := sw.dequeue()
+			if event == nil {
 				continue
 			}
 
-			//dequeued event was from retry call and retry timeout hasn't come
-			if time.Now().Before(dequeuedTime) {
-				sw.eventQueue.ConsumeTimed(fact, dequeuedTime, tokenID)
+			//transform step can produce several objects from one event:
+			//if any of them isn't inserted - only not inserted objects are retried or the event is stored in fallback
+			event.err = sw.insert(event)
+			sw.finish(event)
+		}
+	})
+}
+
+//startBatching runs goroutines to:
+//1. wait for events in the queue
+//2. dequeue and process events, collect them and insert in bulk when the batch is full or max latency has passed
+//events are dequeued only by the batching goroutine, so on close collected events are inserted
+//and not collected ones stay in the queue (see Close)
+func (sw *StreamingWorker) startBatching() {
+	if sw.streamingStorage.IsStaging() {
+		return
+	}
+
+	available := make(chan struct{})
+	consumed := make(chan struct{})
+	safego.RunWithRestart(func() {
+		for {
+			if err := sw.eventQueue.WaitForEvent(); err != nil {
+				if err == events.ErrQueueClosed {
+					return
+				}
+				logging.SystemErrorf("[%s] Error waiting for event in queue: %v", sw.streamingStorage.Name(), err)
 				continue
 			}
 
-			batchHeader, flattenObject, err := sw.processor.ProcessEvent(fact)
-			if err != nil {
-				if err == schema.ErrSkipObject {
-					if !appconfig.Instance.DisableSkipEventsWarn {
-						logging.Warnf("[%s] Event [%s]: %v", sw.streamingStorage.Name(), events.ExtractEventID(fact), err)
-					}
-
-					counters.SkipEvents(sw.streamingStorage.Name(), 1)
-				} else {
-					serialized := fact.Serialize()
-					logging.Errorf("[%s] Unable to process object %s: %v", sw.streamingStorage.Name(), serialized, err)
-					metrics.ErrorTokenEvent(tokenID, sw.streamingStorage.Name())
-					counters.ErrorEvents(sw.streamingStorage.Name(), 1)
-					sw.streamingStorage.Fallback(&events.FailedEvent{
-						Event:   []byte(serialized),
-						Error:   err.Error(),
-						EventID: events.ExtractEventID(fact),
-					})
+			select {
+			case available <- struct{}{}:
+			case <-sw.done:
+				return
+			}
+
+			//wait until the event is dequeued (otherwise it is notified twice)
+			select {
+			case <-consumed:
+			case <-sw.done:
+				return
+			}
+		}
+	})
+
+	sw.batching.Add(1)
+	safego.RunWithRestart(func() {
+		var batch []*streamingEvent
+		var flushTimer <-chan time.Time
+		for {
+			select {
+			case <-available:
+				event := sw.dequeue()
+				select {
+				case consumed <- struct{}{}:
+				case <-sw.done:
+				}
+				if event == nil {
+					continue
 				}
 
-				//cache
-				sw.eventsCache.Error(sw.streamingStorage.Name(), events.ExtractEventID(fact), err.Error())
+				if len(batch) == 0 {
+					flushTimer = time.After(sw.batchConfig.GetMaxLatency())
+				}
+				batch = append(batch, event)
+				if len(batch) < sw.batchConfig.GetMaxEvents() {
+					continue
+				}
+			case <-flushTimer:
+			case <-sw.done:
+				//the worker is closing: insert collected events (the storage is closed after the worker)
+				if len(batch) > 0 {
+					sw.insertBatch(batch)
+				}
+				sw.batching.Done()
+				return
+			}
 
-				continue
+			sw.insertBatch(batch)
+			batch = nil
+			flushTimer = nil
+		}
+	})
+}
+
+//dequeue reads an event from the queue and processes it
+//return nil if there is nothing to insert (queue errors, skipped, filtered, failed or postponed events)
+func (sw *StreamingWorker) dequeue() *streamingEvent {
+	fact, queuedEvent, err := sw.eventQueue.DequeueBlock()
+	if err != nil {
+		if err == events.ErrQueueClosed && sw.closed {
+			return nil
+		}
+		logging.SystemErrorf("[%s] Error reading event from queue: %v", sw.streamingStorage.Name(), err)
+		return nil
+	}
+
+	tokenID := queuedEvent.TokenID
+
+	//dequeued event was from retry call and retry timeout hasn't come
+	if time.Now().Before(queuedEvent.DequeuedTime) {
+		sw.eventQueue.ConsumeRetry(fact, queuedEvent.DequeuedTime, tokenID, queuedEvent.Retries, queuedEvent.InsertedEnvelopes)
+		return nil
+	}
+
+	envelopes, err := sw.processor.ProcessEvent(fact)
+	if err != nil {
+		if err == schema.ErrSkipObject || err == schema.ErrSkipObjectByTransform {
+			if !appconfig.Instance.DisableSkipEventsWarn {
+				logging.Warnf("[%s] Event [%s]: %v", sw.streamingStorage.Name(), events.ExtractEventID(fact), err)
+			}
+
+			counters.SkipEvents(sw.streamingStorage.Name(), 1)
+			metrics.SkipEvents(sw.streamingStorage.Name(), 1)
+		} else if err == schema.ErrFilteredObject {
+			counters.FilterEvents(sw.streamingStorage.Name(), 1)
+			metrics.FilterEvents(sw.streamingStorage.Name(), 1)
+		} else {
+			serialized := fact.Serialize()
+			logging.Errorf("[%s] Unable to process object %s: %v", sw.streamingStorage.Name(), serialized, err)
+			metrics.ErrorTokenEvent(tokenID, sw.streamingStorage.Name())
+			counters.ErrorEvents(sw.streamingStorage.Name(), 1)
+			sw.streamingStorage.Fallback(&events.FailedEvent{
+				Event:   []byte(serialized),
+				Error:   err.Error(),
+				EventID: events.ExtractEventID(fact),
+			})
+		}
+
+		//cache
+		sw.eventsCache.Error(sw.streamingStorage.Name(), events.ExtractEventID(fact), err.Error())
+
+		return nil
+	}
+
+	//don't process empty object
+	if len(envelopes) == 0 {
+		return nil
+	}
+
+	//envelopes which have been inserted by previous attempts are skipped
+	inserted := make([]bool, len(envelopes))
+	for _, index := range queuedEvent.InsertedEnvelopes {
+		if index >= 0 && index < len(inserted) {
+			inserted[index] = true
+		}
+	}
+
+	return &streamingEvent{
+		fact:      fact,
+		tokenID:   tokenID,
+		eventID:   events.ExtractEventID(fact),
+		retries:   queuedEvent.Retries,
+		envelopes: envelopes,
+		inserted:  inserted,
+		tables:    make([]*adapters.Table, len(envelopes)),
+	}
+}
+
+//insert maps every not inserted envelope to table and inserts it with StreamingStorage
+//return first occurred error
+func (sw *StreamingWorker) insert(event *streamingEvent) error {
+	var firstErr error
+	for i, envelope := range event.envelopes {
+		if event.inserted[i] {
+			continue
+		}
+
+		table := sw.getTableHelper().MapTableSchema(envelope.Header)
+
+		if err := sw.streamingStorage.Insert(table, envelope.Event); err != nil {
+			logging.Errorf("[%s] Error inserting object %s to table [%s]: %v", sw.streamingStorage.Name(), envelope.Event.Serialize(), table.Name, err)
+			if firstErr == nil {
+				firstErr = err
 			}
+			continue
+		}
+
+		event.tables[i] = table
+		event.inserted[i] = true
+	}
+
+	return firstErr
+}
 
-			//don't process empty object
-			if !batchHeader.Exists() {
+//insertBatch groups envelopes of all batch events by table and inserts every group in bulk with BulkStreamingStorage:
+//if bulk insertion fails, the group is split until failed objects are found (see insertWithSplit)
+//if any of event envelopes isn't inserted - only not inserted envelopes are retried or the event is stored in fallback
+func (sw *StreamingWorker) insertBatch(batch []*streamingEvent) {
+	type tableObject struct {
+		event         *streamingEvent
+		envelopeIndex int
+	}
+
+	var tableNames []string
+	headers := map[string]*schema.BatchHeader{}
+	tableObjects := map[string][]*tableObject{}
+	for _, event := range batch {
+		for i, envelope := range event.envelopes {
+			if event.inserted[i] {
 				continue
 			}
 
-			table := sw.getTableHelper().MapTableSchema(batchHeader)
-
-			if err := sw.streamingStorage.Insert(table, flattenObject); err != nil {
-				logging.Errorf("[%s] Error inserting object %s to table [%s]: %v", sw.streamingStorage.Name(), flattenObject.Serialize(), table.Name, err)
-				if strings.Contains(err.Error(), "connection refused") ||
-					strings.Contains(err.Error(), "EOF") ||
-					strings.Contains(err.Error(), "write: broken pipe") ||
-					strings.Contains(err.Error(), "context deadline exceeded") ||
-					strings.Contains(err.Error(), "connection reset by peer") {
-					sw.eventQueue.ConsumeTimed(fact, time.Now().Add(20*time.Second), tokenID)
-				} else {
-					sw.streamingStorage.Fallback(&events.FailedEvent{
-						Event:   []byte(fact.Serialize()),
-						Error:   err.Error(),
-						EventID: events.ExtractEventID(flattenObject),
-					})
-				}
+			tableName := envelope.Header.TableName
+			header, ok := headers[tableName]
+			if !ok {
+				header = &schema.BatchHeader{TableName: tableName, Fields: schema.Fields{}}
+				headers[tableName] = header
+				tableNames = append(tableNames, tableName)
+			}
+			header.Fields.Merge(envelope.Header.Fields)
+			tableObjects[tableName] = append(tableObjects[tableName], &tableObject{event: event, envelopeIndex: i})
+		}
+	}
 
-				counters.ErrorEvents(sw.streamingStorage.Name(), 1)
-				//cache
-				sw.eventsCache.Error(sw.streamingStorage.Name(), events.ExtractEventID(fact), err.Error())
+	bulkStorage, bulkSupported := sw.streamingStorage.(BulkStreamingStorage)
+	for _, tableName := range tableNames {
+		table := sw.getTableHelper().MapTableSchema(headers[tableName])
 
-				metrics.ErrorTokenEvent(tokenID, sw.streamingStorage.Name())
+		objects := make([]map[string]interface{}, len(tableObjects[tableName]))
+		for i, tableObject := range tableObjects[tableName] {
+			objects[i] = tableObject.event.envelopes[tableObject.envelopeIndex].Event
+		}
+
+		var errs []error
+		if bulkSupported {
+			errs = insertWithSplit(objects, func(objects []map[string]interface{}) error {
+				return bulkStorage.InsertBatch(table, objects)
+			})
+		} else {
+			errs = make([]error, len(objects))
+			for i, object := range objects {
+				errs[i] = sw.streamingStorage.Insert(table, object)
+			}
+		}
+
+		for i, err := range errs {
+			tableObject := tableObjects[tableName][i]
+			if err != nil {
+				logging.Errorf("[%s] Error inserting object %s to table [%s]: %v", sw.streamingStorage.Name(), events.Event(objects[i]).Serialize(), table.Name, err)
+				if tableObject.event.err == nil {
+					tableObject.event.err = err
+				}
 				continue
 			}
 
-			counters.SuccessEvents(sw.streamingStorage.Name(), 1)
+			tableObject.event.tables[tableObject.envelopeIndex] = table
+			tableObject.event.inserted[tableObject.envelopeIndex] = true
+		}
+	}
 
-			//cache
-			sw.eventsCache.Succeed(sw.streamingStorage.Name(), events.ExtractEventID(fact), flattenObject, table)
+	for _, event := range batch {
+		sw.finish(event)
+	}
+}
+
+//finish handles the event insertion result:
+//failed event is retried later (if the error is retryable and max retries number isn't exceeded) or stored in fallback,
+//inserted event is archived
+func (sw *StreamingWorker) finish(event *streamingEvent) {
+	if event.err != nil {
+		if isRetryableError(event.err) && event.retries < maxStreamingRetries {
+			sw.eventQueue.ConsumeRetry(event.fact, time.Now().Add(streamingRetryDelay), event.tokenID, event.retries+1, event.insertedEnvelopes())
+		} else {
+			if event.retries >= maxStreamingRetries {
+				logging.Warnf("[%s] Event [%s] insertion has been retried %d times. It will be stored in fallback", sw.streamingStorage.Name(), event.eventID, event.retries)
+			}
+			sw.streamingStorage.Fallback(&events.FailedEvent{
+				Event:   []byte(event.fact.Serialize()),
+				Error:   event.err.Error(),
+				EventID: event.eventID,
+			})
+		}
 
-			metrics.SuccessTokenEvent(tokenID, sw.streamingStorage.Name())
+		counters.ErrorEvents(sw.streamingStorage.Name(), 1)
+		//cache
+		sw.eventsCache.Error(sw.streamingStorage.Name(), event.eventID, event.err.Error())
 
-			//archive
-			sw.archiveLogger.Consume(fact, tokenID)
+		metrics.ErrorTokenEvent(event.tokenID, sw.streamingStorage.Name())
+		return
+	}
+
+	//cache (envelopes inserted by previous attempts are skipped)
+	for i, envelope := range event.envelopes {
+		if event.tables[i] != nil {
+			sw.eventsCache.Succeed(sw.streamingStorage.Name(), event.eventID, envelope.Event, event.tables[i])
 		}
-	})
+	}
+
+	counters.SuccessEvents(sw.streamingStorage.Name(), 1)
+
+	metrics.SuccessTokenEvent(event.tokenID, sw.streamingStorage.Name())
+
+	//archive
+	sw.archiveLogger.Consume(event.fact, event.tokenID)
 }
 
-func (sw *StreamingWorker) Close() error {
-	sw.closed = true
+//Close stops the worker and waits until the collected micro-batch is inserted. It is safe to call Close several times
+func (sw *StreamingWorker) Close() (err error) {
+	sw.closeOnce.Do(func() {
+		sw.closed = true
+		close(sw.done)
+		sw.batching.Wait()
 
-	return sw.archiveLogger.Close()
+		err = sw.archiveLogger.Close()
+	})
+
+	return err
 }
 
 func (sw *StreamingWorker) getTableHelper() *TableHelper {
 	num := rand.Intn(len(sw.tableHelper))
 	return sw.tableHelper[num]
 }
+
+//isRetryableError return true if the event should be inserted later: connection errors or adapters.RetryableError (e.g. 5xx HTTP responses)
+func isRetryableError(err error) bool {
+	if _, ok := err.(*adapters.RetryableError); ok {
+		return true
+	}
+
+	return strings.Contains(err.Error(), "connection refused") ||
+		strings.Contains(err.Error(), "EOF") ||
+		strings.Contains(err.Error(), "write: broken pipe") ||
+		strings.Contains(err.Error(), "context deadline exceeded") ||
+		strings.Contains(err.Error(), "connection reset by peer")
+}
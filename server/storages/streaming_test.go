@@ -0,0 +1,145 @@
+package storages
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/appconfig"
+	"github.com/jitsucom/jitsu/server/caching"
+	"github.com/jitsucom/jitsu/server/enrichment"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/jitsucom/jitsu/server/schema"
+	"github.com/jitsucom/jitsu/server/typing"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+//testBulkStreamingStorage records inserted batches
+type testBulkStreamingStorage struct {
+	Storage
+
+	mutex   sync.Mutex
+	batches [][]map[string]interface{}
+}
+
+func (tbs *testBulkStreamingStorage) Insert(dataSchema *adapters.Table, event events.Event) error {
+	return tbs.InsertBatch(dataSchema, []map[string]interface{}{event})
+}
+
+func (tbs *testBulkStreamingStorage) InsertBatch(dataSchema *adapters.Table, objects []map[string]interface{}) error {
+	tbs.mutex.Lock()
+	defer tbs.mutex.Unlock()
+
+	tbs.batches = append(tbs.batches, objects)
+	return nil
+}
+
+func (tbs *testBulkStreamingStorage) getBatches() [][]map[string]interface{} {
+	tbs.mutex.Lock()
+	defer tbs.mutex.Unlock()
+
+	return tbs.batches
+}
+
+func (tbs *testBulkStreamingStorage) Name() string {
+	return "test"
+}
+
+func (tbs *testBulkStreamingStorage) IsStaging() bool {
+	return false
+}
+
+type testWriteCloser struct {
+	bytes.Buffer
+}
+
+func (twc *testWriteCloser) Close() error {
+	return nil
+}
+
+func startTestBatchingWorker(t *testing.T, batchConfig *StreamBatchConfig, eventsCount int) (*StreamingWorker, *testBulkStreamingStorage, *events.PersistentQueue, func()) {
+	viper.Set("server.log.path", "")
+	require.NoError(t, appconfig.Init(false, ""))
+
+	dir, err := ioutil.TempDir("", "streaming_worker")
+	require.NoError(t, err)
+
+	queue, err := events.NewPersistentQueue("test", "queue.dst=test", dir)
+	require.NoError(t, err)
+
+	processor, err := schema.NewProcessor("test", `events`, &schema.DummyMapper{}, []enrichment.Rule{},
+		schema.NewFlattener(), schema.NewTypeResolver(), false, nil)
+	require.NoError(t, err)
+
+	for i := 0; i < eventsCount; i++ {
+		queue.Consume(map[string]interface{}{"eventn_ctx_event_id": i, "event_type": "pageview"}, "token")
+	}
+
+	storage := &testBulkStreamingStorage{}
+	tableHelper := NewTableHelper(nil, nil, map[string]bool{}, map[typing.DataType]string{}, true, 0, nil, "", nil)
+	worker := newStreamingWorker(queue, processor, storage, caching.NewEventsCache(&meta.Dummy{}, 10),
+		logging.NewAsyncLogger(&testWriteCloser{}, false), batchConfig, tableHelper)
+	worker.start()
+
+	return worker, storage, queue, func() {
+		queue.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestStreamingWorkerBatchFlushByLatency(t *testing.T) {
+	worker, storage, _, cleanup := startTestBatchingWorker(t, &StreamBatchConfig{Enabled: true, MaxEvents: 100, MaxLatencyMs: 200}, 3)
+	defer cleanup()
+	defer worker.Close()
+
+	require.Eventually(t, func() bool { return len(storage.getBatches()) > 0 }, 5*time.Second, 10*time.Millisecond,
+		"batch must be inserted after max latency")
+
+	batches := storage.getBatches()
+	require.Len(t, batches, 1)
+	require.Len(t, batches[0], 3)
+}
+
+func TestStreamingWorkerBatchFlushByMaxEvents(t *testing.T) {
+	worker, storage, _, cleanup := startTestBatchingWorker(t, &StreamBatchConfig{Enabled: true, MaxEvents: 2, MaxLatencyMs: 60000}, 5)
+	defer cleanup()
+	defer worker.Close()
+
+	require.Eventually(t, func() bool { return len(storage.getBatches()) == 2 }, 5*time.Second, 10*time.Millisecond,
+		"full batches must be inserted without waiting for max latency")
+
+	for _, batch := range storage.getBatches() {
+		require.Len(t, batch, 2)
+	}
+}
+
+func TestStreamingWorkerBatchFlushOnClose(t *testing.T) {
+	worker, storage, queue, cleanup := startTestBatchingWorker(t, &StreamBatchConfig{Enabled: true, MaxEvents: 100, MaxLatencyMs: 60000}, 3)
+	defer cleanup()
+
+	require.Eventually(t, func() bool { return queue.Size() == 0 }, 5*time.Second, 10*time.Millisecond,
+		"all events must be collected into the batch")
+	require.Empty(t, storage.getBatches())
+
+	//collected batch is inserted before Close returns
+	require.NoError(t, worker.Close())
+	batches := storage.getBatches()
+	require.Len(t, batches, 1)
+	require.Len(t, batches[0], 3)
+
+	//second Close is no-op
+	require.NoError(t, worker.Close())
+
+	//events which are consumed after close stay in the queue
+	queue.Consume(map[string]interface{}{"eventn_ctx_event_id": 3, "event_type": "pageview"}, "token")
+	time.Sleep(100 * time.Millisecond)
+	require.Equal(t, 1, queue.Size())
+	require.Len(t, storage.getBatches(), 1)
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/jitsucom/jitsu/server/adapters"
 	"github.com/jitsucom/jitsu/server/logging"
@@ -28,10 +29,18 @@ type TableHelper struct {
 
 	streamMode bool
 	maxColumns int
+	overflow   *Overflow
+
+	schemaEvolution   string
+	queryLogger       *logging.QueryLogger
+	decisionsMutex    sync.Mutex
+	loggedDecisions   map[string]bool
+	conflictsMutex    sync.Mutex
+	resolvedConflicts map[string]*tableConflicts
 }
 
 func NewTableHelper(manager adapters.TableManager, monitorKeeper MonitorKeeper, pkFields map[string]bool,
-	columnTypesMapping map[typing.DataType]string, streamMode bool, maxColumns int) *TableHelper {
+	columnTypesMapping map[typing.DataType]string, streamMode bool, maxColumns int, overflow *Overflow, schemaEvolution string, queryLogger *logging.QueryLogger) *TableHelper {
 
 	return &TableHelper{
 		manager:       manager,
@@ -43,6 +52,12 @@ func NewTableHelper(manager adapters.TableManager, monitorKeeper MonitorKeeper,
 
 		streamMode: streamMode,
 		maxColumns: maxColumns,
+		overflow:   overflow,
+
+		schemaEvolution:   schemaEvolution,
+		queryLogger:       queryLogger,
+		loggedDecisions:   map[string]bool{},
+		resolvedConflicts: map[string]*tableConflicts{},
 	}
 }
 
@@ -54,9 +69,17 @@ func (th *TableHelper) MapTableSchema(batchHeader *schema.BatchHeader) *adapters
 		Version:  0,
 	}
 
+	if len(batchHeader.PKFields) > 0 {
+		table.PKFields = batchHeader.PKFields
+	}
+
 	for fieldName, field := range batchHeader.Fields {
 		//map storage type
 		sqlType, ok := th.columnTypesMapping[field.GetType()]
+		//native arrays columns are typed with arrays elements type
+		if field.GetType() == typing.ARRAY {
+			sqlType, ok = th.arrayColumnType(field.GetArrayElementType())
+		}
 		if ok {
 			table.Columns[fieldName] = adapters.Column{SQLType: sqlType}
 		} else {
@@ -70,8 +93,35 @@ func (th *TableHelper) MapTableSchema(batchHeader *schema.BatchHeader) *adapters
 //EnsureTable return DB table schema and err if occurred
 //if table doesn't exist - create a new one and increment version
 //if exists - calculate diff, patch existing one with diff and increment version
+//fields beyond max_columns are packed into the overflow column if it is configured (see applyOverflow)
+//column types conflicts are resolved according to schema evolution policy (see resolveConflicts)
+//partitions are created in advance if the manager is an adapters.PartitionManager (see ensurePartitions)
 //return actual db table schema (with actual db types)
 func (th *TableHelper) EnsureTable(destinationName string, dataSchema *adapters.Table) (*adapters.Table, error) {
+	dbSchema, err := th.ensureTable(destinationName, dataSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	th.ensurePartitions(destinationName, dbSchema)
+
+	return dbSchema, nil
+}
+
+//ensurePartitions creates partitions of the current and the next periods if the table is partitioned
+//errors are only logged: rows which don't fit into created partitions are written into the default partition
+func (th *TableHelper) ensurePartitions(destinationName string, dbSchema *adapters.Table) {
+	partitionManager, ok := th.manager.(adapters.PartitionManager)
+	if !ok {
+		return
+	}
+
+	if err := partitionManager.EnsurePartitions(dbSchema.Name, time.Now()); err != nil {
+		logging.Errorf("[%s] %v", destinationName, err)
+	}
+}
+
+func (th *TableHelper) ensureTable(destinationName string, dataSchema *adapters.Table) (*adapters.Table, error) {
 	var dbSchema *adapters.Table
 	var err error
 
@@ -84,21 +134,14 @@ func (th *TableHelper) EnsureTable(destinationName string, dataSchema *adapters.
 		return nil, err
 	}
 
-	//if diff doesn't exist - do nothing
+	//if diff and columns to widen don't exist - do nothing
+	th.applyOverflow(dbSchema, dataSchema)
+	alterColumns := th.resolveConflicts(dbSchema, dataSchema)
 	diff := dbSchema.Diff(dataSchema)
-	if !diff.Exists() {
+	if !diff.Exists() && len(alterColumns) == 0 {
 		return dbSchema, nil
 	}
 
-	//check if max columns error
-	if th.maxColumns > 0 {
-		columnsCount := len(dbSchema.Columns) + len(diff.Columns)
-		if columnsCount > th.maxColumns {
-			//return nil, fmt.Errorf("Count of columns %d should be less or equal 'server.max_columns' (or destination.data_layout.max_columns) setting %d", columnsCount, th.maxColumns)
-			logging.Warnf("Count of columns %d should be less or equal 'server.max_columns' (or destination.data_layout.max_columns) setting %d", columnsCount, th.maxColumns)
-		}
-	}
-
 	//** Diff exists **
 	//patch schema
 	lock, err := th.monitorKeeper.Lock(destinationName, dbSchema.Name)
@@ -110,8 +153,10 @@ func (th *TableHelper) EnsureTable(destinationName string, dataSchema *adapters.
 	defer th.monitorKeeper.Unlock(lock)
 
 	//handle schema local changes (patching was in another goroutine)
+	th.applyOverflow(dbSchema, dataSchema)
+	alterColumns = th.resolveConflicts(dbSchema, dataSchema)
 	diff = dbSchema.Diff(dataSchema)
-	if !diff.Exists() {
+	if !diff.Exists() && len(alterColumns) == 0 {
 		return dbSchema, nil
 	}
 
@@ -130,16 +175,27 @@ func (th *TableHelper) EnsureTable(destinationName string, dataSchema *adapters.
 
 		dbSchema.Version = ver
 
+		th.applyOverflow(dbSchema, dataSchema)
+		alterColumns = th.resolveConflicts(dbSchema, dataSchema)
 		diff = dbSchema.Diff(dataSchema)
 	}
 
-	//check if newSchemaDiff doesn't exist - do nothing
-	if !diff.Exists() {
+	//check if newSchemaDiff and columns to widen don't exist - do nothing
+	if !diff.Exists() && len(alterColumns) == 0 {
 		return dbSchema, nil
 	}
 
-	if err := th.manager.PatchTableSchema(diff); err != nil {
-		return nil, err
+	if diff.Exists() {
+		if err := th.manager.PatchTableSchema(diff); err != nil {
+			return nil, err
+		}
+	}
+
+	//widen columns types (resolveConflicts returns columns only if manager is a ColumnTypeManager)
+	for columnName, column := range alterColumns {
+		if err := th.manager.(adapters.ColumnTypeManager).AlterColumnType(dbSchema.Name, columnName, column); err != nil {
+			return nil, err
+		}
 	}
 
 	newVersion, err := th.monitorKeeper.IncrementVersion(destinationName, diff.Name)
@@ -152,6 +208,9 @@ func (th *TableHelper) EnsureTable(destinationName string, dataSchema *adapters.
 	for k, v := range diff.Columns {
 		dbSchema.Columns[k] = v
 	}
+	for k, v := range alterColumns {
+		dbSchema.Columns[k] = v
+	}
 	//pk fields
 	if len(diff.PKFields) > 0 {
 		dbSchema.PKFields = diff.PKFields
@@ -166,6 +225,22 @@ func (th *TableHelper) EnsureTable(destinationName string, dataSchema *adapters.
 	return dbSchema, nil
 }
 
+//AdaptObjects prepares objects for inserting into dbSchema table (the result of EnsureTable):
+//moves values which don't fit column types into <column>_conflict columns (quarantine schema evolution policy)
+//packs fields which aren't table columns into the overflow column
+//and converts nested objects and arrays into column values (native arrays or JSON strings)
+func (th *TableHelper) AdaptObjects(dbSchema *adapters.Table, objects ...map[string]interface{}) {
+	if dbSchema == nil {
+		return
+	}
+
+	for _, object := range objects {
+		th.quarantineConflicts(dbSchema, object)
+		th.packOverflow(dbSchema, object)
+		th.adaptNestedValues(dbSchema, object)
+	}
+}
+
 func (th *TableHelper) getSavedTableSchema(destinationName string, dataSchema *adapters.Table) (*adapters.Table, error) {
 	th.RLock()
 	dbSchema, ok := th.tables[dataSchema.Name]
@@ -222,6 +297,7 @@ func (th *TableHelper) getOrCreate(destinationName string, dataSchema *adapters.
 
 	//create new or get version
 	if !dbTableSchema.Exists() {
+		th.applyOverflow(dbTableSchema, dataSchema)
 		if err := th.manager.CreateTable(dataSchema); err != nil {
 			return nil, fmt.Errorf("Error creating table %s: %v", dataSchema.Name, err)
 		}
@@ -1,7 +1,9 @@
 package storages
 
 import (
+	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/jitsucom/jitsu/server/adapters"
 	"github.com/jitsucom/jitsu/server/schema"
@@ -32,12 +34,411 @@ func TestMapTableSchema(t *testing.T) {
 			adapters.Table{Name: "test_table", Columns: adapters.Columns{"field1": adapters.Column{SQLType: "text"}, "field2": adapters.Column{SQLType: "text"}},
 				PKFields: map[string]bool{"field1": true}},
 		},
+		{
+			"source primary key fields override configured ones",
+			schema.BatchHeader{TableName: "test_table", Fields: schema.Fields{"field1": schema.NewField(typing.STRING)}, PKFields: map[string]bool{"id": true}},
+			map[string]bool{"field1": true},
+			map[typing.DataType]string{typing.STRING: "text"},
+			adapters.Table{Name: "test_table", Columns: adapters.Columns{"field1": adapters.Column{SQLType: "text"}},
+				PKFields: map[string]bool{"id": true}},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			tableHelper := NewTableHelper(nil, nil, tt.pkFields, tt.columnTypesMapping, false, 0)
+			tableHelper := NewTableHelper(nil, nil, tt.pkFields, tt.columnTypesMapping, false, 0, nil, SchemaEvolutionFail, nil)
 			actual := tableHelper.MapTableSchema(&tt.input)
 			require.Equal(t, tt.expected, *actual, "Tables aren't equal")
 		})
 	}
 }
+
+func TestEnsureTableSchemaEvolution(t *testing.T) {
+	tests := []struct {
+		name            string
+		schemaEvolution string
+		columnTypes     bool
+		expectedColumns adapters.Columns
+		expectedAltered adapters.Columns
+	}{
+		{
+			"fail policy keeps column type",
+			SchemaEvolutionFail,
+			true,
+			adapters.Columns{"field1": adapters.Column{SQLType: "bigint"}},
+			adapters.Columns{},
+		},
+		{
+			"widen policy changes column type to the common supertype",
+			SchemaEvolutionWiden,
+			true,
+			adapters.Columns{"field1": adapters.Column{SQLType: "text"}},
+			adapters.Columns{"field1": adapters.Column{SQLType: "text"}},
+		},
+		{
+			"widen policy without column types changes support keeps column type",
+			SchemaEvolutionWiden,
+			false,
+			adapters.Columns{"field1": adapters.Column{SQLType: "bigint"}},
+			adapters.Columns{},
+		},
+		{
+			"quarantine policy adds conflict column",
+			SchemaEvolutionQuarantine,
+			true,
+			adapters.Columns{"field1": adapters.Column{SQLType: "bigint"}, "field1_conflict": adapters.Column{SQLType: "text"}},
+			adapters.Columns{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := &testTableManager{tables: map[string]*adapters.Table{
+				"test_table": {Name: "test_table", Columns: adapters.Columns{"field1": adapters.Column{SQLType: "bigint"}}, PKFields: map[string]bool{}},
+			}, altered: adapters.Columns{}}
+			var tableManager adapters.TableManager = &testPlainTableManager{manager}
+			if tt.columnTypes {
+				tableManager = manager
+			}
+
+			tableHelper := NewTableHelper(tableManager, &testMonitorKeeper{}, map[string]bool{}, adapters.SchemaToPostgres, false, 0, nil, tt.schemaEvolution, nil)
+			dataSchema := tableHelper.MapTableSchema(&schema.BatchHeader{TableName: "test_table", Fields: schema.Fields{"field1": schema.NewField(typing.STRING)}})
+
+			dbSchema, err := tableHelper.EnsureTable("test", dataSchema)
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedColumns, dbSchema.Columns)
+			require.Equal(t, tt.expectedColumns, manager.tables["test_table"].Columns)
+			require.Equal(t, tt.expectedAltered, manager.altered)
+		})
+	}
+}
+
+func TestResolveConflictsCache(t *testing.T) {
+	tableHelper := NewTableHelper(nil, nil, map[string]bool{}, adapters.SchemaToPostgres, false, 0, nil, SchemaEvolutionQuarantine, nil)
+	dbSchema := &adapters.Table{Name: "test_table", Columns: adapters.Columns{"field1": adapters.Column{SQLType: "bigint"}}, Version: 1}
+
+	for i := 0; i < 3; i++ {
+		dataSchema := &adapters.Table{Name: "test_table", Columns: adapters.Columns{"field1": adapters.Column{SQLType: "text"}}}
+		require.Empty(t, tableHelper.resolveConflicts(dbSchema, dataSchema))
+		require.Equal(t, adapters.Columns{"field1": adapters.Column{SQLType: "text"}, "field1_conflict": adapters.Column{SQLType: "text"}}, dataSchema.Columns)
+	}
+	require.Len(t, tableHelper.resolvedConflicts, 1)
+	require.Equal(t, int64(1), tableHelper.resolvedConflicts["test_table"].version)
+	require.Equal(t, map[string]*conflictResolution{"field1:bigint:text": {conflictColumn: "field1_conflict"}}, tableHelper.resolvedConflicts["test_table"].resolutions)
+
+	//new table schema version drops cached resolutions
+	dbSchema = &adapters.Table{Name: "test_table", Columns: adapters.Columns{"field1": adapters.Column{SQLType: "text"}, "field2": adapters.Column{SQLType: "bigint"}}, Version: 2}
+	dataSchema := &adapters.Table{Name: "test_table", Columns: adapters.Columns{"field1": adapters.Column{SQLType: "text"}, "field2": adapters.Column{SQLType: "boolean"}}}
+	require.Empty(t, tableHelper.resolveConflicts(dbSchema, dataSchema))
+	require.Equal(t, int64(2), tableHelper.resolvedConflicts["test_table"].version)
+	require.Equal(t, map[string]*conflictResolution{"field2:bigint:boolean": {}}, tableHelper.resolvedConflicts["test_table"].resolutions)
+}
+
+func TestValidateSchemaEvolution(t *testing.T) {
+	for _, destinationType := range []string{PostgresType, MySQLType, ClickHouseType} {
+		require.NoError(t, ValidateSchemaEvolution(SchemaEvolutionWiden, destinationType), destinationType)
+	}
+
+	for _, destinationType := range []string{RedshiftType, BigQueryType, SnowflakeType} {
+		require.NoError(t, ValidateSchemaEvolution("", destinationType), destinationType)
+		require.NoError(t, ValidateSchemaEvolution(SchemaEvolutionFail, destinationType), destinationType)
+		require.NoError(t, ValidateSchemaEvolution(SchemaEvolutionQuarantine, destinationType), destinationType)
+		require.Error(t, ValidateSchemaEvolution(SchemaEvolutionWiden, destinationType), destinationType)
+	}
+
+	require.Error(t, ValidateSchemaEvolution("unknown", PostgresType))
+}
+
+func TestQuarantineConflicts(t *testing.T) {
+	dbSchema := &adapters.Table{Name: "test_table", Columns: adapters.Columns{
+		"field1":          adapters.Column{SQLType: "bigint"},
+		"field1_conflict": adapters.Column{SQLType: "text"},
+		"field2":          adapters.Column{SQLType: "bigint"},
+		"field3":          adapters.Column{SQLType: "timestamp without time zone"},
+		"field3_conflict": adapters.Column{SQLType: "text"},
+	}}
+	objects := []map[string]interface{}{
+		{"field1": int64(1), "field2": int64(1), "field3": time.Date(2021, 5, 18, 10, 0, 0, 0, time.UTC)},
+		{"field1": "abc", "field2": "abc", "field3": "not a timestamp"},
+		{"field1": true, "field3": 1.5},
+	}
+
+	fail := NewTableHelper(nil, nil, map[string]bool{}, adapters.SchemaToPostgres, false, 0, nil, SchemaEvolutionFail, nil)
+	fail.AdaptObjects(dbSchema, objects[1])
+	require.Equal(t, map[string]interface{}{"field1": "abc", "field2": "abc", "field3": "not a timestamp"}, objects[1])
+
+	quarantine := NewTableHelper(nil, nil, map[string]bool{}, adapters.SchemaToPostgres, false, 0, nil, SchemaEvolutionQuarantine, nil)
+	quarantine.AdaptObjects(dbSchema, objects...)
+	require.Equal(t, []map[string]interface{}{
+		{"field1": int64(1), "field2": int64(1), "field3": time.Date(2021, 5, 18, 10, 0, 0, 0, time.UTC)},
+		{"field1_conflict": "abc", "field2": "abc", "field3_conflict": "not a timestamp"},
+		{"field1": true, "field3_conflict": "1.5"},
+	}, objects)
+}
+
+func TestEnsureTableOverflow(t *testing.T) {
+	overflow, err := NewOverflow(&OverflowConfig{Enabled: true, Paths: []string{"/properties/Custom"}})
+	require.NoError(t, err)
+
+	manager := &testTableManager{tables: map[string]*adapters.Table{
+		"existing_table": {Name: "existing_table", Columns: adapters.Columns{
+			"eventn_ctx_event_id": adapters.Column{SQLType: "text"},
+			"_timestamp":          adapters.Column{SQLType: "timestamp"},
+		}, PKFields: map[string]bool{}},
+	}, altered: adapters.Columns{}}
+	tableHelper := NewTableHelper(manager, &testMonitorKeeper{}, map[string]bool{}, adapters.SchemaToPostgres, false, 4, overflow, SchemaEvolutionFail, nil)
+
+	//existing table: path field and fields beyond the limit are packed
+	dataSchema := tableHelper.MapTableSchema(&schema.BatchHeader{TableName: "existing_table", Fields: schema.Fields{
+		"eventn_ctx_event_id": schema.NewField(typing.STRING),
+		"_timestamp":          schema.NewField(typing.TIMESTAMP),
+		"field_a":             schema.NewField(typing.INT64),
+		"field_b":             schema.NewField(typing.STRING),
+		"field_c":             schema.NewField(typing.BOOL),
+		"properties_custom_x": schema.NewField(typing.INT64),
+	}})
+	dbSchema, err := tableHelper.EnsureTable("test", dataSchema)
+	require.NoError(t, err)
+	expectedColumns := adapters.Columns{
+		"eventn_ctx_event_id": adapters.Column{SQLType: "text"},
+		"_timestamp":          adapters.Column{SQLType: "timestamp"},
+		"field_a":             adapters.Column{SQLType: "bigint"},
+		"_unmapped_data":      adapters.Column{SQLType: "jsonb"},
+	}
+	require.Equal(t, expectedColumns, dbSchema.Columns)
+	require.Equal(t, expectedColumns, manager.tables["existing_table"].Columns)
+
+	object := map[string]interface{}{"eventn_ctx_event_id": "id1", "field_a": 1, "field_b": "b", "field_c": true, "properties_custom_x": 5}
+	tableHelper.AdaptObjects(dbSchema, object)
+	require.Equal(t, map[string]interface{}{"eventn_ctx_event_id": "id1", "field_a": 1,
+		"_unmapped_data": `{"field_b":"b","field_c":true,"properties_custom_x":5}`}, object)
+
+	//new table: system fields are the last candidates for packing
+	dataSchema = tableHelper.MapTableSchema(&schema.BatchHeader{TableName: "new_table", Fields: schema.Fields{
+		"eventn_ctx_event_id": schema.NewField(typing.STRING),
+		"_timestamp":          schema.NewField(typing.TIMESTAMP),
+		"field_a":             schema.NewField(typing.INT64),
+		"field_b":             schema.NewField(typing.STRING),
+		"field_c":             schema.NewField(typing.BOOL),
+	}})
+	dbSchema, err = tableHelper.EnsureTable("test", dataSchema)
+	require.NoError(t, err)
+	require.Equal(t, adapters.Columns{
+		"eventn_ctx_event_id": adapters.Column{SQLType: "text"},
+		"_timestamp":          adapters.Column{SQLType: "timestamp"},
+		"field_a":             adapters.Column{SQLType: "bigint"},
+		"_unmapped_data":      adapters.Column{SQLType: "jsonb"},
+	}, manager.tables["new_table"].Columns)
+
+	_, err = NewOverflow(&OverflowConfig{Enabled: true, Paths: []string{"/properties//custom"}})
+	require.Error(t, err)
+}
+
+func TestAdaptNestedValues(t *testing.T) {
+	manager := &testTableManager{tables: map[string]*adapters.Table{
+		"existing_table": {Name: "existing_table", Columns: adapters.Columns{
+			"tags_string": adapters.Column{SQLType: "text"},
+			"scores":      adapters.Column{SQLType: "double precision[]"},
+		}, PKFields: map[string]bool{}},
+	}, altered: adapters.Columns{}}
+	tableHelper := NewTableHelper(manager, &testMonitorKeeper{}, map[string]bool{}, adapters.SchemaToPostgres, false, 0, nil, SchemaEvolutionFail, nil)
+
+	fields := schema.Fields{
+		"tags":        schema.NewArrayField(typing.STRING),
+		"ids":         schema.NewArrayField(typing.INT64),
+		"flags":       schema.NewArrayField(typing.BOOL),
+		"nested":      schema.NewArrayField(typing.JSON),
+		"scores":      schema.NewArrayField(typing.INT64),
+		"tags_string": schema.NewArrayField(typing.STRING),
+		"traits":      schema.NewField(typing.JSON),
+	}
+	fields.Merge(schema.Fields{"ids": schema.NewArrayField(typing.UNKNOWN)})
+	dataSchema := tableHelper.MapTableSchema(&schema.BatchHeader{TableName: "existing_table", Fields: fields})
+	dbSchema, err := tableHelper.EnsureTable("test", dataSchema)
+	require.NoError(t, err)
+	require.Equal(t, adapters.Columns{
+		"tags":        adapters.Column{SQLType: "text[]"},
+		"ids":         adapters.Column{SQLType: "bigint[]"},
+		"flags":       adapters.Column{SQLType: "boolean[]"},
+		"nested":      adapters.Column{SQLType: "text[]"},
+		"scores":      adapters.Column{SQLType: "double precision[]"},
+		"tags_string": adapters.Column{SQLType: "text"},
+		"traits":      adapters.Column{SQLType: "jsonb"},
+	}, dbSchema.Columns)
+
+	//arrays are typed native arrays in array columns and JSON strings in other columns
+	object := map[string]interface{}{
+		"tags":        []interface{}{"a", json.Number("1"), nil, map[string]interface{}{"b": true}},
+		"ids":         []interface{}{json.Number("1"), nil, 2},
+		"flags":       []interface{}{true, false},
+		"nested":      []interface{}{map[string]interface{}{"a": 1}},
+		"scores":      []interface{}{1, json.Number("2.5")},
+		"tags_string": []interface{}{"a", 1},
+		"traits":      map[string]interface{}{"plan": "pro", "seats": []interface{}{1, 2}},
+	}
+	tableHelper.AdaptObjects(dbSchema, object)
+	require.Equal(t, map[string]interface{}{
+		"tags":        []string{"a", "1", "", `{"b":true}`},
+		"ids":         []int64{1, 0, 2},
+		"flags":       []bool{true, false},
+		"nested":      []string{`{"a":1}`},
+		"scores":      []float64{1, 2.5},
+		"tags_string": `["a",1]`,
+		"traits":      `{"plan":"pro","seats":[1,2]}`,
+	}, object)
+
+	//elements which can't be converted into the column elements type are kept as is
+	object = map[string]interface{}{"ids": []interface{}{"abc"}}
+	tableHelper.AdaptObjects(dbSchema, object)
+	require.Equal(t, map[string]interface{}{"ids": []interface{}{"abc"}}, object)
+}
+
+func TestArrayColumnType(t *testing.T) {
+	tests := []struct {
+		name                string
+		mapping             map[typing.DataType]string
+		elementType         typing.DataType
+		expectedType        string
+		expectedElementType typing.DataType
+	}{
+		{"postgres integers", adapters.SchemaToPostgres, typing.INT64, "bigint[]", typing.INT64},
+		{"postgres nested objects", adapters.SchemaToPostgres, typing.JSON, "text[]", typing.STRING},
+		{"postgres empty arrays", adapters.SchemaToPostgres, typing.UNKNOWN, "text[]", typing.STRING},
+		{"clickhouse floats", adapters.SchemaToClickhouse, typing.FLOAT64, "Array(Float64)", typing.FLOAT64},
+		{"clickhouse booleans", adapters.SchemaToClickhouse, typing.BOOL, "Array(UInt8)", typing.BOOL},
+		{"bigquery timestamps", adapters.SchemaToBigQueryString, typing.TIMESTAMP, "REPEATED TIMESTAMP", typing.TIMESTAMP},
+		{"snowflake variant", adapters.SchemaToSnowflake, typing.INT64, "variant", typing.STRING},
+		{"mysql json", adapters.SchemaToMySQL, typing.INT64, "JSON", typing.STRING},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tableHelper := NewTableHelper(nil, nil, map[string]bool{}, tt.mapping, false, 0, nil, SchemaEvolutionFail, nil)
+			sqlType, ok := tableHelper.arrayColumnType(tt.elementType)
+			require.True(t, ok)
+			require.Equal(t, tt.expectedType, sqlType)
+			require.Equal(t, tt.expectedElementType, tableHelper.arrayElementType(sqlType))
+		})
+	}
+}
+
+func TestResolveArraysConflicts(t *testing.T) {
+	dbSchema := &adapters.Table{Name: "test_table", Columns: adapters.Columns{
+		"ids":          adapters.Column{SQLType: "bigint[]"},
+		"ids_conflict": adapters.Column{SQLType: "text"},
+		"tags":         adapters.Column{SQLType: "text[]"},
+	}, Version: 1}
+	dataSchema := &adapters.Table{Name: "test_table", Columns: adapters.Columns{
+		"ids":  adapters.Column{SQLType: "numeric(38,18)[]"},
+		"tags": adapters.Column{SQLType: "bigint[]"},
+	}}
+
+	//elements types are widened
+	manager := &testTableManager{tables: map[string]*adapters.Table{}, altered: adapters.Columns{}}
+	widen := NewTableHelper(manager, &testMonitorKeeper{}, map[string]bool{}, adapters.SchemaToPostgres, false, 0, nil, SchemaEvolutionWiden, nil)
+	require.Equal(t, adapters.Columns{"ids": adapters.Column{SQLType: "numeric(38,18)[]"}}, widen.resolveConflicts(dbSchema, dataSchema))
+
+	//arrays with elements which don't fit column elements type are quarantined
+	quarantine := NewTableHelper(nil, nil, map[string]bool{}, adapters.SchemaToPostgres, false, 0, nil, SchemaEvolutionQuarantine, nil)
+	objects := []map[string]interface{}{
+		{"ids": []interface{}{json.Number("1"), 2}, "tags": []interface{}{1}},
+		{"ids": []interface{}{"abc", 1}},
+		{"ids": []interface{}{}},
+	}
+	quarantine.AdaptObjects(dbSchema, objects...)
+	require.Equal(t, []map[string]interface{}{
+		{"ids": []int64{1, 2}, "tags": []string{"1"}},
+		{"ids_conflict": `["abc",1]`},
+		{"ids": []int64{}},
+	}, objects)
+}
+
+//testTableManager is an in-memory adapters.TableManager and adapters.ColumnTypeManager
+type testTableManager struct {
+	tables  map[string]*adapters.Table
+	altered adapters.Columns
+}
+
+func (ttm *testTableManager) GetTableSchema(tableName string) (*adapters.Table, error) {
+	table, ok := ttm.tables[tableName]
+	if !ok {
+		return &adapters.Table{Name: tableName, Columns: adapters.Columns{}, PKFields: map[string]bool{}}, nil
+	}
+
+	columns := adapters.Columns{}
+	for name, column := range table.Columns {
+		columns[name] = column
+	}
+	return &adapters.Table{Name: tableName, Columns: columns, PKFields: table.PKFields}, nil
+}
+
+func (ttm *testTableManager) CreateTable(schemaToCreate *adapters.Table) error {
+	ttm.tables[schemaToCreate.Name] = schemaToCreate
+	return nil
+}
+
+func (ttm *testTableManager) PatchTableSchema(schemaToAdd *adapters.Table) error {
+	for name, column := range schemaToAdd.Columns {
+		ttm.tables[schemaToAdd.Name].Columns[name] = column
+	}
+	return nil
+}
+
+func (ttm *testTableManager) AlterColumnType(tableName, columnName string, column adapters.Column) error {
+	ttm.tables[tableName].Columns[columnName] = column
+	ttm.altered[columnName] = column
+	return nil
+}
+
+//testPlainTableManager hides AlterColumnType of testTableManager
+type testPlainTableManager struct {
+	manager *testTableManager
+}
+
+func (tptm *testPlainTableManager) GetTableSchema(tableName string) (*adapters.Table, error) {
+	return tptm.manager.GetTableSchema(tableName)
+}
+
+func (tptm *testPlainTableManager) CreateTable(schemaToCreate *adapters.Table) error {
+	return tptm.manager.CreateTable(schemaToCreate)
+}
+
+func (tptm *testPlainTableManager) PatchTableSchema(schemaToAdd *adapters.Table) error {
+	return tptm.manager.PatchTableSchema(schemaToAdd)
+}
+
+type testLock struct{}
+
+func (tl *testLock) Unlock()            {}
+func (tl *testLock) Identifier() string { return "test" }
+
+//testMonitorKeeper is a single node MonitorKeeper without real locks
+type testMonitorKeeper struct {
+	version int64
+}
+
+func (tmk *testMonitorKeeper) Lock(system string, collection string) (Lock, error) {
+	return &testLock{}, nil
+}
+
+func (tmk *testMonitorKeeper) TryLock(system string, collection string) (Lock, error) {
+	return &testLock{}, nil
+}
+
+func (tmk *testMonitorKeeper) Unlock(lock Lock) error {
+	return nil
+}
+
+func (tmk *testMonitorKeeper) IsLocked(system string, collection string) (bool, error) {
+	return false, nil
+}
+
+func (tmk *testMonitorKeeper) GetVersion(system string, collection string) (int64, error) {
+	return tmk.version, nil
+}
+
+func (tmk *testMonitorKeeper) IncrementVersion(system string, collection string) (int64, error) {
+	tmk.version++
+	return tmk.version, nil
+}
+
+func (tmk *testMonitorKeeper) Close() error {
+	return nil
+}
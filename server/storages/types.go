@@ -12,18 +12,25 @@ const (
 	RedshiftType        = "redshift"
 	BigQueryType        = "bigquery"
 	PostgresType        = "postgres"
+	MySQLType           = "mysql"
 	ClickHouseType      = "clickhouse"
 	S3Type              = "s3"
 	SnowflakeType       = "snowflake"
 	GoogleAnalyticsType = "google_analytics"
 	FacebookType        = "facebook"
+	KafkaType           = "kafka"
+	WebHookType         = "webhook"
+	GCSType             = "gcs"
+	FileType            = "file"
+	SQLiteType          = "sqlite"
 )
 
 type Storage interface {
 	io.Closer
 	DryRun(payload events.Event) ([]adapters.TableField, error)
 	Store(fileName string, payload []byte, alreadyUploadedTables map[string]bool) (map[string]*StoreResult, int, error)
-	StoreWithParseFunc(fileName string, payload []byte, skipTables map[string]bool, parseFunc func([]byte) (map[string]interface{}, error)) (map[string]*StoreResult, int, error)
+	//StoreWithParseFunc returns events which failed processing: they aren't written into fallback (see StoreWithFallback)
+	StoreWithParseFunc(fileName string, payload []byte, skipTables map[string]bool, parseFunc func([]byte) (map[string]interface{}, error)) (map[string]*StoreResult, []*events.FailedEvent, error)
 	SyncStore(overriddenDataSchema *schema.BatchHeader, objects []map[string]interface{}, timeIntervalValue string) (int, error)
 	Update(object map[string]interface{}) error
 	Fallback(events ...*events.FailedEvent)
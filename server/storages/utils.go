@@ -5,8 +5,30 @@ import (
 	"github.com/jitsucom/jitsu/server/adapters"
 	"github.com/jitsucom/jitsu/server/events"
 	"github.com/jitsucom/jitsu/server/schema"
+	"github.com/jitsucom/jitsu/server/typing"
 )
 
+//StoreWithFallback stores payload with storage.StoreWithParseFunc and writes events which failed processing
+//into the storage fallback only if other events have been inserted ok (otherwise the whole payload will be stored again)
+//return result per table, failed events count and err if occurred
+func StoreWithFallback(storage Storage, fileName string, payload []byte, alreadyUploadedTables map[string]bool,
+	parseFunc func([]byte) (map[string]interface{}, error)) (map[string]*StoreResult, int, error) {
+	tableResults, failedEvents, err := storage.StoreWithParseFunc(fileName, payload, alreadyUploadedTables, parseFunc)
+	if err != nil {
+		return nil, linesCount(payload), err
+	}
+
+	for _, result := range tableResults {
+		if result.Err != nil {
+			return tableResults, len(failedEvents), nil
+		}
+	}
+
+	storage.Fallback(failedEvents...)
+
+	return tableResults, len(failedEvents), nil
+}
+
 //return rows count from byte array
 func linesCount(s []byte) int {
 	nl := []byte{'\n'}
@@ -18,17 +40,40 @@ func linesCount(s []byte) int {
 }
 
 func dryRun(payload events.Event, processor *schema.Processor, tableHelper *TableHelper) ([]adapters.TableField, error) {
-	batchHeader, event, err := processor.ProcessEvent(payload)
+	envelopes, err := processor.ProcessEvent(payload)
 	if err != nil {
 		return nil, err
 	}
 
-	tableSchema := tableHelper.MapTableSchema(batchHeader)
 	var dryRunResponses []adapters.TableField
+	for _, envelope := range envelopes {
+		tableSchema := tableHelper.MapTableSchema(envelope.Header)
 
-	for name, column := range tableSchema.Columns {
-		dryRunResponses = append(dryRunResponses, adapters.TableField{Field: name, Type: column.SQLType, Value: event[name]})
+		for name, column := range tableSchema.Columns {
+			dryRunResponses = append(dryRunResponses, adapters.TableField{Field: name, Type: column.SQLType, Value: envelope.Event[name]})
+		}
 	}
 
 	return dryRunResponses, nil
 }
+
+//fieldsOf returns fields which are present in objects
+//types are taken from fields (new fields e.g. packed JSON or stringified conflict values are strings)
+func fieldsOf(fields schema.Fields, objects []map[string]interface{}) schema.Fields {
+	result := schema.Fields{}
+	for _, object := range objects {
+		for name := range object {
+			if _, ok := result[name]; ok {
+				continue
+			}
+
+			if field, ok := fields[name]; ok {
+				result[name] = field
+			} else {
+				result[name] = schema.NewField(typing.STRING)
+			}
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,127 @@
+package storages
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/jitsucom/jitsu/server/adapters"
+	"github.com/jitsucom/jitsu/server/caching"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/schema"
+)
+
+//WebHook sends events as HTTP requests rendered from templates in stream mode
+//5xx responses are retried by the streaming worker
+type WebHook struct {
+	name            string
+	webHookAdapter  *adapters.WebHook
+	tableHelper     *TableHelper
+	processor       *schema.Processor
+	streamingWorker *StreamingWorker
+	fallbackLogger  *logging.AsyncLogger
+	eventsCache     *caching.EventsCache
+	staged          bool
+}
+
+//NewWebHook return WebHook instance
+//start streaming worker goroutine
+func NewWebHook(config *Config) (Storage, error) {
+	if !config.streamMode {
+		return nil, fmt.Errorf("WebHook destination doesn't support %s mode", BatchMode)
+	}
+
+	whConfig := config.destination.WebHook
+	if err := whConfig.Validate(); err != nil {
+		return nil, err
+	}
+
+	requestDebugLogger := config.loggerFactory.CreateSQLQueryLogger(config.name)
+	webHookAdapter, err := adapters.NewWebHook(whConfig, requestDebugLogger)
+	if err != nil {
+		return nil, err
+	}
+
+	tableHelper := NewTableHelper(webHookAdapter, config.monitorKeeper, config.pkFields, adapters.SchemaToWebHook, config.streamMode, 0, nil, SchemaEvolutionFail, nil)
+
+	wh := &WebHook{
+		name:           config.name,
+		webHookAdapter: webHookAdapter,
+		tableHelper:    tableHelper,
+		processor:      config.processor,
+		fallbackLogger: config.loggerFactory.CreateFailedLogger(config.name),
+		eventsCache:    config.eventsCache,
+		staged:         config.destination.Staged,
+	}
+
+	wh.streamingWorker = newStreamingWorker(config.eventQueue, config.processor, wh, config.eventsCache, config.loggerFactory.CreateStreamingArchiveLogger(config.name), config.streamBatch, tableHelper)
+	wh.streamingWorker.start()
+
+	return wh, nil
+}
+
+func (wh *WebHook) DryRun(payload events.Event) ([]adapters.TableField, error) {
+	return dryRun(payload, wh.processor, wh.tableHelper)
+}
+
+func (wh *WebHook) Insert(table *adapters.Table, event events.Event) (err error) {
+	return wh.webHookAdapter.Send(event)
+}
+
+func (wh *WebHook) Store(fileName string, payload []byte, alreadyUploadedTables map[string]bool) (map[string]*StoreResult, int, error) {
+	return nil, 0, errors.New("WebHook doesn't support Store() func")
+}
+
+func (wh *WebHook) StoreWithParseFunc(fileName string, payload []byte, skipTables map[string]bool, parseFunc func([]byte) (map[string]interface{}, error)) (map[string]*StoreResult, []*events.FailedEvent, error) {
+	return nil, nil, errors.New("WebHook doesn't support StoreWithParseFunc() func")
+}
+
+func (wh *WebHook) SyncStore(overriddenDataSchema *schema.BatchHeader, objects []map[string]interface{}, timeIntervalValue string) (int, error) {
+	return 0, errors.New("WebHook doesn't support SyncStore() func")
+}
+
+func (wh *WebHook) Update(object map[string]interface{}) error {
+	return errors.New("WebHook doesn't support updates")
+}
+
+func (wh *WebHook) GetUsersRecognition() *UserRecognitionConfiguration {
+	return disabledRecognitionConfiguration
+}
+
+//Fallback log event with error to fallback logger
+func (wh *WebHook) Fallback(failedEvents ...*events.FailedEvent) {
+	for _, failedEvent := range failedEvents {
+		wh.fallbackLogger.ConsumeAny(failedEvent)
+	}
+}
+
+func (wh *WebHook) Name() string {
+	return wh.name
+}
+
+func (wh *WebHook) Type() string {
+	return WebHookType
+}
+
+func (wh *WebHook) IsStaging() bool {
+	return wh.staged
+}
+
+func (wh *WebHook) Close() (multiErr error) {
+	if wh.streamingWorker != nil {
+		if err := wh.streamingWorker.Close(); err != nil {
+			multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing streaming worker: %v", wh.Name(), err))
+		}
+	}
+
+	if err := wh.webHookAdapter.Close(); err != nil {
+		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing WebHook client: %v", wh.Name(), err))
+	}
+
+	if err := wh.fallbackLogger.Close(); err != nil {
+		multiErr = multierror.Append(multiErr, fmt.Errorf("[%s] Error closing fallback logger: %v", wh.Name(), err))
+	}
+
+	return
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/jitsucom/jitsu/server/timestamp"
 	"github.com/jitsucom/jitsu/server/uuid"
 	"strings"
+	"time"
 )
 
 type ResultSaver struct {
@@ -23,15 +24,19 @@ type ResultSaver struct {
 	taskLogger   *TaskLogger
 	destinations []storages.Storage
 	metaStorage  meta.Storage
+
+	//tables of overwritten streams where objects of the previous synchronization have been already deleted
+	overwrittenTables map[string]bool
 }
 
 func NewResultSaver(task *meta.Task, tap string, taskLogger *TaskLogger, destinations []storages.Storage, metaStorage meta.Storage) *ResultSaver {
 	return &ResultSaver{
-		task:         task,
-		tap:          tap,
-		taskLogger:   taskLogger,
-		destinations: destinations,
-		metaStorage:  metaStorage,
+		task:              task,
+		tap:               tap,
+		taskLogger:        taskLogger,
+		destinations:      destinations,
+		metaStorage:       metaStorage,
+		overwrittenTables: map[string]bool{},
 	}
 }
 
@@ -54,9 +59,23 @@ func (rs *ResultSaver) Consume(representation *singer.OutputRepresentation) erro
 			events.EnrichWithEventID(object, eventID)
 		}
 
+		//objects of overwritten stream are stored with ALL time interval:
+		//objects of the previous synchronization are deleted with the first stored portion of the stream
+		timeIntervalValue := ""
+		if stream.Overwrite {
+			interval := drivers.NewTimeInterval(drivers.ALL, time.Time{})
+			for _, object := range stream.Objects {
+				events.EnrichWithTimeInterval(object, interval.String(), interval.LowerEndpoint(), interval.UpperEndpoint())
+			}
+
+			if !rs.overwrittenTables[tableName] && len(stream.Objects) > 0 {
+				timeIntervalValue = interval.String()
+			}
+		}
+
 		//Sync stream
 		for _, storage := range rs.destinations {
-			rowsCount, err := storage.SyncStore(stream.BatchHeader, stream.Objects, "")
+			rowsCount, err := storage.SyncStore(stream.BatchHeader, stream.Objects, timeIntervalValue)
 			if err != nil {
 				errMsg := fmt.Sprintf("Error storing %d source objects in [%s] destination: %v", rowsCount, storage.Name(), err)
 				metrics.ErrorSourceEvents(rs.task.Source, storage.Name(), rowsCount)
@@ -68,6 +87,10 @@ func (rs *ResultSaver) Consume(representation *singer.OutputRepresentation) erro
 			metrics.SuccessObjects(rs.task.Source, rowsCount)
 		}
 
+		if timeIntervalValue != "" {
+			rs.overwrittenTables[tableName] = true
+		}
+
 		counters.SuccessSourceEvents(rs.task.Source, len(stream.Objects))
 
 		rs.taskLogger.INFO("Synchronized successfully Table [%s] key fields [%s] objects [%d]", tableName, strings.Join(stream.KeyFields, ","), len(stream.Objects))
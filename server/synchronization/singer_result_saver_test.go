@@ -0,0 +1,59 @@
+package synchronization
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/drivers"
+	"github.com/jitsucom/jitsu/server/events"
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/jitsucom/jitsu/server/schema"
+	"github.com/jitsucom/jitsu/server/singer"
+	"github.com/jitsucom/jitsu/server/storages"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultSaverOverwrite(t *testing.T) {
+	metaStorage := newTestMetaStorage()
+	destination := &testStorage{}
+	task := &meta.Task{ID: "task", Source: "src", Collection: "airbyte"}
+	rs := NewResultSaver(task, "airbyte/source-test", NewTaskLogger(task.ID, metaStorage), []storages.Storage{destination}, metaStorage)
+
+	users := &singer.StreamRepresentation{BatchHeader: &schema.BatchHeader{TableName: "users", PKFields: map[string]bool{"id": true}}, KeyFields: []string{"id"}}
+	orders := &singer.StreamRepresentation{BatchHeader: &schema.BatchHeader{TableName: "orders"}, Overwrite: true}
+	representation := &singer.OutputRepresentation{Streams: map[string]*singer.StreamRepresentation{"users": users, "orders": orders}}
+
+	//the first portion of overwritten stream deletes objects of the previous synchronization
+	users.Objects = []map[string]interface{}{{"id": 1}}
+	orders.Objects = []map[string]interface{}{{"order_id": 10}, {"order_id": 11}}
+	representation.State = map[string]interface{}{"users": 1}
+	require.NoError(t, rs.Consume(representation))
+
+	//next portions are appended
+	users.Objects = []map[string]interface{}{{"id": 2}}
+	orders.Objects = []map[string]interface{}{{"order_id": 12}}
+	representation.State = map[string]interface{}{"users": 2}
+	require.NoError(t, rs.Consume(representation))
+
+	allInterval := drivers.NewTimeInterval(drivers.ALL, time.Time{}).String()
+	callsPerTable := map[string][]*syncStoreCall{}
+	for _, call := range destination.calls {
+		callsPerTable[call.header.TableName] = append(callsPerTable[call.header.TableName], call)
+	}
+
+	require.Len(t, callsPerTable["users"], 2)
+	for _, call := range callsPerTable["users"] {
+		require.Equal(t, "", call.timeIntervalValue, "appended stream objects mustn't be deleted")
+		require.Equal(t, map[string]bool{"id": true}, call.header.PKFields)
+	}
+
+	require.Len(t, callsPerTable["orders"], 2)
+	require.Equal(t, allInterval, callsPerTable["orders"][0].timeIntervalValue)
+	require.Equal(t, "", callsPerTable["orders"][1].timeIntervalValue)
+	require.Equal(t, allInterval, orders.Objects[0][events.EventnKey+"_"+events.TimeChunkKey], "overwritten stream objects must have ALL time interval")
+	require.Nil(t, users.Objects[0][events.EventnKey+"_"+events.TimeChunkKey])
+
+	state, err := metaStorage.GetSignature("src", "airbyte/source-test", drivers.ALL.String())
+	require.NoError(t, err)
+	require.Equal(t, `{"users":2}`, state)
+}
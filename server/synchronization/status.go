@@ -12,6 +12,8 @@ const (
 	RUNNING   Status = "RUNNING"
 	FAILED    Status = "FAILED"
 	SUCCESS   Status = "SUCCESS"
+	//STATE_CHANGED is a status of the task which isn't a synchronization but a record of manual state change (see TaskService.SaveState)
+	STATE_CHANGED Status = "STATE_CHANGED"
 )
 
 func (s Status) String() string {
@@ -28,7 +30,9 @@ func StatusFromString(value string) (Status, error) {
 		return SUCCESS, nil
 	case "RUNNING":
 		return RUNNING, nil
+	case "STATE_CHANGED":
+		return STATE_CHANGED, nil
 	default:
-		return "", fmt.Errorf("Unknown status: %s. Supported: [SCHEDULED, FAILED, SUCCESS, RUNNING]", value)
+		return "", fmt.Errorf("Unknown status: %s. Supported: [SCHEDULED, FAILED, SUCCESS, RUNNING, STATE_CHANGED]", value)
 	}
 }
@@ -172,6 +172,11 @@ func (te *TaskExecutor) execute(i interface{}) {
 		}
 
 		taskErr = te.syncSinger(task, taskLogger, singerDriver, destinationStorages)
+	} else if driver.Type() == drivers.AirbyteType {
+		airbyteDriver, _ := driver.(*drivers.Airbyte)
+		taskErr = te.syncAirbyte(task, taskLogger, airbyteDriver, destinationStorages)
+	} else if sqlDriver, ok := driver.(*drivers.SQL); ok && sqlDriver.IsIncremental() {
+		taskErr = te.syncIncremental(task, taskLogger, sqlDriver, destinationStorages)
 	} else {
 		taskErr = te.sync(task, taskLogger, driver, destinationStorages)
 	}
@@ -233,38 +238,37 @@ func (te *TaskExecutor) sync(task *meta.Task, taskLogger *TaskLogger, driver dri
 
 	taskLogger.INFO("Intervals to sync: [%d]", len(intervalsToSync))
 
+	//primary key fields of SQL source are used for event ID calculation and as destination tables primary key
+	var pkFields []string
+	if sqlDriver, ok := driver.(*drivers.SQL); ok {
+		pkFields = sqlDriver.GetPrimaryKeyFields()
+	}
+
+	streamingDriver, streaming := driver.(drivers.StreamingDriver)
+
 	collectionTable := driver.GetCollectionTable()
-	reformattedTable := schema.Reformat(collectionTable)
+	header := sourceBatchHeader(schema.Reformat(collectionTable), pkFields)
 	for _, intervalToSync := range intervalsToSync {
 		taskLogger.INFO("Running [%s] synchronization", intervalToSync.String())
 
-		objects, err := driver.GetObjectsFor(intervalToSync)
-		if err != nil {
-			return fmt.Errorf("Error [%s] synchronization: %v", intervalToSync.String(), err)
-		}
-
-		for _, object := range objects {
-			//enrich with values
-			object["src"] = "source"
-			object[timestamp.Key] = timestamp.NowUTC()
-			events.EnrichWithEventID(object, uuid.GetHash(object))
-			events.EnrichWithCollection(object, task.Collection)
-			events.EnrichWithTimeInterval(object, intervalToSync.String(), intervalToSync.LowerEndpoint(), intervalToSync.UpperEndpoint())
-		}
-		for _, storage := range destinationStorages {
-			rowsCount, err := storage.SyncStore(&schema.BatchHeader{TableName: reformattedTable}, objects, intervalToSync.String())
+		if streaming {
+			if err := te.syncIntervalByPages(task, taskLogger, streamingDriver, intervalToSync, header, pkFields, destinationStorages); err != nil {
+				return err
+			}
+		} else {
+			objects, err := driver.GetObjectsFor(intervalToSync)
 			if err != nil {
-				metrics.ErrorSourceEvents(task.Source, storage.Name(), rowsCount)
-				metrics.ErrorObjects(task.Source, rowsCount)
-				return fmt.Errorf("Error storing %d source objects in [%s] destination: %v", rowsCount, storage.Name(), err)
+				return fmt.Errorf("Error [%s] synchronization: %v", intervalToSync.String(), err)
 			}
 
-			metrics.SuccessSourceEvents(task.Source, storage.Name(), rowsCount)
-			metrics.SuccessObjects(task.Source, rowsCount)
+			for _, object := range objects {
+				enrichSourceObject(object, task.Collection, intervalToSync, pkFields)
+			}
+			if err := te.storeObjects(task, destinationStorages, header, objects, intervalToSync.String()); err != nil {
+				return err
+			}
 		}
 
-		counters.SuccessSourceEvents(task.Source, len(objects))
-
 		if err := te.metaStorage.SaveSignature(task.Source, collectionMetaKey, intervalToSync.String(), intervalToSync.CalculateSignatureFrom(now)); err != nil {
 			logging.SystemErrorf("Unable to save source [%s] collection [%s] signature: %v", task.Source, task.Collection, err)
 		}
@@ -275,6 +279,117 @@ func (te *TaskExecutor) sync(task *meta.Task, taskLogger *TaskLogger, driver dri
 	return nil
 }
 
+//syncIntervalByPages stores interval objects page by page.
+//objects of the previous synchronization are deleted only with the first page and the interval signature is saved
+//by the caller after the last page. If synchronization fails after the first page, the interval contains only stored pages
+//until the next successful synchronization (the whole interval will be synchronized again). Return error if occurred
+func (te *TaskExecutor) syncIntervalByPages(task *meta.Task, taskLogger *TaskLogger, streamingDriver drivers.StreamingDriver, interval *drivers.TimeInterval,
+	header *schema.BatchHeader, pkFields []string, destinationStorages []storages.Storage) error {
+	pages := 0
+	err := streamingDriver.StreamObjectsFor(interval, func(objects []map[string]interface{}) error {
+		timeIntervalValue := ""
+		if pages == 0 {
+			timeIntervalValue = interval.String()
+		}
+
+		for _, object := range objects {
+			enrichSourceObject(object, task.Collection, interval, pkFields)
+		}
+		if err := te.storeObjects(task, destinationStorages, header, objects, timeIntervalValue); err != nil {
+			return err
+		}
+
+		pages++
+		taskLogger.INFO("Interval [%s] page [%d] with [%d] objects has been stored", interval.String(), pages, len(objects))
+		return nil
+	})
+	if err != nil {
+		if pages > 0 {
+			msg := fmt.Sprintf("Interval [%s] synchronization has failed after [%d] stored pages: destinations contain only part of the interval objects until the next successful synchronization", interval.String(), pages)
+			logging.Warnf("[%s] %s", task.ID, msg)
+			taskLogger.WARN("%s", msg)
+		}
+
+		return fmt.Errorf("Error [%s] synchronization: %v", interval.String(), err)
+	}
+
+	return nil
+}
+
+//syncIncremental sync only new and updated objects of incremental SQL source collection:
+//objects with cursor field value greater than or equal to stored one. Objects are stored by pages and
+//the page cursor value is saved after every page (checkpoint). Objects with the stored cursor value are loaded again,
+//so they are deduplicated by primary key fields (or by eventn_ctx_event_id if primary key fields aren't configured)
+//Return error if occurred
+func (te *TaskExecutor) syncIncremental(task *meta.Task, taskLogger *TaskLogger, sqlDriver *drivers.SQL, destinationStorages []storages.Storage) error {
+	collectionMetaKey := task.Collection + "_" + sqlDriver.GetCollectionTable()
+	cursor, err := te.metaStorage.GetSignature(task.Source, collectionMetaKey, drivers.CursorSignatureKey)
+	if err != nil {
+		return fmt.Errorf("Error getting cursor value from meta storage: %v", err)
+	}
+
+	if cursor != "" {
+		taskLogger.INFO("Running incremental synchronization from cursor [%s] value: %s", sqlDriver.GetCursorField(), cursor)
+	} else {
+		taskLogger.INFO("Running initial incremental synchronization by cursor [%s]", sqlDriver.GetCursorField())
+	}
+
+	interval := drivers.NewTimeInterval(drivers.ALL, time.Time{})
+	pkFields := sqlDriver.GetPrimaryKeyFields()
+	header := sourceBatchHeader(schema.Reformat(sqlDriver.GetCollectionTable()), pkFields)
+	if len(header.PKFields) == 0 {
+		header.PKFields = map[string]bool{events.EventnCtxEventID: true}
+	}
+	objectsCount := 0
+	err = sqlDriver.StreamObjectsAfter(cursor, func(objects []map[string]interface{}, pageCursor string) error {
+		for _, object := range objects {
+			enrichSourceObject(object, task.Collection, interval, pkFields)
+		}
+
+		//empty time interval: previous objects mustn't be deleted
+		if err := te.storeObjects(task, destinationStorages, header, objects, ""); err != nil {
+			return err
+		}
+
+		//checkpoint
+		if err := te.metaStorage.SaveSignature(task.Source, collectionMetaKey, drivers.CursorSignatureKey, pageCursor); err != nil {
+			return fmt.Errorf("Unable to save cursor value [%s]: %v", pageCursor, err)
+		}
+
+		objectsCount += len(objects)
+		taskLogger.INFO("Page with [%d] objects has been stored. Cursor [%s] value: %s", len(objects), sqlDriver.GetCursorField(), pageCursor)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("Error incremental synchronization: %v", err)
+	}
+
+	taskLogger.INFO("[%d] new or updated objects have been synchronized!", objectsCount)
+
+	return nil
+}
+
+//storeObjects stores objects in all destinations and updates metrics and counters
+//previous objects with timeIntervalValue are deleted (if it isn't empty)
+func (te *TaskExecutor) storeObjects(task *meta.Task, destinationStorages []storages.Storage, header *schema.BatchHeader, objects []map[string]interface{}, timeIntervalValue string) error {
+	for _, storage := range destinationStorages {
+		//storages might change header fields
+		rowsCount, err := storage.SyncStore(&schema.BatchHeader{TableName: header.TableName, PKFields: header.PKFields}, objects, timeIntervalValue)
+		if err != nil {
+			metrics.ErrorSourceEvents(task.Source, storage.Name(), rowsCount)
+			metrics.ErrorObjects(task.Source, rowsCount)
+			return fmt.Errorf("Error storing %d source objects in [%s] destination: %v", rowsCount, storage.Name(), err)
+		}
+
+		metrics.SuccessSourceEvents(task.Source, storage.Name(), rowsCount)
+		metrics.SuccessObjects(task.Source, rowsCount)
+	}
+
+	counters.SuccessSourceEvents(task.Source, len(objects))
+
+	return nil
+}
+
 //syncSinger sync singer source. Return err if occurred
 func (te *TaskExecutor) syncSinger(task *meta.Task, taskLogger *TaskLogger, singerDriver *drivers.Singer, destinationStorages []storages.Storage) error {
 	//get singer state
@@ -300,6 +415,57 @@ func (te *TaskExecutor) syncSinger(task *meta.Task, taskLogger *TaskLogger, sing
 	return nil
 }
 
+//sourceBatchHeader returns BatchHeader with reformatted source primary key fields
+func sourceBatchHeader(tableName string, pkFields []string) *schema.BatchHeader {
+	header := &schema.BatchHeader{TableName: tableName}
+	if len(pkFields) > 0 {
+		header.PKFields = map[string]bool{}
+		for _, pkField := range pkFields {
+			header.PKFields[schema.Reformat(pkField)] = true
+		}
+	}
+
+	return header
+}
+
+//enrichSourceObject enriches source object with system fields values
+//event ID is calculated from primary key fields (if they are provided) or from the whole object
+func enrichSourceObject(object map[string]interface{}, collection string, interval *drivers.TimeInterval, pkFields []string) {
+	object["src"] = "source"
+	object[timestamp.Key] = timestamp.NowUTC()
+	if len(pkFields) > 0 {
+		events.EnrichWithEventID(object, uuid.GetKeysHash(object, pkFields))
+	} else {
+		events.EnrichWithEventID(object, uuid.GetHash(object))
+	}
+	events.EnrichWithCollection(object, collection)
+	events.EnrichWithTimeInterval(object, interval.String(), interval.LowerEndpoint(), interval.UpperEndpoint())
+}
+
+//syncAirbyte sync airbyte source. Return err if occurred
+func (te *TaskExecutor) syncAirbyte(task *meta.Task, taskLogger *TaskLogger, airbyteDriver *drivers.Airbyte, destinationStorages []storages.Storage) error {
+	//get airbyte state
+	airbyteState, err := te.metaStorage.GetSignature(task.Source, airbyteDriver.GetConnector(), drivers.ALL.String())
+	if err != nil {
+		return fmt.Errorf("Error getting state from meta storage: %v", err)
+	}
+
+	if airbyteState != "" {
+		taskLogger.INFO("Running synchronization with state: %s", airbyteState)
+	} else {
+		taskLogger.INFO("Running synchronization")
+	}
+
+	rs := NewResultSaver(task, airbyteDriver.GetConnector(), taskLogger, destinationStorages, te.metaStorage)
+
+	err = airbyteDriver.Load(airbyteState, taskLogger, rs)
+	if err != nil {
+		return fmt.Errorf("Error synchronization: %v", err)
+	}
+
+	return nil
+}
+
 //handleError write logs, update task status and logs in Redis
 func (te *TaskExecutor) handleError(task *meta.Task, taskLogger *TaskLogger, msg string, systemErr bool) {
 	if systemErr {
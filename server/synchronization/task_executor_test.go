@@ -0,0 +1,187 @@
+package synchronization
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/drivers"
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/jitsucom/jitsu/server/schema"
+	"github.com/jitsucom/jitsu/server/storages"
+	"github.com/stretchr/testify/require"
+)
+
+//testMetaStorage is an in-memory meta.Storage with signatures, tasks, tasks queue and task logs
+type testMetaStorage struct {
+	meta.Dummy
+
+	mutex      sync.Mutex
+	signatures map[string]map[string]string
+	tasks      []*meta.Task
+	queue      []*meta.Task
+	taskLogs   map[string][]string
+}
+
+func newTestMetaStorage() *testMetaStorage {
+	return &testMetaStorage{signatures: map[string]map[string]string{}, taskLogs: map[string][]string{}}
+}
+
+func (tms *testMetaStorage) GetSignature(sourceID, collection, interval string) (string, error) {
+	tms.mutex.Lock()
+	defer tms.mutex.Unlock()
+	return tms.signatures[sourceID+"_"+collection][interval], nil
+}
+
+func (tms *testMetaStorage) SaveSignature(sourceID, collection, interval, signature string) error {
+	tms.mutex.Lock()
+	defer tms.mutex.Unlock()
+	key := sourceID + "_" + collection
+	if _, ok := tms.signatures[key]; !ok {
+		tms.signatures[key] = map[string]string{}
+	}
+	tms.signatures[key][interval] = signature
+	return nil
+}
+
+func (tms *testMetaStorage) GetSignatures(sourceID, collection string) (map[string]string, error) {
+	tms.mutex.Lock()
+	defer tms.mutex.Unlock()
+	result := map[string]string{}
+	for interval, signature := range tms.signatures[sourceID+"_"+collection] {
+		result[interval] = signature
+	}
+	return result, nil
+}
+
+func (tms *testMetaStorage) DeleteSignature(sourceID, collection, interval string) error {
+	tms.mutex.Lock()
+	defer tms.mutex.Unlock()
+	delete(tms.signatures[sourceID+"_"+collection], interval)
+	return nil
+}
+
+func (tms *testMetaStorage) DeleteSignatures(sourceID, collection string) error {
+	tms.mutex.Lock()
+	defer tms.mutex.Unlock()
+	delete(tms.signatures, sourceID+"_"+collection)
+	return nil
+}
+
+func (tms *testMetaStorage) CreateTask(sourceID, collection string, task *meta.Task, createdAt time.Time) error {
+	tms.mutex.Lock()
+	defer tms.mutex.Unlock()
+	tms.tasks = append(tms.tasks, task)
+	return nil
+}
+
+func (tms *testMetaStorage) PushTask(task *meta.Task) error {
+	tms.mutex.Lock()
+	defer tms.mutex.Unlock()
+	tms.queue = append(tms.queue, task)
+	return nil
+}
+
+func (tms *testMetaStorage) IsTaskInQueue(sourceID, collection string) (string, bool, error) {
+	tms.mutex.Lock()
+	defer tms.mutex.Unlock()
+	for _, task := range tms.queue {
+		if task.Source == sourceID && task.Collection == collection {
+			return task.ID, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+func (tms *testMetaStorage) AppendTaskLog(taskID string, now time.Time, message, level string) error {
+	tms.mutex.Lock()
+	defer tms.mutex.Unlock()
+	tms.taskLogs[taskID] = append(tms.taskLogs[taskID], level+" "+message)
+	return nil
+}
+
+//testStreamingDriver passes configured pages to the consumer and returns err after them
+type testStreamingDriver struct {
+	drivers.Driver
+
+	pages [][]map[string]interface{}
+	err   error
+}
+
+func (tsd *testStreamingDriver) StreamObjectsFor(interval *drivers.TimeInterval, consumer drivers.PageConsumer) error {
+	for _, page := range tsd.pages {
+		if err := consumer(page); err != nil {
+			return err
+		}
+	}
+
+	return tsd.err
+}
+
+//syncStoreCall is a testStorage.SyncStore call
+type syncStoreCall struct {
+	header            *schema.BatchHeader
+	objectsCount      int
+	timeIntervalValue string
+}
+
+//testStorage records SyncStore calls
+type testStorage struct {
+	storages.Storage
+
+	calls []*syncStoreCall
+}
+
+func (ts *testStorage) SyncStore(overriddenDataSchema *schema.BatchHeader, objects []map[string]interface{}, timeIntervalValue string) (int, error) {
+	ts.calls = append(ts.calls, &syncStoreCall{header: overriddenDataSchema, objectsCount: len(objects), timeIntervalValue: timeIntervalValue})
+	return len(objects), nil
+}
+
+func (ts *testStorage) Name() string {
+	return "test_destination"
+}
+
+func TestSyncIntervalByPages(t *testing.T) {
+	metaStorage := newTestMetaStorage()
+	executor := &TaskExecutor{metaStorage: metaStorage}
+	task := &meta.Task{ID: "task1", Source: "source1", Collection: "collection1"}
+	interval := drivers.NewTimeInterval(drivers.ALL, time.Time{})
+	header := &schema.BatchHeader{TableName: "users", PKFields: map[string]bool{"id": true}}
+	pages := [][]map[string]interface{}{
+		{{"id": 1}, {"id": 2}},
+		{{"id": 3}},
+	}
+
+	//previous objects are deleted only with the first page
+	storage := &testStorage{}
+	driver := &testStreamingDriver{pages: pages}
+	err := executor.syncIntervalByPages(task, NewTaskLogger(task.ID, metaStorage), driver, interval, header, []string{"id"}, []storages.Storage{storage})
+	require.NoError(t, err)
+	require.Len(t, storage.calls, 2)
+	require.Equal(t, &syncStoreCall{header: header, objectsCount: 2, timeIntervalValue: interval.String()}, storage.calls[0])
+	require.Equal(t, &syncStoreCall{header: header, objectsCount: 1, timeIntervalValue: ""}, storage.calls[1])
+	require.Equal(t, "source", pages[1][0]["src"])
+
+	//failure after stored pages is reported as a warning
+	storage = &testStorage{}
+	driver = &testStreamingDriver{pages: pages, err: errors.New("connection lost")}
+	err = executor.syncIntervalByPages(task, NewTaskLogger("task2", metaStorage), driver, interval, header, nil, []storages.Storage{storage})
+	require.EqualError(t, err, "Error ["+interval.String()+"] synchronization: connection lost")
+	require.Len(t, storage.calls, 2)
+	require.Contains(t, metaStorage.taskLogs["task2"][len(metaStorage.taskLogs["task2"])-1], "warn [task2] Interval ["+interval.String()+"] synchronization has failed after [2] stored pages")
+
+	//failure before the first page doesn't change stored objects
+	storage = &testStorage{}
+	driver = &testStreamingDriver{err: errors.New("connection refused")}
+	err = executor.syncIntervalByPages(task, NewTaskLogger("task3", metaStorage), driver, interval, header, nil, []storages.Storage{storage})
+	require.EqualError(t, err, "Error ["+interval.String()+"] synchronization: connection refused")
+	require.Empty(t, storage.calls)
+	require.Empty(t, metaStorage.taskLogs["task3"])
+}
+
+func TestSourceBatchHeader(t *testing.T) {
+	require.Equal(t, &schema.BatchHeader{TableName: "users"}, sourceBatchHeader("users", nil))
+	require.Equal(t, &schema.BatchHeader{TableName: "users", PKFields: map[string]bool{"id": true, "tenant_id": true}},
+		sourceBatchHeader("users", []string{"ID", "tenant_id"}))
+}
@@ -20,6 +20,10 @@ func (tl *TaskLogger) INFO(format string, v ...interface{}) {
 	tl.log(format, logging.INFO.String(), v...)
 }
 
+func (tl *TaskLogger) WARN(format string, v ...interface{}) {
+	tl.log(format, logging.WARN.String(), v...)
+}
+
 func (tl *TaskLogger) ERROR(format string, v ...interface{}) {
 	tl.log(format, logging.ERROR.String(), v...)
 }
@@ -0,0 +1,319 @@
+package synchronization
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jitsucom/jitsu/server/coordination"
+	"github.com/jitsucom/jitsu/server/drivers"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/jitsucom/jitsu/server/meta"
+	"github.com/jitsucom/jitsu/server/storages"
+	"github.com/jitsucom/jitsu/server/timestamp"
+	uuid "github.com/satori/go.uuid"
+)
+
+//Source collection state types
+const (
+	//IntervalsState is a signature per synchronized time interval (native drivers)
+	IntervalsState = "intervals"
+	//CursorState is the last synchronized cursor value (incremental SQL collections)
+	CursorState = "cursor"
+	//ConnectorState is a Singer tap or Airbyte connector state JSON
+	ConnectorState = "connector"
+)
+
+//StateDto is used in State API (handlers.StateHandler)
+type StateDto struct {
+	Source     string            `json:"source,omitempty"`
+	Collection string            `json:"collection,omitempty"`
+	Type       string            `json:"type,omitempty"`
+	State      interface{}       `json:"state,omitempty"`
+	Intervals  map[string]string `json:"intervals,omitempty"`
+}
+
+//stateKey is a location of the source collection state in meta.Storage
+//intervalKey is empty for IntervalsState (every interval has own signature)
+type stateKey struct {
+	stateType     string
+	collectionKey string
+	intervalKey   string
+}
+
+//getStateKey returns meta.Storage keys which are used by TaskExecutor for the collection driver
+func getStateKey(collection string, driver drivers.Driver) *stateKey {
+	if driver.Type() == drivers.SingerType {
+		singerDriver, _ := driver.(*drivers.Singer)
+		return &stateKey{stateType: ConnectorState, collectionKey: singerDriver.GetTap(), intervalKey: drivers.ALL.String()}
+	}
+
+	if driver.Type() == drivers.AirbyteType {
+		airbyteDriver, _ := driver.(*drivers.Airbyte)
+		return &stateKey{stateType: ConnectorState, collectionKey: airbyteDriver.GetConnector(), intervalKey: drivers.ALL.String()}
+	}
+
+	collectionKey := collection + "_" + driver.GetCollectionTable()
+	if sqlDriver, ok := driver.(*drivers.SQL); ok && sqlDriver.IsIncremental() {
+		return &stateKey{stateType: CursorState, collectionKey: collectionKey, intervalKey: drivers.CursorSignatureKey}
+	}
+
+	return &stateKey{stateType: IntervalsState, collectionKey: collectionKey}
+}
+
+//GetState returns source collection synchronization state
+func (ts *TaskService) GetState(sourceID, collection string) (*StateDto, error) {
+	key, err := ts.resolveStateKey(sourceID, collection)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &StateDto{Source: sourceID, Collection: collection, Type: key.stateType}
+	if key.stateType == IntervalsState {
+		signatures, err := ts.metaStorage.GetSignatures(sourceID, key.collectionKey)
+		if err != nil {
+			return nil, fmt.Errorf("Error getting intervals signatures from meta storage: %v", err)
+		}
+
+		result.Intervals = signatures
+		return result, nil
+	}
+
+	state, err := ts.metaStorage.GetSignature(sourceID, key.collectionKey, key.intervalKey)
+	if err != nil {
+		return nil, fmt.Errorf("Error getting state from meta storage: %v", err)
+	}
+
+	if state == "" {
+		return result, nil
+	}
+
+	if key.stateType == ConnectorState {
+		var stateObject interface{}
+		if err := json.Unmarshal([]byte(state), &stateObject); err == nil {
+			result.State = stateObject
+			return result, nil
+		}
+	}
+
+	result.State = state
+	return result, nil
+}
+
+//SaveState overwrites source collection synchronization state:
+//connector state JSON, cursor value or all intervals signatures
+func (ts *TaskService) SaveState(sourceID, collection string, state *StateDto) error {
+	key, err := ts.resolveStateKey(sourceID, collection)
+	if err != nil {
+		return err
+	}
+
+	var stateValue string
+	switch key.stateType {
+	case IntervalsState:
+		if state.Intervals == nil {
+			return errors.New("'intervals' are required for the collection state")
+		}
+	case CursorState:
+		cursor, ok := state.State.(string)
+		if !ok || cursor == "" {
+			return errors.New("'state' must be a not empty cursor value string")
+		}
+		stateValue = cursor
+	case ConnectorState:
+		switch v := state.State.(type) {
+		case nil:
+			return errors.New("'state' is required")
+		case string:
+			stateValue = v
+		default:
+			b, err := json.Marshal(v)
+			if err != nil {
+				return fmt.Errorf("Error marshalling state: %v", err)
+			}
+			stateValue = string(b)
+		}
+	}
+
+	lock, err := ts.lockState(sourceID, collection)
+	if err != nil {
+		return err
+	}
+	defer ts.monitorKeeper.Unlock(lock)
+
+	if key.stateType == IntervalsState {
+		if err := ts.metaStorage.DeleteSignatures(sourceID, key.collectionKey); err != nil {
+			return fmt.Errorf("Error deleting intervals signatures from meta storage: %v", err)
+		}
+
+		for interval, signature := range state.Intervals {
+			if err := ts.metaStorage.SaveSignature(sourceID, key.collectionKey, interval, signature); err != nil {
+				return fmt.Errorf("Error saving interval [%s] signature in meta storage: %v", interval, err)
+			}
+		}
+
+		ts.appendStateLog(sourceID, collection, "", "Intervals signatures have been overwritten manually: %v", state.Intervals)
+		return nil
+	}
+
+	if err := ts.metaStorage.SaveSignature(sourceID, key.collectionKey, key.intervalKey, stateValue); err != nil {
+		return fmt.Errorf("Error saving state in meta storage: %v", err)
+	}
+
+	ts.appendStateLog(sourceID, collection, "", "State has been overwritten manually: %s", stateValue)
+	return nil
+}
+
+//ClearState deletes source collection synchronization state. The next synchronization will be a full one
+func (ts *TaskService) ClearState(sourceID, collection string) error {
+	key, err := ts.resolveStateKey(sourceID, collection)
+	if err != nil {
+		return err
+	}
+
+	lock, err := ts.lockState(sourceID, collection)
+	if err != nil {
+		return err
+	}
+	defer ts.monitorKeeper.Unlock(lock)
+
+	if err := ts.clearState(sourceID, key, nil); err != nil {
+		return err
+	}
+
+	ts.appendStateLog(sourceID, collection, "", "State has been cleared manually")
+	return nil
+}
+
+//Resync clears state of the selected intervals (or the whole state if intervals are empty) and runs synchronization task
+//intervals might be selected only for collections with IntervalsState. Returns created task ID
+func (ts *TaskService) Resync(sourceID, collection string, intervals []string) (string, error) {
+	key, err := ts.resolveStateKey(sourceID, collection)
+	if err != nil {
+		return "", err
+	}
+
+	if len(intervals) > 0 && key.stateType != IntervalsState {
+		return "", fmt.Errorf("Intervals can't be selected for the collection with [%s] state", key.stateType)
+	}
+
+	lock, err := ts.lockState(sourceID, collection)
+	if err != nil {
+		return "", err
+	}
+
+	err = ts.clearState(sourceID, key, intervals)
+	ts.monitorKeeper.Unlock(lock)
+	if err != nil {
+		return "", err
+	}
+
+	resyncMsg := "Full resync has been requested manually"
+	if len(intervals) > 0 {
+		resyncMsg = fmt.Sprintf("Resync of intervals [%s] has been requested manually", strings.Join(intervals, ", "))
+	}
+
+	taskID, err := ts.Sync(sourceID, collection, NOW)
+	if err == ErrSourceCollectionIsSyncing {
+		//the task in the queue will be run with cleared state
+		ts.appendStateLog(sourceID, collection, taskID, "%s", resyncMsg)
+		return taskID, err
+	}
+	if err != nil {
+		ts.appendStateLog(sourceID, collection, "", "%s. State has been cleared but sync task hasn't been created: %v", resyncMsg, err)
+		return "", err
+	}
+
+	ts.appendStateLog(sourceID, collection, taskID, "%s", resyncMsg)
+	return taskID, nil
+}
+
+//resolveStateKey returns state key of the existing source collection
+func (ts *TaskService) resolveStateKey(sourceID, collection string) (*stateKey, error) {
+	if ts.metaStorage == nil {
+		return nil, ErrMetaStorageRequired
+	}
+
+	sourceUnit, err := ts.sourceService.GetSource(sourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	driver, ok := sourceUnit.DriverPerCollection[collection]
+	if !ok {
+		return nil, fmt.Errorf("Collection with id [%s] wasn't found in source [%s]", collection, sourceID)
+	}
+
+	return getStateKey(collection, driver), nil
+}
+
+//lockState returns the source collection lock (the same as TaskExecutor uses)
+//or ErrSourceCollectionIsSyncing if the collection is being synchronized
+func (ts *TaskService) lockState(sourceID, collection string) (storages.Lock, error) {
+	lock, err := ts.monitorKeeper.TryLock(sourceID, collection)
+	if err != nil {
+		if err == coordination.ErrAlreadyLocked {
+			return nil, ErrSourceCollectionIsSyncing
+		}
+
+		return nil, err
+	}
+
+	return lock, nil
+}
+
+//clearState deletes selected intervals signatures or the whole state
+func (ts *TaskService) clearState(sourceID string, key *stateKey, intervals []string) error {
+	if key.stateType != IntervalsState {
+		if err := ts.metaStorage.DeleteSignature(sourceID, key.collectionKey, key.intervalKey); err != nil {
+			return fmt.Errorf("Error deleting state from meta storage: %v", err)
+		}
+
+		return nil
+	}
+
+	if len(intervals) == 0 {
+		if err := ts.metaStorage.DeleteSignatures(sourceID, key.collectionKey); err != nil {
+			return fmt.Errorf("Error deleting intervals signatures from meta storage: %v", err)
+		}
+
+		return nil
+	}
+
+	for _, interval := range intervals {
+		if err := ts.metaStorage.DeleteSignature(sourceID, key.collectionKey, interval); err != nil {
+			return fmt.Errorf("Error deleting interval [%s] signature from meta storage: %v", interval, err)
+		}
+	}
+
+	return nil
+}
+
+//appendStateLog writes manual state change message into the sync task logs
+//if taskID is empty, a dedicated finished task with STATE_CHANGED status is created for the message
+func (ts *TaskService) appendStateLog(sourceID, collection, taskID, format string, v ...interface{}) {
+	if taskID == "" {
+		now := time.Now().UTC()
+		task := &meta.Task{
+			ID:         fmt.Sprintf("%s_%s_state_%s", sourceID, collection, uuid.NewV4().String()),
+			Source:     sourceID,
+			Collection: collection,
+			CreatedAt:  now.Format(timestamp.Layout),
+			StartedAt:  now.Format(timestamp.Layout),
+			FinishedAt: now.Format(timestamp.Layout),
+			Status:     STATE_CHANGED.String(),
+		}
+
+		if err := ts.metaStorage.CreateTask(sourceID, collection, task, now); err != nil {
+			logging.SystemErrorf("Error saving state change task of source [%s] collection [%s]: %v", sourceID, collection, err)
+			logging.Infof("[%s_%s] "+format, append([]interface{}{sourceID, collection}, v...)...)
+			return
+		}
+
+		taskID = task.ID
+	}
+
+	NewTaskLogger(taskID, ts.metaStorage).INFO(format, v...)
+}
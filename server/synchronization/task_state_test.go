@@ -0,0 +1,327 @@
+package synchronization
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/jitsucom/jitsu/server/airbyte"
+	"github.com/jitsucom/jitsu/server/coordination"
+	"github.com/jitsucom/jitsu/server/drivers"
+	"github.com/jitsucom/jitsu/server/singer"
+	"github.com/jitsucom/jitsu/server/sources"
+	"github.com/jitsucom/jitsu/server/storages"
+	"github.com/stretchr/testify/require"
+)
+
+//testLock is a testMonitorKeeper lock
+type testLock struct {
+	identifier string
+}
+
+func (tl *testLock) Unlock() {}
+
+func (tl *testLock) Identifier() string {
+	return tl.identifier
+}
+
+//testMonitorKeeper is an in-memory storages.MonitorKeeper which returns coordination.ErrAlreadyLocked without waiting
+type testMonitorKeeper struct {
+	storages.MonitorKeeper
+
+	mutex sync.Mutex
+	locks map[string]bool
+}
+
+func newTestMonitorKeeper() *testMonitorKeeper {
+	return &testMonitorKeeper{locks: map[string]bool{}}
+}
+
+func (tmk *testMonitorKeeper) TryLock(system string, collection string) (storages.Lock, error) {
+	tmk.mutex.Lock()
+	defer tmk.mutex.Unlock()
+	identifier := system + "_" + collection
+	if tmk.locks[identifier] {
+		return nil, coordination.ErrAlreadyLocked
+	}
+	tmk.locks[identifier] = true
+	return &testLock{identifier: identifier}, nil
+}
+
+func (tmk *testMonitorKeeper) Unlock(lock storages.Lock) error {
+	tmk.mutex.Lock()
+	defer tmk.mutex.Unlock()
+	delete(tmk.locks, lock.Identifier())
+	return nil
+}
+
+func (tmk *testMonitorKeeper) IsLocked(system string, collection string) (bool, error) {
+	tmk.mutex.Lock()
+	defer tmk.mutex.Unlock()
+	return tmk.locks[system+"_"+collection], nil
+}
+
+//testIntervalsDriver is a native driver which stores a signature per interval
+type testIntervalsDriver struct {
+	drivers.Driver
+
+	table string
+}
+
+func (tid *testIntervalsDriver) Type() string {
+	return drivers.GoogleAnalyticsType
+}
+
+func (tid *testIntervalsDriver) GetCollectionTable() string {
+	return tid.table
+}
+
+func newTestSingerDriver(t *testing.T) drivers.Driver {
+	venvDir, err := ioutil.TempDir("", "singer_venv")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		singer.Instance = nil
+		os.RemoveAll(venvDir)
+	})
+
+	require.NoError(t, singer.Init("python3", venvDir, false, nil))
+
+	driver, err := drivers.NewSinger(context.Background(),
+		&drivers.SourceConfig{Name: "src", Type: drivers.SingerType, Config: map[string]interface{}{"tap": "tap-test", "config": map[string]interface{}{"key": "value"}}},
+		&drivers.Collection{Name: "tap", Type: drivers.SingerType})
+	require.NoError(t, err)
+
+	return driver
+}
+
+func newTestAirbyteDriver(t *testing.T, image string) drivers.Driver {
+	configDir, err := ioutil.TempDir("", "airbyte_config")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		airbyte.Instance = nil
+		os.RemoveAll(configDir)
+	})
+
+	require.NoError(t, airbyte.Init(configDir, nil))
+
+	driver, err := drivers.NewAirbyte(context.Background(),
+		&drivers.SourceConfig{Name: "src", Type: drivers.AirbyteType, Config: map[string]interface{}{"image": image, "config": map[string]interface{}{"key": "value"}}},
+		&drivers.Collection{Name: "connector", Type: drivers.AirbyteType})
+	require.NoError(t, err)
+
+	return driver
+}
+
+//newTestStateTaskService returns TaskService with the source [src] which has intervals [events], cursor [users]
+//and connector [tap] collections
+func newTestStateTaskService(t *testing.T) (*TaskService, *testMetaStorage, *testMonitorKeeper) {
+	metaStorage := newTestMetaStorage()
+	monitorKeeper := newTestMonitorKeeper()
+	sourceService := sources.NewTestServiceWithSources(map[string]*sources.Unit{
+		"src": {
+			SourceType: drivers.GoogleAnalyticsType,
+			DriverPerCollection: map[string]drivers.Driver{
+				"events": &testIntervalsDriver{table: "events_table"},
+				"users": drivers.NewTestSQL(&drivers.Collection{SourceID: "src", Name: "users"},
+					&drivers.SQLCollectionParameters{Mode: drivers.IncrementalMode, CursorField: "updated_at"}),
+				"tap": newTestSingerDriver(t),
+			},
+		},
+	})
+
+	return NewTaskService(sourceService, nil, metaStorage, monitorKeeper), metaStorage, monitorKeeper
+}
+
+//requireStateChangedTask checks that the last created task is a STATE_CHANGED task with the message in logs
+func requireStateChangedTask(t *testing.T, metaStorage *testMetaStorage, collection, message string) {
+	require.NotEmpty(t, metaStorage.tasks)
+	task := metaStorage.tasks[len(metaStorage.tasks)-1]
+	require.Equal(t, STATE_CHANGED.String(), task.Status)
+	require.Equal(t, "src", task.Source)
+	require.Equal(t, collection, task.Collection)
+	require.NotEmpty(t, task.FinishedAt)
+	require.Equal(t, []string{"info [" + task.ID + "] " + message}, metaStorage.taskLogs[task.ID])
+}
+
+func TestGetStateKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		driver   drivers.Driver
+		expected *stateKey
+	}{
+		{
+			"native driver intervals",
+			&testIntervalsDriver{table: "events_table"},
+			&stateKey{stateType: IntervalsState, collectionKey: "events_events_table"},
+		},
+		{
+			"full refresh sql",
+			drivers.NewTestSQL(&drivers.Collection{SourceID: "src", Name: "events"}, &drivers.SQLCollectionParameters{}),
+			&stateKey{stateType: IntervalsState, collectionKey: "events_src_events"},
+		},
+		{
+			"incremental sql cursor",
+			drivers.NewTestSQL(&drivers.Collection{SourceID: "src", Name: "events", TableName: "events_table"},
+				&drivers.SQLCollectionParameters{Mode: drivers.IncrementalMode, CursorField: "updated_at"}),
+			&stateKey{stateType: CursorState, collectionKey: "events_events_table", intervalKey: drivers.CursorSignatureKey},
+		},
+		{
+			"singer tap state",
+			newTestSingerDriver(t),
+			&stateKey{stateType: ConnectorState, collectionKey: "tap-test", intervalKey: drivers.ALL.String()},
+		},
+		{
+			"airbyte connector state",
+			newTestAirbyteDriver(t, "airbyte/source-test:0.1.0"),
+			&stateKey{stateType: ConnectorState, collectionKey: "airbyte/source-test", intervalKey: drivers.ALL.String()},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, getStateKey("events", tt.driver))
+		})
+	}
+}
+
+func TestIntervalsState(t *testing.T) {
+	taskService, metaStorage, _ := newTestStateTaskService(t)
+
+	state, err := taskService.GetState("src", "events")
+	require.NoError(t, err)
+	require.Equal(t, &StateDto{Source: "src", Collection: "events", Type: IntervalsState, Intervals: map[string]string{}}, state)
+
+	require.EqualError(t, taskService.SaveState("src", "events", &StateDto{State: "value"}), "'intervals' are required for the collection state")
+
+	intervals := map[string]string{"MONTH:2021-01": "signature1", "MONTH:2021-02": "signature2"}
+	require.NoError(t, metaStorage.SaveSignature("src", "events_events_table", "MONTH:2020-12", "old"))
+	require.NoError(t, taskService.SaveState("src", "events", &StateDto{Intervals: intervals}))
+	requireStateChangedTask(t, metaStorage, "events", "Intervals signatures have been overwritten manually: map[MONTH:2021-01:signature1 MONTH:2021-02:signature2]")
+
+	state, err = taskService.GetState("src", "events")
+	require.NoError(t, err)
+	require.Equal(t, intervals, state.Intervals, "intervals must be overwritten")
+
+	//resync of the selected interval
+	taskID, err := taskService.Resync("src", "events", []string{"MONTH:2021-01"})
+	require.NoError(t, err)
+	require.Len(t, metaStorage.queue, 1)
+	require.Equal(t, taskID, metaStorage.queue[0].ID)
+	require.Equal(t, SCHEDULED.String(), metaStorage.queue[0].Status)
+	require.Equal(t, []string{"info [" + taskID + "] Resync of intervals [MONTH:2021-01] has been requested manually"}, metaStorage.taskLogs[taskID])
+
+	state, err = taskService.GetState("src", "events")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"MONTH:2021-02": "signature2"}, state.Intervals, "only selected intervals must be cleared")
+
+	//resync while the task is in the queue
+	queuedTaskID, err := taskService.Resync("src", "events", nil)
+	require.Equal(t, ErrSourceCollectionIsSyncing, err)
+	require.Equal(t, taskID, queuedTaskID)
+	require.Len(t, metaStorage.queue, 1)
+	require.Equal(t, "info ["+taskID+"] Full resync has been requested manually", metaStorage.taskLogs[taskID][1])
+
+	state, err = taskService.GetState("src", "events")
+	require.NoError(t, err)
+	require.Empty(t, state.Intervals)
+
+	require.NoError(t, taskService.SaveState("src", "events", &StateDto{Intervals: intervals}))
+	require.NoError(t, taskService.ClearState("src", "events"))
+	requireStateChangedTask(t, metaStorage, "events", "State has been cleared manually")
+
+	state, err = taskService.GetState("src", "events")
+	require.NoError(t, err)
+	require.Empty(t, state.Intervals)
+}
+
+func TestCursorState(t *testing.T) {
+	taskService, metaStorage, _ := newTestStateTaskService(t)
+
+	require.EqualError(t, taskService.SaveState("src", "users", &StateDto{State: 10}), "'state' must be a not empty cursor value string")
+
+	require.NoError(t, taskService.SaveState("src", "users", &StateDto{State: "2021-01-01T00:00:00Z"}))
+	requireStateChangedTask(t, metaStorage, "users", "State has been overwritten manually: 2021-01-01T00:00:00Z")
+
+	signature, err := metaStorage.GetSignature("src", "users_src_users", drivers.CursorSignatureKey)
+	require.NoError(t, err)
+	require.Equal(t, "2021-01-01T00:00:00Z", signature)
+
+	state, err := taskService.GetState("src", "users")
+	require.NoError(t, err)
+	require.Equal(t, &StateDto{Source: "src", Collection: "users", Type: CursorState, State: "2021-01-01T00:00:00Z"}, state)
+
+	_, err = taskService.Resync("src", "users", []string{"MONTH:2021-01"})
+	require.EqualError(t, err, "Intervals can't be selected for the collection with [cursor] state")
+	require.Empty(t, metaStorage.queue)
+
+	require.NoError(t, taskService.ClearState("src", "users"))
+	state, err = taskService.GetState("src", "users")
+	require.NoError(t, err)
+	require.Nil(t, state.State)
+}
+
+func TestConnectorState(t *testing.T) {
+	taskService, metaStorage, _ := newTestStateTaskService(t)
+
+	require.EqualError(t, taskService.SaveState("src", "tap", &StateDto{}), "'state' is required")
+
+	connectorState := map[string]interface{}{"bookmarks": map[string]interface{}{"users": map[string]interface{}{"updated_at": "2021-01-01"}}}
+	require.NoError(t, taskService.SaveState("src", "tap", &StateDto{State: connectorState}))
+	requireStateChangedTask(t, metaStorage, "tap", `State has been overwritten manually: {"bookmarks":{"users":{"updated_at":"2021-01-01"}}}`)
+
+	signature, err := metaStorage.GetSignature("src", "tap-test", drivers.ALL.String())
+	require.NoError(t, err)
+	require.Equal(t, `{"bookmarks":{"users":{"updated_at":"2021-01-01"}}}`, signature)
+
+	state, err := taskService.GetState("src", "tap")
+	require.NoError(t, err)
+	require.Equal(t, &StateDto{Source: "src", Collection: "tap", Type: ConnectorState, State: connectorState}, state)
+
+	//full resync
+	taskID, err := taskService.Resync("src", "tap", nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"info [" + taskID + "] Full resync has been requested manually"}, metaStorage.taskLogs[taskID])
+
+	state, err = taskService.GetState("src", "tap")
+	require.NoError(t, err)
+	require.Nil(t, state.State)
+}
+
+func TestStateLockConflict(t *testing.T) {
+	taskService, metaStorage, monitorKeeper := newTestStateTaskService(t)
+
+	require.NoError(t, metaStorage.SaveSignature("src", "events_events_table", "MONTH:2021-01", "signature1"))
+
+	//the collection is being synchronized
+	lock, err := monitorKeeper.TryLock("src", "events")
+	require.NoError(t, err)
+
+	require.Equal(t, ErrSourceCollectionIsSyncing, taskService.SaveState("src", "events", &StateDto{Intervals: map[string]string{}}))
+	require.Equal(t, ErrSourceCollectionIsSyncing, taskService.ClearState("src", "events"))
+	_, err = taskService.Resync("src", "events", []string{"MONTH:2021-01"})
+	require.Equal(t, ErrSourceCollectionIsSyncing, err)
+
+	state, err := taskService.GetState("src", "events")
+	require.NoError(t, err, "state might be viewed during synchronization")
+	require.Equal(t, map[string]string{"MONTH:2021-01": "signature1"}, state.Intervals, "state mustn't be changed during synchronization")
+	require.Empty(t, metaStorage.tasks)
+	require.Empty(t, metaStorage.queue)
+
+	require.NoError(t, monitorKeeper.Unlock(lock))
+	require.NoError(t, taskService.ClearState("src", "events"))
+	require.Empty(t, monitorKeeper.locks, "state lock must be released")
+}
+
+func TestStateUnknownSourceCollection(t *testing.T) {
+	taskService, _, _ := newTestStateTaskService(t)
+
+	_, err := taskService.GetState("src", "unknown")
+	require.EqualError(t, err, "Collection with id [unknown] wasn't found in source [src]")
+
+	_, err = taskService.GetState("unknown", "events")
+	require.EqualError(t, err, "Source [unknown] doesn't exist")
+
+	_, err = NewTestTaskService().GetState("src", "events")
+	require.Equal(t, ErrMetaStorageRequired, err)
+}
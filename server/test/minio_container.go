@@ -0,0 +1,125 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/jitsucom/jitsu/server/logging"
+	"github.com/testcontainers/testcontainers-go"
+	tcWait "github.com/testcontainers/testcontainers-go/wait"
+)
+
+const (
+	minioDefaultPort = "9000/tcp"
+	minioAccessKey   = "minioadmin"
+	minioSecretKey   = "minioadmin"
+	minioBucket      = "test"
+	minioRegion      = "us-east-1"
+
+	envMinioPortVariable = "MINIO_TEST_PORT"
+)
+
+//MinioContainer is a MinIO (S3 compatible storage) testcontainer with created bucket
+type MinioContainer struct {
+	Container testcontainers.Container
+	Context   context.Context
+	Host      string
+	Port      int
+	AccessKey string
+	SecretKey string
+	Bucket    string
+	Region    string
+}
+
+//NewMinioContainer creates new MinIO test container if MINIO_TEST_PORT is not defined. Otherwise uses MinIO at defined port.
+//This logic is required for running test at CI environment
+func NewMinioContainer(ctx context.Context) (*MinioContainer, error) {
+	mc := &MinioContainer{Context: ctx, Host: "localhost", AccessKey: minioAccessKey, SecretKey: minioSecretKey,
+		Bucket: minioBucket, Region: minioRegion}
+
+	if envMinioPort := os.Getenv(envMinioPortVariable); envMinioPort != "" {
+		port, err := strconv.Atoi(envMinioPort)
+		if err != nil {
+			return nil, err
+		}
+		mc.Port = port
+	} else {
+		container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+			ContainerRequest: testcontainers.ContainerRequest{
+				Image:        "minio/minio",
+				ExposedPorts: []string{minioDefaultPort},
+				Env:          map[string]string{"MINIO_ROOT_USER": minioAccessKey, "MINIO_ROOT_PASSWORD": minioSecretKey},
+				Cmd:          []string{"server", "/data"},
+				WaitingFor:   tcWait.ForHTTP("/minio/health/live").WithPort(minioDefaultPort).WithStartupTimeout(time.Second * 30),
+			},
+			Started: true,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		mc.Container = container
+		mc.Host, err = container.Host(ctx)
+		if err != nil {
+			return nil, err
+		}
+		port, err := container.MappedPort(ctx, minioDefaultPort)
+		if err != nil {
+			return nil, err
+		}
+		mc.Port = port.Int()
+	}
+
+	if err := mc.createBucket(); err != nil {
+		mc.Close()
+		return nil, err
+	}
+
+	return mc, nil
+}
+
+//Endpoint returns MinIO S3 API URL
+func (mc *MinioContainer) Endpoint() string {
+	return fmt.Sprintf("http://%s:%d", mc.Host, mc.Port)
+}
+
+//Client returns S3 client configured for MinIO
+func (mc *MinioContainer) Client() *s3.S3 {
+	awsConfig := aws.NewConfig().
+		WithCredentials(credentials.NewStaticCredentials(mc.AccessKey, mc.SecretKey, "")).
+		WithRegion(mc.Region).
+		WithEndpoint(mc.Endpoint()).
+		WithS3ForcePathStyle(true)
+	return s3.New(session.Must(session.NewSession()), awsConfig)
+}
+
+//createBucket creates the test bucket if it doesn't exist
+func (mc *MinioContainer) createBucket() error {
+	client := mc.Client()
+	if _, err := client.HeadBucket(&s3.HeadBucketInput{Bucket: aws.String(mc.Bucket)}); err == nil {
+		return nil
+	}
+
+	if _, err := client.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String(mc.Bucket)}); err != nil {
+		return fmt.Errorf("Error creating MinIO bucket [%s]: %v", mc.Bucket, err)
+	}
+
+	return nil
+}
+
+//Close terminates underlying docker container
+func (mc *MinioContainer) Close() {
+	if mc.Container != nil {
+		err := mc.Container.Terminate(mc.Context)
+		if err != nil {
+			logging.Error("Failed to stop container")
+		}
+	}
+}
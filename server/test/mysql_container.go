@@ -6,62 +6,61 @@ import (
 	"fmt"
 	"os"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/docker/go-connections/nat"
+	_ "github.com/go-sql-driver/mysql"
 	"github.com/jitsucom/jitsu/server/logging"
 	"github.com/testcontainers/testcontainers-go"
 	tcWait "github.com/testcontainers/testcontainers-go/wait"
 )
 
 const (
-	mysqlDefaultPort = "3306/tcp"
-	mysqlUser        = "test"
-	mysqlPassword    = "test"
-	mysqlDatabase    = "test"
-	mysqlSchema      = "public"
+	mySQLDefaultPort = "3306/tcp"
+	mySQLUser        = "test"
+	mySQLPassword    = "test"
+	mySQLDatabase    = "test"
 
-	envMysqlPortVariable = "MS_TEST_PORT"
+	envMySQLPortVariable = "MS_TEST_PORT"
 )
 
-//MysqlContainer is a Mysql testcontainer
-type MysqlContainer struct {
+//MySQLContainer is a MySQL testcontainer
+type MySQLContainer struct {
 	Container testcontainers.Container
 	Context   context.Context
 	Host      string
 	Port      int
 	Database  string
-	Schema    string
 	Username  string
 	Password  string
 }
 
-//NewMysqlContainer creates new Mysql test container if MS_TEST_PORT is not defined. Otherwise uses db at defined port. This logic is required
+//NewMySQLContainer creates new MySQL test container if MS_TEST_PORT is not defined. Otherwise uses db at defined port. This logic is required
 //for running test at CI environment
-func NewMysqlContainer(ctx context.Context) (*MysqlContainer, error) {
-	if os.Getenv(envMysqlPortVariable) != "" {
-		port, err := strconv.Atoi(os.Getenv(envMysqlPortVariable))
+func NewMySQLContainer(ctx context.Context) (*MySQLContainer, error) {
+	if os.Getenv(envMySQLPortVariable) != "" {
+		port, err := strconv.Atoi(os.Getenv(envMySQLPortVariable))
 		if err != nil {
 			return nil, err
 		}
-		return &MysqlContainer{Context: ctx, Host: "localhost", Port: port,
-			Schema: mysqlSchema, Database: mysqlDatabase, Username: mysqlUser, Password: mysqlPassword}, nil
+		return &MySQLContainer{Context: ctx, Host: "localhost", Port: port,
+			Database: mySQLDatabase, Username: mySQLUser, Password: mySQLPassword}, nil
 	}
 	dbSettings := make(map[string]string, 0)
-	dbSettings["MYSQL_USER"] = mysqlUser
-	dbSettings["MYSQL_ROOT_PASSWORD"] = mysqlPassword
-	dbSettings["MYSQL_DATABASE"] = mysqlDatabase
+	dbSettings["MYSQL_USER"] = mySQLUser
+	dbSettings["MYSQL_PASSWORD"] = mySQLPassword
+	dbSettings["MYSQL_ROOT_PASSWORD"] = mySQLPassword
+	dbSettings["MYSQL_DATABASE"] = mySQLDatabase
 	dbURL := func(port nat.Port) string {
-		return fmt.Sprintf("mysql://%s:%s@localhost:%s/%s?sslmode=disable", mysqlUser, mysqlPassword, port.Port(), mysqlDatabase)
+		return fmt.Sprintf("%s:%s@tcp(localhost:%s)/%s", mySQLUser, mySQLPassword, port.Port(), mySQLDatabase)
 	}
 
 	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
 		ContainerRequest: testcontainers.ContainerRequest{
-			Image:        "mysql:latest",
-			ExposedPorts: []string{"3306/tcp", "33060/tcp"}, //[]string{mysqlDefaultPort},
+			Image:        "mysql:8.0",
+			ExposedPorts: []string{mySQLDefaultPort},
 			Env:          dbSettings,
-			WaitingFor:   tcWait.ForSQL(mysqlDefaultPort, "mysql", dbURL).Timeout(time.Second * 15),
+			WaitingFor:   tcWait.ForSQL(mySQLDefaultPort, "mysql", dbURL).Timeout(time.Second * 60),
 		},
 		Started: true,
 	})
@@ -76,26 +75,21 @@ func NewMysqlContainer(ctx context.Context) (*MysqlContainer, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &MysqlContainer{Container: container, Context: ctx, Host: host, Port: port.Int(),
-		Schema: mysqlSchema, Database: mysqlDatabase, Username: mysqlUser, Password: mysqlPassword}, nil
+	return &MySQLContainer{Container: container, Context: ctx, Host: host, Port: port.Int(),
+		Database: mySQLDatabase, Username: mySQLUser, Password: mySQLPassword}, nil
 }
 
 //CountRows returns row count in DB table with name = table
 //or error if occurred
-func (pgc *MysqlContainer) CountRows(table string) (int, error) {
-	connectionString := fmt.Sprintf("host=%s port=%d dbname=%s user=%s password=%s sslmode=disable",
-		pgc.Host, pgc.Port, pgc.Database, pgc.Username, pgc.Password)
-	dataSource, err := sql.Open("mysql", connectionString)
+func (mc *MySQLContainer) CountRows(table string) (int, error) {
+	dataSource, err := sql.Open("mysql", mc.connectionString())
 	if err != nil {
 		return -1, err
 	}
-	rows, err := dataSource.Query(fmt.Sprintf("SELECT count(*) from %s", table))
-	if err != nil {
-		errMessage := err.Error()
-		if strings.HasPrefix(errMessage, "pq: relation") && strings.HasSuffix(errMessage, "does not exist") {
-			return 0, err
-		}
+	defer dataSource.Close()
 
+	rows, err := dataSource.Query(fmt.Sprintf("SELECT count(*) from `%s`", table))
+	if err != nil {
 		return -1, err
 	}
 	defer rows.Close()
@@ -107,14 +101,14 @@ func (pgc *MysqlContainer) CountRows(table string) (int, error) {
 
 //GetAllSortedRows returns all selected row from table ordered according to orderClause
 //or error if occurred
-func (pgc *MysqlContainer) GetAllSortedRows(table, orderClause string) ([]map[string]interface{}, error) {
-	connectionString := fmt.Sprintf("host=%s port=%d dbname=%s user=%s password=%s sslmode=disable",
-		pgc.Host, pgc.Port, pgc.Database, pgc.Username, pgc.Password)
-	dataSource, err := sql.Open("mysql", connectionString)
+func (mc *MySQLContainer) GetAllSortedRows(table, orderClause string) ([]map[string]interface{}, error) {
+	dataSource, err := sql.Open("mysql", mc.connectionString())
 	if err != nil {
 		return nil, err
 	}
-	rows, err := dataSource.Query(fmt.Sprintf("SELECT * from %s %s", table, orderClause))
+	defer dataSource.Close()
+
+	rows, err := dataSource.Query(fmt.Sprintf("SELECT * from `%s` %s", table, orderClause))
 	if err != nil {
 		return nil, err
 	}
@@ -137,10 +131,14 @@ func (pgc *MysqlContainer) GetAllSortedRows(table, orderClause string) ([]map[st
 
 		// Create our map, and retrieve the value for each column from the pointers slice,
 		// storing it in the map with the name of the column as the key.
+		//MySQL driver returns text values as []byte
 		object := make(map[string]interface{})
 		for i, colName := range cols {
-			val := columnPointers[i].(*interface{})
-			object[colName] = *val
+			val := *columnPointers[i].(*interface{})
+			if b, ok := val.([]byte); ok {
+				val = string(b)
+			}
+			object[colName] = val
 		}
 
 		objects = append(objects, object)
@@ -150,11 +148,15 @@ func (pgc *MysqlContainer) GetAllSortedRows(table, orderClause string) ([]map[st
 }
 
 //Close terminates underlying docker container
-func (pgc *MysqlContainer) Close() {
-	if pgc.Container != nil {
-		err := pgc.Container.Terminate(pgc.Context)
+func (mc *MySQLContainer) Close() {
+	if mc.Container != nil {
+		err := mc.Container.Terminate(mc.Context)
 		if err != nil {
 			logging.Error("Failed to stop container")
 		}
 	}
 }
+
+func (mc *MySQLContainer) connectionString() string {
+	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s", mc.Username, mc.Password, mc.Host, mc.Port, mc.Database)
+}
@@ -1,6 +1,7 @@
 package typing
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
@@ -31,6 +32,14 @@ var (
 		right: &typeNode{t: TIMESTAMP},
 	}
 
+	//typecastTreeTypes is a Typecast tree representation of types which aren't a part of the tree
+	typecastTreeTypes = map[DataType]DataType{
+		JSON:    STRING,
+		ARRAY:   STRING,
+		DATE:    TIMESTAMP,
+		DECIMAL: FLOAT64,
+	}
+
 	DefaultTypes = map[string]DataType{
 		timestamp.Key:               TIMESTAMP,
 		"eventn_ctx_utc_time":       TIMESTAMP,
@@ -42,6 +51,8 @@ var (
 		rule{from: INT64, to: STRING}:     numberToString,
 		rule{from: FLOAT64, to: STRING}:   numberToString,
 		rule{from: TIMESTAMP, to: STRING}: timestampToString,
+		rule{from: JSON, to: STRING}:      jsonToString,
+		rule{from: ARRAY, to: STRING}:     jsonToString,
 
 		rule{from: BOOL, to: INT64}: boolToNumber,
 
@@ -107,8 +118,32 @@ func Convert(toType DataType, v interface{}) (interface{}, error) {
 }
 
 //GetCommonAncestorType returns lowest common ancestor type
+//types which aren't a part of Typecast tree are compared as their tree representations (see typecastTreeTypes):
+//common type of JSON and ARRAY is JSON, DATE and TIMESTAMP is TIMESTAMP, DECIMAL and any number is DECIMAL
 func GetCommonAncestorType(t1, t2 DataType) DataType {
-	return lowestCommonAncestor(typecastTree, t1, t2)
+	if t1 == t2 {
+		return t1
+	}
+
+	if (t1 == JSON || t1 == ARRAY) && (t2 == JSON || t2 == ARRAY) {
+		return JSON
+	}
+
+	common := lowestCommonAncestor(typecastTree, typecastTreeType(t1), typecastTreeType(t2))
+	if common == FLOAT64 && (t1 == DECIMAL || t2 == DECIMAL) {
+		return DECIMAL
+	}
+
+	return common
+}
+
+//typecastTreeType returns type representation in Typecast tree
+func typecastTreeType(t DataType) DataType {
+	if treeType, ok := typecastTreeTypes[t]; ok {
+		return treeType
+	}
+
+	return t
 }
 
 func lowestCommonAncestor(root *typeNode, t1, t2 DataType) DataType {
@@ -178,6 +213,16 @@ func timestampToString(v interface{}) (interface{}, error) {
 	}
 }
 
+//jsonToString returns JSON representation of nested object or array
+func jsonToString(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("Error jsonToString() for value: %v: %v", v, err)
+	}
+
+	return string(b), nil
+}
+
 func numberToFloat(v interface{}) (interface{}, error) {
 	switch v.(type) {
 	case int:
@@ -57,6 +57,20 @@ func TestConvert(t *testing.T) {
 			"2020-07-20T10:15:23.000000Z",
 			"",
 		},
+		{
+			"object -> string",
+			map[string]interface{}{"key": []interface{}{1, "a"}},
+			STRING,
+			`{"key":[1,"a"]}`,
+			"",
+		},
+		{
+			"array -> string",
+			[]interface{}{1, "a", nil},
+			STRING,
+			`[1,"a",null]`,
+			"",
+		},
 		{
 			"int -> float",
 			123,
@@ -262,6 +276,72 @@ func TestGetCommonAncestorType(t *testing.T) {
 			TIMESTAMP,
 			STRING,
 		},
+		{
+			"json+json=json",
+			JSON,
+			JSON,
+			JSON,
+		},
+		{
+			"int64+json=string",
+			INT64,
+			JSON,
+			STRING,
+		},
+		{
+			"json+timestamp=string",
+			JSON,
+			TIMESTAMP,
+			STRING,
+		},
+		{
+			"array+array=array",
+			ARRAY,
+			ARRAY,
+			ARRAY,
+		},
+		{
+			"array+json=json",
+			ARRAY,
+			JSON,
+			JSON,
+		},
+		{
+			"array+string=string",
+			STRING,
+			ARRAY,
+			STRING,
+		},
+		{
+			"date+timestamp=timestamp",
+			DATE,
+			TIMESTAMP,
+			TIMESTAMP,
+		},
+		{
+			"date+int64=string",
+			DATE,
+			INT64,
+			STRING,
+		},
+		{
+			"decimal+int64=decimal",
+			INT64,
+			DECIMAL,
+			DECIMAL,
+		},
+		{
+			"decimal+float64=decimal",
+			DECIMAL,
+			FLOAT64,
+			DECIMAL,
+		},
+		{
+			"decimal+timestamp=string",
+			DECIMAL,
+			TIMESTAMP,
+			STRING,
+		},
 	}
 
 	for _, tt := range tests {
@@ -3,6 +3,7 @@ package typing
 import (
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"strings"
 	"time"
 
@@ -27,6 +28,14 @@ const (
 	STRING
 	//TIMESTAMP type for string values that match timestamp pattern
 	TIMESTAMP
+	//JSON type for serialized JSON objects (isn't a part of Typecast tree)
+	JSON
+	//ARRAY type for arrays values (isn't a part of Typecast tree)
+	ARRAY
+	//DATE type for date values without time (isn't a part of Typecast tree)
+	DATE
+	//DECIMAL type for exact numeric values (isn't a part of Typecast tree)
+	DECIMAL
 )
 
 var (
@@ -36,6 +45,10 @@ var (
 		"double":    FLOAT64,
 		"timestamp": TIMESTAMP,
 		"boolean":   BOOL,
+		"json":      JSON,
+		"array":     ARRAY,
+		"date":      DATE,
+		"decimal":   DECIMAL,
 	}
 	typeToInputString = map[DataType]string{
 		STRING:    "string",
@@ -43,6 +56,10 @@ var (
 		FLOAT64:   "double",
 		TIMESTAMP: "timestamp",
 		BOOL:      "boolean",
+		JSON:      "json",
+		ARRAY:     "array",
+		DATE:      "date",
+		DECIMAL:   "decimal",
 	}
 )
 
@@ -60,6 +77,14 @@ func (dt DataType) String() string {
 		return "TIMESTAMP"
 	case BOOL:
 		return "BOOL"
+	case JSON:
+		return "JSON"
+	case ARRAY:
+		return "ARRAY"
+	case DATE:
+		return "DATE"
+	case DECIMAL:
+		return "DECIMAL"
 	case UNKNOWN:
 		return "UNKNOWN"
 	}
@@ -130,6 +155,7 @@ func ReformatTimeValue(value interface{}) interface{} {
 }
 
 //TypeFromValue return DataType from v type
+//nested objects are JSON and slices are ARRAY (they are kept by schema.Flattener only with native types configuration)
 func TypeFromValue(v interface{}) (DataType, error) {
 	switch v.(type) {
 	case string:
@@ -142,7 +168,45 @@ func TypeFromValue(v interface{}) (DataType, error) {
 		return TIMESTAMP, nil
 	case bool:
 		return BOOL, nil
+	case map[string]interface{}:
+		return JSON, nil
 	default:
+		if v != nil && reflect.TypeOf(v).Kind() == reflect.Slice {
+			return ARRAY, nil
+		}
 		return UNKNOWN, fmt.Errorf("Unknown DataType for value: %v type: %t", v, v)
 	}
 }
+
+//ArrayElementType returns common ancestor type of array elements (see GetCommonAncestorType)
+//json.Number elements are INT64 or FLOAT64, nested objects and arrays are JSON, nil elements are skipped
+//returns UNKNOWN if v isn't an array or doesn't have not nil elements (e.g. empty array)
+func ArrayElementType(v interface{}) DataType {
+	arrayValue := reflect.ValueOf(v)
+	if arrayValue.Kind() != reflect.Slice {
+		return UNKNOWN
+	}
+
+	elementType, found := UNKNOWN, false
+	for i := 0; i < arrayValue.Len(); i++ {
+		element := ReformatValue(arrayValue.Index(i).Interface())
+		if element == nil {
+			continue
+		}
+
+		t, err := TypeFromValue(element)
+		if err != nil {
+			t = STRING
+		} else if t == ARRAY {
+			t = JSON
+		}
+
+		if found {
+			elementType = GetCommonAncestorType(elementType, t)
+		} else {
+			elementType, found = t, true
+		}
+	}
+
+	return elementType
+}
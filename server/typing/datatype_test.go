@@ -16,6 +16,10 @@ func TestDataTypeValues(t *testing.T) {
 	require.Equal(t, DataType(3), FLOAT64)
 	require.Equal(t, DataType(4), STRING)
 	require.Equal(t, DataType(5), TIMESTAMP)
+	require.Equal(t, DataType(6), JSON)
+	require.Equal(t, DataType(7), ARRAY)
+	require.Equal(t, DataType(8), DATE)
+	require.Equal(t, DataType(9), DECIMAL)
 }
 
 func TestTypeFromString(t *testing.T) {
@@ -61,6 +65,30 @@ func TestTypeFromString(t *testing.T) {
 			TIMESTAMP,
 			"",
 		},
+		{
+			"Json ok",
+			"json",
+			JSON,
+			"",
+		},
+		{
+			"Array ok",
+			"array",
+			ARRAY,
+			"",
+		},
+		{
+			"Date ok",
+			"date",
+			DATE,
+			"",
+		},
+		{
+			"Decimal ok",
+			"Decimal",
+			DECIMAL,
+			"",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -215,6 +243,24 @@ func TestTypeFromValue(t *testing.T) {
 			BOOL,
 			"",
 		},
+		{
+			"object ok",
+			map[string]interface{}{"key": 1},
+			JSON,
+			"",
+		},
+		{
+			"array ok",
+			[]interface{}{1, "a"},
+			ARRAY,
+			"",
+		},
+		{
+			"typed array ok",
+			[]string{},
+			ARRAY,
+			"",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -229,6 +275,70 @@ func TestTypeFromValue(t *testing.T) {
 	}
 }
 
+func TestArrayElementType(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    interface{}
+		expected DataType
+	}{
+		{
+			"Not an array",
+			"abc",
+			UNKNOWN,
+		},
+		{
+			"Empty array",
+			[]interface{}{},
+			UNKNOWN,
+		},
+		{
+			"Nil elements",
+			[]interface{}{nil, nil},
+			UNKNOWN,
+		},
+		{
+			"Strings",
+			[]string{"a", "b"},
+			STRING,
+		},
+		{
+			"Integers with nil",
+			[]interface{}{json.Number("1"), nil, 2},
+			INT64,
+		},
+		{
+			"Integers and floats",
+			[]interface{}{json.Number("1"), json.Number("2.5")},
+			FLOAT64,
+		},
+		{
+			"Booleans",
+			[]interface{}{true, false},
+			BOOL,
+		},
+		{
+			"Numbers and strings",
+			[]interface{}{1, "a"},
+			STRING,
+		},
+		{
+			"Nested objects and arrays",
+			[]interface{}{map[string]interface{}{"a": 1}, []interface{}{1}},
+			JSON,
+		},
+		{
+			"Nested objects and numbers",
+			[]interface{}{map[string]interface{}{"a": 1}, 1},
+			STRING,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, ArrayElementType(tt.input))
+		})
+	}
+}
+
 func TestReformat(t *testing.T) {
 	tests := []struct {
 		name         string